@@ -19,12 +19,17 @@ type AggregateFunction interface {
 	MergeDecimal(latestState *AggState, aggState *AggState, index int, reverse bool) error
 
 	ValueType() common.ColumnType
+	ArgType() common.ColumnType
 	ArgExpression() *common.Expression
 	RequiresExtraState() bool
+	// RequiresValueCounts returns true if the function needs the count of each of the values it has seen, see
+	// ValueCounts
+	RequiresValueCounts() bool
 }
 
 type aggregateFunctionBase struct {
 	argExpression *common.Expression
+	argType       common.ColumnType
 	valueType     common.ColumnType
 }
 
@@ -74,12 +79,25 @@ const (
 	SumAggregateFunctionType AggFunctionType = iota
 	CountAggregateFunctionType
 	FirstRowAggregateFunctionType
+	MinAggregateFunctionType
+	MaxAggregateFunctionType
+	AvgAggregateFunctionType
+	VarPopAggregateFunctionType
+	VarSampAggregateFunctionType
+	StddevPopAggregateFunctionType
+	StddevSampAggregateFunctionType
 )
 
 func (b *aggregateFunctionBase) ValueType() common.ColumnType {
 	return b.valueType
 }
 
+// ArgType is the type the argument is evaluated as. This is the same as the value type unless the function is over
+// distinct values.
+func (b *aggregateFunctionBase) ArgType() common.ColumnType {
+	return b.argType
+}
+
 func (b *aggregateFunctionBase) ArgExpression() *common.Expression {
 	return b.argExpression
 }
@@ -88,8 +106,12 @@ func (b *aggregateFunctionBase) RequiresExtraState() bool {
 	return false
 }
 
+func (b *aggregateFunctionBase) RequiresValueCounts() bool {
+	return false
+}
+
 func NewAggregateFunction(argExpression *common.Expression, funcType AggFunctionType, valueType common.ColumnType) (AggregateFunction, error) {
-	base := aggregateFunctionBase{argExpression: argExpression, valueType: valueType}
+	base := aggregateFunctionBase{argExpression: argExpression, argType: valueType, valueType: valueType}
 	switch funcType {
 	case SumAggregateFunctionType:
 		return &SumAggregateFunction{aggregateFunctionBase: base}, nil
@@ -97,7 +119,36 @@ func NewAggregateFunction(argExpression *common.Expression, funcType AggFunction
 		return &CountAggregateFunction{aggregateFunctionBase: base}, nil
 	case FirstRowAggregateFunctionType:
 		return &FirstRowAggregateFunction{aggregateFunctionBase: base}, nil
+	case MinAggregateFunctionType:
+		return &MinMaxAggregateFunction{aggregateFunctionBase: base}, nil
+	case MaxAggregateFunctionType:
+		return &MinMaxAggregateFunction{aggregateFunctionBase: base, max: true}, nil
+	case AvgAggregateFunctionType:
+		return &AvgAggregateFunction{aggregateFunctionBase: base}, nil
+	case VarPopAggregateFunctionType:
+		return &VarianceAggregateFunction{aggregateFunctionBase: base}, nil
+	case VarSampAggregateFunctionType:
+		return &VarianceAggregateFunction{aggregateFunctionBase: base, sample: true}, nil
+	case StddevPopAggregateFunctionType:
+		return &VarianceAggregateFunction{aggregateFunctionBase: base, stddev: true}, nil
+	case StddevSampAggregateFunctionType:
+		return &VarianceAggregateFunction{aggregateFunctionBase: base, stddev: true, sample: true}, nil
 	default:
 		return nil, errors.Errorf("unexpected aggregate function type %d", funcType)
 	}
 }
+
+// NewDistinctAggregateFunction creates an aggregate function over the distinct values of its argument, e.g.
+// COUNT(DISTINCT x). The argument is evaluated as argType.
+func NewDistinctAggregateFunction(argExpression *common.Expression, funcType AggFunctionType, argType common.ColumnType,
+	valueType common.ColumnType) (AggregateFunction, error) {
+	if funcType != SumAggregateFunctionType && funcType != CountAggregateFunctionType {
+		return nil, errors.Errorf("unexpected distinct aggregate function type %d", funcType)
+	}
+	inner, err := NewAggregateFunction(nil, funcType, valueType)
+	if err != nil {
+		return nil, err
+	}
+	base := aggregateFunctionBase{argExpression: argExpression, argType: argType, valueType: valueType}
+	return &DistinctAggregateFunction{aggregateFunctionBase: base, inner: inner}, nil
+}
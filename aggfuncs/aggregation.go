@@ -0,0 +1,196 @@
+package aggfuncs
+
+import (
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/tidb/expression"
+	"github.com/squareup/pranadb/tidb/expression/aggregation"
+)
+
+// AggregateFunctionInfo describes an aggregate function of an aggregation
+type AggregateFunctionInfo struct {
+	FuncType   AggFunctionType
+	Distinct   bool
+	ArgExpr    *common.Expression
+	ArgType    common.ColumnType // only used for distinct functions, where the argument can be of a different type
+	ReturnType common.ColumnType
+}
+
+// NewAggregateFunctionInfo creates the AggregateFunctionInfo for an aggregate function chosen by the planner
+func NewAggregateFunctionInfo(funcDesc *aggregation.AggFuncDesc) (*AggregateFunctionInfo, error) {
+	argExprs := funcDesc.Args
+	if len(argExprs) > 1 {
+		return nil, errors.Error("more than one aggregate function arg")
+	}
+	var argExpr *common.Expression
+	if len(argExprs) == 1 {
+		argExpr = common.NewExpression(argExprs[0])
+	}
+	var funcType AggFunctionType
+	switch funcDesc.Name {
+	case "sum":
+		funcType = SumAggregateFunctionType
+	case "count":
+		funcType = CountAggregateFunctionType
+	case "firstrow":
+		funcType = FirstRowAggregateFunctionType
+	case "min":
+		funcType = MinAggregateFunctionType
+	case "max":
+		funcType = MaxAggregateFunctionType
+	case "avg":
+		funcType = AvgAggregateFunctionType
+	case "var_pop":
+		funcType = VarPopAggregateFunctionType
+	case "var_samp":
+		funcType = VarSampAggregateFunctionType
+	case "stddev_pop":
+		funcType = StddevPopAggregateFunctionType
+	case "stddev_samp":
+		funcType = StddevSampAggregateFunctionType
+	default:
+		return nil, errors.Errorf("unexpected aggregate function %s", funcDesc.Name)
+	}
+	distinct := funcDesc.HasDistinct
+	if distinct && (funcType == MinAggregateFunctionType || funcType == MaxAggregateFunctionType) {
+		// The min and max of the distinct values are the same as the min and max of all values
+		distinct = false
+	}
+	if distinct && funcType != CountAggregateFunctionType && funcType != SumAggregateFunctionType {
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "DISTINCT is not supported with %s", funcDesc.Name)
+	}
+	info := &AggregateFunctionInfo{
+		FuncType:   funcType,
+		Distinct:   distinct,
+		ArgExpr:    argExpr,
+		ReturnType: common.ConvertTiDBTypeToPranaType(funcDesc.RetTp),
+	}
+	if distinct {
+		info.ArgType = common.ConvertTiDBTypeToPranaType(argExprs[0].GetType())
+	}
+	return info, nil
+}
+
+// FirstRowOfColumn returns the index of the FIRSTROW aggregate function that selects the column, or -1 if there isn't
+// one
+func FirstRowOfColumn(funcDescs []*aggregation.AggFuncDesc, infos []*AggregateFunctionInfo, col *expression.Column) int {
+	for i, funcDesc := range funcDescs {
+		if funcDesc.Name != "firstrow" || infos[i].ArgExpr == nil {
+			continue
+		}
+		if argCol, ok := funcDesc.Args[0].(*expression.Column); ok && argCol.Index == col.Index {
+			return i
+		}
+	}
+	return -1
+}
+
+func CreateAggregateFunctions(infos []*AggregateFunctionInfo) ([]AggregateFunction, error) {
+	aggFuncs := make([]AggregateFunction, len(infos))
+	for index, funcInfo := range infos {
+		var aggFunc AggregateFunction
+		var err error
+		if funcInfo.Distinct {
+			aggFunc, err = NewDistinctAggregateFunction(funcInfo.ArgExpr, funcInfo.FuncType, funcInfo.ArgType, funcInfo.ReturnType)
+		} else {
+			aggFunc, err = NewAggregateFunction(funcInfo.ArgExpr, funcInfo.FuncType, funcInfo.ReturnType)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		aggFuncs[index] = aggFunc
+	}
+	return aggFuncs, nil
+}
+
+// EvaluateAggregateFunctions adds a row to, or removes it from, the aggregate state. Functions without an argument are
+// not evaluated.
+func EvaluateAggregateFunctions(aggFuncs []AggregateFunction, aggState *AggState, row *common.Row, reverse bool) error {
+	for index, aggFunc := range aggFuncs {
+		if aggFunc.ArgExpression() == nil {
+			continue
+		}
+		switch aggFunc.ArgType().Type {
+		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+			arg, null, err := aggFunc.ArgExpression().EvalInt64(row)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = aggFunc.EvalInt64(arg, null, aggState, index, reverse)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		case common.TypeDecimal:
+			arg, null, err := aggFunc.ArgExpression().EvalDecimal(row)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = aggFunc.EvalDecimal(arg, null, aggState, index, reverse)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		case common.TypeDouble:
+			arg, null, err := aggFunc.ArgExpression().EvalFloat64(row)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = aggFunc.EvalFloat64(arg, null, aggState, index, reverse)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		case common.TypeVarchar, common.TypeVarbinary:
+			arg, null, err := aggFunc.ArgExpression().EvalString(row)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = aggFunc.EvalString(arg, null, aggState, index, reverse)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		case common.TypeTimestamp:
+			arg, null, err := aggFunc.ArgExpression().EvalTimestamp(row)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			err = aggFunc.EvalTimestamp(arg, null, aggState, index, reverse)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		default:
+			return errors.Errorf("unexpected column type %d", aggFunc.ArgType())
+		}
+	}
+	return nil
+}
+
+// MergeAggregateFunctions merges the aggregate state of a partial aggregation into, or removes it from, the state of
+// the full aggregation
+func MergeAggregateFunctions(aggFuncs []AggregateFunction, toMerge *AggState, currState *AggState, reverse bool) error {
+	for index, aggFunc := range aggFuncs {
+		switch aggFunc.ValueType().Type {
+		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+			if err := aggFunc.MergeInt64(toMerge, currState, index, reverse); err != nil {
+				return err
+			}
+		case common.TypeDecimal:
+			if err := aggFunc.MergeDecimal(toMerge, currState, index, reverse); err != nil {
+				return err
+			}
+		case common.TypeDouble:
+			if err := aggFunc.MergeFloat64(toMerge, currState, index, reverse); err != nil {
+				return err
+			}
+		case common.TypeVarchar, common.TypeVarbinary:
+			if err := aggFunc.MergeString(toMerge, currState, index, reverse); err != nil {
+				return err
+			}
+		case common.TypeTimestamp:
+			if err := aggFunc.MergeTimestamp(toMerge, currState, index, reverse); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("unexpected column type %d", aggFunc.ValueType())
+		}
+	}
+	return nil
+}
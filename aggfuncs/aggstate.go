@@ -16,6 +16,7 @@ type AggState struct {
 	changed      bool
 	size         int
 	extraState   [][]byte
+	valueCounts  ValueCounts
 }
 
 func NewAggState(size int) *AggState {
@@ -40,6 +41,7 @@ func (as *AggState) IsNull(index int) bool {
 func (as *AggState) SetInt64(index int, val int64) {
 	as.set[index] = true
 	as.changed = true
+	as.null[index] = false
 	ptrInt64 := (*int64)(unsafe.Pointer(&as.state[index])) // nolint: gosec
 	*ptrInt64 = val
 }
@@ -52,6 +54,7 @@ func (as *AggState) GetInt64(index int) int64 {
 func (as *AggState) SetFloat64(index int, val float64) {
 	as.set[index] = true
 	as.changed = true
+	as.null[index] = false
 	ptrFloat64 := (*float64)(unsafe.Pointer(&as.state[index])) // nolint: gosec
 	*ptrFloat64 = val
 }
@@ -64,6 +67,7 @@ func (as *AggState) GetFloat64(index int) float64 {
 func (as *AggState) SetString(index int, val string) {
 	as.set[index] = true
 	as.changed = true
+	as.null[index] = false
 	as.checkCreateStrState()
 	as.strState[index] = val
 }
@@ -101,9 +105,19 @@ func (as *AggState) checkCreateExtraState() {
 	}
 }
 
+// SetValueCounts sets where the functions that require value counts keep them
+func (as *AggState) SetValueCounts(valueCounts ValueCounts) {
+	as.valueCounts = valueCounts
+}
+
+func (as *AggState) GetValueCounts() ValueCounts {
+	return as.valueCounts
+}
+
 func (as *AggState) SetDecimal(index int, val common.Decimal) error {
 	as.set[index] = true
 	as.changed = true
+	as.null[index] = false
 	as.checkCreateDecimalState()
 	as.decimalState[index] = val
 	return nil
@@ -119,6 +133,9 @@ func (as *AggState) GetDecimal(index int) common.Decimal {
 func (as *AggState) checkCreateDecimalState() {
 	if as.decimalState == nil {
 		as.decimalState = make([]common.Decimal, as.size)
+		for i := range as.decimalState {
+			as.decimalState[i] = *common.ZeroDecimal()
+		}
 	}
 }
 
@@ -149,3 +166,61 @@ func (as *AggState) IsSet(index int) bool {
 func (as *AggState) IsChanged() bool {
 	return as.changed
 }
+
+// InitWithRow sets the aggregate state from the first len(colTypes) columns of a row
+func (as *AggState) InitWithRow(row *common.Row, colTypes []common.ColumnType) error {
+	for i, colType := range colTypes {
+		if row.IsNull(i) {
+			as.SetNull(i)
+		} else {
+			switch colType.Type {
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+				as.SetInt64(i, row.GetInt64(i))
+			case common.TypeDecimal:
+				if err := as.SetDecimal(i, row.GetDecimal(i)); err != nil {
+					return errors.WithStack(err)
+				}
+			case common.TypeDouble:
+				as.SetFloat64(i, row.GetFloat64(i))
+			case common.TypeVarchar, common.TypeVarbinary:
+				as.SetString(i, row.GetString(i))
+			case common.TypeTimestamp:
+				if err := as.SetTimestamp(i, row.GetTimestamp(i)); err != nil {
+					return errors.WithStack(err)
+				}
+			default:
+				return errors.Errorf("unexpected column type %d", colType)
+			}
+		}
+	}
+	return nil
+}
+
+// AppendToRows appends the aggregate state to the first len(colTypes) columns of rows
+func (as *AggState) AppendToRows(rows *common.Rows, colTypes []common.ColumnType) error {
+	for i, colType := range colTypes {
+		if as.IsNull(i) {
+			rows.AppendNullToColumn(i)
+		} else {
+			switch colType.Type {
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+				rows.AppendInt64ToColumn(i, as.GetInt64(i))
+			case common.TypeDecimal:
+				rows.AppendDecimalToColumn(i, as.GetDecimal(i))
+			case common.TypeDouble:
+				rows.AppendFloat64ToColumn(i, as.GetFloat64(i))
+			case common.TypeVarchar, common.TypeVarbinary:
+				rows.AppendStringToColumn(i, as.GetString(i))
+			case common.TypeTimestamp:
+				ts, err := as.GetTimestamp(i)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				rows.AppendTimestampToColumn(i, ts)
+			default:
+				return errors.Errorf("unexpected column type %d", colType)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package aggfuncs
+
+import (
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+// DISTINCT
+// ========
+
+// DistinctAggregateFunction calculates an aggregate function over the distinct values of its argument, e.g.
+// COUNT(DISTINCT x). It keeps a count of each value in the value counts of the aggregation, and a value is only passed
+// on to the inner function when it is seen for the first time or when the last row with the value is removed. These
+// changes to the distinct values are kept in the extra state, which is sent to the full aggregation but never stored,
+// and the full aggregation merges the changes from each partial aggregation in the same way.
+type DistinctAggregateFunction struct {
+	aggregateFunctionBase
+	inner AggregateFunction
+}
+
+func (d *DistinctAggregateFunction) RequiresExtraState() bool {
+	return true
+}
+
+func (d *DistinctAggregateFunction) RequiresValueCounts() bool {
+	return true
+}
+
+func (d *DistinctAggregateFunction) EvalInt64(value int64, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return d.update(value, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) EvalFloat64(value float64, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return d.update(value, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) EvalString(value string, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return d.update(value, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) EvalTimestamp(value common.Timestamp, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return d.update(value, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) EvalDecimal(value common.Decimal, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return d.update(value, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) MergeInt64(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	return d.merge(latestState, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) MergeFloat64(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	return d.merge(latestState, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) MergeString(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	return d.merge(latestState, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) MergeTimestamp(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	return d.merge(latestState, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) MergeDecimal(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	return d.merge(latestState, aggState, index, reverse)
+}
+
+func (d *DistinctAggregateFunction) update(value interface{}, aggState *AggState, index int, reverse bool) error {
+	delta := int64(1)
+	if reverse {
+		delta = -1
+	}
+	return d.add(value, delta, aggState, index)
+}
+
+// merge applies the changes to the distinct values of a partial aggregation
+func (d *DistinctAggregateFunction) merge(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	changes, err := decodeValueCounts(latestState.GetExtraState(index), d.ArgType())
+	if err != nil {
+		return err
+	}
+	for _, vc := range changes {
+		delta := vc.count
+		if reverse {
+			delta = -delta
+		}
+		if err := d.add(vc.value, delta, aggState, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DistinctAggregateFunction) add(value interface{}, delta int64, aggState *AggState, index int) error {
+	prevCount, newCount, err := aggState.GetValueCounts().Add(index, value, delta)
+	if err != nil {
+		return err
+	}
+	if prevCount == 0 && newCount != 0 {
+		if err := d.evalInner(value, aggState, index, false); err != nil {
+			return err
+		}
+		return d.addChange(value, 1, aggState, index)
+	}
+	if prevCount != 0 && newCount == 0 {
+		if err := d.evalInner(value, aggState, index, true); err != nil {
+			return err
+		}
+		return d.addChange(value, -1, aggState, index)
+	}
+	return nil
+}
+
+// addChange records that a value has been added to, or removed from, the distinct values
+func (d *DistinctAggregateFunction) addChange(value interface{}, delta int64, aggState *AggState, index int) error {
+	changes, err := decodeValueCounts(aggState.GetExtraState(index), d.ArgType())
+	if err != nil {
+		return err
+	}
+	changes, _, _ = changes.change(value, delta)
+	extraState, err := encodeValueCounts(changes, d.ArgType())
+	if err != nil {
+		return err
+	}
+	aggState.SetExtraState(index, extraState)
+	return nil
+}
+
+func (d *DistinctAggregateFunction) evalInner(value interface{}, aggState *AggState, index int, reverse bool) error {
+	switch d.ValueType().Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+		// COUNT only needs to know that there is a value, and it can be of any type
+		return d.inner.EvalInt64(0, false, aggState, index, reverse)
+	case common.TypeDouble:
+		return d.inner.EvalFloat64(value.(float64), false, aggState, index, reverse) //nolint:forcetypeassert
+	case common.TypeDecimal:
+		return d.inner.EvalDecimal(value.(common.Decimal), false, aggState, index, reverse) //nolint:forcetypeassert
+	default:
+		return errors.Errorf("unexpected column type %d", d.ValueType().Type)
+	}
+}
@@ -0,0 +1,143 @@
+package aggfuncs
+
+import (
+	"github.com/squareup/pranadb/common"
+)
+
+// MIN and MAX
+// ===========
+
+// MinMaxAggregateFunction calculates MIN or MAX. The value can't be calculated from the previous value when the
+// current min or max is removed, so the function keeps a count of each value in the value counts of the aggregation,
+// and the result is then the next value with a non-zero count. In a partial aggregation these are the values from the
+// rows, and in the full aggregation they are the results of the partial aggregations.
+type MinMaxAggregateFunction struct {
+	aggregateFunctionBase
+	max bool
+}
+
+func (m *MinMaxAggregateFunction) RequiresValueCounts() bool {
+	return true
+}
+
+func (m *MinMaxAggregateFunction) EvalInt64(value int64, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return m.update(value, aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) EvalFloat64(value float64, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return m.update(value, aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) EvalString(value string, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return m.update(value, aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) EvalTimestamp(value common.Timestamp, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return m.update(value, aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) EvalDecimal(value common.Decimal, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return m.update(value, aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) MergeInt64(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	if latestState.IsNull(index) {
+		return nil
+	}
+	return m.update(latestState.GetInt64(index), aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) MergeFloat64(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	if latestState.IsNull(index) {
+		return nil
+	}
+	return m.update(latestState.GetFloat64(index), aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) MergeString(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	if latestState.IsNull(index) {
+		return nil
+	}
+	return m.update(latestState.GetString(index), aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) MergeTimestamp(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	if latestState.IsNull(index) {
+		return nil
+	}
+	ts, err := latestState.GetTimestamp(index)
+	if err != nil {
+		return err
+	}
+	return m.update(ts, aggState, index, reverse)
+}
+
+func (m *MinMaxAggregateFunction) MergeDecimal(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	if latestState.IsNull(index) {
+		return nil
+	}
+	return m.update(latestState.GetDecimal(index), aggState, index, reverse)
+}
+
+// update adds the value to, or removes it from, the values seen and sets the result to the new min or max. The
+// values only need to be searched when the last of the current min or max is removed.
+func (m *MinMaxAggregateFunction) update(value interface{}, aggState *AggState, index int, reverse bool) error {
+	delta := int64(1)
+	if reverse {
+		delta = -1
+	}
+	valueCounts := aggState.GetValueCounts()
+	_, newCount, err := valueCounts.Add(index, value, delta)
+	if err != nil {
+		return err
+	}
+	hasResult := aggState.IsSet(index) && !aggState.IsNull(index)
+	var result interface{}
+	if hasResult {
+		result, err = getValue(aggState, index, m.ValueType())
+		if err != nil {
+			return err
+		}
+	}
+	if !reverse {
+		if !hasResult || m.before(value, result) {
+			return setValue(value, aggState, index)
+		}
+		return nil
+	}
+	if newCount != 0 || !hasResult || compareValues(value, result) != 0 {
+		return nil
+	}
+	first, err := valueCounts.First(index)
+	if err != nil {
+		return err
+	}
+	if first == nil {
+		aggState.SetNull(index)
+		return nil
+	}
+	return setValue(first, aggState, index)
+}
+
+// before returns true if v1 would be the result ahead of v2
+func (m *MinMaxAggregateFunction) before(v1 interface{}, v2 interface{}) bool {
+	if m.max {
+		return compareValues(v1, v2) > 0
+	}
+	return compareValues(v1, v2) < 0
+}
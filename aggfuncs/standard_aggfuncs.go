@@ -49,11 +49,17 @@ func (s *SumAggregateFunction) EvalDecimal(value common.Decimal, null bool, aggS
 }
 
 func (s *SumAggregateFunction) MergeFloat64(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	if latestState.IsNull(index) {
+		return nil
+	}
 	value := latestState.GetFloat64(index)
 	return s.EvalFloat64(value, false, aggState, index, reverse)
 }
 
 func (s *SumAggregateFunction) MergeDecimal(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	if latestState.IsNull(index) {
+		return nil
+	}
 	value := latestState.GetDecimal(index)
 	return s.EvalDecimal(value, false, aggState, index, reverse)
 }
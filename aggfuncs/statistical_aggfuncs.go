@@ -0,0 +1,217 @@
+package aggfuncs
+
+import (
+	"math"
+
+	"github.com/pingcap/parser/mysql"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+// AVG
+// ===
+
+// AvgAggregateFunction calculates AVG. The sum and count of the values are kept in the extra state, and the full
+// aggregation adds up the sums and counts of the partial aggregations.
+type AvgAggregateFunction struct {
+	aggregateFunctionBase
+}
+
+type avgState struct {
+	count      int64
+	sumFloat64 float64
+	sumDecimal common.Decimal
+}
+
+func (a *AvgAggregateFunction) RequiresExtraState() bool {
+	return true
+}
+
+func (a *AvgAggregateFunction) EvalFloat64(value float64, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return a.update(&avgState{count: 1, sumFloat64: value}, aggState, index, reverse)
+}
+
+func (a *AvgAggregateFunction) EvalDecimal(value common.Decimal, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return a.update(&avgState{count: 1, sumDecimal: value}, aggState, index, reverse)
+}
+
+func (a *AvgAggregateFunction) MergeFloat64(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	return a.merge(latestState, aggState, index, reverse)
+}
+
+func (a *AvgAggregateFunction) MergeDecimal(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	return a.merge(latestState, aggState, index, reverse)
+}
+
+func (a *AvgAggregateFunction) merge(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	toMerge, err := a.decodeState(latestState.GetExtraState(index))
+	if err != nil {
+		return err
+	}
+	return a.update(toMerge, aggState, index, reverse)
+}
+
+func (a *AvgAggregateFunction) update(toAdd *avgState, aggState *AggState, index int, reverse bool) error {
+	state, err := a.decodeState(aggState.GetExtraState(index))
+	if err != nil {
+		return err
+	}
+	if reverse {
+		state.count -= toAdd.count
+	} else {
+		state.count += toAdd.count
+	}
+	var avg interface{}
+	if a.ValueType().Type == common.TypeDecimal {
+		var sum *common.Decimal
+		if reverse {
+			sum, err = state.sumDecimal.Subtract(&toAdd.sumDecimal)
+		} else {
+			sum, err = state.sumDecimal.Add(&toAdd.sumDecimal)
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		state.sumDecimal = *sum
+		if state.count != 0 {
+			decAvg, err := sum.Divide(common.NewDecFromInt64(state.count), a.ValueType().DecScale)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			avg = *decAvg
+		}
+	} else {
+		if reverse {
+			state.sumFloat64 -= toAdd.sumFloat64
+		} else {
+			state.sumFloat64 += toAdd.sumFloat64
+		}
+		if state.count != 0 {
+			avg = state.sumFloat64 / float64(state.count)
+		}
+	}
+	extraState, err := a.encodeState(state)
+	if err != nil {
+		return err
+	}
+	aggState.SetExtraState(index, extraState)
+	if state.count == 0 {
+		aggState.SetNull(index)
+		return nil
+	}
+	return setValue(avg, aggState, index)
+}
+
+func (a *AvgAggregateFunction) encodeState(state *avgState) ([]byte, error) {
+	buff := common.AppendUint64ToBufferLE(make([]byte, 0, 48), uint64(state.count))
+	if a.ValueType().Type == common.TypeDecimal {
+		// The sum can have more digits than the average
+		buff, err := common.AppendDecimalToBuffer(buff, state.sumDecimal, mysql.MaxDecimalWidth, a.ValueType().DecScale)
+		return buff, errors.WithStack(err)
+	}
+	return common.AppendFloat64ToBufferLE(buff, state.sumFloat64), nil
+}
+
+func (a *AvgAggregateFunction) decodeState(buff []byte) (*avgState, error) {
+	state := &avgState{sumDecimal: *common.ZeroDecimal()}
+	if buff == nil {
+		return state, nil
+	}
+	var offset int
+	state.count, offset = common.ReadInt64FromBufferLE(buff, 0)
+	if a.ValueType().Type == common.TypeDecimal {
+		var err error
+		state.sumDecimal, _, err = common.ReadDecimalFromBuffer(buff, offset, mysql.MaxDecimalWidth, a.ValueType().DecScale)
+		return state, errors.WithStack(err)
+	}
+	state.sumFloat64, _ = common.ReadFloat64FromBufferLE(buff, offset)
+	return state, nil
+}
+
+// VAR_POP, VAR_SAMP, STDDEV_POP and STDDEV_SAMP
+// =============================================
+
+// VarianceAggregateFunction calculates the population or sample variance or standard deviation. The count, sum and
+// sum of squares of the values are kept in the extra state, and the full aggregation adds up the states of the partial
+// aggregations.
+type VarianceAggregateFunction struct {
+	aggregateFunctionBase
+	sample bool
+	stddev bool
+}
+
+type varianceState struct {
+	count      int64
+	sum        float64
+	sumSquares float64
+}
+
+func (v *VarianceAggregateFunction) RequiresExtraState() bool {
+	return true
+}
+
+func (v *VarianceAggregateFunction) EvalFloat64(value float64, null bool, aggState *AggState, index int, reverse bool) error {
+	if null {
+		return nil
+	}
+	return v.update(&varianceState{count: 1, sum: value, sumSquares: value * value}, aggState, index, reverse)
+}
+
+func (v *VarianceAggregateFunction) MergeFloat64(latestState *AggState, aggState *AggState, index int, reverse bool) error {
+	toMerge := v.decodeState(latestState.GetExtraState(index))
+	return v.update(toMerge, aggState, index, reverse)
+}
+
+func (v *VarianceAggregateFunction) update(toAdd *varianceState, aggState *AggState, index int, reverse bool) error {
+	state := v.decodeState(aggState.GetExtraState(index))
+	if reverse {
+		state.count -= toAdd.count
+		state.sum -= toAdd.sum
+		state.sumSquares -= toAdd.sumSquares
+	} else {
+		state.count += toAdd.count
+		state.sum += toAdd.sum
+		state.sumSquares += toAdd.sumSquares
+	}
+	buff := common.AppendUint64ToBufferLE(make([]byte, 0, 24), uint64(state.count))
+	buff = common.AppendFloat64ToBufferLE(buff, state.sum)
+	buff = common.AppendFloat64ToBufferLE(buff, state.sumSquares)
+	aggState.SetExtraState(index, buff)
+
+	n := float64(state.count)
+	if v.sample {
+		n--
+	}
+	if n <= 0 {
+		aggState.SetNull(index)
+		return nil
+	}
+	variance := (state.sumSquares - state.sum*state.sum/float64(state.count)) / n
+	if variance < 0 {
+		// Rounding errors when rows are removed can leave a tiny negative variance
+		variance = 0
+	}
+	if v.stddev {
+		variance = math.Sqrt(variance)
+	}
+	aggState.SetFloat64(index, variance)
+	return nil
+}
+
+func (v *VarianceAggregateFunction) decodeState(buff []byte) *varianceState {
+	state := &varianceState{}
+	if buff == nil {
+		return state
+	}
+	var offset int
+	state.count, offset = common.ReadInt64FromBufferLE(buff, 0)
+	state.sum, offset = common.ReadFloat64FromBufferLE(buff, offset)
+	state.sumSquares, _ = common.ReadFloat64FromBufferLE(buff, offset)
+	return state
+}
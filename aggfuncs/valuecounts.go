@@ -0,0 +1,341 @@
+package aggfuncs
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/tidb/util/codec"
+)
+
+// ValueCounts holds the values seen by the aggregate functions that require them, see RequiresValueCounts, along with
+// how many times each value has been seen. A push aggregation keeps them as rows of its values table, keyed by group,
+// function and value, so finding the lowest or highest value is a scan over the first of the keys. A pull
+// aggregation keeps them in memory.
+type ValueCounts interface {
+	// Add adds delta to the count of the value for the function at index, removing the value if the count falls to
+	// zero. It returns the count of the value before and after the change.
+	Add(index int, value interface{}, delta int64) (int64, int64, error)
+	// First returns the first value with a non-zero count for the function at index, or nil if there isn't one. The
+	// values are in descending order for the functions that DescendingValues returns true for, ascending otherwise.
+	First(index int) (interface{}, error)
+}
+
+// DescendingValues returns true if the function wants the highest of its values first, i.e. it is MAX
+func DescendingValues(aggFunc AggregateFunction) bool {
+	minMax, ok := aggFunc.(*MinMaxAggregateFunction)
+	return ok && minMax.max
+}
+
+// NewMemValueCounts creates ValueCounts for the aggregate functions which are held in memory
+func NewMemValueCounts(aggFuncs []AggregateFunction) ValueCounts {
+	m := &memValueCounts{
+		values:     make([]valueCounts, len(aggFuncs)),
+		descending: make([]bool, len(aggFuncs)),
+	}
+	for i, aggFunc := range aggFuncs {
+		m.descending[i] = DescendingValues(aggFunc)
+	}
+	return m
+}
+
+type memValueCounts struct {
+	values     []valueCounts
+	descending []bool
+}
+
+func (m *memValueCounts) Add(index int, value interface{}, delta int64) (int64, int64, error) {
+	values, prevCount, newCount, err := m.values[index].add(value, delta)
+	if err != nil {
+		return 0, 0, err
+	}
+	m.values[index] = values
+	return prevCount, newCount, nil
+}
+
+func (m *memValueCounts) First(index int) (interface{}, error) {
+	values := m.values[index]
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if m.descending[index] {
+		return values[len(values)-1].value, nil
+	}
+	return values[0].value, nil
+}
+
+// valueCount is the number of times a value has been seen
+type valueCount struct {
+	value interface{}
+	count int64
+}
+
+// valueCounts holds the distinct values seen by an aggregate function along with how many times each one has been
+// seen, in ascending order of value
+type valueCounts []valueCount
+
+// add adds delta to the count of the value, removing the value if the count falls to zero. It returns the count of
+// the value before and after the change.
+func (v valueCounts) add(value interface{}, delta int64) (valueCounts, int64, int64, error) {
+	v, prevCount, newCount := v.change(value, delta)
+	if newCount < 0 {
+		return nil, 0, 0, errors.Errorf("count of value %v is negative", value)
+	}
+	return v, prevCount, newCount, nil
+}
+
+// change is like add, but the count can be negative, e.g. when the counts are changes to other counts
+func (v valueCounts) change(value interface{}, delta int64) (valueCounts, int64, int64) {
+	pos := sort.Search(len(v), func(i int) bool {
+		return compareValues(v[i].value, value) >= 0
+	})
+	found := pos < len(v) && compareValues(v[pos].value, value) == 0
+	var prevCount int64
+	if found {
+		prevCount = v[pos].count
+	}
+	newCount := prevCount + delta
+	switch {
+	case newCount == 0 && found:
+		v = append(v[:pos], v[pos+1:]...)
+	case newCount != 0 && found:
+		v[pos].count = newCount
+	case newCount != 0:
+		v = append(v, valueCount{})
+		copy(v[pos+1:], v[pos:])
+		v[pos] = valueCount{value: value, count: newCount}
+	}
+	return v, prevCount, newCount
+}
+
+func compareValues(v1 interface{}, v2 interface{}) int {
+	switch val1 := v1.(type) {
+	case int64:
+		val2 := v2.(int64) //nolint:forcetypeassert
+		switch {
+		case val1 < val2:
+			return -1
+		case val1 > val2:
+			return 1
+		}
+		return 0
+	case float64:
+		val2 := v2.(float64) //nolint:forcetypeassert
+		switch {
+		case val1 < val2:
+			return -1
+		case val1 > val2:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(val1, v2.(string)) //nolint:forcetypeassert
+	case common.Timestamp:
+		return val1.Compare(v2.(common.Timestamp)) //nolint:forcetypeassert
+	case common.Decimal:
+		val2 := v2.(common.Decimal) //nolint:forcetypeassert
+		return val1.CompareTo(&val2)
+	default:
+		panic(errors.Errorf("unexpected value type %T", v1))
+	}
+}
+
+// encodeValueCounts encodes the values as the number of distinct values, followed by each value and its count, e.g.
+// to send the changes to the distinct values of a partial aggregation to the full aggregation
+func encodeValueCounts(values valueCounts, valueType common.ColumnType) ([]byte, error) {
+	buff := common.AppendUint32ToBufferLE(make([]byte, 0, 4+len(values)*16), uint32(len(values)))
+	for _, vc := range values {
+		var err error
+		buff, err = appendValue(buff, vc.value, valueType)
+		if err != nil {
+			return nil, err
+		}
+		buff = common.AppendUint64ToBufferLE(buff, uint64(vc.count))
+	}
+	return buff, nil
+}
+
+func decodeValueCounts(buff []byte, valueType common.ColumnType) (valueCounts, error) {
+	if buff == nil {
+		return nil, nil
+	}
+	numValues, offset := common.ReadUint32FromBufferLE(buff, 0)
+	values := make(valueCounts, numValues)
+	for i := range values {
+		var value interface{}
+		var err error
+		value, offset, err = readValue(buff, offset, valueType)
+		if err != nil {
+			return nil, err
+		}
+		var count int64
+		count, offset = common.ReadInt64FromBufferLE(buff, offset)
+		values[i] = valueCount{value: value, count: count}
+	}
+	return values, nil
+}
+
+func appendValue(buff []byte, value interface{}, valueType common.ColumnType) ([]byte, error) {
+	switch valueType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+		return common.AppendUint64ToBufferLE(buff, uint64(value.(int64))), nil //nolint:forcetypeassert
+	case common.TypeDouble:
+		return common.AppendFloat64ToBufferLE(buff, value.(float64)), nil //nolint:forcetypeassert
+	case common.TypeVarchar, common.TypeVarbinary:
+		return common.AppendStringToBufferLE(buff, value.(string)), nil //nolint:forcetypeassert
+	case common.TypeTimestamp:
+		buff, err := common.AppendTimestampToBuffer(buff, value.(common.Timestamp)) //nolint:forcetypeassert
+		return buff, errors.WithStack(err)
+	case common.TypeDecimal:
+		// Encoded as a string so the value keeps its scale
+		dec := value.(common.Decimal) //nolint:forcetypeassert
+		return common.AppendStringToBufferLE(buff, dec.String()), nil
+	default:
+		return nil, errors.Errorf("unexpected column type %d", valueType.Type)
+	}
+}
+
+func readValue(buff []byte, offset int, valueType common.ColumnType) (interface{}, int, error) {
+	switch valueType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+		value, offset := common.ReadInt64FromBufferLE(buff, offset)
+		return value, offset, nil
+	case common.TypeDouble:
+		value, offset := common.ReadFloat64FromBufferLE(buff, offset)
+		return value, offset, nil
+	case common.TypeVarchar, common.TypeVarbinary:
+		value, offset := common.ReadStringFromBufferLE(buff, offset)
+		return value, offset, nil
+	case common.TypeTimestamp:
+		value, offset, err := common.ReadTimestampFromBuffer(buff, offset, valueType.FSP)
+		return value, offset, errors.WithStack(err)
+	case common.TypeDecimal:
+		str, offset := common.ReadStringFromBufferLE(buff, offset)
+		value, err := common.NewDecFromString(str)
+		if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+		return *value, offset, nil
+	default:
+		return nil, 0, errors.Errorf("unexpected column type %d", valueType.Type)
+	}
+}
+
+// EncodeValueKey appends the value to a key such that keys for values of the same type sort in the order of the
+// values, or in reverse order if descending is true
+func EncodeValueKey(key []byte, value interface{}, valueType common.ColumnType, descending bool) ([]byte, error) {
+	start := len(key)
+	switch valueType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+		key = common.KeyEncodeInt64(key, value.(int64)) //nolint:forcetypeassert
+	case common.TypeDouble:
+		key = common.KeyEncodeFloat64(key, value.(float64)) //nolint:forcetypeassert
+	case common.TypeVarchar, common.TypeVarbinary:
+		key = codec.EncodeBytes(key, []byte(value.(string))) //nolint:forcetypeassert
+	case common.TypeTimestamp:
+		var err error
+		key, err = common.KeyEncodeTimestamp(key, value.(common.Timestamp)) //nolint:forcetypeassert
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case common.TypeDecimal:
+		var err error
+		key, err = common.KeyEncodeDecimal(key, value.(common.Decimal), valueType.DecPrecision, valueType.DecScale) //nolint:forcetypeassert
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	default:
+		return nil, errors.Errorf("unexpected column type %d", valueType.Type)
+	}
+	if descending {
+		invertBytes(key[start:])
+	}
+	return key, nil
+}
+
+// DecodeValueKey decodes a value encoded with EncodeValueKey
+func DecodeValueKey(buff []byte, valueType common.ColumnType, descending bool) (interface{}, error) {
+	if descending {
+		buff = invertBytes(common.CopyByteSlice(buff))
+	}
+	switch valueType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+		u, _ := common.ReadUint64FromBufferBE(buff, 0)
+		return int64(u ^ common.SignBitMask), nil
+	case common.TypeDouble:
+		u, _ := common.ReadUint64FromBufferBE(buff, 0)
+		if u&common.SignBitMask != 0 {
+			u &^= common.SignBitMask
+		} else {
+			u = ^u
+		}
+		return math.Float64frombits(u), nil
+	case common.TypeVarchar, common.TypeVarbinary:
+		_, b, err := codec.DecodeBytes(buff, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return string(b), nil
+	case common.TypeTimestamp:
+		u, _ := common.ReadUint64FromBufferBE(buff, 0)
+		ts := common.Timestamp{}
+		if err := ts.FromPackedUint(u); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return ts, nil
+	case common.TypeDecimal:
+		dec := common.Decimal{}
+		if _, err := dec.Decode(buff, 0, valueType.DecPrecision, valueType.DecScale); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return dec, nil
+	default:
+		return nil, errors.Errorf("unexpected column type %d", valueType.Type)
+	}
+}
+
+func invertBytes(buff []byte) []byte {
+	for i, b := range buff {
+		buff[i] = ^b
+	}
+	return buff
+}
+
+// setValue sets the result of the function to the value
+func setValue(value interface{}, aggState *AggState, index int) error {
+	switch v := value.(type) {
+	case int64:
+		aggState.SetInt64(index, v)
+	case float64:
+		aggState.SetFloat64(index, v)
+	case string:
+		aggState.SetString(index, v)
+	case common.Timestamp:
+		return aggState.SetTimestamp(index, v)
+	case common.Decimal:
+		return aggState.SetDecimal(index, v)
+	default:
+		return errors.Errorf("unexpected value type %T", value)
+	}
+	return nil
+}
+
+// getValue gets the result of the function
+func getValue(aggState *AggState, index int, valueType common.ColumnType) (interface{}, error) {
+	switch valueType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+		return aggState.GetInt64(index), nil
+	case common.TypeDouble:
+		return aggState.GetFloat64(index), nil
+	case common.TypeVarchar, common.TypeVarbinary:
+		return aggState.GetString(index), nil
+	case common.TypeTimestamp:
+		return aggState.GetTimestamp(index)
+	case common.TypeDecimal:
+		return aggState.GetDecimal(index), nil
+	default:
+		return nil, errors.Errorf("unexpected column type %d", valueType.Type)
+	}
+}
@@ -41,6 +41,8 @@ type Server struct {
 	sessTimeout          time.Duration
 	protoRegistry        *protolib.ProtoRegistry
 	metaController       *meta.Controller
+	nodeID               int
+	apiServerAddresses   []string
 }
 
 func NewAPIServer(metaController *meta.Controller, ce *command.Executor, protobufs *protolib.ProtoRegistry, cfg conf.Config) *Server {
@@ -49,6 +51,8 @@ func NewAPIServer(metaController *meta.Controller, ce *command.Executor, protobu
 		ce:                   ce,
 		protoRegistry:        protobufs,
 		serverAddress:        cfg.APIServerListenAddresses[cfg.NodeID],
+		nodeID:               cfg.NodeID,
+		apiServerAddresses:   cfg.APIServerListenAddresses,
 		expSessCheckInterval: cfg.APIServerSessionCheckInterval,
 		sessTimeout:          cfg.APIServerSessionTimeout,
 	}
@@ -163,42 +167,16 @@ func (s *Server) ExecuteSQLStatement(in *service.ExecuteSQLStatementRequest, str
 	executor, err := s.ce.ExecuteSQLStatement(session, in.Statement)
 	if err != nil {
 		log.Errorf("failed to execute statement %+v", err)
-		var perr errors.PranaError
-		if errors.As(err, &perr) {
-			return perr
-		}
-		// For internal errors we don't return internal error messages to the CLI as this would leak
-		// server implementation details. Instead, we generate a sequence number and add that to the message
-		// and log the internal error in the server logs with the sequence number so it can be looked up
-		seq := atomic.AddInt64(&s.errorSequence, 1)
-		perr = errors.NewInternalError(seq)
-		log.Errorf("internal error occurred with sequence number %d\n%v", seq, err)
-		return perr
+		return s.clientError(err)
 	}
 
 	// First send column definitions.
-	columns := &service.Columns{}
-	names := executor.SimpleColNames()
-	for i, typ := range executor.ColTypes() {
-		name := names[i]
-		column := &service.Column{
-			Name: name,
-			Type: service.ColumnType(typ.Type),
-		}
-		if typ.Type == common.TypeDecimal {
-			column.DecimalParams = &service.DecimalParams{
-				DecimalPrecision: uint32(typ.DecPrecision),
-				DecimalScale:     uint32(typ.DecScale),
-			}
-		}
-		columns.Columns = append(columns.Columns, column)
-	}
+	columns := columnsToProto(executor.SimpleColNames(), executor.ColTypes())
 	if err := stream.Send(&service.ExecuteSQLStatementResponse{Result: &service.ExecuteSQLStatementResponse_Columns{Columns: columns}}); err != nil {
 		return errors.WithStack(err)
 	}
 
 	// Then start sending pages until complete.
-	numCols := len(executor.ColTypes())
 	limit := int(in.PageSize)
 	for {
 		// Transcode rows.
@@ -209,40 +187,10 @@ func (s *Server) ExecuteSQLStatement(in *service.ExecuteSQLStatementRequest, str
 		prows := make([]*service.Row, rows.RowCount())
 		for i := 0; i < rows.RowCount(); i++ {
 			row := rows.GetRow(i)
-			colVals := make([]*service.ColValue, numCols)
-			for colNum, colType := range executor.ColTypes() {
-				colVal := &service.ColValue{}
-				colVals[colNum] = colVal
-				if row.IsNull(colNum) {
-					colVal.Value = &service.ColValue_IsNull{IsNull: true}
-				} else {
-					switch colType.Type {
-					case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
-						colVal.Value = &service.ColValue_IntValue{IntValue: row.GetInt64(colNum)}
-					case common.TypeDouble:
-						colVal.Value = &service.ColValue_FloatValue{FloatValue: row.GetFloat64(colNum)}
-					case common.TypeVarchar:
-						colVal.Value = &service.ColValue_StringValue{StringValue: row.GetString(colNum)}
-					case common.TypeDecimal:
-						dec := row.GetDecimal(colNum)
-						// We encode the decimal as a string
-						colVal.Value = &service.ColValue_StringValue{StringValue: dec.String()}
-					case common.TypeTimestamp:
-						ts := row.GetTimestamp(colNum)
-						gt, err := ts.GoTime(time.UTC)
-						if err != nil {
-							return err
-						}
-						// We encode a datetime as *microseconds* past epoch
-						unixTime := gt.UnixNano() / 1000
-						colVal.Value = &service.ColValue_IntValue{IntValue: unixTime}
-					default:
-						panic(fmt.Sprintf("unexpected column type %d", colType.Type))
-					}
-				}
+			prows[i], err = rowToProto(&row, executor.ColTypes())
+			if err != nil {
+				return errors.WithStack(err)
 			}
-			pRow := &service.Row{Values: colVals}
-			prows[i] = pRow
 		}
 		numRows := rows.RowCount()
 		results := &service.Page{
@@ -259,6 +207,81 @@ func (s *Server) ExecuteSQLStatement(in *service.ExecuteSQLStatementRequest, str
 	return nil
 }
 
+// clientError returns the error to send to the client
+func (s *Server) clientError(err error) error {
+	var perr errors.PranaError
+	if errors.As(err, &perr) {
+		return perr
+	}
+	// For internal errors we don't return internal error messages to the CLI as this would leak
+	// server implementation details. Instead, we generate a sequence number and add that to the message
+	// and log the internal error in the server logs with the sequence number so it can be looked up
+	seq := atomic.AddInt64(&s.errorSequence, 1)
+	perr = errors.NewInternalError(seq)
+	log.Errorf("internal error occurred with sequence number %d\n%v", seq, err)
+	return perr
+}
+
+func columnsToProto(names []string, colTypes []common.ColumnType) *service.Columns {
+	columns := &service.Columns{}
+	for i, typ := range colTypes {
+		name := names[i]
+		column := &service.Column{
+			Name: name,
+			Type: service.ColumnType(typ.Type),
+		}
+		if typ.Type == common.TypeDecimal {
+			column.DecimalParams = &service.DecimalParams{
+				DecimalPrecision: uint32(typ.DecPrecision),
+				DecimalScale:     uint32(typ.DecScale),
+			}
+		}
+		columns.Columns = append(columns.Columns, column)
+	}
+	return columns
+}
+
+// rowToProto transcodes the first len(colTypes) columns of the row
+func rowToProto(row *common.Row, colTypes []common.ColumnType) (*service.Row, error) {
+	colVals := make([]*service.ColValue, len(colTypes))
+	for colNum, colType := range colTypes {
+		colVal := &service.ColValue{}
+		colVals[colNum] = colVal
+		if row.IsNull(colNum) {
+			colVal.Value = &service.ColValue_IsNull{IsNull: true}
+		} else {
+			switch colType.Type {
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+				colVal.Value = &service.ColValue_IntValue{IntValue: row.GetInt64(colNum)}
+			case common.TypeBoolean:
+				colVal.Value = &service.ColValue_BoolValue{BoolValue: row.GetBool(colNum)}
+			case common.TypeDouble:
+				colVal.Value = &service.ColValue_FloatValue{FloatValue: row.GetFloat64(colNum)}
+			case common.TypeVarchar:
+				colVal.Value = &service.ColValue_StringValue{StringValue: row.GetString(colNum)}
+			case common.TypeVarbinary:
+				colVal.Value = &service.ColValue_BytesValue{BytesValue: row.GetBytes(colNum)}
+			case common.TypeDecimal:
+				dec := row.GetDecimal(colNum)
+				// We encode the decimal as a string
+				colVal.Value = &service.ColValue_StringValue{StringValue: dec.String()}
+			case common.TypeTimestamp:
+				ts := row.GetTimestamp(colNum)
+				gt, err := ts.GoTime(time.UTC)
+				if err != nil {
+					return nil, err
+				}
+				// We encode a datetime as *microseconds* past epoch
+				unixTime := gt.UnixNano() / 1000
+				colVal.Value = &service.ColValue_IntValue{IntValue: unixTime}
+			default:
+				panic(fmt.Sprintf("unexpected column type %d", colType.Type))
+			}
+		}
+	}
+	return &service.Row{Values: colVals}, nil
+}
+
 func (s *Server) RegisterProtobufs(ctx context.Context, request *service.RegisterProtobufsRequest) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, s.protoRegistry.RegisterFiles(request.GetDescriptors())
 }
@@ -0,0 +1,423 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/protos/squareup/cash/pranadb/v1/service"
+	"github.com/squareup/pranadb/push"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const subscribeSnapshotPageSize = 1000
+
+// Subscribe sends the current contents of a materialized view followed by the changes to it as they are committed.
+//
+// The changes to a materialized view are committed on the node that's the leader of the shard the row is in, so the
+// node the client subscribes to subscribes to each node in the cluster, using local_only requests, and merges the
+// changes. The position sent to the client contains the position of each node, so when the client resubscribes with
+// it each node resumes from where it was. If any node can't resume, e.g. because it has been restarted, the current
+// contents are sent again. We start receiving changes before we query the current contents, so some changes can
+// be received which are already in the snapshot - applying them again after the snapshot gives the same result.
+func (s *Server) Subscribe(in *service.SubscribeRequest, stream service.PranaDBService_SubscribeServer) error {
+
+	defer common.PanicHandler()
+
+	mvInfo, ok := s.metaController.GetMaterializedView(in.Schema, in.MaterializedView)
+	if !ok {
+		return errors.NewUnknownMaterializedViewError(in.Schema, in.MaterializedView)
+	}
+	schema, ok := s.metaController.GetSchema(in.Schema)
+	if !ok {
+		return errors.NewUnknownMaterializedViewError(in.Schema, in.MaterializedView)
+	}
+	colNames, colTypes := visibleColumns(mvInfo.TableInfo)
+	if err := stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_Columns{
+		Columns: columnsToProto(colNames, colTypes)}}); err != nil {
+		return errors.WithStack(err)
+	}
+	var err error
+	if in.LocalOnly {
+		err = s.subscribeLocal(in, schema, colTypes, stream)
+	} else {
+		err = s.subscribeCluster(in, schema, colTypes, stream)
+	}
+	if err != nil {
+		log.Debugf("subscription to %s.%s ended %v", in.Schema, in.MaterializedView, err)
+		return s.clientError(err)
+	}
+	return nil
+}
+
+// subscribeLocal sends the changes committed on this node. The current contents aren't sent, just an empty snapshot
+// if the subscription didn't resume, so the subscribing node knows to send the current contents.
+func (s *Server) subscribeLocal(in *service.SubscribeRequest, schema *common.Schema, colTypes []common.ColumnType,
+	stream service.PranaDBService_SubscribeServer) error {
+	sub, err := s.ce.GetPushEngine().Subscribe(schema, in.MaterializedView, in.Filter, in.Position)
+	if err != nil {
+		return err
+	}
+	if !sub.Resumed() {
+		if err := stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_SnapshotStart{
+			SnapshotStart: &service.SnapshotStart{}}}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err := stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_SnapshotEnd{
+		SnapshotEnd: &service.SnapshotEnd{Position: sub.Position()}}}); err != nil {
+		return errors.WithStack(err)
+	}
+	ls := &localSubscription{sub: sub, colTypes: colTypes}
+	return ls.sendChanges(stream.Context().Done(), func(change *service.Change) error {
+		return stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_Change{Change: change}})
+	})
+}
+
+func (s *Server) subscribeCluster(in *service.SubscribeRequest, schema *common.Schema, colTypes []common.ColumnType,
+	stream service.PranaDBService_SubscribeServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	positions := decodeClusterPosition(in.Position)
+	subs, err := s.subscribeNodes(ctx, in, schema, colTypes, positions)
+	if err != nil {
+		return err
+	}
+	resumed := true
+	for _, sub := range subs {
+		resumed = resumed && sub.resumed()
+	}
+	if !resumed {
+		if in.Position != "" {
+			// Some nodes might have resumed, they need to start from now too as we're sending the current contents
+			cancel()
+			ctx, cancel = context.WithCancel(stream.Context())
+			defer cancel()
+			subs, err = s.subscribeNodes(ctx, in, schema, colTypes, nil)
+			if err != nil {
+				return err
+			}
+		}
+		positions = make(map[int]string, len(subs))
+		for nodeID, sub := range subs {
+			positions[nodeID] = sub.position()
+		}
+		if err := s.sendSnapshot(in, schema, positions, stream); err != nil {
+			return err
+		}
+	}
+
+	// Merge the changes from each node
+	changes := make(chan nodeChange, len(subs))
+	for nodeID, sub := range subs {
+		go func(nodeID int, sub nodeSubscription) {
+			err := sub.sendChanges(ctx.Done(), func(change *service.Change) error {
+				select {
+				case changes <- nodeChange{nodeID: nodeID, change: change}:
+					return nil
+				case <-ctx.Done():
+					return nil
+				}
+			})
+			select {
+			case changes <- nodeChange{nodeID: nodeID, err: err}:
+			case <-ctx.Done():
+			}
+		}(nodeID, sub)
+	}
+	for {
+		select {
+		case nc := <-changes:
+			if nc.change == nil {
+				if nc.err == nil {
+					nc.err = errors.Errorf("subscription to node %d ended", nc.nodeID)
+				}
+				return nc.err
+			}
+			positions[nc.nodeID] = nc.change.Position
+			nc.change.Position = encodeClusterPosition(positions)
+			if err := stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_Change{
+				Change: nc.change}}); err != nil {
+				return errors.WithStack(err)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// subscribeNodes subscribes to the changes committed on each node in the cluster
+func (s *Server) subscribeNodes(ctx context.Context, in *service.SubscribeRequest, schema *common.Schema,
+	colTypes []common.ColumnType, positions map[int]string) (map[int]nodeSubscription, error) {
+	// We subscribe to this node first so the request is checked before subscribing to the other nodes
+	sub, err := s.ce.GetPushEngine().Subscribe(schema, in.MaterializedView, in.Filter, positions[s.nodeID])
+	if err != nil {
+		return nil, err
+	}
+	subs := make(map[int]nodeSubscription, len(s.apiServerAddresses))
+	subs[s.nodeID] = &localSubscription{sub: sub, colTypes: colTypes}
+	for nodeID, address := range s.apiServerAddresses {
+		if nodeID == s.nodeID {
+			continue
+		}
+		sub, err := subscribeRemote(ctx, address, &service.SubscribeRequest{
+			Schema:           in.Schema,
+			MaterializedView: in.MaterializedView,
+			Filter:           in.Filter,
+			Position:         positions[nodeID],
+			LocalOnly:        true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		subs[nodeID] = sub
+	}
+	return subs, nil
+}
+
+// sendSnapshot sends the current contents of the materialized view
+func (s *Server) sendSnapshot(in *service.SubscribeRequest, schema *common.Schema, positions map[int]string,
+	stream service.PranaDBService_SubscribeServer) error {
+	session := s.ce.CreateSession()
+	session.UseSchema(schema)
+	defer func() {
+		if err := session.Close(s.metaController); err != nil {
+			log.Errorf("failed to close session %+v", err)
+		}
+	}()
+	query := fmt.Sprintf("select * from %s", in.MaterializedView)
+	if strings.TrimSpace(in.Filter) != "" {
+		query = fmt.Sprintf("%s where %s", query, in.Filter)
+	}
+	executor, err := s.ce.ExecuteSQLStatement(session, query)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_SnapshotStart{
+		SnapshotStart: &service.SnapshotStart{}}}); err != nil {
+		return errors.WithStack(err)
+	}
+	for {
+		rows, err := executor.GetRows(subscribeSnapshotPageSize)
+		if err != nil {
+			return err
+		}
+		prows := make([]*service.Row, rows.RowCount())
+		for i := 0; i < rows.RowCount(); i++ {
+			row := rows.GetRow(i)
+			prows[i], err = rowToProto(&row, executor.ColTypes())
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		numRows := rows.RowCount()
+		if numRows > 0 {
+			page := &service.Page{Count: uint64(numRows), Rows: prows}
+			if err := stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_Page{
+				Page: page}}); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if numRows < subscribeSnapshotPageSize {
+			break
+		}
+	}
+	return stream.Send(&service.SubscribeResponse{Result: &service.SubscribeResponse_SnapshotEnd{
+		SnapshotEnd: &service.SnapshotEnd{Position: encodeClusterPosition(positions)}}})
+}
+
+type nodeChange struct {
+	nodeID int
+	change *service.Change
+	err    error
+}
+
+// nodeSubscription is a subscription to the changes committed on one node
+type nodeSubscription interface {
+	resumed() bool
+	position() string
+	// sendChanges calls send with each change until done is closed or there's an error
+	sendChanges(done <-chan struct{}, send func(change *service.Change) error) error
+}
+
+type localSubscription struct {
+	sub      *push.Subscription
+	colTypes []common.ColumnType
+}
+
+func (l *localSubscription) resumed() bool {
+	return l.sub.Resumed()
+}
+
+func (l *localSubscription) position() string {
+	return l.sub.Position()
+}
+
+func (l *localSubscription) sendChanges(done <-chan struct{}, send func(change *service.Change) error) error {
+	for {
+		changes, err := l.sub.NextChanges(done)
+		if err != nil {
+			return err
+		}
+		if changes == nil {
+			return nil
+		}
+		for _, change := range changes {
+			pChange := &service.Change{Position: change.Position}
+			row := change.CurrRow
+			switch {
+			case change.PrevRow == nil:
+				pChange.Type = service.ChangeType_CHANGE_TYPE_INSERT
+			case change.CurrRow == nil:
+				pChange.Type = service.ChangeType_CHANGE_TYPE_DELETE
+				row = change.PrevRow
+			default:
+				pChange.Type = service.ChangeType_CHANGE_TYPE_UPDATE
+			}
+			pChange.Row, err = rowToProto(row, l.colTypes)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if err := send(pChange); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type remoteSubscription struct {
+	stream        service.PranaDBService_SubscribeClient
+	isResumed     bool
+	startPosition string
+}
+
+// subscribeRemote sends a local_only subscribe request to another node, and waits for it to subscribe
+func subscribeRemote(ctx context.Context, address string, in *service.SubscribeRequest) (*remoteSubscription, error) {
+	conn, err := grpc.DialContext(ctx, address, grpc.WithInsecure())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	go func() {
+		<-ctx.Done()
+		if err := conn.Close(); err != nil {
+			log.Debugf("failed to close connection %v", err)
+		}
+	}()
+	stream, err := service.NewPranaDBServiceClient(conn).Subscribe(ctx, in)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sub := &remoteSubscription{stream: stream, isResumed: true}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, remoteError(err)
+		}
+		switch result := resp.Result.(type) {
+		case *service.SubscribeResponse_Columns:
+		case *service.SubscribeResponse_SnapshotStart:
+			sub.isResumed = false
+		case *service.SubscribeResponse_SnapshotEnd:
+			sub.startPosition = result.SnapshotEnd.Position
+			return sub, nil
+		default:
+			return nil, errors.Errorf("unexpected subscribe response %v", resp)
+		}
+	}
+}
+
+func (r *remoteSubscription) resumed() bool {
+	return r.isResumed
+}
+
+func (r *remoteSubscription) position() string {
+	return r.startPosition
+}
+
+func (r *remoteSubscription) sendChanges(done <-chan struct{}, send func(change *service.Change) error) error {
+	for {
+		resp, err := r.stream.Recv()
+		if err != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+				return remoteError(err)
+			}
+		}
+		change := resp.GetChange()
+		if change == nil {
+			return errors.Errorf("unexpected subscribe response %v", resp)
+		}
+		if err := send(change); err != nil {
+			return err
+		}
+	}
+}
+
+// remoteError returns the PranaError sent by another node, e.g. when the materialized view is dropped, so it can be
+// sent to the client
+func remoteError(err error) error {
+	msg := status.Convert(err).Message()
+	var code int
+	if _, serr := fmt.Sscanf(msg, "PDB%04d - ", &code); serr == nil {
+		return errors.NewPranaError(errors.ErrorCode(code), msg)
+	}
+	return errors.WithStack(err)
+}
+
+// encodeClusterPosition encodes the position of each node, e.g. 0=1638316800000000000:42;1=1638316800000000001:7
+func encodeClusterPosition(positions map[int]string) string {
+	nodeIDs := make([]int, 0, len(positions))
+	for nodeID := range positions {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Ints(nodeIDs)
+	parts := make([]string, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		parts[i] = fmt.Sprintf("%d=%s", nodeID, positions[nodeID])
+	}
+	return strings.Join(parts, ";")
+}
+
+// decodeClusterPosition decodes a position from encodeClusterPosition. Anything that isn't a valid position is
+// ignored, which means the subscription can't resume.
+func decodeClusterPosition(position string) map[int]string {
+	positions := make(map[int]string)
+	if position == "" {
+		return positions
+	}
+	for _, part := range strings.Split(position, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		nodeID, err := strconv.Atoi(kv[0])
+		if err != nil {
+			continue
+		}
+		positions[nodeID] = kv[1]
+	}
+	return positions
+}
+
+// visibleColumns returns the names and types of the visible columns of the table, the hidden ones always come last
+func visibleColumns(tableInfo *common.TableInfo) ([]string, []common.ColumnType) {
+	numVisible := len(tableInfo.ColumnTypes)
+	if tableInfo.ColsVisible != nil {
+		numVisible = 0
+		for _, visible := range tableInfo.ColsVisible {
+			if visible {
+				numVisible++
+			}
+		}
+	}
+	return tableInfo.ColumnNames[:numVisible], tableInfo.ColumnTypes[:numVisible]
+}
@@ -149,8 +149,6 @@ func (c *Client) doExecuteStatement(sessionID string, statement string, ch chan
 
 func (c *Client) doExecuteStatementWithError(sessionID string, statement string, ch chan string) (int, error) {
 
-	utc, _ := time.LoadLocation("UTC")
-
 	stream, err := c.client.ExecuteSQLStatement(context.Background(), &service.ExecuteSQLStatementRequest{
 		SessionId: sessionID,
 		Statement: statement,
@@ -186,37 +184,7 @@ func (c *Client) doExecuteStatementWithError(sessionID string, statement string,
 			}
 			page := result.Page
 			for _, row := range page.Rows {
-				values := row.Values
-				sb := strings.Builder{}
-				sb.WriteRune('|')
-				for colIndex, colType := range columnTypes {
-					value := values[colIndex]
-					if value.GetIsNull() {
-						sb.WriteString("null|")
-					} else {
-						var sc string
-						switch colType.Type {
-						case common.TypeVarchar:
-							sc = value.GetStringValue()
-						case common.TypeTinyInt, common.TypeBigInt, common.TypeInt:
-							sc = fmt.Sprintf("%d", value.GetIntValue())
-						case common.TypeDecimal:
-							sc = value.GetStringValue()
-						case common.TypeDouble:
-							sc = fmt.Sprintf("%g", value.GetFloatValue())
-						case common.TypeTimestamp:
-							unixTime := value.GetIntValue()
-							gt := time.UnixMicro(unixTime).In(utc)
-							sc = fmt.Sprintf("%d-%02d-%02d %02d:%02d:%02d.%06d",
-								gt.Year(), gt.Month(), gt.Day(), gt.Hour(), gt.Minute(), gt.Second(), gt.Nanosecond()/1000)
-						case common.TypeUnknown:
-							sc = "??"
-						}
-						sb.WriteString(sc)
-						sb.WriteRune('|')
-					}
-				}
-				ch <- sb.String()
+				ch <- formatRow(row, columnTypes)
 				rowCount++
 			}
 		}
@@ -224,6 +192,44 @@ func (c *Client) doExecuteStatementWithError(sessionID string, statement string,
 	return rowCount, nil
 }
 
+func formatRow(row *service.Row, columnTypes []common.ColumnType) string {
+	values := row.Values
+	sb := strings.Builder{}
+	sb.WriteRune('|')
+	for colIndex, colType := range columnTypes {
+		value := values[colIndex]
+		if value.GetIsNull() {
+			sb.WriteString("null|")
+		} else {
+			var sc string
+			switch colType.Type {
+			case common.TypeVarchar:
+				sc = value.GetStringValue()
+			case common.TypeTinyInt, common.TypeBigInt, common.TypeInt:
+				sc = fmt.Sprintf("%d", value.GetIntValue())
+			case common.TypeBoolean:
+				sc = fmt.Sprintf("%t", value.GetBoolValue())
+			case common.TypeVarbinary:
+				sc = common.FormatBytes(value.GetBytesValue())
+			case common.TypeDecimal:
+				sc = value.GetStringValue()
+			case common.TypeDouble:
+				sc = fmt.Sprintf("%g", value.GetFloatValue())
+			case common.TypeTimestamp:
+				unixTime := value.GetIntValue()
+				gt := time.UnixMicro(unixTime).In(time.UTC)
+				sc = fmt.Sprintf("%d-%02d-%02d %02d:%02d:%02d.%06d",
+					gt.Year(), gt.Month(), gt.Day(), gt.Hour(), gt.Minute(), gt.Second(), gt.Nanosecond()/1000)
+			case common.TypeUnknown:
+				sc = "??"
+			}
+			sb.WriteString(sc)
+			sb.WriteRune('|')
+		}
+	}
+	return sb.String()
+}
+
 func toColumnTypes(result *service.Columns) (names []string, types []common.ColumnType) {
 	types = make([]common.ColumnType, len(result.Columns))
 	names = make([]string, len(result.Columns))
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/protos/squareup/cash/pranadb/v1/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Subscription is a subscription to the changes to a materialized view
+type Subscription struct {
+	lock     sync.Mutex
+	lines    chan string
+	position string
+	cancel   context.CancelFunc
+}
+
+// Subscribe subscribes to a materialized view. The current contents and then the changes to it are received on the
+// channel returned by Lines. If position is the position of an earlier subscription the subscription resumes from
+// there if possible, rather than receiving the current contents again.
+func (c *Client) Subscribe(schemaName string, mvName string, filter string, position string) (*Subscription, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.started {
+		return nil, errors.Error("not started")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.client.Subscribe(ctx, &service.SubscribeRequest{
+		Schema:           schemaName,
+		MaterializedView: mvName,
+		Filter:           filter,
+		Position:         position,
+	})
+	if err != nil {
+		cancel()
+		return nil, errors.WithStack(err)
+	}
+	sub := &Subscription{
+		lines:    make(chan string, maxBufferedLines),
+		position: position,
+		cancel:   cancel,
+	}
+	go sub.receive(stream)
+	return sub, nil
+}
+
+// Lines returns the channel the output of the subscription is received on. The rows in the current contents are
+// received between "snapshot start" and "snapshot end" lines, and each change is received as a line starting with
+// "insert", "update" or "delete". The channel is closed when the subscription ends.
+func (s *Subscription) Lines() <-chan string {
+	return s.lines
+}
+
+// Position returns the position to resume from to receive the changes after the ones received so far
+func (s *Subscription) Position() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.position
+}
+
+func (s *Subscription) Close() {
+	s.cancel()
+}
+
+func (s *Subscription) setPosition(position string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.position = position
+}
+
+func (s *Subscription) receive(stream service.PranaDBService_SubscribeClient) {
+	defer close(s.lines)
+	var columnTypes []common.ColumnType
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if status.Code(err) != codes.Canceled {
+				s.lines <- stripgRPCPrefix(err).Error()
+			}
+			return
+		}
+		switch result := resp.Result.(type) {
+		case *service.SubscribeResponse_Columns:
+			_, columnTypes = toColumnTypes(result.Columns)
+		case *service.SubscribeResponse_SnapshotStart:
+			s.lines <- "snapshot start"
+		case *service.SubscribeResponse_Page:
+			for _, row := range result.Page.Rows {
+				s.lines <- formatRow(row, columnTypes)
+			}
+		case *service.SubscribeResponse_SnapshotEnd:
+			s.setPosition(result.SnapshotEnd.Position)
+			s.lines <- "snapshot end"
+		case *service.SubscribeResponse_Change:
+			var changeType string
+			switch result.Change.Type {
+			case service.ChangeType_CHANGE_TYPE_INSERT:
+				changeType = "insert"
+			case service.ChangeType_CHANGE_TYPE_UPDATE:
+				changeType = "update"
+			case service.ChangeType_CHANGE_TYPE_DELETE:
+				changeType = "delete"
+			default:
+				changeType = fmt.Sprintf("unknown change type %d", result.Change.Type)
+			}
+			s.setPosition(result.Change.Position)
+			s.lines <- fmt.Sprintf("%s %s", changeType, formatRow(result.Change.Row, columnTypes))
+		}
+	}
+}
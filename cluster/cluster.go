@@ -92,6 +92,10 @@ type QueryExecutionInfo struct {
 	ShardID     uint64
 	IsPs        bool
 	SystemQuery bool
+	// ExecutorID identifies the remote executor of the query to execute, when the query has more than one, e.g. a join
+	ExecutorID uint32
+	// LookupKeys are the serialized rows of join keys to look up, when executing the inner side of an index lookup join
+	LookupKeys []byte
 }
 
 func (q *QueryExecutionInfo) GetArgs() []interface{} {
@@ -154,11 +158,11 @@ func decodePsArgs(offset int, buff []byte) ([]interface{}, int, error) {
 	for i := 0; i < int(numArgs); i++ {
 		argType := argTypes[i]
 		switch argType.Type {
-		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 			args[i], offset = common.ReadUint64FromBufferLE(buff, offset)
 		case common.TypeDouble:
 			args[i], offset = common.ReadFloat64FromBufferLE(buff, offset)
-		case common.TypeVarchar:
+		case common.TypeVarchar, common.TypeVarbinary:
 			args[i], offset = common.ReadStringFromBufferLE(buff, offset)
 		case common.TypeDecimal:
 			args[i], offset, err = common.ReadDecimalFromBuffer(buff, offset, argType.DecPrecision, argType.DecScale)
@@ -201,6 +205,9 @@ func (q *QueryExecutionInfo) Serialize(buff []byte) ([]byte, error) {
 		b = 0
 	}
 	buff = append(buff, b)
+	buff = common.AppendUint32ToBufferLE(buff, q.ExecutorID)
+	buff = common.AppendUint32ToBufferLE(buff, uint32(len(q.LookupKeys)))
+	buff = append(buff, q.LookupKeys...)
 	return buff, nil
 }
 
@@ -220,6 +227,13 @@ func (q *QueryExecutionInfo) Deserialize(buff []byte) error {
 	q.IsPs = buff[offset] == 1
 	offset++
 	q.SystemQuery = buff[offset] == 1
+	offset++
+	q.ExecutorID, offset = common.ReadUint32FromBufferLE(buff, offset)
+	var lookupKeysLen uint32
+	lookupKeysLen, offset = common.ReadUint32FromBufferLE(buff, offset)
+	if lookupKeysLen > 0 {
+		q.LookupKeys = append([]byte{}, buff[offset:offset+int(lookupKeysLen)]...)
+	}
 	return nil
 }
 
@@ -245,6 +259,12 @@ type ShardCallback interface {
 	Write(batch WriteBatch) error
 }
 
+// KeyRange is the keys from StartKey, inclusive, to EndKey, exclusive
+type KeyRange struct {
+	StartKey []byte
+	EndKey   []byte
+}
+
 type KVPair struct {
 	Key   []byte
 	Value []byte
@@ -82,6 +82,11 @@ In the case of forwarding rows we have just consumed from Kafka, sequence is sim
 In the case of forwarding rows to from a partial aggregation to a full aggregation, a batch of rows, as identified by
 a single value of batch_sequence in the receiver table will be processed by the aggregation. The sequence value for the
 dedup key used when forwarding the partial aggregation results to other shards will be taken from the batch_sequence.
+The same applies when forwarding rows to the shard that owns the join key for a join.
+
+As many rows can be forwarded to the same shard while processing a single batch, many rows in the same forward write
+batch can have the same dedup key. The shard state machine only checks the first of these - the rest of the rows with
+the same dedup key in that write batch are then accepted or ignored along with it.
 */
 func DoDedup(shardID uint64, dedupKey []byte, dedupMap map[string]uint64) (bool, error) {
 
@@ -441,6 +441,14 @@ func (d *Dragon) WriteBatchLocally(batch *cluster.WriteBatch) error {
 	}); err != nil {
 		return err
 	}
+	if err := batch.ForEachDeleteRange(func(startKey []byte, endKey []byte) error {
+		if err := pebBatch.DeleteRange(startKey, endKey, nil); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
 	if err := errors.WithStack(d.pebble.Apply(pebBatch, nosyncWriteOptions)); err != nil {
 		return err
 	}
@@ -154,8 +154,13 @@ func (s *ShardOnDiskStateMachine) maybeTriggerRemoteWriteOccurred() {
 }
 
 func (s *ShardOnDiskStateMachine) handleWrite(batch *pebble.Batch, bytes []byte, forward bool) error {
-	puts, deletes := s.deserializeWriteBatch(bytes, 1, forward)
+	puts, deletes, rangeDeletes := s.deserializeWriteBatch(bytes, 1, forward)
 
+	// Rows in the same batch with the same dedup key are either all accepted or all ignored
+	var dedupResults map[string]bool
+	if forward {
+		dedupResults = make(map[string]bool)
+	}
 	for _, kvPair := range puts {
 
 		var key []byte
@@ -165,9 +170,14 @@ func (s *ShardOnDiskStateMachine) handleWrite(batch *pebble.Batch, bytes []byte,
 			remoteConsumerBytes := kvPair.Key[25:] // The rest is just the remote consumer id
 
 			if enableDupDetection {
-				ignore, err := s.checkDedup(dedupKey, batch)
-				if err != nil {
-					return err
+				ignore, ok := dedupResults[string(dedupKey)]
+				if !ok {
+					var err error
+					ignore, err = s.checkDedup(dedupKey, batch)
+					if err != nil {
+						return err
+					}
+					dedupResults[string(dedupKey)] = ignore
 				}
 				if ignore {
 					continue
@@ -203,6 +213,9 @@ func (s *ShardOnDiskStateMachine) handleWrite(batch *pebble.Batch, bytes []byte,
 	if forward && len(deletes) != 0 {
 		panic("deletes not supported for forward write")
 	}
+	if forward && len(rangeDeletes) != 0 {
+		panic("range deletes not supported for forward write")
+	}
 	for _, k := range deletes {
 		s.checkKey(k)
 		err := batch.Delete(k, nil)
@@ -210,12 +223,20 @@ func (s *ShardOnDiskStateMachine) handleWrite(batch *pebble.Batch, bytes []byte,
 			return errors.WithStack(err)
 		}
 	}
+	for _, kr := range rangeDeletes {
+		s.checkKey(kr.StartKey)
+		s.checkKey(kr.EndKey)
+		if err := batch.DeleteRange(kr.StartKey, kr.EndKey, nil); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	return nil
 }
 
 // We deserialize into simple slices for puts and deletes as we don't need the actual WriteBatch instance in the
 // state machine
-func (s *ShardOnDiskStateMachine) deserializeWriteBatch(buff []byte, offset int, forward bool) (puts []cluster.KVPair, deletes [][]byte) {
+func (s *ShardOnDiskStateMachine) deserializeWriteBatch(buff []byte, offset int, forward bool) (puts []cluster.KVPair,
+	deletes [][]byte, rangeDeletes []cluster.KeyRange) {
 	numPuts, offset := common.ReadUint32FromBufferLE(buff, offset)
 	puts = make([]cluster.KVPair, numPuts)
 	for i := 0; i < int(numPuts); i++ {
@@ -252,7 +273,24 @@ func (s *ShardOnDiskStateMachine) deserializeWriteBatch(buff []byte, offset int,
 		offset += kLen
 		deletes[i] = k
 	}
-	return puts, deletes
+	if offset == len(buff) {
+		// The batch was written before range deletes were added
+		return puts, deletes, nil
+	}
+	numRangeDeletes, offset := common.ReadUint32FromBufferLE(buff, offset)
+	rangeDeletes = make([]cluster.KeyRange, numRangeDeletes)
+	for i := 0; i < int(numRangeDeletes); i++ {
+		var sl uint32
+		sl, offset = common.ReadUint32FromBufferLE(buff, offset)
+		startKey := buff[offset : offset+int(sl)]
+		offset += int(sl)
+		var el uint32
+		el, offset = common.ReadUint32FromBufferLE(buff, offset)
+		endKey := buff[offset : offset+int(el)]
+		offset += int(el)
+		rangeDeletes[i] = cluster.KeyRange{StartKey: startKey, EndKey: endKey}
+	}
+	return puts, deletes, rangeDeletes
 }
 
 func (s *ShardOnDiskStateMachine) checkDedup(key []byte, batch *pebble.Batch) (ignore bool, err error) {
@@ -0,0 +1,41 @@
+package dragon
+
+import (
+	"testing"
+
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeserializeWriteBatch(t *testing.T) {
+	wb := cluster.NewWriteBatch(1000)
+	wb.AddPut([]byte("key1"), []byte("value1"))
+	wb.AddDelete([]byte("key2"))
+	wb.AddDeleteRange([]byte("key3"), []byte("key4"))
+	buff := wb.Serialize([]byte{shardStateMachineCommandWrite})
+
+	s := &ShardOnDiskStateMachine{}
+	puts, deletes, rangeDeletes := s.deserializeWriteBatch(buff, 1, false)
+	require.Equal(t, []cluster.KVPair{{Key: []byte("key1"), Value: []byte("value1")}}, puts)
+	require.Equal(t, [][]byte{[]byte("key2")}, deletes)
+	require.Equal(t, []cluster.KeyRange{{StartKey: []byte("key3"), EndKey: []byte("key4")}}, rangeDeletes)
+}
+
+func TestDeserializeWriteBatchWithoutRangeDeletes(t *testing.T) {
+	// A batch serialized before range deletes were added
+	wb := cluster.NewWriteBatch(1000)
+	wb.AddPut([]byte("key1"), []byte("value1"))
+	wb.AddDelete([]byte("key2"))
+	buff := []byte{shardStateMachineCommandWrite}
+	buff = common.AppendUint32ToBufferLE(buff, uint32(wb.NumPuts))
+	buff = append(buff, wb.Puts...)
+	buff = common.AppendUint32ToBufferLE(buff, uint32(wb.NumDeletes))
+	buff = append(buff, wb.Deletes...)
+
+	s := &ShardOnDiskStateMachine{}
+	puts, deletes, rangeDeletes := s.deserializeWriteBatch(buff, 1, false)
+	require.Equal(t, []cluster.KVPair{{Key: []byte("key1"), Value: []byte("value1")}}, puts)
+	require.Equal(t, [][]byte{[]byte("key2")}, deletes)
+	require.Empty(t, rangeDeletes)
+}
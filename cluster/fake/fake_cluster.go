@@ -197,6 +197,8 @@ func (f *FakeCluster) WriteForwardBatch(batch *cluster.WriteBatch) error {
 		dedupMap = make(map[string]uint64)
 		f.dedupMaps[batch.ShardID] = dedupMap
 	}
+	// Rows in the same batch with the same dedup key are either all accepted or all ignored
+	dedupResults := make(map[string]bool)
 	if err := batch.ForEachPut(func(key []byte, value []byte) error {
 
 		enableDupDetection := key[0] == 1
@@ -205,9 +207,14 @@ func (f *FakeCluster) WriteForwardBatch(batch *cluster.WriteBatch) error {
 		remoteConsumerBytes := key[25:] // The rest is just the remote consumer id
 
 		if enableDupDetection {
-			ignore, err := cluster.DoDedup(batch.ShardID, dedupKey, dedupMap)
-			if err != nil {
-				return err
+			ignore, ok := dedupResults[string(dedupKey)]
+			if !ok {
+				var err error
+				ignore, err = cluster.DoDedup(batch.ShardID, dedupKey, dedupMap)
+				if err != nil {
+					return err
+				}
+				dedupResults[string(dedupKey)] = ignore
 			}
 			if ignore {
 				return nil
@@ -230,7 +237,7 @@ func (f *FakeCluster) WriteForwardBatch(batch *cluster.WriteBatch) error {
 	f.receiverSequences[batch.ShardID] = receiverSequence
 	batchSequence++
 	f.batchSequences[batch.ShardID] = batchSequence
-	if batch.NumDeletes != 0 {
+	if batch.NumDeletes != 0 || batch.NumRangeDeletes != 0 {
 		panic("deletes not supported in forward batch")
 	}
 	return f.writeBatchInternal(filteredBatch, true)
@@ -269,6 +276,20 @@ func (f *FakeCluster) writeBatchInternal(batch *cluster.WriteBatch, forward bool
 	}); err != nil {
 		return err
 	}
+	if err := batch.ForEachDeleteRange(func(startKey []byte, endKey []byte) error {
+		pairs, err := f.localScanWithBtree(f.btree, startKey, endKey, -1)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, pair := range pairs {
+			if err := f.deleteInternal(&kvWrapper{key: pair.Key}); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
 	if forward {
 		shardListener := f.shardListeners[batch.ShardID]
 		go shardListener.RemoteWriteOccurred()
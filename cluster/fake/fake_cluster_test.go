@@ -156,6 +156,32 @@ func testScan(t *testing.T, limit int, expected int) {
 	}
 }
 
+func TestDeleteRange(t *testing.T) {
+	clust := startFakeCluster(t)
+	defer stopClustFunc(t, clust)
+
+	shardID := uint64(123545)
+	wb := cluster.NewWriteBatch(shardID)
+	for i := 0; i < 10; i++ {
+		wb.AddPut([]byte(fmt.Sprintf("foo-%02d", i)), []byte(fmt.Sprintf("somevalue%02d", i)))
+	}
+	err := clust.WriteBatch(wb)
+	require.NoError(t, err)
+
+	wb = cluster.NewWriteBatch(shardID)
+	wb.AddDeleteRange([]byte("foo-03"), []byte("foo-07"))
+	err = clust.WriteBatch(wb)
+	require.NoError(t, err)
+
+	res, err := clust.LocalScan([]byte("foo-"), []byte("foo."), -1)
+	require.NoError(t, err)
+	var keys []string
+	for _, kvPair := range res {
+		keys = append(keys, string(kvPair.Key))
+	}
+	require.Equal(t, []string{"foo-00", "foo-01", "foo-02", "foo-07", "foo-08", "foo-09"}, keys)
+}
+
 func createWriteBatchWithPuts(shardID uint64, puts ...cluster.KVPair) cluster.WriteBatch {
 	wb := cluster.NewWriteBatch(shardID)
 	for _, kvPair := range puts {
@@ -4,13 +4,16 @@ import (
 	"github.com/squareup/pranadb/common"
 )
 
-// WriteBatch represents some Puts and deletes that will be written atomically by the underlying storage implementation
+// WriteBatch represents some Puts and deletes that will be written atomically by the underlying storage implementation.
+// The puts are applied first, then the deletes and then the range deletes.
 type WriteBatch struct {
 	ShardID            uint64
 	Puts               []byte
 	Deletes            []byte
+	RangeDeletes       []byte
 	NumPuts            int
 	NumDeletes         int
+	NumRangeDeletes    int
 	committedCallbacks []CommittedCallback
 }
 
@@ -24,6 +27,8 @@ type KVReceiver func([]byte, []byte) error
 
 type KReceiver func([]byte) error
 
+type KeyRangeReceiver func(startKey []byte, endKey []byte) error
+
 type CommittedCallback func() error
 
 func (wb *WriteBatch) AddPut(k []byte, v []byte) {
@@ -37,8 +42,16 @@ func (wb *WriteBatch) AddDelete(k []byte) {
 	wb.NumDeletes++
 }
 
+// AddDeleteRange deletes all the keys from startKey, inclusive, to endKey, exclusive. Both keys must be in the shard of
+// the batch.
+func (wb *WriteBatch) AddDeleteRange(startKey []byte, endKey []byte) {
+	wb.RangeDeletes = appendBytesWithLength(wb.RangeDeletes, startKey)
+	wb.RangeDeletes = appendBytesWithLength(wb.RangeDeletes, endKey)
+	wb.NumRangeDeletes++
+}
+
 func (wb *WriteBatch) HasWrites() bool {
-	return len(wb.Puts) > 0 || len(wb.Deletes) > 0
+	return len(wb.Puts) > 0 || len(wb.Deletes) > 0 || len(wb.RangeDeletes) > 0
 }
 
 func (wb *WriteBatch) Serialize(buff []byte) []byte {
@@ -46,6 +59,9 @@ func (wb *WriteBatch) Serialize(buff []byte) []byte {
 	buff = append(buff, wb.Puts...)
 	buff = common.AppendUint32ToBufferLE(buff, uint32(wb.NumDeletes))
 	buff = append(buff, wb.Deletes...)
+	// The range deletes come last, so a batch serialized before they were added can still be deserialized
+	buff = common.AppendUint32ToBufferLE(buff, uint32(wb.NumRangeDeletes))
+	buff = append(buff, wb.RangeDeletes...)
 	return buff
 }
 
@@ -96,6 +112,24 @@ func (wb *WriteBatch) ForEachDelete(kReceiver KReceiver) error {
 	return nil
 }
 
+func (wb *WriteBatch) ForEachDeleteRange(receiver KeyRangeReceiver) error {
+	offset := 0
+	for offset < len(wb.RangeDeletes) {
+		ls, _ := common.ReadUint32FromBufferLE(wb.RangeDeletes, offset)
+		offset += 4
+		startKey := wb.RangeDeletes[offset : offset+int(ls)]
+		offset += int(ls)
+		le, _ := common.ReadUint32FromBufferLE(wb.RangeDeletes, offset)
+		offset += 4
+		endKey := wb.RangeDeletes[offset : offset+int(le)]
+		offset += int(le)
+		if err := receiver(startKey, endKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func appendBytesWithLength(buff []byte, bytes []byte) []byte {
 	buff = common.AppendUint32ToBufferLE(buff, uint32(len(bytes)))
 	buff = append(buff, bytes...)
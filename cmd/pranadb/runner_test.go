@@ -62,6 +62,7 @@ func createConfigWithAllFields() conf.Config {
 				Properties: map[string]string{
 					"fakeKafkaID": fmt.Sprintf("%d", 1),
 				},
+				SchemaRegistryURL: "http://localhost:8081",
 			},
 		},
 		DataSnapshotEntries:           1001,
@@ -82,5 +83,6 @@ func createConfigWithAllFields() conf.Config {
 		RaftRTTMs:                     100,
 		RaftElectionRTT:               300,
 		RaftHeartbeatRTT:              30,
+		RetentionCheckInterval:        30 * time.Second,
 	}
 }
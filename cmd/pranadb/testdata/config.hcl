@@ -46,6 +46,7 @@ kafka-brokers = {
     "properties"  = {
       "fakeKafkaID" = "1"
     }
+    "schema-registry-url" = "http://localhost:8081"
   }
 }
 
@@ -53,3 +54,4 @@ global-ingest-limit-rows-per-sec = 5000
 raft-rtt-ms                       = 100
 raft-heartbeat-rtt                = 30
 raft-election-rtt                 = 300
+retention-check-interval          = "30s"
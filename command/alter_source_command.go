@@ -0,0 +1,293 @@
+package command
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/squareup/pranadb/command/parser"
+	"github.com/squareup/pranadb/command/parser/selector"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/kafka"
+	"github.com/squareup/pranadb/push/source"
+)
+
+// AlterSourceCommand resets the offsets of a source, so it consumes the topic again from a position, pauses or resumes
+// it, or adds a column to it. To reset the offsets the consumers of the source are stopped on every node before the
+// offsets of its consumer group are moved, then started again. A column is added the same way, so no node ingests
+// messages without it once another has it. A paused source is stopped on every node, and stays stopped, including after
+// a restart, until it's resumed.
+type AlterSourceCommand struct {
+	lock           sync.Mutex
+	e              *Executor
+	schemaName     string
+	sql            string
+	tableSequences []uint64
+	ast            *parser.AlterSource
+	sourceInfo     *common.SourceInfo
+	offsets        map[kafka.TopicPartition]int64
+}
+
+func (c *AlterSourceCommand) CommandType() DDLCommandType {
+	return DDLCommandTypeAlterSource
+}
+
+func (c *AlterSourceCommand) SchemaName() string {
+	return c.schemaName
+}
+
+func (c *AlterSourceCommand) SQL() string {
+	return c.sql
+}
+
+func (c *AlterSourceCommand) TableSequences() []uint64 {
+	return c.tableSequences
+}
+
+func (c *AlterSourceCommand) LockName() string {
+	return c.schemaName + "/"
+}
+
+func NewOriginatingAlterSourceCommand(e *Executor, schemaName string, sql string, tableSequences []uint64,
+	ast *parser.AlterSource) *AlterSourceCommand {
+	return &AlterSourceCommand{
+		e:              e,
+		schemaName:     schemaName,
+		sql:            sql,
+		tableSequences: tableSequences,
+		ast:            ast,
+	}
+}
+
+func NewAlterSourceCommand(e *Executor, schemaName string, sql string, tableSequences []uint64) *AlterSourceCommand {
+	return &AlterSourceCommand{
+		e:              e,
+		schemaName:     schemaName,
+		sql:            sql,
+		tableSequences: tableSequences,
+	}
+}
+
+func (c *AlterSourceCommand) Before() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sourceInfo, err := c.getSourceInfo()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.sourceInfo = sourceInfo
+	if c.ast.AddColumn != nil {
+		_, _, _, err := c.getAddedColumn()
+		return errors.WithStack(err)
+	}
+	if !c.ast.ResetOffsets {
+		return nil
+	}
+	if sourceInfo.TopicInfo.HasMultipleTopics() && sourceInfo.TopicInfo.OffsetResets >= source.MaxOffsetResets {
+		return errors.NewPranaErrorf(errors.InvalidStatement,
+			"The offsets of source %s can't be reset more than %d times as it has multiple topics", sourceInfo.Name,
+			source.MaxOffsetResets)
+	}
+
+	// By default the source is reset to where it started from when it was created
+	startFrom := sourceInfo.TopicInfo.StartFrom
+	if c.ast.StartFrom != nil {
+		startFrom, err = common.ParseStartFrom(*c.ast.StartFrom)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := checkStartFrom(startFrom, sourceInfo.TopicInfo.HasMultipleTopics()); err != nil {
+			return errors.WithStack(err)
+		}
+	} else if startFrom == nil {
+		startFrom = &common.StartFrom{Kind: common.StartFromEarliest}
+	}
+	// We find the offsets before the source is stopped, so if the position is invalid the source carries on as it was
+	src, err := c.e.pushEngine.GetSource(sourceInfo.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.offsets, err = src.GetStartOffsets(startFrom)
+	return errors.WithStack(err)
+}
+
+func (c *AlterSourceCommand) OnPhase(phase int32) error {
+	switch phase {
+	case 0:
+		return c.onPhase0()
+	case 1:
+		return c.onPhase1()
+	default:
+		panic("invalid phase")
+	}
+}
+
+func (c *AlterSourceCommand) NumPhases() int {
+	return 2
+}
+
+func (c *AlterSourceCommand) onPhase0() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sourceInfo == nil {
+		sourceInfo, err := c.getSourceInfo()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.sourceInfo = sourceInfo
+	}
+	src, err := c.e.pushEngine.GetSource(c.sourceInfo.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	switch {
+	case c.ast.Pause:
+		return src.Pause()
+	case c.ast.Resume:
+		// The source is started once the cluster knows it's no longer paused
+		return nil
+	default:
+		// Stop the consumers of the source, so none of them are in the consumer group when the offsets are moved, and
+		// none of them ingest messages without a column that's being added
+		return src.Stop()
+	}
+}
+
+func (c *AlterSourceCommand) onPhase1() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	src, err := c.e.pushEngine.GetSource(c.sourceInfo.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// The source is replaced with an altered copy - the registered one is shared, so it mustn't be changed in place
+	sourceInfo, err := c.alteredSourceInfo()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if !c.ast.Pause && !c.ast.Resume {
+		// The source is stopped, so nothing is ingesting its messages while we change it
+		if err := c.e.pushEngine.AlterSource(sourceInfo); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err := c.e.metaController.ReplaceSource(sourceInfo); err != nil {
+		return errors.WithStack(err)
+	}
+	switch {
+	case c.ast.Pause:
+		return nil
+	case c.ast.Resume:
+		return src.Resume()
+	default:
+		// If it's paused it isn't started again
+		return src.Start()
+	}
+}
+
+func (c *AlterSourceCommand) AfterPhase(phase int32) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if phase != 0 {
+		return nil
+	}
+	if !c.ast.Pause && !c.ast.Resume && c.ast.AddColumn == nil {
+		src, err := c.e.pushEngine.GetSource(c.sourceInfo.ID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := src.SetOffsets(c.offsets); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	sourceInfo, err := c.alteredSourceInfo()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if sourceInfo.TopicInfo.HasMultipleTopics() {
+		// Topics might have been given indexes since the source was registered. The source is stopped on every node
+		// now, so no more are given until we've persisted it.
+		persisted, err := source.LoadSourceInfo(c.e.pullEngine, c.sourceInfo.ID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		sourceInfo.TopicInfo.TopicIndexes = persisted.TopicInfo.TopicIndexes
+	}
+	return c.e.metaController.PersistSource(sourceInfo)
+}
+
+// alteredSourceInfo returns a copy of the source with the alteration made to it
+func (c *AlterSourceCommand) alteredSourceInfo() (*common.SourceInfo, error) {
+	sourceInfo := *c.sourceInfo
+	topicInfo := *sourceInfo.TopicInfo
+	sourceInfo.TopicInfo = &topicInfo
+	switch {
+	case c.ast.Pause:
+		topicInfo.Paused = true
+	case c.ast.Resume:
+		topicInfo.Paused = false
+	case c.ast.AddColumn != nil:
+		name, colType, colSelector, err := c.getAddedColumn()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		sourceInfo.TableInfo = sourceInfo.TableInfo.WithAddedColumn(name, colType, c.tableSequences[0])
+		topicInfo.ColSelectors = append(append(make([]selector.ColumnSelector, 0, len(topicInfo.ColSelectors)+1),
+			topicInfo.ColSelectors...), colSelector)
+	default:
+		// The number of resets is part of the originator id, so messages which were ingested before the reset aren't
+		// ignored as duplicates when they're consumed again, including after a restart
+		topicInfo.OffsetResets++
+	}
+	return &sourceInfo, nil
+}
+
+func (c *AlterSourceCommand) getSourceInfo() (*common.SourceInfo, error) {
+	if c.ast == nil {
+		ast, err := parser.Parse(c.sql)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ast.Alter == nil || ast.Alter.Source == nil {
+			return nil, errors.Errorf("not an alter source command %s", c.sql)
+		}
+		c.ast = ast.Alter.Source
+	}
+	sourceInfo, ok := c.e.metaController.GetSource(c.schemaName, c.ast.Name)
+	if !ok {
+		return nil, errors.NewUnknownSourceError(c.schemaName, c.ast.Name)
+	}
+	return sourceInfo, nil
+}
+
+// getAddedColumn returns the name, type and selector of the column being added
+func (c *AlterSourceCommand) getAddedColumn() (string, common.ColumnType, selector.ColumnSelector, error) {
+	addColumn := c.ast.AddColumn
+	for _, colName := range c.sourceInfo.ColumnNames {
+		if strings.EqualFold(colName, addColumn.Column.Name) {
+			return "", common.ColumnType{}, selector.ColumnSelector{}, errors.NewPranaErrorf(errors.InvalidStatement,
+				"Source %s already has a column %s", c.sourceInfo.Name, colName)
+		}
+	}
+	if len(c.sourceInfo.TopicInfo.ColSelectors) == 0 {
+		return "", common.ColumnType{}, selector.ColumnSelector{}, errors.NewPranaErrorf(errors.InvalidStatement,
+			"Source %s has no column selectors", c.sourceInfo.Name)
+	}
+	colType, err := addColumn.Column.ToColumnType()
+	if err != nil {
+		return "", common.ColumnType{}, selector.ColumnSelector{}, errors.WithStack(err)
+	}
+	colSelector, err := selector.ParseColumnSelector(addColumn.Selector)
+	if err != nil {
+		return "", common.ColumnType{}, selector.ColumnSelector{}, errors.NewPranaErrorf(errors.InvalidSelector,
+			"invalid column selector %q", addColumn.Selector)
+	}
+	if err := checkColumnSelector(colSelector); err != nil {
+		return "", common.ColumnType{}, selector.ColumnSelector{}, errors.WithStack(err)
+	}
+	return addColumn.Column.Name, colType, colSelector, nil
+}
@@ -110,7 +110,12 @@ func (e *Executor) ExecuteSQLStatement(session *sess.Session, sql string) (exec.
 		ex, err := e.execExecute(session, ast.Execute)
 		return ex, errors.WithStack(err)
 	case ast.Create != nil && ast.Create.Source != nil:
-		sequences, err := e.generateTableIDSequences(1)
+		numSequences := 1
+		if sourceHasRetention(ast.Create.Source) {
+			// The retention index needs an ID too
+			numSequences++
+		}
+		sequences, err := e.generateTableIDSequences(numSequences)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -121,7 +126,16 @@ func (e *Executor) ExecuteSQLStatement(session *sess.Session, sql string) (exec.
 		}
 		return exec.Empty, nil
 	case ast.Create != nil && ast.Create.MaterializedView != nil:
-		sequences, err := e.generateTableIDSequences(3)
+		session.Planner().RefreshInfoSchema()
+		numSequences, err := push.NumTableIDsRequired(session.Planner(), ast.Create.MaterializedView.Query.String())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if mvHasRetention(ast.Create.MaterializedView) {
+			// The retention index needs an ID too
+			numSequences++
+		}
+		sequences, err := e.generateTableIDSequences(numSequences)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -142,6 +156,28 @@ func (e *Executor) ExecuteSQLStatement(session *sess.Session, sql string) (exec.
 			return nil, errors.WithStack(err)
 		}
 		return exec.Empty, nil
+	case ast.Create != nil && ast.Create.Sink != nil:
+		sequences, err := e.generateTableIDSequences(1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		command := NewOriginatingCreateSinkCommand(e, session.Schema.Name, sql, sequences, ast.Create.Sink)
+		err = e.ddlRunner.RunCommand(command)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return exec.Empty, nil
+	case ast.Create != nil && ast.Create.Table != nil:
+		sequences, err := e.generateTableIDSequences(1)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		command := NewOriginatingCreateTableCommand(e, session.Schema.Name, sql, sequences, ast.Create.Table)
+		err = e.ddlRunner.RunCommand(command)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return exec.Empty, nil
 	case ast.Drop != nil && ast.Drop.Source:
 		command := NewOriginatingDropSourceCommand(e, session.Schema.Name, sql, ast.Drop.Name)
 		err = e.ddlRunner.RunCommand(command)
@@ -163,6 +199,44 @@ func (e *Executor) ExecuteSQLStatement(session *sess.Session, sql string) (exec.
 			return nil, errors.WithStack(err)
 		}
 		return exec.Empty, nil
+	case ast.Drop != nil && ast.Drop.Sink:
+		command := NewOriginatingDropSinkCommand(e, session.Schema.Name, sql, ast.Drop.Name)
+		err = e.ddlRunner.RunCommand(command)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return exec.Empty, nil
+	case ast.Drop != nil && ast.Drop.Table:
+		command := NewOriginatingDropTableCommand(e, session.Schema.Name, sql, ast.Drop.Name)
+		err = e.ddlRunner.RunCommand(command)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return exec.Empty, nil
+	case ast.Alter != nil && ast.Alter.Source != nil:
+		var sequences []uint64
+		if ast.Alter.Source.AddColumn != nil {
+			// The sequence tells which materialized views were created before the column was added
+			sequences, err = e.generateTableIDSequences(1)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		command := NewOriginatingAlterSourceCommand(e, session.Schema.Name, sql, sequences, ast.Alter.Source)
+		err = e.ddlRunner.RunCommand(command)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return exec.Empty, nil
+	case ast.Insert != nil:
+		ex, err := e.execInsert(session, ast.Insert)
+		return ex, errors.WithStack(err)
+	case ast.Update != nil:
+		ex, err := e.execUpdate(session, ast.Update)
+		return ex, errors.WithStack(err)
+	case ast.Delete != nil:
+		ex, err := e.execDelete(session, ast.Delete)
+		return ex, errors.WithStack(err)
 	case ast.Use != "":
 		return e.execUse(session, ast.Use)
 	case ast.Show != nil && ast.Show.Tables != "":
@@ -177,6 +251,12 @@ func (e *Executor) ExecuteSQLStatement(session *sess.Session, sql string) (exec.
 			return nil, errors.WithStack(err)
 		}
 		return rows, nil
+	case ast.Show != nil && ast.Show.SourceStatus:
+		rows, err := e.execShowSourceStatus(session, ast.Show.SourceName)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return rows, nil
 	case ast.Describe != "":
 		rows, err := e.execDescribe(session, ast.Describe)
 		if err != nil {
@@ -238,6 +318,24 @@ func (e *Executor) generateTableIDSequences(numValues int) ([]uint64, error) {
 	return tableIDSequences, nil
 }
 
+func sourceHasRetention(ast *parser.CreateSource) bool {
+	for _, opt := range ast.TopicInformation {
+		if opt.Retention != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func mvHasRetention(ast *parser.CreateMaterializedView) bool {
+	for _, opt := range ast.Options {
+		if opt.Retention != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *Executor) execPrepare(session *sess.Session, sql string) (exec.PullExecutor, error) {
 	// TODO we should really use the parser to do this
 	sql = strings.ToLower(sql)
@@ -270,7 +368,7 @@ func (e *Executor) execUse(session *sess.Session, schemaName string) (exec.PullE
 }
 
 func (e *Executor) execShowTables(session *sess.Session) (exec.PullExecutor, error) {
-	rows, err := e.pullEngine.ExecuteQuery("sys", fmt.Sprintf("select name, kind from tables where schema_name='%s' and kind <> 'internal' order by kind, name", session.Schema.Name))
+	rows, err := e.pullEngine.ExecuteQuery("sys", fmt.Sprintf("select name, kind from tables where schema_name='%s' and kind <> 'internal' and kind <> 'sink' order by kind, name", session.Schema.Name))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -320,7 +418,7 @@ func describeRows(tableInfo *common.TableInfo) (exec.PullExecutor, error) {
 
 func (e *Executor) execDescribe(session *sess.Session, tableName string) (exec.PullExecutor, error) {
 	// NB: We select a specific set of columns because the Decode*Row() methods expect a row with *_info columns on certain predefined positions.
-	rows, err := e.pullEngine.ExecuteQuery("sys", fmt.Sprintf("select id, kind, schema_name, name, table_info, topic_info, query, mv_name from tables where schema_name='%s' and name='%s'", session.Schema.Name, tableName))
+	rows, err := e.pullEngine.ExecuteQuery("sys", fmt.Sprintf("select id, kind, schema_name, name, table_info, topic_info, query, mv_name from tables where schema_name='%s' and name='%s' and kind <> 'sink'", session.Schema.Name, tableName))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -338,6 +436,8 @@ func (e *Executor) execDescribe(session *sess.Session, tableName string) (exec.P
 		tableInfo = meta.DecodeSourceInfoRow(&tableRow).TableInfo
 	case meta.TableKindMaterializedView:
 		tableInfo = meta.DecodeMaterializedViewInfoRow(&tableRow).TableInfo
+	case meta.TableKindUserTable:
+		tableInfo = meta.DecodeUserTableInfoRow(&tableRow).TableInfo
 	case meta.TableKindInternal:
 		// NB: This case is for completness as sys.table doesn't know about internal tables.
 		tableInfo = meta.DecodeInternalTableInfoRow(&tableRow).TableInfo
@@ -363,6 +463,9 @@ func storeToDeleteBatch(tableID uint64, clust cluster.Cluster) (*cluster.ToDelet
 	for _, shardID := range clust.GetAllShardIDs() {
 		prefix := table.EncodeTableKeyPrefix(tableID, shardID, 16)
 		prefixes = append(prefixes, prefix)
+		// The watermark of the table on the shard, if it has one, see push.receivedWatermarks
+		watermarkPrefix := table.EncodeTableKeyPrefix(common.WatermarkTableID, shardID, 24)
+		prefixes = append(prefixes, common.AppendUint64ToBufferBE(watermarkPrefix, tableID))
 	}
 	batch := &cluster.ToDeleteBatch{
 		ConditionalTableID: tableID,
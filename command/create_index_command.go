@@ -148,7 +148,10 @@ func (c *CreateIndexCommand) getIndexInfo(ast *parser.CreateIndex) (*common.Inde
 	if !ok {
 		tab, ok = c.e.metaController.GetMaterializedView(c.SchemaName(), ast.TableName)
 		if !ok {
-			return nil, errors.NewUnknownSourceOrMaterializedViewError(c.SchemaName(), ast.TableName)
+			tab, ok = c.e.metaController.GetUserTable(c.SchemaName(), ast.TableName)
+			if !ok {
+				return nil, errors.NewUnknownSourceOrMaterializedViewError(c.SchemaName(), ast.TableName)
+			}
 		}
 	}
 	tabInfo := tab.GetTableInfo()
@@ -12,16 +12,21 @@ import (
 	"sync"
 )
 
+// CreateMVCommand creates a materialized view, or replaces the materialized view with the same name for CREATE OR
+// REPLACE. A replacement is created and filled alongside the materialized view it replaces, which can still be queried
+// while it's filled. Then they're swapped on every node, and the materialized view which was replaced is dropped.
 type CreateMVCommand struct {
-	lock           sync.Mutex
-	e              *Executor
-	pl             *parplan.Planner
-	schema         *common.Schema
-	createMVSQL    string
-	tableSequences []uint64
-	mv             *push.MaterializedView
-	ast            *parser.CreateMaterializedView
-	toDeleteBatch  *cluster.ToDeleteBatch
+	lock                  sync.Mutex
+	e                     *Executor
+	pl                    *parplan.Planner
+	schema                *common.Schema
+	createMVSQL           string
+	tableSequences        []uint64
+	mv                    *push.MaterializedView
+	replaced              *push.MaterializedView
+	ast                   *parser.CreateMaterializedView
+	toDeleteBatch         *cluster.ToDeleteBatch
+	replacedToDeleteBatch *cluster.ToDeleteBatch
 }
 
 func (c *CreateMVCommand) CommandType() DDLCommandType {
@@ -96,9 +101,12 @@ func (c *CreateMVCommand) Before() error {
 		return errors.WithStack(err)
 	}
 	c.mv = mv
-	_, ok := c.e.metaController.GetMaterializedView(mv.Info.SchemaName, mv.Info.Name)
-	if ok {
-		return errors.NewMaterializedViewAlreadyExistsError(mv.Info.SchemaName, mv.Info.Name)
+	if err := c.findReplaced(); err != nil {
+		return errors.WithStack(err)
+	}
+	if c.replaced != nil {
+		// The materialized view being replaced is in storage with the name
+		return nil
 	}
 	rows, err := c.e.pullEngine.ExecuteQuery("sys",
 		fmt.Sprintf("select id from tables where schema_name='%s' and name='%s' and kind='%s'", c.mv.Info.SchemaName, c.mv.Info.Name, meta.TableKindMaterializedView))
@@ -124,6 +132,9 @@ func (c *CreateMVCommand) onPhase0() error {
 			return errors.WithStack(err)
 		}
 		c.mv = mv
+		if err := c.findReplaced(); err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
 	// We store rows in the to_delete table - if MV creation fails (e.g. node crashes) then on restart the MV state will
@@ -163,7 +174,11 @@ func (c *CreateMVCommand) onPhase2() error {
 	if err := c.e.pushEngine.RegisterMV(c.mv); err != nil {
 		return errors.WithStack(err)
 	}
-	if err := c.e.metaController.RegisterMaterializedView(c.mv.Info, c.mv.InternalTables); err != nil {
+	if c.replaced != nil {
+		if err := c.swapReplaced(); err != nil {
+			return errors.WithStack(err)
+		}
+	} else if err := c.e.metaController.RegisterMaterializedView(c.mv.Info, c.mv.InternalTables); err != nil {
 		return err
 	}
 	// Maybe inject an error after fill and after row in tables table is persisted but before to_delete rows removed
@@ -178,7 +193,8 @@ func (c *CreateMVCommand) onPhase2() error {
 func (c *CreateMVCommand) AfterPhase(phase int32) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if phase == 1 {
+	switch phase {
+	case 1:
 		// Maybe inject an error after fill but before row in tables table is persisted
 		if err := c.e.FailureInjector().GetFailpoint("create_mv_1").CheckFail(); err != nil {
 			return err
@@ -188,17 +204,142 @@ func (c *CreateMVCommand) AfterPhase(phase int32) error {
 		// We only do this on the originating node
 		// We need to do this *before* the MV is available to clients otherwise a node failure and restart could cause
 		// the MV to disappear after it's been used
-		return c.e.metaController.PersistMaterializedView(c.mv.Info, c.mv.InternalTables)
+		if c.replaced == nil {
+			return c.e.metaController.PersistMaterializedView(c.mv.Info, c.mv.InternalTables)
+		}
+
+		// The data of the replaced MV is deleted on restart if there's a failure once it's no longer in the tables table
+		var err error
+		c.replacedToDeleteBatch, err = storeToDeleteBatch(c.replaced.Info.ID, c.e.cluster)
+		if err != nil {
+			return err
+		}
+		return c.e.metaController.PersistReplacedMaterializedView(c.replaced.Info, c.replaced.InternalTables, c.mv.Info,
+			c.mv.InternalTables)
+	case 2:
+		if c.replacedToDeleteBatch != nil {
+			return c.e.cluster.RemoveToDeleteBatch(c.replacedToDeleteBatch)
+		}
 	}
 	return nil
 }
 
+// findReplaced finds the MV with the same name as the new one, which it replaces. It's an error if there is one and
+// the statement isn't CREATE OR REPLACE
+func (c *CreateMVCommand) findReplaced() error {
+	schemaName, mvName := c.mv.Info.SchemaName, c.mv.Info.Name
+	mvInfo, ok := c.e.metaController.GetMaterializedView(schemaName, mvName)
+	if !ok {
+		return nil
+	}
+	if !c.ast.OrReplace {
+		return errors.NewMaterializedViewAlreadyExistsError(schemaName, mvName)
+	}
+	replaced, err := c.e.pushEngine.GetMaterializedView(mvInfo.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Anything consuming the replaced MV would be left consuming nothing
+	childMVs, indexes, sinks := c.consumersOfReplaced(mvInfo, replaced)
+	if len(childMVs) != 0 || len(indexes) != 0 || len(sinks) != 0 {
+		return errors.NewMaterializedViewHasChildrenReplaceError(schemaName, mvName, childMVs, indexes, sinks)
+	}
+	if c.mv.ConsumesFrom(mvName) {
+		return errors.NewPranaErrorf(errors.InvalidStatement, "Materialized view %s.%s cannot select from itself",
+			schemaName, mvName)
+	}
+	c.replaced = replaced
+	c.mv.SetReplacing()
+	return nil
+}
+
+// consumersOfReplaced returns the names of the materialized views, indexes and sinks which consume from the replaced
+// materialized view
+func (c *CreateMVCommand) consumersOfReplaced(mvInfo *common.MaterializedViewInfo,
+	replaced *push.MaterializedView) ([]string, []string, []string) {
+	mvName := mvInfo.Name
+	indexConsumers := make(map[string]string, len(mvInfo.IndexInfos))
+	for indexName := range mvInfo.IndexInfos {
+		indexConsumers[fmt.Sprintf("%s.%s", mvName, indexName)] = indexName
+	}
+	var childMVs, indexes, sinks []string
+	for _, consumerName := range replaced.GetConsumingMVs() {
+		if indexName, ok := indexConsumers[consumerName]; ok {
+			indexes = append(indexes, indexName)
+		} else if sinkInfo, ok := c.schema.GetSink(consumerName); ok && sinkInfo.MaterializedViewName == mvName {
+			sinks = append(sinks, consumerName)
+		} else {
+			childMVs = append(childMVs, consumerName)
+		}
+	}
+	return childMVs, indexes, sinks
+}
+
+// swapReplaced swaps the replaced MV for the new one, which is already registered with the push engine, then drops it
+func (c *CreateMVCommand) swapReplaced() error {
+	var itNames []string
+	for _, it := range c.replaced.InternalTables {
+		itNames = append(itNames, it.Name)
+	}
+	if err := c.e.metaController.RegisterReplacedMaterializedView(itNames, c.mv.Info, c.mv.InternalTables); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := c.replaced.Disconnect(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := c.mv.FinishReplace(); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := c.e.pushEngine.RemoveMV(c.replaced.Info.ID); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.replaced.Drop()
+}
+
 func (c *CreateMVCommand) createMVFromAST(ast *parser.CreateMaterializedView) (*push.MaterializedView, error) {
 	mvName := ast.Name.String()
 	querySQL := ast.Query.String()
 	seqGenerator := common.NewPreallocSeqGen(c.tableSequences)
 	tableID := seqGenerator.GenerateSequence()
-	return push.CreateMaterializedView(c.e.pushEngine, c.pl, c.schema, mvName, querySQL, tableID, seqGenerator)
+	mv, err := push.CreateMaterializedView(c.e.pushEngine, c.pl, c.schema, mvName, querySQL, tableID, true,
+		seqGenerator)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	retention, err := getMVRetentionInfo(ast.Options, mv.Info)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if retention != nil {
+		// The ID of the retention index comes after the IDs of the tables of the materialized view
+		retention.IndexID = seqGenerator.GenerateSequence()
+	}
+	mv.Info.Retention = retention
+	return mv, nil
+}
+
+// getMVRetentionInfo returns the retention given by the options of a CREATE MATERIALIZED VIEW statement. Unlike a
+// source, a materialized view has no ingest time, so the retention column must be given.
+func getMVRetentionInfo(options []*parser.MaterializedViewOption, mvInfo *common.MaterializedViewInfo) (*common.RetentionInfo, error) {
+	var retention, retentionCol, retentionPropagate string
+	for _, opt := range options {
+		switch {
+		case opt.Retention != "":
+			retention = opt.Retention
+		case opt.RetentionColumn != "":
+			retentionCol = opt.RetentionColumn
+		case opt.RetentionPropagate != "":
+			retentionPropagate = opt.RetentionPropagate
+		}
+	}
+	if retention != "" && retentionCol == "" {
+		return nil, errors.NewInvalidStatementError("retention of a materialized view requires retentionColumn")
+	}
+	colIndex := make(map[string]int, len(mvInfo.ColumnNames))
+	for i, colName := range mvInfo.ColumnNames {
+		colIndex[colName] = i
+	}
+	return getRetentionInfo(retention, retentionCol, retentionPropagate, colIndex, mvInfo.ColumnTypes)
 }
 
 func (c *CreateMVCommand) createMV() (*push.MaterializedView, error) {
@@ -209,5 +350,6 @@ func (c *CreateMVCommand) createMV() (*push.MaterializedView, error) {
 	if ast.Create == nil || ast.Create.MaterializedView == nil {
 		return nil, errors.Errorf("not a create materialized view %s", c.createMVSQL)
 	}
-	return c.createMVFromAST(ast.Create.MaterializedView)
+	c.ast = ast.Create.MaterializedView
+	return c.createMVFromAST(c.ast)
 }
@@ -0,0 +1,237 @@
+package command
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/squareup/pranadb/command/parser"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/kafka"
+	"github.com/squareup/pranadb/meta"
+)
+
+type CreateSinkCommand struct {
+	lock           sync.Mutex
+	e              *Executor
+	schemaName     string
+	sql            string
+	tableSequences []uint64
+	ast            *parser.CreateSink
+	sinkInfo       *common.SinkInfo
+}
+
+func (c *CreateSinkCommand) CommandType() DDLCommandType {
+	return DDLCommandTypeCreateSink
+}
+
+func (c *CreateSinkCommand) SchemaName() string {
+	return c.schemaName
+}
+
+func (c *CreateSinkCommand) SQL() string {
+	return c.sql
+}
+
+func (c *CreateSinkCommand) TableSequences() []uint64 {
+	return c.tableSequences
+}
+
+func (c *CreateSinkCommand) LockName() string {
+	return c.schemaName + "/"
+}
+
+func NewOriginatingCreateSinkCommand(e *Executor, schemaName string, sql string, tableSequences []uint64, ast *parser.CreateSink) *CreateSinkCommand {
+	return &CreateSinkCommand{
+		e:              e,
+		schemaName:     schemaName,
+		sql:            sql,
+		tableSequences: tableSequences,
+		ast:            ast,
+	}
+}
+
+func NewCreateSinkCommand(e *Executor, schemaName string, sql string, tableSequences []uint64) *CreateSinkCommand {
+	return &CreateSinkCommand{
+		e:              e,
+		schemaName:     schemaName,
+		sql:            sql,
+		tableSequences: tableSequences,
+	}
+}
+
+func (c *CreateSinkCommand) Before() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var err error
+	c.sinkInfo, err = c.getSinkInfo(c.ast)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.validate()
+}
+
+func (c *CreateSinkCommand) validate() error {
+	mvInfo, ok := c.e.metaController.GetMaterializedView(c.schemaName, c.sinkInfo.MaterializedViewName)
+	if !ok {
+		return errors.NewUnknownMaterializedViewError(c.schemaName, c.sinkInfo.MaterializedViewName)
+	}
+	if _, ok := c.e.metaController.GetSink(c.schemaName, c.sinkInfo.Name); ok {
+		return errors.NewSinkAlreadyExistsError(c.schemaName, c.sinkInfo.Name)
+	}
+	rows, err := c.e.pullEngine.ExecuteQuery("sys",
+		fmt.Sprintf("select id from tables where schema_name='%s' and name='%s' and kind='%s'", c.sinkInfo.SchemaName, c.sinkInfo.Name, meta.TableKindSink))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if rows.RowCount() != 0 {
+		return errors.Errorf("sink with name %s.%s already exists in storage", c.sinkInfo.SchemaName, c.sinkInfo.Name)
+	}
+	// Check we can encode messages with the key and value encodings
+	topicInfo := c.sinkInfo.TopicInfo
+	_, err = kafka.NewMessageEncoder(topicInfo.KeyEncoding, topicInfo.ValueEncoding, c.e.protoRegistry)
+	if err != nil {
+		return err
+	}
+	return validateSinkKey(topicInfo.KeyEncoding, mvInfo.TableInfo)
+}
+
+// validateSinkKey checks that the key of the MV can be encoded with the key encoding - we must do this up front as a
+// row that can't be encoded would stop the sink
+func validateSinkKey(keyEncoding common.KafkaEncoding, tableInfo *common.TableInfo) error {
+	var keyType common.Type
+	switch keyEncoding.Encoding {
+	case common.EncodingJSON:
+		return nil
+	case common.EncodingStringBytes:
+		keyType = common.TypeVarchar
+	case common.EncodingInt64BE:
+		keyType = common.TypeBigInt
+	case common.EncodingInt32BE, common.EncodingInt16BE:
+		keyType = common.TypeInt
+	case common.EncodingFloat64BE, common.EncodingFloat32BE:
+		keyType = common.TypeDouble
+	}
+	if len(tableInfo.PrimaryKeyCols) != 1 || tableInfo.ColumnTypes[tableInfo.PrimaryKeyCols[0]].Type != keyType {
+		colType := common.ColumnType{Type: keyType}
+		return errors.NewPranaErrorf(errors.UnknownTopicEncoding,
+			"Key encoding %s requires materialized view %s to have a single key column of type %s",
+			keyEncoding.Encoding, tableInfo.Name, colType.String())
+	}
+	return nil
+}
+
+func (c *CreateSinkCommand) OnPhase(phase int32) error {
+	switch phase {
+	case 0:
+		return c.onPhase0()
+	case 1:
+		return c.onPhase1()
+	default:
+		panic("invalid phase")
+	}
+}
+
+func (c *CreateSinkCommand) NumPhases() int {
+	return 2
+}
+
+func (c *CreateSinkCommand) onPhase0() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sinkInfo == nil {
+		ast, err := parser.Parse(c.sql)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if ast.Create == nil || ast.Create.Sink == nil {
+			return errors.Errorf("not a create sink %s", c.sql)
+		}
+		c.sinkInfo, err = c.getSinkInfo(ast.Create.Sink)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// Create the sink in the push engine - this publishes the current contents of the MV and then any changes to it
+	return c.e.pushEngine.CreateSink(c.sinkInfo, true)
+}
+
+func (c *CreateSinkCommand) onPhase1() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Register the sink in the in memory meta data
+	return c.e.metaController.RegisterSink(c.sinkInfo)
+}
+
+func (c *CreateSinkCommand) AfterPhase(phase int32) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if phase == 0 {
+		// We persist the sink *before* it is registered - otherwise if failure occurs the sink can disappear after
+		// being used
+		return c.e.metaController.PersistSink(c.sinkInfo)
+	}
+	return nil
+}
+
+func (c *CreateSinkCommand) getSinkInfo(ast *parser.CreateSink) (*common.SinkInfo, error) {
+	var (
+		keyEncoding, valueEncoding common.KafkaEncoding
+		propsMap                   map[string]string
+		brokerName, topicName      string
+	)
+	for _, opt := range ast.TopicInformation {
+		switch {
+		case opt.KeyEncoding != "":
+			keyEncoding = common.KafkaEncodingFromString(opt.KeyEncoding)
+			if keyEncoding.Encoding == common.EncodingUnknown {
+				return nil, errors.NewPranaErrorf(errors.UnknownTopicEncoding, "Unknown topic encoding %s", opt.KeyEncoding)
+			}
+		case opt.ValueEncoding != "":
+			valueEncoding = common.KafkaEncodingFromString(opt.ValueEncoding)
+			if valueEncoding.Encoding == common.EncodingUnknown {
+				return nil, errors.NewPranaErrorf(errors.UnknownTopicEncoding, "Unknown topic encoding %s", opt.ValueEncoding)
+			}
+		case opt.Properties != nil:
+			propsMap = make(map[string]string, len(opt.Properties))
+			for _, prop := range opt.Properties {
+				propsMap[prop.Key] = prop.Value
+			}
+		case opt.BrokerName != "":
+			brokerName = opt.BrokerName
+		case opt.TopicName != "":
+			topicName = opt.TopicName
+		default:
+			return nil, errors.NewInvalidStatementError("only BrokerName, TopicName, KeyEncoding, ValueEncoding and Properties can be specified for a sink")
+		}
+	}
+	if keyEncoding == common.KafkaEncodingUnknown {
+		return nil, errors.NewInvalidStatementError("keyEncoding is required")
+	}
+	if valueEncoding == common.KafkaEncodingUnknown {
+		return nil, errors.NewInvalidStatementError("valueEncoding is required")
+	}
+	if brokerName == "" {
+		return nil, errors.NewInvalidStatementError("brokerName is required")
+	}
+	if topicName == "" {
+		return nil, errors.NewInvalidStatementError("topicName is required")
+	}
+	return &common.SinkInfo{
+		ID:                   c.tableSequences[0],
+		SchemaName:           c.schemaName,
+		Name:                 ast.Name,
+		MaterializedViewName: ast.MaterializedViewName,
+		TopicInfo: &common.TopicInfo{
+			BrokerName:    brokerName,
+			TopicName:     topicName,
+			KeyEncoding:   keyEncoding,
+			ValueEncoding: valueEncoding,
+			Properties:    propsMap,
+		},
+	}, nil
+}
@@ -2,6 +2,8 @@ package command
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"sync"
 
 	"github.com/alecthomas/repr"
@@ -9,8 +11,10 @@ import (
 	"github.com/squareup/pranadb/command/parser/selector"
 	"github.com/squareup/pranadb/common"
 	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/kafka"
 	"github.com/squareup/pranadb/meta"
 	"github.com/squareup/pranadb/push/source"
+	"github.com/squareup/pranadb/tidb/expression"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
@@ -102,20 +106,27 @@ func (c *CreateSourceCommand) validate() error {
 	}
 
 	for _, sel := range topicInfo.ColSelectors {
-		if sel.MetaKey == nil && len(sel.Selector) == 0 {
-			return errors.NewPranaErrorf(errors.InvalidSelector, "invalid column selector %q", sel)
-		}
-		if sel.MetaKey != nil {
-			f := *sel.MetaKey
-			if !(f == "header" || f == "key" || f == "timestamp") {
-				return errors.NewPranaErrorf(errors.InvalidSelector, `invalid metadata key in column selector %q. Valid values are "header", "key", "timestamp".`, sel)
-			}
+		if err := checkColumnSelector(sel); err != nil {
+			return errors.WithStack(err)
 		}
 	}
 
 	return nil
 }
 
+func checkColumnSelector(sel selector.ColumnSelector) error {
+	if sel.MetaKey == nil && len(sel.Selector) == 0 {
+		return errors.NewPranaErrorf(errors.InvalidSelector, "invalid column selector %q", sel)
+	}
+	if sel.MetaKey != nil {
+		f := *sel.MetaKey
+		if !(f == "header" || f == "key" || f == "value" || f == "timestamp" || f == "topic") {
+			return errors.NewPranaErrorf(errors.InvalidSelector, `invalid metadata key in column selector %q. Valid values are "header", "key", "value", "timestamp", "topic".`, sel)
+		}
+	}
+	return nil
+}
+
 func (c *CreateSourceCommand) OnPhase(phase int32) error {
 	switch phase {
 	case 0:
@@ -178,6 +189,17 @@ func (c *CreateSourceCommand) AfterPhase(phase int32) error {
 	defer c.lock.Unlock()
 
 	if phase == 0 {
+		// The consumers of the source haven't been started yet, so this is where we move them to where the source
+		// starts from. By default a new consumer group starts from the earliest message.
+		if startFrom := c.sourceInfo.TopicInfo.StartFrom; startFrom != nil && startFrom.Kind != common.StartFromEarliest {
+			offsets, err := c.source.GetStartOffsets(startFrom)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if err := c.source.SetOffsets(offsets); err != nil {
+				return errors.WithStack(err)
+			}
+		}
 		// We persist the source *before* it is registered - otherwise if failure occurs source can disappear after
 		// being used
 		return c.e.metaController.PersistSource(c.sourceInfo)
@@ -187,44 +209,24 @@ func (c *CreateSourceCommand) AfterPhase(phase int32) error {
 
 // nolint: gocyclo
 func (c *CreateSourceCommand) getSourceInfo(ast *parser.CreateSource) (*common.SourceInfo, error) {
-	var (
-		colNames []string
-		colTypes []common.ColumnType
-		colIndex = map[string]int{}
-		pkCols   []int
-	)
-	for i, option := range ast.Options {
-		switch {
-		case option.Column != nil:
-			// Convert AST column definition to a ColumnType.
-			col := option.Column
-			colIndex[col.Name] = i
-			colNames = append(colNames, col.Name)
-			colType, err := col.ToColumnType()
-			if err != nil {
-				return nil, errors.WithStack(err)
-			}
-			colTypes = append(colTypes, colType)
-
-		case len(option.PrimaryKey) > 0:
-			for _, pk := range option.PrimaryKey {
-				index, ok := colIndex[pk]
-				if !ok {
-					return nil, errors.Errorf("invalid primary key column %q", option.PrimaryKey)
-				}
-				pkCols = append(pkCols, index)
-			}
-
-		default:
-			panic(repr.String(option))
-		}
+	colNames, colTypes, colIndex, pkCols, err := getColumns(ast.Options)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
 	var (
-		headerEncoding, keyEncoding, valueEncoding common.KafkaEncoding
-		propsMap                                   map[string]string
-		colSelectors                               []selector.ColumnSelector
-		brokerName, topicName                      string
+		headerEncoding, keyEncoding, valueEncoding  common.KafkaEncoding
+		propsMap                                    map[string]string
+		colSelectors                                []selector.ColumnSelector
+		brokerName, topicName, topicPattern         string
+		topicNames                                  []string
+		eventTimeCol, allowedLateness, idleTimeout  string
+		retention, retentionCol, retentionPropagate string
+		csvDelimiter, csvQuote                      *string
+		semantics                                   = common.SourceSemanticsUpsert
+		errorPolicy                                 = common.SourceErrorPolicyFail
+		deadLetterTopic                             string
+		startFrom                                   *common.StartFrom
 	)
 	for _, opt := range ast.TopicInformation {
 		switch {
@@ -258,6 +260,43 @@ func (c *CreateSourceCommand) getSourceInfo(ast *parser.CreateSource) (*common.S
 			brokerName = opt.BrokerName
 		case opt.TopicName != "":
 			topicName = opt.TopicName
+		case opt.TopicNames != nil:
+			topicNames = opt.TopicNames
+		case opt.TopicPattern != "":
+			topicPattern = opt.TopicPattern
+		case opt.EventTime != "":
+			eventTimeCol = opt.EventTime
+		case opt.AllowedLateness != "":
+			allowedLateness = opt.AllowedLateness
+		case opt.IdleTimeout != "":
+			idleTimeout = opt.IdleTimeout
+		case opt.Semantics != "":
+			semantics = common.SourceSemanticsFromString(opt.Semantics)
+			if semantics == common.SourceSemanticsUnknown {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Unknown semantics %s", opt.Semantics)
+			}
+		case opt.Retention != "":
+			retention = opt.Retention
+		case opt.RetentionColumn != "":
+			retentionCol = opt.RetentionColumn
+		case opt.RetentionPropagate != "":
+			retentionPropagate = opt.RetentionPropagate
+		case opt.CSVDelimiter != nil:
+			csvDelimiter = opt.CSVDelimiter
+		case opt.CSVQuote != nil:
+			csvQuote = opt.CSVQuote
+		case opt.ErrorPolicy != "":
+			errorPolicy = common.SourceErrorPolicyFromString(opt.ErrorPolicy)
+			if errorPolicy == common.SourceErrorPolicyUnknown {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Unknown errorPolicy %s", opt.ErrorPolicy)
+			}
+		case opt.DeadLetterTopic != "":
+			deadLetterTopic = opt.DeadLetterTopic
+		case opt.StartFrom != "":
+			startFrom, err = common.ParseStartFrom(opt.StartFrom)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
 		}
 	}
 	if headerEncoding == common.KafkaEncodingUnknown {
@@ -272,8 +311,11 @@ func (c *CreateSourceCommand) getSourceInfo(ast *parser.CreateSource) (*common.S
 	if brokerName == "" {
 		return nil, errors.NewPranaError(errors.InvalidStatement, "brokerName is required")
 	}
-	if topicName == "" {
-		return nil, errors.NewPranaError(errors.InvalidStatement, "topicName is required")
+	if err := checkTopics(topicName, topicNames, topicPattern); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(topicNames) == 1 {
+		topicName, topicNames = topicNames[0], nil
 	}
 	lc := len(colSelectors)
 	if lc > 0 && lc != len(colTypes) {
@@ -281,14 +323,103 @@ func (c *CreateSourceCommand) getSourceInfo(ast *parser.CreateSource) (*common.S
 			"Number of column selectors (%d) must match number of columns (%d)", lc, len(colTypes))
 	}
 
+	if deadLetterTopic != "" && errorPolicy != common.SourceErrorPolicyDeadLetter {
+		return nil, errors.NewInvalidStatementError("deadLetterTopic requires errorPolicy deadletter")
+	}
+	if deadLetterTopic != "" && deadLetterTopic == topicName {
+		return nil, errors.NewInvalidStatementError("deadLetterTopic must be different to topicName")
+	}
+	for _, name := range topicNames {
+		if deadLetterTopic == name {
+			return nil, errors.NewInvalidStatementError("deadLetterTopic must not be one of topicNames")
+		}
+	}
+	if deadLetterTopic != "" && topicPattern != "" && regexp.MustCompile(kafka.TopicPattern(topicPattern)).MatchString(deadLetterTopic) {
+		return nil, errors.NewInvalidStatementError("deadLetterTopic must not match topicPattern")
+	}
+	if err := checkStartFrom(startFrom, len(topicNames) > 0 || topicPattern != ""); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	csvOptions, err := getCSVOptions(csvDelimiter, csvQuote, headerEncoding, keyEncoding, valueEncoding)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	eventTime, err := getEventTimeInfo(eventTimeCol, allowedLateness, idleTimeout, colIndex, colTypes)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if semantics == common.SourceSemanticsChangelog {
+		// A tombstone has no value, so the key of the row it deletes must come from the message key
+		for _, pkCol := range pkCols {
+			if lc == 0 || colSelectors[pkCol].MetaKey == nil || *colSelectors[pkCol].MetaKey != "key" {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement,
+					"Primary key column %s must be selected from the message key for changelog semantics", colNames[pkCol])
+			}
+		}
+	}
+
+	if retentionCol == "" && retention != "" && eventTime != nil {
+		retentionCol = colNames[eventTime.ColIndex]
+	}
+	retentionInfo, err := getRetentionInfo(retention, retentionCol, retentionPropagate, colIndex, colTypes)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if retentionInfo != nil {
+		// The ID of the retention index comes after the ID of the source
+		retentionInfo.IndexID = c.tableSequences[1]
+	}
+
+	var colsVisible []bool
+	if retentionInfo != nil && retentionCol == "" {
+		// There's no column to tell how old a row is, so we record when it was ingested
+		colsVisible = visibleCols(len(colNames))
+		colNames = append(colNames, common.IngestTimeColumnName)
+		colTypes = append(colTypes, common.NewTimestampColumnType(6))
+		colsVisible = append(colsVisible, false)
+		retentionInfo.ColIndex = len(colNames) - 1
+	}
+	if semantics == common.SourceSemanticsAppend {
+		if len(pkCols) != 0 {
+			return nil, errors.NewInvalidStatementError("a source with append semantics cannot have a primary key")
+		}
+		// Rows are keyed on the partition and offset of the message, which is unique across the topic and is also what
+		// duplicate detection uses, so a redelivered message replaces the row it created
+		if colsVisible == nil {
+			colsVisible = visibleCols(len(colNames))
+		}
+		if len(topicNames) > 0 || topicPattern != "" {
+			// The partition and offset are only unique within a topic
+			colNames = append(colNames, common.TopicColumnName)
+			colTypes = append(colTypes, common.VarcharColumnType)
+			colsVisible = append(colsVisible, false)
+			pkCols = []int{len(colNames) - 1}
+		}
+		colNames = append(colNames, common.PartitionIDColumnName, common.OffsetColumnName)
+		colTypes = append(colTypes, common.BigIntColumnType, common.BigIntColumnType)
+		colsVisible = append(colsVisible, false, false)
+		pkCols = append(pkCols, len(colNames)-2, len(colNames)-1)
+	}
+
 	topicInfo := &common.TopicInfo{
-		BrokerName:     brokerName,
-		TopicName:      topicName,
-		HeaderEncoding: headerEncoding,
-		KeyEncoding:    keyEncoding,
-		ValueEncoding:  valueEncoding,
-		ColSelectors:   colSelectors,
-		Properties:     propsMap,
+		BrokerName:      brokerName,
+		TopicName:       topicName,
+		TopicNames:      topicNames,
+		TopicPattern:    topicPattern,
+		TopicIndexes:    topicIndexes(topicNames),
+		HeaderEncoding:  headerEncoding,
+		KeyEncoding:     keyEncoding,
+		ValueEncoding:   valueEncoding,
+		ColSelectors:    colSelectors,
+		Properties:      propsMap,
+		EventTime:       eventTime,
+		Semantics:       semantics,
+		CSVOptions:      csvOptions,
+		ErrorPolicy:     errorPolicy,
+		DeadLetterTopic: deadLetterTopic,
+		StartFrom:       startFrom,
 	}
 	tableInfo := common.TableInfo{
 		ID:             c.tableSequences[0],
@@ -298,9 +429,222 @@ func (c *CreateSourceCommand) getSourceInfo(ast *parser.CreateSource) (*common.S
 		ColumnNames:    colNames,
 		ColumnTypes:    colTypes,
 		IndexInfos:     nil,
+		ColsVisible:    colsVisible,
+		Retention:      retentionInfo,
 	}
 	return &common.SourceInfo{
 		TableInfo: &tableInfo,
 		TopicInfo: topicInfo,
 	}, nil
 }
+
+// getColumns returns the columns and primary key columns defined by the table options of a CREATE SOURCE or CREATE
+// TABLE statement, along with the index of each column by name
+func getColumns(options []*parser.TableOption) ([]string, []common.ColumnType, map[string]int, []int, error) {
+	var (
+		colNames []string
+		colTypes []common.ColumnType
+		colIndex = map[string]int{}
+		pkCols   []int
+	)
+	for i, option := range options {
+		switch {
+		case option.Column != nil:
+			// Convert AST column definition to a ColumnType.
+			col := option.Column
+			colIndex[col.Name] = i
+			colNames = append(colNames, col.Name)
+			colType, err := col.ToColumnType()
+			if err != nil {
+				return nil, nil, nil, nil, errors.WithStack(err)
+			}
+			colTypes = append(colTypes, colType)
+
+		case len(option.PrimaryKey) > 0:
+			for _, pk := range option.PrimaryKey {
+				index, ok := colIndex[pk]
+				if !ok {
+					return nil, nil, nil, nil, errors.Errorf("invalid primary key column %q", option.PrimaryKey)
+				}
+				pkCols = append(pkCols, index)
+			}
+
+		default:
+			panic(repr.String(option))
+		}
+	}
+	return colNames, colTypes, colIndex, pkCols, nil
+}
+
+func getEventTimeInfo(eventTimeCol string, allowedLateness string, idleTimeout string, colIndex map[string]int,
+	colTypes []common.ColumnType) (*common.EventTimeInfo, error) {
+	if eventTimeCol == "" {
+		if allowedLateness != "" || idleTimeout != "" {
+			return nil, errors.NewInvalidStatementError("allowedLateness and idleTimeout require eventTime")
+		}
+		return nil, nil
+	}
+	index, ok := colIndex[eventTimeCol]
+	if !ok {
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Unknown eventTime column %s", eventTimeCol)
+	}
+	if colTypes[index].Type != common.TypeTimestamp {
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "eventTime column %s must be a timestamp", eventTimeCol)
+	}
+	eventTime := &common.EventTimeInfo{ColIndex: index, IdleTimeout: common.DefaultIdleTimeout}
+	if allowedLateness != "" {
+		lateness, err := expression.ParseInterval(allowedLateness)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		eventTime.AllowedLateness = lateness
+	}
+	if idleTimeout != "" {
+		timeout, err := expression.ParseInterval(idleTimeout)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		eventTime.IdleTimeout = timeout
+	}
+	return eventTime, nil
+}
+
+// getRetentionInfo returns the retention of a source or materialized view with the given retention options, or nil if
+// it doesn't have one. If no retention column is given, the column index of the retention must be set by the caller.
+func getRetentionInfo(retention string, retentionCol string, propagate string, colIndex map[string]int,
+	colTypes []common.ColumnType) (*common.RetentionInfo, error) {
+	if retention == "" {
+		if retentionCol != "" || propagate != "" {
+			return nil, errors.NewInvalidStatementError("retentionColumn and retentionPropagate require retention")
+		}
+		return nil, nil
+	}
+	period, err := expression.ParseInterval(retention)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	retentionInfo := &common.RetentionInfo{Period: period, Propagate: true}
+	if retentionCol != "" {
+		index, ok := colIndex[retentionCol]
+		if !ok {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Unknown retentionColumn %s", retentionCol)
+		}
+		if colTypes[index].Type != common.TypeTimestamp {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "retentionColumn %s must be a timestamp", retentionCol)
+		}
+		retentionInfo.ColIndex = index
+	}
+	if propagate != "" {
+		retentionInfo.Propagate, err = strconv.ParseBool(propagate)
+		if err != nil {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid retentionPropagate %s, must be true or false",
+				propagate)
+		}
+	}
+	return retentionInfo, nil
+}
+
+// getCSVOptions returns how the CSV encoded parts of the messages are split into fields, or nil if none of the
+// encodings is CSV
+func getCSVOptions(delimiter *string, quote *string, encodings ...common.KafkaEncoding) (*common.CSVOptions, error) {
+	usesCSV := false
+	for _, enc := range encodings {
+		if enc.Encoding == common.EncodingCSV {
+			usesCSV = true
+		}
+	}
+	if !usesCSV {
+		if delimiter != nil || quote != nil {
+			return nil, errors.NewInvalidStatementError("csvDelimiter and csvQuote require a csv encoding")
+		}
+		return nil, nil
+	}
+	csvOptions := common.DefaultCSVOptions
+	if delimiter != nil {
+		d := []rune(*delimiter)
+		if len(d) != 1 || d[0] == '\n' || d[0] == '\r' {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid csvDelimiter %q, must be a single character",
+				*delimiter)
+		}
+		csvOptions.Delimiter = d[0]
+	}
+	if quote != nil {
+		q := []rune(*quote)
+		switch {
+		case len(q) == 0:
+			// Fields aren't quoted
+			csvOptions.Quote = 0
+		case len(q) == 1 && q[0] != csvOptions.Delimiter:
+			csvOptions.Quote = q[0]
+		default:
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement,
+				"Invalid csvQuote %q, must be empty or a single character other than the delimiter", *quote)
+		}
+	}
+	return &csvOptions, nil
+}
+
+func visibleCols(numCols int) []bool {
+	colsVisible := make([]bool, numCols)
+	for i := range colsVisible {
+		colsVisible[i] = true
+	}
+	return colsVisible
+}
+
+// checkTopics checks that exactly one of topicName, topicNames and topicPattern is specified
+func checkTopics(topicName string, topicNames []string, topicPattern string) error {
+	specified := 0
+	for _, ok := range []bool{topicName != "", topicNames != nil, topicPattern != ""} {
+		if ok {
+			specified++
+		}
+	}
+	if specified == 0 {
+		return errors.NewPranaErrorf(errors.InvalidStatement, "topicName, topicNames or topicPattern is required")
+	}
+	if specified > 1 {
+		return errors.NewPranaErrorf(errors.InvalidStatement, "only one of topicName, topicNames and topicPattern can be specified")
+	}
+	if len(topicNames) > source.MaxTopicIndex+1 {
+		return errors.NewPranaErrorf(errors.InvalidStatement, "topicNames cannot contain more than %d topics",
+			source.MaxTopicIndex+1)
+	}
+	names := make(map[string]struct{}, len(topicNames))
+	for _, name := range topicNames {
+		if name == "" {
+			return errors.NewPranaErrorf(errors.InvalidStatement, "topicNames cannot contain an empty topic name")
+		}
+		if _, ok := names[name]; ok {
+			return errors.NewPranaErrorf(errors.InvalidStatement, "topicNames contains topic %s more than once", name)
+		}
+		names[name] = struct{}{}
+	}
+	if topicPattern != "" {
+		if _, err := regexp.Compile(kafka.TopicPattern(topicPattern)); err != nil {
+			return errors.NewPranaErrorf(errors.InvalidStatement, "Invalid topicPattern %q: %v", topicPattern, err)
+		}
+	}
+	return nil
+}
+
+// topicIndexes gives each of the topic names its index, or returns nil if there aren't any
+func topicIndexes(topicNames []string) map[string]uint32 {
+	if len(topicNames) == 0 {
+		return nil
+	}
+	indexes := make(map[string]uint32, len(topicNames))
+	for i, name := range topicNames {
+		indexes[name] = uint32(i)
+	}
+	return indexes
+}
+
+// checkStartFrom checks that the start position can be used with the topics of a source. The offsets of partitions
+// can only be given for a source with a single topic, as they don't say which topic the partitions belong to.
+func checkStartFrom(startFrom *common.StartFrom, multipleTopics bool) error {
+	if startFrom != nil && startFrom.Kind == common.StartFromOffsets && multipleTopics {
+		return errors.NewPranaErrorf(errors.InvalidStatement, "startFrom offsets can only be used with a source which has a single topic")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package command
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/squareup/pranadb/command/parser"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/meta"
+)
+
+type CreateTableCommand struct {
+	lock           sync.Mutex
+	e              *Executor
+	schemaName     string
+	sql            string
+	tableSequences []uint64
+	ast            *parser.CreateTable
+	tableInfo      *common.UserTableInfo
+}
+
+func (c *CreateTableCommand) CommandType() DDLCommandType {
+	return DDLCommandTypeCreateTable
+}
+
+func (c *CreateTableCommand) SchemaName() string {
+	return c.schemaName
+}
+
+func (c *CreateTableCommand) SQL() string {
+	return c.sql
+}
+
+func (c *CreateTableCommand) TableSequences() []uint64 {
+	return c.tableSequences
+}
+
+func (c *CreateTableCommand) LockName() string {
+	return c.schemaName + "/"
+}
+
+func NewOriginatingCreateTableCommand(e *Executor, schemaName string, sql string, tableSequences []uint64, ast *parser.CreateTable) *CreateTableCommand {
+	return &CreateTableCommand{
+		e:              e,
+		schemaName:     schemaName,
+		sql:            sql,
+		tableSequences: tableSequences,
+		ast:            ast,
+	}
+}
+
+func NewCreateTableCommand(e *Executor, schemaName string, sql string, tableSequences []uint64) *CreateTableCommand {
+	return &CreateTableCommand{
+		e:              e,
+		schemaName:     schemaName,
+		sql:            sql,
+		tableSequences: tableSequences,
+	}
+}
+
+func (c *CreateTableCommand) Before() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var err error
+	c.tableInfo, err = c.getTableInfo(c.ast)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.validate()
+}
+
+func (c *CreateTableCommand) validate() error {
+	schema, ok := c.e.metaController.GetSchema(c.schemaName)
+	if ok {
+		if _, ok := schema.GetTable(c.tableInfo.Name); ok {
+			return errors.NewTableAlreadyExistsError(c.schemaName, c.tableInfo.Name)
+		}
+	}
+	rows, err := c.e.pullEngine.ExecuteQuery("sys",
+		fmt.Sprintf("select id from tables where schema_name='%s' and name='%s' and kind='%s'", c.tableInfo.SchemaName, c.tableInfo.Name, meta.TableKindUserTable))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if rows.RowCount() != 0 {
+		return errors.Errorf("table with name %s.%s already exists in storage", c.tableInfo.SchemaName, c.tableInfo.Name)
+	}
+	return nil
+}
+
+func (c *CreateTableCommand) OnPhase(phase int32) error {
+	switch phase {
+	case 0:
+		return c.onPhase0()
+	case 1:
+		return c.onPhase1()
+	default:
+		panic("invalid phase")
+	}
+}
+
+func (c *CreateTableCommand) NumPhases() int {
+	return 2
+}
+
+func (c *CreateTableCommand) onPhase0() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// If receiving on prepare from broadcast on the originating node, tableInfo will already be set
+	if c.tableInfo == nil {
+		ast, err := parser.Parse(c.sql)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if ast.Create == nil || ast.Create.Table == nil {
+			return errors.Errorf("not a create table %s", c.sql)
+		}
+		c.tableInfo, err = c.getTableInfo(ast.Create.Table)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// Create the table in the push engine so it can receive forwarded rows
+	_, err := c.e.pushEngine.CreateUserTable(c.tableInfo)
+	return errors.WithStack(err)
+}
+
+func (c *CreateTableCommand) onPhase1() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Register the table in the in memory meta data
+	return c.e.metaController.RegisterUserTable(c.tableInfo)
+}
+
+func (c *CreateTableCommand) AfterPhase(phase int32) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if phase == 0 {
+		// We persist the table *before* it is registered - otherwise if failure occurs the table can disappear after
+		// being used
+		return c.e.metaController.PersistUserTable(c.tableInfo)
+	}
+	return nil
+}
+
+func (c *CreateTableCommand) getTableInfo(ast *parser.CreateTable) (*common.UserTableInfo, error) {
+	colNames, colTypes, _, pkCols, err := getColumns(ast.Options)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(pkCols) == 0 {
+		return nil, errors.NewInvalidStatementError("primary key is required")
+	}
+	tableInfo := common.TableInfo{
+		ID:             c.tableSequences[0],
+		SchemaName:     c.schemaName,
+		Name:           ast.Name,
+		PrimaryKeyCols: pkCols,
+		ColumnNames:    colNames,
+		ColumnTypes:    colTypes,
+		IndexInfos:     nil,
+	}
+	return &common.UserTableInfo{TableInfo: &tableInfo}, nil
+}
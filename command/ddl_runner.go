@@ -50,6 +50,11 @@ const (
 	DDLCommandTypeDropMV
 	DDLCommandTypeCreateIndex
 	DDLCommandTypeDropIndex
+	DDLCommandTypeCreateSink
+	DDLCommandTypeDropSink
+	DDLCommandTypeCreateTable
+	DDLCommandTypeDropTable
+	DDLCommandTypeAlterSource
 )
 
 func NewDDLCommandRunner(ce *Executor) *DDLCommandRunner {
@@ -74,6 +79,16 @@ func NewDDLCommand(e *Executor, commandType DDLCommandType, schemaName string, s
 		return NewCreateIndexCommand(e, schemaName, sql, tableSequences)
 	case DDLCommandTypeDropIndex:
 		return NewDropIndexCommand(e, schemaName, sql)
+	case DDLCommandTypeCreateSink:
+		return NewCreateSinkCommand(e, schemaName, sql, tableSequences)
+	case DDLCommandTypeDropSink:
+		return NewDropSinkCommand(e, schemaName, sql)
+	case DDLCommandTypeCreateTable:
+		return NewCreateTableCommand(e, schemaName, sql, tableSequences)
+	case DDLCommandTypeDropTable:
+		return NewDropTableCommand(e, schemaName, sql)
+	case DDLCommandTypeAlterSource:
+		return NewAlterSourceCommand(e, schemaName, sql, tableSequences)
 	default:
 		panic("invalid ddl command")
 	}
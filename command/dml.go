@@ -0,0 +1,250 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/squareup/pranadb/command/parser"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/pull/exec"
+	"github.com/squareup/pranadb/push"
+	"github.com/squareup/pranadb/push/source"
+	"github.com/squareup/pranadb/sess"
+)
+
+// execInsert writes the rows of an INSERT statement to a user table. A row replaces any existing row with the same key.
+func (e *Executor) execInsert(session *sess.Session, insert *parser.Insert) (exec.PullExecutor, error) {
+	tableInfo, userTable, err := e.getUserTable(session, insert.TableName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// The index of the table column for each value in a row
+	var colIndexes []int
+	if len(insert.ColumnNames) == 0 {
+		for i := range tableInfo.ColumnNames {
+			colIndexes = append(colIndexes, i)
+		}
+	} else {
+		seen := make(map[int]struct{}, len(insert.ColumnNames))
+		for _, colName := range insert.ColumnNames {
+			colIndex, err := getColumnIndex(tableInfo, colName)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if _, ok := seen[colIndex]; ok {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Column %s is specified more than once", colName)
+			}
+			seen[colIndex] = struct{}{}
+			colIndexes = append(colIndexes, colIndex)
+		}
+	}
+	rows := common.NewRows(tableInfo.ColumnTypes, len(insert.Rows))
+	for i, insertRow := range insert.Rows {
+		if len(insertRow.Values) != len(colIndexes) {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement,
+				"Row %d has %d values but %d columns were expected", i+1, len(insertRow.Values), len(colIndexes))
+		}
+		// Columns which aren't specified are null
+		vals := make([]interface{}, len(tableInfo.ColumnNames))
+		for j, value := range insertRow.Values {
+			vals[colIndexes[j]] = literalValue(value)
+		}
+		if err := appendRow(tableInfo, rows, vals); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err := userTable.Upsert(rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return exec.Empty, nil
+}
+
+// execUpdate updates the rows of a user table which match the WHERE clause of an UPDATE statement. The rows are found
+// and the new values calculated with a pull query, then the changed columns are written to the rows on the shards that
+// own them, see push.UserTable.HandleRemoteRows. A value which refers to a column, such as an increment, is rejected -
+// it would be calculated from the row as it was read, and so could lose a concurrent write.
+func (e *Executor) execUpdate(session *sess.Session, update *parser.Update) (exec.PullExecutor, error) {
+	tableInfo, userTable, err := e.getUserTable(session, update.TableName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// The index in the query results of the new value for each assigned column
+	assigned := make(map[int]int, len(update.Assignments))
+	numCols := len(tableInfo.ColumnNames)
+	numExprs := 0
+	sb := strings.Builder{}
+	sb.WriteString("select ")
+	sb.WriteString(strings.Join(tableInfo.ColumnNames, ", "))
+	for _, assignment := range update.Assignments {
+		colIndex, err := getColumnIndex(tableInfo, assignment.Column)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if tableInfo.IsPrimaryKeyCol(colIndex) {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Primary key column %s cannot be updated",
+				assignment.Column)
+		}
+		if _, ok := assigned[colIndex]; ok {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Column %s is assigned more than once",
+				assignment.Column)
+		}
+		expr := assignment.Value.String()
+		// An expression which can't be parsed fails the query below
+		if exprAst, err := session.Planner().Parse("select " + expr); err == nil && exprAst.ReferencesColumns() {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement,
+				"The value assigned to column %s cannot refer to columns, as concurrent updates could be lost",
+				assignment.Column)
+		}
+		if strings.EqualFold(strings.TrimSpace(expr), "null") {
+			// The planner can't give a type to a null literal, so we don't select it
+			assigned[colIndex] = -1
+			continue
+		}
+		assigned[colIndex] = numCols + numExprs
+		numExprs++
+		sb.WriteString(",")
+		sb.WriteString(expr)
+	}
+	sb.WriteString(" from ")
+	sb.WriteString(update.TableName)
+	if update.Where != nil {
+		sb.WriteString(" where")
+		sb.WriteString(update.Where.String())
+	}
+	results, err := e.executeQuery(session, sb.String())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resultTypes := results.ColumnTypes()
+	prevRows := common.NewRows(tableInfo.ColumnTypes, results.RowCount())
+	rows := common.NewRows(tableInfo.ColumnTypes, results.RowCount())
+	for i := 0; i < results.RowCount(); i++ {
+		result := results.GetRow(i)
+		prevVals := make([]interface{}, numCols)
+		vals := make([]interface{}, numCols)
+		for colIndex := range vals {
+			prevVals[colIndex] = rowValue(&result, colIndex, resultTypes[colIndex])
+			resultIndex, ok := assigned[colIndex]
+			if !ok {
+				resultIndex = colIndex
+			} else if resultIndex == -1 {
+				continue
+			}
+			vals[colIndex] = rowValue(&result, resultIndex, resultTypes[resultIndex])
+		}
+		if err := appendRow(tableInfo, prevRows, prevVals); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := appendRow(tableInfo, rows, vals); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err := userTable.Update(prevRows, rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return exec.Empty, nil
+}
+
+// execDelete deletes the rows of a user table which match the WHERE clause of a DELETE statement. The rows are found
+// with a pull query, so this isn't atomic - rows which are inserted after the query aren't deleted.
+func (e *Executor) execDelete(session *sess.Session, del *parser.Delete) (exec.PullExecutor, error) {
+	tableInfo, userTable, err := e.getUserTable(session, del.TableName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	query := fmt.Sprintf("select %s from %s", strings.Join(tableInfo.ColumnNames, ", "), del.TableName)
+	if del.Where != nil {
+		query += " where" + del.Where.String()
+	}
+	rows, err := e.executeQuery(session, query)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := userTable.Delete(rows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return exec.Empty, nil
+}
+
+func (e *Executor) getUserTable(session *sess.Session, tableName string) (*common.UserTableInfo, *push.UserTable, error) {
+	tableInfo, ok := e.metaController.GetUserTable(session.Schema.Name, tableName)
+	if !ok {
+		return nil, nil, errors.NewUnknownTableError(session.Schema.Name, tableName)
+	}
+	userTable, err := e.pushEngine.GetUserTable(tableInfo.ID)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return tableInfo, userTable, nil
+}
+
+func (e *Executor) executeQuery(session *sess.Session, query string) (*common.Rows, error) {
+	session.Planner().RefreshInfoSchema()
+	return e.pullEngine.ExecuteQueryInSession(session, query)
+}
+
+func getColumnIndex(tableInfo *common.UserTableInfo, colName string) (int, error) {
+	for i, name := range tableInfo.ColumnNames {
+		if strings.EqualFold(name, colName) {
+			return i, nil
+		}
+	}
+	return 0, errors.NewPranaErrorf(errors.InvalidStatement, "Table %s.%s does not have a column %s",
+		tableInfo.SchemaName, tableInfo.Name, colName)
+}
+
+// appendRow coerces the values to the types of the columns of the table and appends them as a row
+func appendRow(tableInfo *common.UserTableInfo, rows *common.Rows, vals []interface{}) error {
+	for colIndex, val := range vals {
+		colName := tableInfo.ColumnNames[colIndex]
+		if val == nil && tableInfo.IsPrimaryKeyCol(colIndex) {
+			return errors.NewPranaErrorf(errors.InvalidStatement, "Primary key column %s cannot be null", colName)
+		}
+		if err := source.AppendCoercedValue(rows, colIndex, tableInfo.ColumnTypes[colIndex], val); err != nil {
+			return errors.NewPranaErrorf(errors.InvalidStatement, "Invalid value for column %s: %s", colName,
+				err.Error())
+		}
+	}
+	return nil
+}
+
+func literalValue(value *parser.Value) interface{} {
+	switch {
+	case value.String != nil:
+		return *value.String
+	case value.Number != nil:
+		// Numbers are coerced from their string representation, so that decimals don't lose precision
+		return *value.Number
+	case value.Bool != nil:
+		return strings.EqualFold(*value.Bool, "true")
+	default:
+		return nil
+	}
+}
+
+func rowValue(row *common.Row, colIndex int, colType common.ColumnType) interface{} {
+	if row.IsNull(colIndex) {
+		return nil
+	}
+	switch colType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+		return row.GetInt64(colIndex)
+	case common.TypeBoolean:
+		return row.GetBool(colIndex)
+	case common.TypeDouble:
+		return row.GetFloat64(colIndex)
+	case common.TypeVarchar:
+		return row.GetString(colIndex)
+	case common.TypeVarbinary:
+		return row.GetBytes(colIndex)
+	case common.TypeDecimal:
+		dec := row.GetDecimal(colIndex)
+		return &dec
+	case common.TypeTimestamp:
+		ts := row.GetTimestamp(colIndex)
+		return &ts
+	default:
+		panic(fmt.Sprintf("unexpected column type %d", colType.Type))
+	}
+}
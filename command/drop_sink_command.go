@@ -0,0 +1,127 @@
+package command
+
+import (
+	"sync"
+
+	"github.com/squareup/pranadb/command/parser"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+type DropSinkCommand struct {
+	lock       sync.Mutex
+	e          *Executor
+	schemaName string
+	sql        string
+	sinkName   string
+	sinkInfo   *common.SinkInfo
+}
+
+func (c *DropSinkCommand) CommandType() DDLCommandType {
+	return DDLCommandTypeDropSink
+}
+
+func (c *DropSinkCommand) SchemaName() string {
+	return c.schemaName
+}
+
+func (c *DropSinkCommand) SQL() string {
+	return c.sql
+}
+
+func (c *DropSinkCommand) TableSequences() []uint64 {
+	return nil
+}
+
+func (c *DropSinkCommand) LockName() string {
+	return c.schemaName + "/"
+}
+
+func NewOriginatingDropSinkCommand(e *Executor, schemaName string, sql string, sinkName string) *DropSinkCommand {
+	return &DropSinkCommand{
+		e:          e,
+		schemaName: schemaName,
+		sql:        sql,
+		sinkName:   sinkName,
+	}
+}
+
+func NewDropSinkCommand(e *Executor, schemaName string, sql string) *DropSinkCommand {
+	return &DropSinkCommand{
+		e:          e,
+		schemaName: schemaName,
+		sql:        sql,
+	}
+}
+
+func (c *DropSinkCommand) Before() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	sinkInfo, err := c.getSinkInfo()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.sinkInfo = sinkInfo
+	return nil
+}
+
+func (c *DropSinkCommand) OnPhase(phase int32) error {
+	switch phase {
+	case 0:
+		return c.onPhase0()
+	default:
+		panic("invalid phase")
+	}
+}
+
+func (c *DropSinkCommand) NumPhases() int {
+	return 1
+}
+
+func (c *DropSinkCommand) onPhase0() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sinkInfo == nil {
+		sinkInfo, err := c.getSinkInfo()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.sinkInfo = sinkInfo
+	}
+	if err := c.e.metaController.UnregisterSink(c.schemaName, c.sinkInfo.Name); err != nil {
+		return errors.WithStack(err)
+	}
+	// This disconnects the sink from the MV and closes the producer
+	return c.e.pushEngine.RemoveSink(c.sinkInfo)
+}
+
+func (c *DropSinkCommand) AfterPhase(phase int32) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if phase == 0 {
+		// Delete the sink info from the tables table
+		return c.e.metaController.DeleteSink(c.sinkInfo.ID)
+	}
+	return nil
+}
+
+func (c *DropSinkCommand) getSinkInfo() (*common.SinkInfo, error) {
+	if c.sinkName == "" {
+		ast, err := parser.Parse(c.sql)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ast.Drop == nil || !ast.Drop.Sink {
+			return nil, errors.Errorf("not a drop sink command %s", c.sql)
+		}
+		c.sinkName = ast.Drop.Name
+	}
+	sinkInfo, ok := c.e.metaController.GetSink(c.schemaName, c.sinkName)
+	if !ok {
+		return nil, errors.NewUnknownSinkError(c.schemaName, c.sinkName)
+	}
+	return sinkInfo, nil
+}
@@ -0,0 +1,163 @@
+package command
+
+import (
+	"sync"
+
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/command/parser"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+type DropTableCommand struct {
+	lock          sync.Mutex
+	e             *Executor
+	schemaName    string
+	sql           string
+	tableName     string
+	tableInfo     *common.UserTableInfo
+	toDeleteBatch *cluster.ToDeleteBatch
+}
+
+func (c *DropTableCommand) CommandType() DDLCommandType {
+	return DDLCommandTypeDropTable
+}
+
+func (c *DropTableCommand) SchemaName() string {
+	return c.schemaName
+}
+
+func (c *DropTableCommand) SQL() string {
+	return c.sql
+}
+
+func (c *DropTableCommand) TableSequences() []uint64 {
+	return nil
+}
+
+func (c *DropTableCommand) LockName() string {
+	return c.schemaName + "/"
+}
+
+func NewOriginatingDropTableCommand(e *Executor, schemaName string, sql string, tableName string) *DropTableCommand {
+	return &DropTableCommand{
+		e:          e,
+		schemaName: schemaName,
+		sql:        sql,
+		tableName:  tableName,
+	}
+}
+
+func NewDropTableCommand(e *Executor, schemaName string, sql string) *DropTableCommand {
+	return &DropTableCommand{
+		e:          e,
+		schemaName: schemaName,
+		sql:        sql,
+	}
+}
+
+func (c *DropTableCommand) Before() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	tableInfo, err := c.getTableInfo()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.tableInfo = tableInfo
+
+	userTable, err := c.e.pushEngine.GetUserTable(tableInfo.ID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	consuming := userTable.GetConsumingMVs()
+	if len(consuming) != 0 {
+		return errors.NewTableHasChildrenError(c.tableInfo.SchemaName, c.tableInfo.Name, consuming)
+	}
+	return nil
+}
+
+func (c *DropTableCommand) OnPhase(phase int32) error {
+	switch phase {
+	case 0:
+		return c.onPhase0()
+	case 1:
+		return c.onPhase1()
+	default:
+		panic("invalid phase")
+	}
+}
+
+func (c *DropTableCommand) NumPhases() int {
+	return 2
+}
+
+func (c *DropTableCommand) onPhase0() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Table should be removed from in memory metadata so no more statements can write to it
+	if c.tableInfo == nil {
+		tableInfo, err := c.getTableInfo()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.tableInfo = tableInfo
+	}
+	return c.e.metaController.UnregisterUserTable(c.schemaName, c.tableInfo.Name)
+}
+
+func (c *DropTableCommand) onPhase1() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// Remove the table from the push engine and delete all it's data
+	userTable, err := c.e.pushEngine.RemoveUserTable(c.tableInfo)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return userTable.Drop()
+}
+
+func (c *DropTableCommand) AfterPhase(phase int32) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch phase {
+	case 0:
+		// We record prefixes in the to_delete table - this makes sure the table data is deleted on restart if failure
+		// occurs after this
+		var err error
+		c.toDeleteBatch, err = storeToDeleteBatch(c.tableInfo.ID, c.e.cluster)
+		if err != nil {
+			return err
+		}
+
+		// Delete the table from the tables table - this must happen before the table data is deleted or we can
+		// end up with a partial table on recovery after failure
+		return c.e.metaController.DeleteUserTable(c.tableInfo.ID)
+	case 1:
+		// Now delete rows from the to_delete table
+		return c.e.cluster.RemoveToDeleteBatch(c.toDeleteBatch)
+	}
+
+	return nil
+}
+
+func (c *DropTableCommand) getTableInfo() (*common.UserTableInfo, error) {
+	if c.tableName == "" {
+		ast, err := parser.Parse(c.sql)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if ast.Drop == nil || !ast.Drop.Table {
+			return nil, errors.Errorf("not a drop table command %s", c.sql)
+		}
+		c.tableName = ast.Drop.Name
+	}
+	tableInfo, ok := c.e.metaController.GetUserTable(c.schemaName, c.tableName)
+	if !ok {
+		return nil, errors.NewUnknownTableError(c.schemaName, c.tableName)
+	}
+	return tableInfo, nil
+}
@@ -26,8 +26,28 @@ type RawQuery struct {
 }
 
 func (r *RawQuery) String() string {
+	return rawTokensString(r.Tokens)
+}
+
+// RawExpr represents a raw SQL expression that can be passed through directly. It ends at the first comma, closing
+// parenthesis or WHERE that is not nested in parentheses.
+type RawExpr struct {
+	Tokens []lexer.Token
+	Parts  []*RawExprPart `@@+`
+}
+
+type RawExprPart struct {
+	Nested []*RawExprPart `  "(" (@@ | ",")* ")"`
+	Token  string         `| @(!("," | "(" | ")" | ";" | "WHERE"))`
+}
+
+func (r *RawExpr) String() string {
+	return rawTokensString(r.Tokens)
+}
+
+func rawTokensString(tokens []lexer.Token) string {
 	out := strings.Builder{}
-	for _, token := range r.Tokens {
+	for _, token := range tokens {
 		v := token.Value
 		if token.Type == parser.Lexer().Symbols()["String"] {
 			// THIS IS A HACK! Need to fix participle bug that's stripping the quotes from the raw tokens
@@ -49,8 +69,16 @@ func (r *Ref) String() string {
 
 // CreateMaterializedView statement.
 type CreateMaterializedView struct {
-	Name  *Ref      `@@ "AS"`
-	Query *RawQuery `@@`
+	OrReplace bool                      `@("OR" "REPLACE")? "MATERIALIZED" "VIEW"`
+	Name      *Ref                      `@@`
+	Options   []*MaterializedViewOption `("WITH" "(" @@ ("," @@)* ")")? "AS"`
+	Query     *RawQuery                 `@@`
+}
+
+type MaterializedViewOption struct {
+	Retention          string `"Retention" "=" @String`
+	RetentionColumn    string `|"RetentionColumn" "=" @String`
+	RetentionPropagate string `|"RetentionPropagate" "=" @String`
 }
 
 type ColumnDef struct {
@@ -58,8 +86,8 @@ type ColumnDef struct {
 
 	Name string `@Ident`
 
-	Type       common.Type `@(("VARCHAR"|"TINYINT"|"INT"|"BIGINT"|"TIMESTAMP"|"DOUBLE"|"DECIMAL"))` // Conversion done by common.Type.Capture()
-	Parameters []int       `("(" @Number ("," @Number)* ")")?`                                      // Optional parameters to the type(x [, x, ...])
+	Type       common.Type `@(("VARCHAR"|"TINYINT"|"INT"|"BIGINT"|"TIMESTAMP"|"DOUBLE"|"DECIMAL"|"BOOLEAN"|"BOOL"|"VARBINARY"))` // Conversion done by common.Type.Capture()
+	Parameters []int       `("(" @Number ("," @Number)* ")")?`                                                                   // Optional parameters to the type(x [, x, ...])
 }
 
 func (c *ColumnDef) ToColumnType() (common.ColumnType, error) {
@@ -99,14 +127,34 @@ type CreateSource struct {
 	TopicInformation []*TopicInformation `"WITH" "(" @@ ("," @@)* ")"`
 }
 
+// CreateTable statement.
+type CreateTable struct {
+	Name    string         `@Ident`
+	Options []*TableOption `"(" @@ ("," @@)* ")"` // Table options.
+}
+
 type TopicInformation struct {
-	BrokerName     string                        `"BrokerName" "=" @String`
-	TopicName      string                        `|"TopicName" "=" @String`
-	HeaderEncoding string                        `|"HeaderEncoding" "=" @String`
-	KeyEncoding    string                        `|"KeyEncoding" "=" @String`
-	ValueEncoding  string                        `|"ValueEncoding" "=" @String`
-	ColSelectors   []*selector.ColumnSelectorAST `|"ColumnSelectors" "=" "(" (@@ ("," @@)*)? ")"`
-	Properties     []*TopicInfoProperty          `|"Properties" "=" "(" (@@ ("," @@)*)? ")"`
+	BrokerName         string                        `"BrokerName" "=" @String`
+	TopicName          string                        `|"TopicName" "=" @String`
+	TopicNames         []string                      `|"TopicNames" "=" "(" @String ("," @String)* ")"`
+	TopicPattern       string                        `|"TopicPattern" "=" @String`
+	HeaderEncoding     string                        `|"HeaderEncoding" "=" @String`
+	KeyEncoding        string                        `|"KeyEncoding" "=" @String`
+	ValueEncoding      string                        `|"ValueEncoding" "=" @String`
+	ColSelectors       []*selector.ColumnSelectorAST `|"ColumnSelectors" "=" "(" (@@ ("," @@)*)? ")"`
+	Properties         []*TopicInfoProperty          `|"Properties" "=" "(" (@@ ("," @@)*)? ")"`
+	EventTime          string                        `|"EventTime" "=" @String`
+	AllowedLateness    string                        `|"AllowedLateness" "=" @String`
+	IdleTimeout        string                        `|"IdleTimeout" "=" @String`
+	Semantics          string                        `|"Semantics" "=" @String`
+	Retention          string                        `|"Retention" "=" @String`
+	RetentionColumn    string                        `|"RetentionColumn" "=" @String`
+	RetentionPropagate string                        `|"RetentionPropagate" "=" @String`
+	CSVDelimiter       *string                       `|"CSVDelimiter" "=" @String`
+	CSVQuote           *string                       `|"CSVQuote" "=" @String`
+	ErrorPolicy        string                        `|"ErrorPolicy" "=" @String`
+	DeadLetterTopic    string                        `|"DeadLetterTopic" "=" @String`
+	StartFrom          string                        `|"StartFrom" "=" @String`
 }
 
 type ColSelector struct {
@@ -124,36 +172,106 @@ type CreateIndex struct {
 	ColumnNames []*ColumnName `"(" @@ ("," @@)* ")"`
 }
 
+type CreateSink struct {
+	Name                 string              `@Ident "FROM"`
+	MaterializedViewName string              `@Ident`
+	TopicInformation     []*TopicInformation `"WITH" "(" @@ ("," @@)* ")"`
+}
+
 type ColumnName struct {
 	Name string `@Ident`
 }
 
 // Create statement.
 type Create struct {
-	MaterializedView *CreateMaterializedView `  "MATERIALIZED" "VIEW" @@`
+	MaterializedView *CreateMaterializedView `  @@`
 	Source           *CreateSource           `| "SOURCE" @@`
+	Table            *CreateTable            `| "TABLE" @@`
 	Index            *CreateIndex            `| "INDEX" @@`
+	Sink             *CreateSink             `| "SINK" @@`
 }
 
 // Drop statement
 type Drop struct {
 	MaterializedView bool   `(   @"MATERIALIZED" "VIEW"`
 	Source           bool   `  | @"SOURCE"`
+	Table            bool   `  | @"TABLE"`
+	Sink             bool   `  | @"SINK"`
 	Index            bool   `  | @"INDEX" )`
 	Name             string `@Ident `
 	TableName        string `("ON" @Ident)?`
 }
 
+// AlterSource statement
+type AlterSource struct {
+	Name         string     `@Ident`
+	ResetOffsets bool       `( @"RESET" "OFFSETS"`
+	StartFrom    *string    `  ("TO" @String)?`
+	Pause        bool       `| @"PAUSE"`
+	Resume       bool       `| @"RESUME"`
+	AddColumn    *AddColumn `| "ADD" "COLUMN" @@ )`
+}
+
+// AddColumn is a column added to a source, with the selector which selects its value from the messages
+type AddColumn struct {
+	Column   *ColumnDef `@@`
+	Selector string     `"SELECTOR" @String`
+}
+
+// Alter statement
+type Alter struct {
+	Source *AlterSource `"SOURCE" @@`
+}
+
 // Execute statement.
 type Execute struct {
 	PsID int64    `@Number`
 	Args []string `(@String | @Number)*`
 }
 
+// Insert statement. If no column names are given values must be provided for all the columns of the table.
+type Insert struct {
+	TableName   string       `"INTO" @Ident`
+	ColumnNames []string     `("(" @Ident ("," @Ident)* ")")?`
+	Rows        []*InsertRow `"VALUES" @@ ("," @@)*`
+}
+
+type InsertRow struct {
+	Values []*Value `"(" @@ ("," @@)* ")"`
+}
+
+// Value is a literal value
+type Value struct {
+	Null   bool    `  @"NULL"`
+	String *string `| @String`
+	Number *string `| @Number`
+	Bool   *string `| @("TRUE" | "FALSE")`
+}
+
+// Update statement.
+type Update struct {
+	TableName   string        `@Ident "SET"`
+	Assignments []*Assignment `@@ ("," @@)*`
+	Where       *RawQuery     `("WHERE" @@)?`
+}
+
+type Assignment struct {
+	Column string   `@Ident "="`
+	Value  *RawExpr `@@`
+}
+
+// Delete statement.
+type Delete struct {
+	TableName string    `"FROM" @Ident`
+	Where     *RawQuery `("WHERE" @@)?`
+}
+
 // Show statement
 type Show struct {
-	Tables  string `  @"TABLES"`
-	Schemas string `| @"SCHEMAS"`
+	Tables       string `  @"TABLES"`
+	Schemas      string `| @"SCHEMAS"`
+	SourceStatus bool   `| @("SOURCE" "STATUS")`
+	SourceName   string `  @Ident?`
 }
 
 // AST root.
@@ -165,6 +283,10 @@ type AST struct {
 	Execute  *Execute ` | "EXECUTE" @@`
 	Drop     *Drop    ` | "DROP" @@ `
 	Create   *Create  ` | "CREATE" @@ `
+	Alter    *Alter   ` | "ALTER" @@ `
 	Show     *Show    ` | "SHOW" @@ `
+	Insert   *Insert  ` | "INSERT" @@ `
+	Update   *Update  ` | "UPDATE" @@ `
+	Delete   *Delete  ` | "DELETE" @@ `
 	Describe string   ` | "DESCRIBE" @Ident ) ";"?`
 }
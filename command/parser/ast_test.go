@@ -91,6 +91,96 @@ func TestParse(t *testing.T) {
 				},
 			},
 		}}, ""},
+		{"CreateSourceWithEventTime", `
+			create source events(
+			event_id bigint,
+			event_time timestamp,
+			primary key (event_id)
+		) with (
+			brokername = "testbroker",
+			topicname = "testtopic",
+			headerencoding = "json",
+			keyencoding = "json",
+			valueencoding = "json",
+			eventtime = "event_time",
+			allowedlateness = "10 seconds"
+		)`, &AST{Create: &Create{
+			Source: &CreateSource{
+				Name: "events",
+				Options: []*TableOption{
+					{Column: &ColumnDef{Pos: lexer.Position{Offset: 29, Line: 3, Column: 4}, Name: "event_id", Type: common.Type(3)}},
+					{Column: &ColumnDef{Pos: lexer.Position{Offset: 49, Line: 4, Column: 4}, Name: "event_time", Type: common.Type(7)}},
+					{PrimaryKey: []string{"event_id"}},
+				},
+				TopicInformation: []*TopicInformation{
+					{BrokerName: "testbroker"},
+					{TopicName: "testtopic"},
+					{HeaderEncoding: "json"},
+					{KeyEncoding: "json"},
+					{ValueEncoding: "json"},
+					{EventTime: "event_time"},
+					{AllowedLateness: "10 seconds"},
+				},
+			},
+		}}, ""},
+		{"CreateSink", `
+			create sink sensor_summary_sink from sensor_summary with (
+			brokername = "testbroker",
+			topicname = "testtopic",
+			keyencoding = "int64be",
+			valueencoding = "json"
+		)`, &AST{Create: &Create{
+			Sink: &CreateSink{
+				Name:                 "sensor_summary_sink",
+				MaterializedViewName: "sensor_summary",
+				TopicInformation: []*TopicInformation{
+					{BrokerName: "testbroker"},
+					{TopicName: "testtopic"},
+					{KeyEncoding: "int64be"},
+					{ValueEncoding: "json"},
+				},
+			},
+		}}, ""},
+		{"CreateTable", `
+			create table currencies(
+			code varchar,
+			rate decimal(10, 4),
+			primary key (code)
+		)`, &AST{Create: &Create{
+			Table: &CreateTable{
+				Name: "currencies",
+				Options: []*TableOption{
+					{Column: &ColumnDef{Pos: lexer.Position{Offset: 32, Line: 3, Column: 4}, Name: "code", Type: common.Type(6)}},
+					{Column: &ColumnDef{Pos: lexer.Position{Offset: 49, Line: 4, Column: 4}, Name: "rate", Type: common.Type(5), Parameters: []int{10, 4}}},
+					{PrimaryKey: []string{"code"}},
+				},
+			},
+		}}, ""},
+		{
+			"Insert", `INSERT INTO currencies (code, rate) VALUES ('USD', 1), ('EUR', -1.25), ('XXX', NULL)`,
+			&AST{Insert: &Insert{
+				TableName:   "currencies",
+				ColumnNames: []string{"code", "rate"},
+				Rows: []*InsertRow{
+					{Values: []*Value{{String: stringRef("USD")}, {Number: stringRef("1")}}},
+					{Values: []*Value{{String: stringRef("EUR")}, {Number: stringRef("-1.25")}}},
+					{Values: []*Value{{String: stringRef("XXX")}, {Null: true}}},
+				},
+			}}, "",
+		},
+		{
+			"InsertNoColumnNames", `INSERT INTO currencies VALUES ('USD', 1)`,
+			&AST{Insert: &Insert{
+				TableName: "currencies",
+				Rows: []*InsertRow{
+					{Values: []*Value{{String: stringRef("USD")}, {Number: stringRef("1")}}},
+				},
+			}}, "",
+		},
+		{
+			"DeleteAll", `DELETE FROM currencies`,
+			&AST{Delete: &Delete{TableName: "currencies"}}, "",
+		},
 		{
 			"DropSource", "DROP SOURCE test_source_1",
 			&AST{Drop: &Drop{Source: true, Name: "test_source_1"}}, "",
@@ -99,6 +189,14 @@ func TestParse(t *testing.T) {
 			"DropMaterializedView", "DROP MATERIALIZED VIEW test_mv_1",
 			&AST{Drop: &Drop{MaterializedView: true, Name: "test_mv_1"}}, "",
 		},
+		{
+			"DropTable", "DROP TABLE currencies",
+			&AST{Drop: &Drop{Table: true, Name: "currencies"}}, "",
+		},
+		{
+			"DropSink", "DROP SINK test_sink_1",
+			&AST{Drop: &Drop{Sink: true, Name: "test_sink_1"}}, "",
+		},
 		{
 			"ExecutePreparedStatement", `EXECUTE 8 432 123.32 "hello world"`,
 			&AST{Execute: &Execute{PsID: 8, Args: []string{"432", "123.32", "hello world"}}}, "",
@@ -136,6 +234,168 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseUpdateAndDelete(t *testing.T) {
+	ast, err := Parse(`UPDATE currencies SET rate = round(rate * 1.1, 4), name = 'x' WHERE code IN ('USD', 'EUR');`)
+	require.NoError(t, err)
+	require.Equal(t, "currencies", ast.Update.TableName)
+	require.Equal(t, 2, len(ast.Update.Assignments))
+	require.Equal(t, "rate", ast.Update.Assignments[0].Column)
+	require.Equal(t, " round(rate * 1.1, 4)", ast.Update.Assignments[0].Value.String())
+	require.Equal(t, "name", ast.Update.Assignments[1].Column)
+	require.Equal(t, ` "x"`, ast.Update.Assignments[1].Value.String())
+	require.Equal(t, ` code IN ("USD", "EUR")`, ast.Update.Where.String())
+
+	ast, err = Parse(`UPDATE currencies SET rate = 1`)
+	require.NoError(t, err)
+	require.Equal(t, " 1", ast.Update.Assignments[0].Value.String())
+	require.Nil(t, ast.Update.Where)
+
+	ast, err = Parse(`DELETE FROM currencies WHERE rate > 1`)
+	require.NoError(t, err)
+	require.Equal(t, "currencies", ast.Delete.TableName)
+	require.Equal(t, " rate > 1", ast.Delete.Where.String())
+}
+
+func TestParseCreateMVWithRetention(t *testing.T) {
+	ast, err := Parse(`CREATE MATERIALIZED VIEW recent WITH (retention = '1 day', retentioncolumn = 'event_time', ` +
+		`retentionpropagate = 'false') AS SELECT * FROM events`)
+	require.NoError(t, err)
+	mv := ast.Create.MaterializedView
+	require.Equal(t, "recent", mv.Name.String())
+	require.Equal(t, []*MaterializedViewOption{
+		{Retention: "1 day"},
+		{RetentionColumn: "event_time"},
+		{RetentionPropagate: "false"},
+	}, mv.Options)
+	require.Equal(t, " SELECT * FROM events", mv.Query.String())
+}
+
+func TestParseCreateSourceCSVOptions(t *testing.T) {
+	ast, err := Parse(`CREATE SOURCE lines (id BIGINT, PRIMARY KEY (id)) WITH (valueencoding = "csv", ` +
+		`csvdelimiter = "\t", csvquote = "", columnselectors = ([0]))`)
+	require.NoError(t, err)
+	options := ast.Create.Source.TopicInformation
+	require.Equal(t, "csv", options[0].ValueEncoding)
+	require.Equal(t, stringRef("\t"), options[1].CSVDelimiter)
+	require.Equal(t, stringRef(""), options[2].CSVQuote)
+	require.Len(t, options[3].ColSelectors, 1)
+}
+
+func TestParseCreateSourceTopics(t *testing.T) {
+	ast, err := Parse(`CREATE SOURCE payments (id BIGINT, PRIMARY KEY (id)) WITH (topicnames = ("payments.us", "payments.eu"), ` +
+		`topicpattern = "payments\\..*")`)
+	require.NoError(t, err)
+	options := ast.Create.Source.TopicInformation
+	require.Equal(t, []string{"payments.us", "payments.eu"}, options[0].TopicNames)
+	require.Equal(t, `payments\..*`, options[1].TopicPattern)
+}
+
+func TestParseAlterSourceResetOffsets(t *testing.T) {
+	ast, err := Parse(`ALTER SOURCE payments RESET OFFSETS`)
+	require.NoError(t, err)
+	require.Equal(t, &AlterSource{Name: "payments", ResetOffsets: true}, ast.Alter.Source)
+
+	ast, err = Parse(`alter source payments reset offsets to "timestamp:2022-01-01 00:00:00"`)
+	require.NoError(t, err)
+	require.Equal(t, &AlterSource{Name: "payments", ResetOffsets: true, StartFrom: stringRef("timestamp:2022-01-01 00:00:00")},
+		ast.Alter.Source)
+}
+
+func TestParseAlterSourcePauseResume(t *testing.T) {
+	ast, err := Parse(`ALTER SOURCE payments PAUSE`)
+	require.NoError(t, err)
+	require.Equal(t, &AlterSource{Name: "payments", Pause: true}, ast.Alter.Source)
+
+	ast, err = Parse(`alter source payments resume`)
+	require.NoError(t, err)
+	require.Equal(t, &AlterSource{Name: "payments", Resume: true}, ast.Alter.Source)
+
+	_, err = Parse(`ALTER SOURCE payments PAUSE RESET OFFSETS`)
+	require.Error(t, err)
+}
+
+func TestParseAlterSourceAddColumn(t *testing.T) {
+	ast, err := Parse(`ALTER SOURCE payments ADD COLUMN currency VARCHAR SELECTOR 'meta("header").currency'`)
+	require.NoError(t, err)
+	addColumn := ast.Alter.Source.AddColumn
+	require.NotNil(t, addColumn)
+	require.Equal(t, "currency", addColumn.Column.Name)
+	require.Equal(t, common.TypeVarchar, addColumn.Column.Type)
+	require.Equal(t, `meta("header").currency`, addColumn.Selector)
+
+	ast, err = Parse(`alter source payments add column amount decimal(10, 2) selector "amount"`)
+	require.NoError(t, err)
+	addColumn = ast.Alter.Source.AddColumn
+	require.Equal(t, common.TypeDecimal, addColumn.Column.Type)
+	require.Equal(t, []int{10, 2}, addColumn.Column.Parameters)
+	require.Equal(t, "amount", addColumn.Selector)
+
+	_, err = Parse(`ALTER SOURCE payments ADD COLUMN currency VARCHAR`)
+	require.Error(t, err)
+}
+
+func TestParseCreateOrReplaceMaterializedView(t *testing.T) {
+	ast, err := Parse(`CREATE OR REPLACE MATERIALIZED VIEW myview AS SELECT * FROM table`)
+	require.NoError(t, err)
+	mv := ast.Create.MaterializedView
+	require.NotNil(t, mv)
+	require.True(t, mv.OrReplace)
+	require.Equal(t, "myview", mv.Name.String())
+	require.Equal(t, " SELECT * FROM table", mv.Query.String())
+
+	ast, err = Parse(`create materialized view myview as select * from table`)
+	require.NoError(t, err)
+	require.False(t, ast.Create.MaterializedView.OrReplace)
+
+	_, err = Parse(`CREATE OR REPLACE SOURCE mysource(id bigint, primary key (id))`)
+	require.Error(t, err)
+}
+
+func TestParseShowSourceStatus(t *testing.T) {
+	ast, err := Parse(`SHOW SOURCE STATUS`)
+	require.NoError(t, err)
+	require.Equal(t, &Show{SourceStatus: true}, ast.Show)
+
+	ast, err = Parse(`show source status payments`)
+	require.NoError(t, err)
+	require.Equal(t, &Show{SourceStatus: true, SourceName: "payments"}, ast.Show)
+}
+
+func TestParseBooleanColumnDef(t *testing.T) {
+	ast, err := Parse(`CREATE TABLE flags(id BIGINT, enabled BOOLEAN, archived bool, PRIMARY KEY (id))`)
+	require.NoError(t, err)
+	options := ast.Create.Table.Options
+	require.Equal(t, common.TypeBoolean, options[1].Column.Type)
+	require.Equal(t, common.TypeBoolean, options[2].Column.Type)
+	colType, err := options[1].Column.ToColumnType()
+	require.NoError(t, err)
+	require.Equal(t, common.BooleanColumnType, colType)
+
+	ast, err = Parse(`CREATE TABLE flags(id BIGINT, enabled BOOLEAN(1), PRIMARY KEY (id))`)
+	require.NoError(t, err)
+	_, err = ast.Create.Table.Options[1].Column.ToColumnType()
+	require.Error(t, err)
+
+	ast, err = Parse(`INSERT INTO flags VALUES (1, true, FALSE)`)
+	require.NoError(t, err)
+	values := ast.Insert.Rows[0].Values
+	require.Equal(t, "true", *values[1].Bool)
+	require.Equal(t, "FALSE", *values[2].Bool)
+}
+
+func TestParseVarbinaryColumnDef(t *testing.T) {
+	ast, err := Parse(`CREATE TABLE blobs(id BIGINT, payload VARBINARY, PRIMARY KEY (id))`)
+	require.NoError(t, err)
+	colType, err := ast.Create.Table.Options[1].Column.ToColumnType()
+	require.NoError(t, err)
+	require.Equal(t, common.VarbinaryColumnType, colType)
+
+	ast, err = Parse(`CREATE TABLE blobs(id BIGINT, payload VARBINARY(10), PRIMARY KEY (id))`)
+	require.NoError(t, err)
+	_, err = ast.Create.Table.Options[1].Column.ToColumnType()
+	require.Error(t, err)
+}
+
 func intRef(v int) *int {
 	return &v
 }
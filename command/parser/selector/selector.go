@@ -20,29 +20,23 @@ import (
 
 type ColumnSelectorAST struct {
 	MetaKey *string      `( "meta" "(" @String ")" |`
-	Field   *string      `  @Ident )`
+	Field   *string      `  @Ident )?`
 	Index   []*Index     `( "[" @@ "]" )*`
 	Next    *SelectorAST `("." @@)?`
 }
 
+// ToSelector converts the AST to a ColumnSelector. A selector can start with an index rather than a field, e.g. `[2]`,
+// to select from a value which is an array, such as a CSV encoded message.
 func (s *ColumnSelectorAST) ToSelector() ColumnSelector {
-	if s.MetaKey != nil {
-		return ColumnSelector{
-			MetaKey:  s.MetaKey,
-			Selector: s.Next.ToSelector(),
-		}
-	}
-	f := ""
+	var sel Selector
 	if s.Field != nil {
-		f = *s.Field
+		sel = append(sel, Path{Field: s.Field})
 	}
+	sel = append(sel, indexPaths(s.Index)...)
+	sel = append(sel, s.Next.ToSelector()...)
 	return ColumnSelector{
-		MetaKey: s.MetaKey,
-		Selector: (&SelectorAST{
-			Field: f,
-			Index: s.Index,
-			Next:  s.Next,
-		}).ToSelector(),
+		MetaKey:  s.MetaKey,
+		Selector: sel,
 	}
 }
 
@@ -68,7 +62,10 @@ func (s ColumnSelector) String() string {
 		v += fmt.Sprintf(`meta("%s")`, *s.MetaKey)
 	}
 	if len(s.Selector) > 0 {
-		v += "." + s.Selector.String()
+		if s.MetaKey != nil && s.Selector[0].Field != nil {
+			v += "."
+		}
+		v += s.Selector.String()
 	}
 	return v
 }
@@ -88,19 +85,23 @@ func (a *SelectorAST) ToSelector() Selector {
 	var sel []Path
 	for ; a != nil; a = a.Next {
 		sel = append(sel, Path{Field: &a.Field})
-		if len(a.Index) > 0 {
-			for _, idx := range a.Index {
-				if idx.Number != nil {
-					sel = append(sel, Path{NumberIndex: idx.Number})
-				} else {
-					sel = append(sel, Path{Field: idx.String})
-				}
-			}
-		}
+		sel = append(sel, indexPaths(a.Index)...)
 	}
 	return sel
 }
 
+func indexPaths(index []*Index) []Path {
+	var paths []Path
+	for _, idx := range index {
+		if idx.Number != nil {
+			paths = append(paths, Path{NumberIndex: idx.Number})
+		} else {
+			paths = append(paths, Path{Field: idx.String})
+		}
+	}
+	return paths
+}
+
 // Selector is a protobuf path selector.
 type Selector []Path
 
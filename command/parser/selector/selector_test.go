@@ -49,6 +49,16 @@ func TestParseColumnSelector(t *testing.T) {
 			selector: `meta("key").hello.world`,
 			want:     ColumnSelector{MetaKey: stringRef("key"), Selector: newSelector("hello", "world")},
 		},
+		{
+			name:     "index",
+			selector: `[2]`,
+			want:     ColumnSelector{Selector: newSelector(2)},
+		},
+		{
+			name:     "meta index",
+			selector: `meta("key")[1].hello`,
+			want:     ColumnSelector{MetaKey: stringRef("key"), Selector: newSelector(1, "hello")},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -0,0 +1,212 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/kafka"
+	"github.com/squareup/pranadb/protos/squareup/cash/pranadb/v1/notifications"
+	"github.com/squareup/pranadb/pull/exec"
+	"github.com/squareup/pranadb/push/source"
+	"github.com/squareup/pranadb/sess"
+)
+
+const (
+	sourceStatusRunning = "running"
+	sourceStatusPaused  = "paused"
+	sourceStatusStopped = "stopped"
+)
+
+var sourceStatusColumnNames = []string{"source", "status", "topic", "partition_id", "committed_offset", "end_offset",
+	"lag", "ingest_rate", "last_error"}
+
+var sourceStatusRowsFactory = common.NewRowsFactory(
+	[]common.ColumnType{
+		common.VarcharColumnType, // source
+		common.VarcharColumnType, // status
+		common.VarcharColumnType, // topic
+		common.BigIntColumnType,  // partition_id
+		common.BigIntColumnType,  // committed_offset
+		common.BigIntColumnType,  // end_offset
+		common.BigIntColumnType,  // lag
+		common.DoubleColumnType,  // ingest_rate
+		common.VarcharColumnType, // last_error
+	},
+)
+
+// execShowSourceStatus returns a row for each partition consumed by the sources of the schema, or by the source with
+// the name if there is one. The offsets come from Kafka, so they're for the whole cluster. The ingest rate and the
+// last error are only known on the nodes which consume the source, so they're collected from all the nodes: the ingest
+// rate is the sum of the rates on each node, and the last error is that of each node which has one, labelled with the
+// id of the node.
+func (e *Executor) execShowSourceStatus(session *sess.Session, sourceName string) (exec.PullExecutor, error) {
+	var sourceInfos []*common.SourceInfo
+	if sourceName != "" {
+		sourceInfo, ok := e.metaController.GetSource(session.Schema.Name, sourceName)
+		if !ok {
+			return nil, errors.NewUnknownSourceError(session.Schema.Name, sourceName)
+		}
+		sourceInfos = append(sourceInfos, sourceInfo)
+	} else {
+		for name := range session.Schema.GetAllTableInfos() {
+			if sourceInfo, ok := e.metaController.GetSource(session.Schema.Name, name); ok {
+				sourceInfos = append(sourceInfos, sourceInfo)
+			}
+		}
+		sort.Slice(sourceInfos, func(i, j int) bool {
+			return sourceInfos[i].Name < sourceInfos[j].Name
+		})
+	}
+	nodeStatuses, err := e.getSourceNodeStatuses(sourceInfos)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	rows := sourceStatusRowsFactory.NewRows(len(sourceInfos))
+	for _, sourceInfo := range sourceInfos {
+		src, err := e.pushEngine.GetSource(sourceInfo.ID)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		appendSourceStatusRows(rows, sourceInfo.Name, src, e.cluster.GetNodeID(), nodeStatuses[sourceInfo.ID])
+	}
+	staticRows, err := exec.NewStaticRows(sourceStatusColumnNames, rows)
+	return staticRows, errors.WithStack(err)
+}
+
+// sourceNodeStatus is the status of a source on a node of the cluster
+type sourceNodeStatus struct {
+	nodeID int
+	*notifications.SourceNodeStatus
+}
+
+// getSourceNodeStatuses asks all the nodes of the cluster for the status of the sources on them, and returns the
+// statuses of each source ordered by node id. Nodes which aren't available are left out.
+func (e *Executor) getSourceNodeStatuses(sourceInfos []*common.SourceInfo) (map[uint64][]sourceNodeStatus, error) {
+	req := &notifications.SourceStatusRequest{SourceIds: make([]uint64, len(sourceInfos))}
+	for i, sourceInfo := range sourceInfos {
+		req.SourceIds[i] = sourceInfo.ID
+	}
+	resps, err := e.notifClient.BroadcastRequest(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	statuses := make(map[uint64][]sourceNodeStatus, len(sourceInfos))
+	for _, r := range resps {
+		resp, ok := r.(*notifications.SourceStatusResponse)
+		if !ok {
+			return nil, errors.Errorf("unexpected response to source status request %v", r)
+		}
+		for _, status := range resp.Statuses {
+			statuses[status.SourceId] = append(statuses[status.SourceId], sourceNodeStatus{
+				nodeID:           int(resp.NodeId),
+				SourceNodeStatus: status,
+			})
+		}
+	}
+	for _, sourceStatuses := range statuses {
+		sort.Slice(sourceStatuses, func(i, j int) bool {
+			return sourceStatuses[i].nodeID < sourceStatuses[j].nodeID
+		})
+	}
+	return statuses, nil
+}
+
+func appendSourceStatusRows(rows *common.Rows, sourceName string, src *source.Source, nodeID int,
+	nodeStatuses []sourceNodeStatus) {
+	status := sourceStatusStopped
+	if src.IsPaused() {
+		status = sourceStatusPaused
+	} else if src.IsRunning() {
+		status = sourceStatusRunning
+	}
+	var ingestRate float64
+	var lastErrors []string
+	for _, nodeStatus := range nodeStatuses {
+		ingestRate += nodeStatus.IngestRate
+		if nodeStatus.LastError != "" {
+			lastErrors = append(lastErrors, fmt.Sprintf("node %d: %s", nodeStatus.nodeID, nodeStatus.LastError))
+		}
+	}
+	committed, starts, ends, err := getSourceOffsets(src)
+	if err != nil {
+		// We still show the status of the source if Kafka can't be reached, as that's likely when it's needed
+		log.Warnf("failed to get offsets of source %s: %v", sourceName, err)
+		if len(lastErrors) == 0 {
+			lastErrors = append(lastErrors, fmt.Sprintf("node %d: %s", nodeID, err.Error()))
+		}
+	}
+	lastError := strings.Join(lastErrors, "; ")
+	appendRow := func(tp *kafka.TopicPartition) {
+		rows.AppendStringToColumn(0, sourceName)
+		rows.AppendStringToColumn(1, status)
+		if tp == nil {
+			rows.AppendNullToColumn(2)
+			rows.AppendNullToColumn(3)
+			rows.AppendNullToColumn(4)
+			rows.AppendNullToColumn(5)
+			rows.AppendNullToColumn(6)
+		} else {
+			rows.AppendStringToColumn(2, tp.Topic)
+			rows.AppendInt64ToColumn(3, int64(tp.PartitionID))
+			end := ends[*tp]
+			// If the group hasn't committed an offset yet it consumes from the start of the partition
+			next, ok := committed[*tp]
+			if ok {
+				rows.AppendInt64ToColumn(4, next)
+			} else {
+				rows.AppendNullToColumn(4)
+				next = starts[*tp]
+			}
+			rows.AppendInt64ToColumn(5, end)
+			lag := end - next
+			if lag < 0 {
+				lag = 0
+			}
+			rows.AppendInt64ToColumn(6, lag)
+		}
+		rows.AppendFloat64ToColumn(7, ingestRate)
+		if lastError == "" {
+			rows.AppendNullToColumn(8)
+		} else {
+			rows.AppendStringToColumn(8, lastError)
+		}
+	}
+	if len(ends) == 0 {
+		appendRow(nil)
+		return
+	}
+	partitions := make([]kafka.TopicPartition, 0, len(ends))
+	for tp := range ends {
+		partitions = append(partitions, tp)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Topic != partitions[j].Topic {
+			return partitions[i].Topic < partitions[j].Topic
+		}
+		return partitions[i].PartitionID < partitions[j].PartitionID
+	})
+	for i := range partitions {
+		appendRow(&partitions[i])
+	}
+}
+
+// getSourceOffsets returns the committed offsets of the source, and the offsets of the start and end of each partition
+func getSourceOffsets(src *source.Source) (map[kafka.TopicPartition]int64, map[kafka.TopicPartition]int64, map[kafka.TopicPartition]int64, error) {
+	committed, err := src.GetCommittedOffsets()
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+	starts, err := src.GetStartOffsets(&common.StartFrom{Kind: common.StartFromEarliest})
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+	ends, err := src.GetStartOffsets(&common.StartFrom{Kind: common.StartFromLatest})
+	if err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+	return committed, starts, ends, nil
+}
@@ -46,7 +46,7 @@ func RowsEqual(t *testing.T, expected common.Row, actual common.Row, colTypes []
 		require.Equal(t, expectedNull, actualNull)
 		if !expectedNull {
 			switch colType.Type {
-			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 				val1 := expected.GetInt64(colIndex)
 				val2 := actual.GetInt64(colIndex)
 				require.Equal(t, val1, val2)
@@ -58,7 +58,7 @@ func RowsEqual(t *testing.T, expected common.Row, actual common.Row, colTypes []
 				val1 := expected.GetFloat64(colIndex)
 				val2 := actual.GetFloat64(colIndex)
 				require.Equal(t, val1, val2)
-			case common.TypeVarchar:
+			case common.TypeVarchar, common.TypeVarbinary:
 				val1 := expected.GetString(colIndex)
 				val2 := actual.GetString(colIndex)
 				require.Equal(t, val1, val2)
@@ -83,11 +83,11 @@ func AppendRow(t *testing.T, rows *common.Rows, colTypes []common.ColumnType, co
 			rows.AppendNullToColumn(i)
 		} else {
 			switch colType.Type {
-			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 				rows.AppendInt64ToColumn(i, int64(colVal.(int)))
 			case common.TypeDouble:
 				rows.AppendFloat64ToColumn(i, colVal.(float64))
-			case common.TypeVarchar:
+			case common.TypeVarchar, common.TypeVarbinary:
 				rows.AppendStringToColumn(i, colVal.(string))
 			case common.TypeDecimal:
 				dec, err := common.NewDecFromString(colVal.(string))
@@ -3,6 +3,7 @@ package common
 import (
 	"fmt"
 
+	"github.com/pingcap/parser/charset"
 	"github.com/pingcap/parser/mysql"
 	"github.com/squareup/pranadb/tidb/types"
 )
@@ -26,6 +27,18 @@ func ConvertPranaTypeToTiDBType(columnType ColumnType) *types.FieldType {
 		ft = types.NewFieldType(mysql.TypeVarchar)
 	case TypeTimestamp:
 		ft = types.NewFieldType(mysql.TypeTimestamp)
+	case TypeBoolean:
+		// TiDB has no boolean type, it uses TINYINT(1) like MySQL. The flag tells it apart from a TINYINT(1) column
+		ft = types.NewFieldType(mysql.TypeTiny)
+		ft.Flen = 1
+		ft.Flag |= mysql.IsBooleanFlag
+	case TypeVarbinary:
+		// Like MySQL, TiDB has VARBINARY as a VARCHAR with the binary character set
+		ft = types.NewFieldType(mysql.TypeVarchar)
+		ft.Flag |= mysql.BinaryFlag
+		ft.Charset = charset.CharsetBin
+		ft.Collate = charset.CollationBin
+		return ft
 	default:
 		panic(fmt.Sprintf("unknown column type %d", columnType))
 	}
@@ -36,8 +49,11 @@ func ConvertPranaTypeToTiDBType(columnType ColumnType) *types.FieldType {
 func ConvertTiDBTypeToPranaType(columnType *types.FieldType) ColumnType {
 	switch columnType.Tp {
 	case mysql.TypeTiny:
+		if mysql.HasIsBooleanFlag(columnType.Flag) {
+			return BooleanColumnType
+		}
 		return TinyIntColumnType
-	case mysql.TypeLong:
+	case mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong:
 		return IntColumnType
 	case mysql.TypeLonglong:
 		return BigIntColumnType
@@ -46,9 +62,15 @@ func ConvertTiDBTypeToPranaType(columnType *types.FieldType) ColumnType {
 	case mysql.TypeNewDecimal:
 		// The TiDB expression does not calculate the right precision and scale so we just use maximum
 		return NewDecimalColumnType(65, 30)
-	case mysql.TypeVarchar:
+	case mysql.TypeVarchar, mysql.TypeVarString, mysql.TypeString, mysql.TypeTinyBlob, mysql.TypeBlob,
+		mysql.TypeMediumBlob, mysql.TypeLongBlob:
+		if types.IsBinaryStr(columnType) {
+			return VarbinaryColumnType
+		}
+		// Functions such as LOWER and CONCAT return these types
 		return VarcharColumnType
-	case mysql.TypeTimestamp:
+	case mysql.TypeTimestamp, mysql.TypeDate, mysql.TypeDatetime:
+		// Functions such as DATE return these types
 		return TimestampColumnType
 	default:
 		panic(fmt.Sprintf("unknown colum type %d", columnType.Tp))
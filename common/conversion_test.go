@@ -0,0 +1,24 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/pingcap/parser/mysql"
+	"github.com/squareup/pranadb/tidb/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertTiDBTypeToPranaTypeBoolean(t *testing.T) {
+	require.Equal(t, BooleanColumnType, ConvertTiDBTypeToPranaType(ConvertPranaTypeToTiDBType(BooleanColumnType)))
+	require.Equal(t, TinyIntColumnType, ConvertTiDBTypeToPranaType(ConvertPranaTypeToTiDBType(TinyIntColumnType)))
+
+	// A TINYINT(1) is still a TINYINT unless it's flagged as a boolean
+	ft := types.NewFieldType(mysql.TypeTiny)
+	ft.Flen = 1
+	require.Equal(t, TinyIntColumnType, ConvertTiDBTypeToPranaType(ft))
+}
+
+func TestConvertTiDBTypeToPranaTypeVarbinary(t *testing.T) {
+	require.Equal(t, VarbinaryColumnType, ConvertTiDBTypeToPranaType(ConvertPranaTypeToTiDBType(VarbinaryColumnType)))
+	require.Equal(t, VarcharColumnType, ConvertTiDBTypeToPranaType(ConvertPranaTypeToTiDBType(VarcharColumnType)))
+}
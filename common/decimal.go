@@ -88,6 +88,18 @@ func (d *Decimal) Subtract(other *Decimal) (*Decimal, error) {
 	return NewDecimal(result), nil
 }
 
+// Divide divides the decimal by other, rounding the result to scale decimal places
+func (d *Decimal) Divide(other *Decimal, scale int) (*Decimal, error) {
+	result := &types.MyDecimal{}
+	if err := types.DecimalDiv(d.decimal, other.decimal, result, types.DivFracIncr); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := result.Round(result, scale, types.ModeHalfEven); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return NewDecimal(result), nil
+}
+
 func (d *Decimal) String() string {
 	return string(d.decimal.ToString())
 }
@@ -55,6 +55,12 @@ func AppendStringToBufferLE(buffer []byte, value string) []byte {
 	return buffPtr
 }
 
+func AppendBytesToBufferLE(buffer []byte, value []byte) []byte {
+	buffPtr := AppendUint32ToBufferLE(buffer, uint32(len(value)))
+	buffPtr = append(buffPtr, value...)
+	return buffPtr
+}
+
 func AppendDecimalToBuffer(buffer []byte, dec Decimal, precision, scale int) ([]byte, error) {
 	return dec.Encode(buffer, precision, scale)
 }
@@ -163,6 +169,14 @@ func ReadStringFromBufferLE(buffer []byte, offset int) (val string, off int) {
 	return str, offset
 }
 
+func ReadBytesFromBufferLE(buffer []byte, offset int) (val []byte, off int) {
+	lu, offset := ReadUint32FromBufferLE(buffer, offset)
+	l := int(lu)
+	val = buffer[offset : offset+l]
+	offset += l
+	return val, offset
+}
+
 func ReadStringFromBufferBE(buffer []byte, offset int) (val string, off int) {
 	lu, offset := ReadUint32FromBufferBE(buffer, offset)
 	l := int(lu)
@@ -171,6 +185,14 @@ func ReadStringFromBufferBE(buffer []byte, offset int) (val string, off int) {
 	return str, offset
 }
 
+func ReadBytesFromBufferBE(buffer []byte, offset int) (val []byte, off int) {
+	lu, offset := ReadUint32FromBufferBE(buffer, offset)
+	l := int(lu)
+	val = buffer[offset : offset+l]
+	offset += l
+	return val, offset
+}
+
 // Are we running on a machine with a little endian architecture?
 func isLittleEndian() bool {
 	val := uint64(123456)
@@ -40,6 +40,11 @@ func KeyEncodeString(buffer []byte, val string) []byte {
 	return append(buffer, val...)
 }
 
+func KeyEncodeBytes(buffer []byte, val []byte) []byte {
+	buffer = AppendUint32ToBufferBE(buffer, uint32(len(val)))
+	return append(buffer, val...)
+}
+
 func KeyEncodeTimestamp(buffer []byte, val Timestamp) ([]byte, error) {
 	enc, err := val.ToPackedUint()
 	if err != nil {
@@ -69,6 +74,20 @@ func EncodeKeyElement(value interface{}, colType ColumnType, buffer []byte) ([]b
 			return nil, errors.Errorf("expected %v to be int64", value)
 		}
 		buffer = KeyEncodeInt64(buffer, valInt64)
+	case TypeBoolean:
+		// Booleans are encoded as 1 or 0, as they are held in rows
+		var valInt64 int64
+		switch v := value.(type) {
+		case bool:
+			if v {
+				valInt64 = 1
+			}
+		case int64:
+			valInt64 = v
+		default:
+			return nil, errors.Errorf("expected %v to be bool", value)
+		}
+		buffer = KeyEncodeInt64(buffer, valInt64)
 	case TypeDecimal:
 		valDec, ok := value.(Decimal)
 		if !ok {
@@ -91,6 +110,17 @@ func EncodeKeyElement(value interface{}, colType ColumnType, buffer []byte) ([]b
 			return nil, errors.Errorf("expected %v to be string", value)
 		}
 		buffer = KeyEncodeString(buffer, valString)
+	case TypeVarbinary:
+		var valBytes []byte
+		switch v := value.(type) {
+		case []byte:
+			valBytes = v
+		case string:
+			valBytes = []byte(v)
+		default:
+			return nil, errors.Errorf("expected %v to be []byte", value)
+		}
+		buffer = KeyEncodeBytes(buffer, valBytes)
 	case TypeTimestamp:
 		valTime, ok := value.(Timestamp)
 		if !ok {
@@ -136,10 +166,26 @@ func EncodeIndexKeyCols(row *Row, colIndexes []int, colTypes []ColumnType, buffe
 	return buffer, nil
 }
 
+// EncodeNullableKeyCols encodes key columns which can be null. If nullMarkers is set each column is preceded by a
+// marker like an index key column, otherwise a null column is encoded like the zero value of its type, see
+// TableInfo.KeyNullMarkers.
+func EncodeNullableKeyCols(row *Row, colIndexes []int, colTypes []ColumnType, nullMarkers bool, buffer []byte) ([]byte, error) {
+	if nullMarkers {
+		return EncodeIndexKeyCols(row, colIndexes, colTypes, buffer)
+	}
+	return EncodeKeyCols(row, colIndexes, colTypes, buffer)
+}
+
 func EncodeKeyCol(row *Row, colIndex int, colType ColumnType, buffer []byte) ([]byte, error) {
+	if row.IsNull(colIndex) {
+		// Key columns can be null in rows output by a left outer join or grouped by a nullable column. The value stored
+		// for a null column is whatever was last appended to the column, so without null markers we encode the zero
+		// value of the type to keep the key deterministic
+		return encodeNullKeyCol(colType, buffer)
+	}
 	// Key columns must be stored in big-endian so whole key can be compared byte-wise
 	switch colType.Type {
-	case TypeTinyInt, TypeInt, TypeBigInt:
+	case TypeTinyInt, TypeInt, TypeBigInt, TypeBoolean:
 		// We store as unsigned so convert signed to unsigned
 		valInt64 := row.GetInt64(colIndex)
 		buffer = KeyEncodeInt64(buffer, valInt64)
@@ -156,6 +202,8 @@ func EncodeKeyCol(row *Row, colIndex int, colType ColumnType, buffer []byte) ([]
 	case TypeVarchar:
 		valString := row.GetString(colIndex)
 		buffer = KeyEncodeString(buffer, valString)
+	case TypeVarbinary:
+		buffer = KeyEncodeBytes(buffer, row.GetBytes(colIndex))
 	case TypeTimestamp:
 		valTime := row.GetTimestamp(colIndex)
 		var err error
@@ -169,6 +217,25 @@ func EncodeKeyCol(row *Row, colIndex int, colType ColumnType, buffer []byte) ([]
 	return buffer, nil
 }
 
+func encodeNullKeyCol(colType ColumnType, buffer []byte) ([]byte, error) {
+	switch colType.Type {
+	case TypeTinyInt, TypeInt, TypeBigInt, TypeBoolean:
+		return KeyEncodeInt64(buffer, 0), nil
+	case TypeDecimal:
+		return KeyEncodeDecimal(buffer, *ZeroDecimal(), colType.DecPrecision, colType.DecScale)
+	case TypeDouble:
+		return KeyEncodeFloat64(buffer, 0), nil
+	case TypeVarchar:
+		return KeyEncodeString(buffer, ""), nil
+	case TypeVarbinary:
+		return KeyEncodeBytes(buffer, nil), nil
+	case TypeTimestamp:
+		return AppendTimestampToBuffer(buffer, Timestamp{})
+	default:
+		return nil, errors.Errorf("unexpected column type %d", colType)
+	}
+}
+
 func DecodeIndexOrPKCols(buffer []byte, offset int, pk bool, indexOrPKColTypes []ColumnType, indexOrPKOutputCols []int, rows *Rows) (int, error) {
 	for i, outputCol := range indexOrPKOutputCols {
 		colType := indexOrPKColTypes[i]
@@ -193,7 +260,7 @@ func DecodeIndexOrPKCol(buffer []byte, offset int, colType ColumnType, outputCol
 		}
 	} else {
 		switch colType.Type {
-		case TypeTinyInt, TypeInt, TypeBigInt:
+		case TypeTinyInt, TypeInt, TypeBigInt, TypeBoolean:
 			var u uint64
 			u, offset = ReadUint64FromBufferBE(buffer, offset)
 			if outputColIndex != -1 {
@@ -222,6 +289,12 @@ func DecodeIndexOrPKCol(buffer []byte, offset int, colType ColumnType, outputCol
 			if outputColIndex != -1 {
 				rows.AppendStringToColumn(outputColIndex, val)
 			}
+		case TypeVarbinary:
+			var val []byte
+			val, offset = ReadBytesFromBufferBE(buffer, offset)
+			if outputColIndex != -1 {
+				rows.AppendBytesToColumn(outputColIndex, val)
+			}
 		case TypeTimestamp:
 			var (
 				val Timestamp
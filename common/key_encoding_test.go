@@ -27,6 +27,39 @@ func TestKeyEncodeInt64(t *testing.T) {
 	}
 }
 
+func TestKeyEncodeBoolean(t *testing.T) {
+	encodedFalse, err := EncodeKeyElement(false, BooleanColumnType, nil)
+	require.NoError(t, err)
+	encodedTrue, err := EncodeKeyElement(true, BooleanColumnType, nil)
+	require.NoError(t, err)
+	checkLessThan(t, encodedFalse, encodedTrue)
+	// The key of a row with a boolean column is the same as the key of the value
+	rows := NewRows([]ColumnType{BooleanColumnType}, 1)
+	rows.AppendBoolToColumn(0, true)
+	row := rows.GetRow(0)
+	encodedCol, err := EncodeKeyCol(&row, 0, BooleanColumnType, nil)
+	require.NoError(t, err)
+	require.Equal(t, encodedTrue, encodedCol)
+}
+
+func TestKeyEncodeVarbinary(t *testing.T) {
+	val := []byte{0xff, 0x00, 'a'}
+	encodedVal, err := EncodeKeyElement(val, VarbinaryColumnType, nil)
+	require.NoError(t, err)
+	// The key of a row with a varbinary column is the same as the key of the value, and decodes back to it
+	rows := NewRows([]ColumnType{VarbinaryColumnType}, 1)
+	rows.AppendBytesToColumn(0, val)
+	row := rows.GetRow(0)
+	encodedCol, err := EncodeKeyCol(&row, 0, VarbinaryColumnType, nil)
+	require.NoError(t, err)
+	require.Equal(t, encodedVal, encodedCol)
+	decoded := NewRows([]ColumnType{VarbinaryColumnType}, 1)
+	_, err = DecodeIndexOrPKCols(encodedCol, 0, true, []ColumnType{VarbinaryColumnType}, []int{0}, decoded)
+	require.NoError(t, err)
+	decodedRow := decoded.GetRow(0)
+	require.Equal(t, val, decodedRow.GetBytes(0))
+}
+
 func TestKeyEncodeFloat64(t *testing.T) {
 	vals := []float64{
 		-math.MaxFloat64,
@@ -125,6 +158,93 @@ func TestKeyEncodeTimestamp(t *testing.T) {
 	}
 }
 
+func TestEncodeKeyColsNull(t *testing.T) {
+	colTypes := []ColumnType{BigIntColumnType, DoubleColumnType, VarcharColumnType, NewDecimalColumnType(10, 2),
+		TimestampColumnType}
+	keyCols := []int{0, 1, 2, 3, 4}
+	// The encoding of a null key column must not depend on the values appended to the column before it
+	var encoded [][]byte
+	for _, val := range []int64{1, 2} {
+		rows := NewRows(colTypes, 2)
+		dec := NewDecFromInt64(val)
+		rows.AppendInt64ToColumn(0, val)
+		rows.AppendFloat64ToColumn(1, float64(val))
+		rows.AppendStringToColumn(2, "foo")
+		rows.AppendDecimalToColumn(3, *dec)
+		rows.AppendTimestampToColumn(4, NewTimestampFromString("2021-01-02 12:34:56"))
+		for i := range colTypes {
+			rows.AppendNullToColumn(i)
+		}
+		row := rows.GetRow(1)
+		key, err := EncodeKeyCols(&row, keyCols, colTypes, nil)
+		require.NoError(t, err)
+		encoded = append(encoded, key)
+	}
+	require.Equal(t, encoded[0], encoded[1])
+}
+
+func TestEncodeNullableKeyColsNullAndZero(t *testing.T) {
+	colTypes := []ColumnType{BigIntColumnType, DoubleColumnType, VarcharColumnType, NewDecimalColumnType(10, 2),
+		TimestampColumnType, BooleanColumnType, VarbinaryColumnType}
+	for i, colType := range colTypes {
+		rows := NewRows(colTypes, 3)
+		rows.AppendNullToColumn(i)
+		switch colType.Type {
+		case TypeBigInt:
+			rows.AppendInt64ToColumn(i, 0)
+			rows.AppendInt64ToColumn(i, -1)
+		case TypeDouble:
+			rows.AppendFloat64ToColumn(i, 0)
+			rows.AppendFloat64ToColumn(i, -1)
+		case TypeVarchar:
+			rows.AppendStringToColumn(i, "")
+			rows.AppendStringToColumn(i, "a")
+		case TypeDecimal:
+			rows.AppendDecimalToColumn(i, *ZeroDecimal())
+			rows.AppendDecimalToColumn(i, *NewDecFromInt64(-1))
+		case TypeTimestamp:
+			rows.AppendTimestampToColumn(i, Timestamp{})
+			rows.AppendTimestampToColumn(i, NewTimestampFromString("2021-01-02 12:34:56"))
+		case TypeBoolean:
+			rows.AppendBoolToColumn(i, false)
+			rows.AppendBoolToColumn(i, true)
+		case TypeVarbinary:
+			rows.AppendBytesToColumn(i, []byte{})
+			rows.AppendBytesToColumn(i, []byte{0xff})
+		}
+		var keys [][]byte
+		for j := 0; j < 3; j++ {
+			row := rows.GetRow(j)
+			key, err := EncodeNullableKeyCols(&row, []int{i}, colTypes, true, nil)
+			require.NoError(t, err)
+			keys = append(keys, key)
+		}
+		// With null markers a null key doesn't collide with the zero value of the type, and sorts before any value
+		require.NotEqual(t, keys[0], keys[1])
+		checkLessThan(t, keys[0], keys[1])
+		checkLessThan(t, keys[0], keys[2])
+
+		// Both decode back to the values they were encoded from
+		decoded := NewRows([]ColumnType{colType}, 2)
+		for j := 0; j < 2; j++ {
+			_, err := DecodeIndexOrPKCols(keys[j], 0, false, []ColumnType{colType}, []int{0}, decoded)
+			require.NoError(t, err)
+		}
+		nullRow := decoded.GetRow(0)
+		zeroRow := decoded.GetRow(1)
+		require.True(t, nullRow.IsNull(0))
+		require.False(t, zeroRow.IsNull(0))
+
+		// Without them the key is the same as the key of a row without the null
+		row := rows.GetRow(1)
+		withoutMarkers, err := EncodeNullableKeyCols(&row, []int{i}, colTypes, false, nil)
+		require.NoError(t, err)
+		expected, err := EncodeKeyCols(&row, []int{i}, colTypes, nil)
+		require.NoError(t, err)
+		require.Equal(t, expected, withoutMarkers)
+	}
+}
+
 func encodeInt64(val int64) []byte {
 	return KeyEncodeInt64([]byte{}, val)
 }
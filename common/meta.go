@@ -3,8 +3,11 @@ package common
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/squareup/pranadb/command/parser/selector"
 	"github.com/squareup/pranadb/errors"
@@ -21,6 +24,8 @@ const (
 	TypeDecimal
 	TypeVarchar
 	TypeTimestamp
+	TypeBoolean
+	TypeVarbinary
 )
 
 func (t *Type) Capture(tokens []string) error {
@@ -40,6 +45,10 @@ func (t *Type) Capture(tokens []string) error {
 		*t = TypeDouble
 	case "TIMESTAMP":
 		*t = TypeTimestamp
+	case "BOOLEAN", "BOOL":
+		*t = TypeBoolean
+	case "VARBINARY":
+		*t = TypeVarbinary
 	default:
 		return errors.Errorf("unknown column type %s", text)
 	}
@@ -63,6 +72,10 @@ func (t Type) String() string {
 		return "varchar"
 	case TypeTimestamp:
 		return "timestamp"
+	case TypeBoolean:
+		return "boolean"
+	case TypeVarbinary:
+		return "varbinary"
 	case TypeUnknown:
 	}
 	return "unknown"
@@ -75,15 +88,19 @@ var (
 	DoubleColumnType    = ColumnType{Type: TypeDouble}
 	VarcharColumnType   = ColumnType{Type: TypeVarchar}
 	TimestampColumnType = ColumnType{Type: TypeTimestamp}
+	BooleanColumnType   = ColumnType{Type: TypeBoolean}
+	VarbinaryColumnType = ColumnType{Type: TypeVarbinary}
 	UnknownColumnType   = ColumnType{Type: TypeUnknown}
 
 	// ColumnTypesByType allows lookup of non-parameterised ColumnType by Type.
 	ColumnTypesByType = map[Type]ColumnType{
-		TypeTinyInt: TinyIntColumnType,
-		TypeInt:     IntColumnType,
-		TypeBigInt:  BigIntColumnType,
-		TypeDouble:  DoubleColumnType,
-		TypeVarchar: VarcharColumnType,
+		TypeTinyInt:   TinyIntColumnType,
+		TypeInt:       IntColumnType,
+		TypeBigInt:    BigIntColumnType,
+		TypeDouble:    DoubleColumnType,
+		TypeVarchar:   VarcharColumnType,
+		TypeBoolean:   BooleanColumnType,
+		TypeVarbinary: VarbinaryColumnType,
 	}
 )
 
@@ -102,6 +119,10 @@ func InferColumnType(value interface{}) ColumnType {
 		return DoubleColumnType
 	case Timestamp:
 		return TimestampColumnType
+	case bool:
+		return BooleanColumnType
+	case []byte:
+		return VarbinaryColumnType
 	default:
 		panic(fmt.Sprintf("can't infer column of type %T", value))
 	}
@@ -155,7 +176,16 @@ type TableInfo struct {
 	ColumnTypes    []ColumnType
 	IndexInfos     map[string]*IndexInfo
 	ColsVisible    []bool
-	Internal       bool
+	// ColsAddedAt is the table sequence each column was added at with ALTER SOURCE ... ADD COLUMN, or zero for the
+	// columns the table was created with. It's nil if no columns have been added.
+	ColsAddedAt []uint64
+	Internal    bool
+	Retention   *RetentionInfo
+	// KeyNullMarkers is set if each key column in the keys of the table is preceded by a marker which says whether
+	// it's null, see EncodeIndexKeyCols. Key columns of materialized views and their internal tables can be null, e.g.
+	// group by columns. Materialized views created before this was added don't have the markers, so it's part of the
+	// stored table info.
+	KeyNullMarkers bool
 	pKColsSet      map[int]struct{}
 }
 
@@ -172,6 +202,27 @@ func (t *TableInfo) String() string {
 	return fmt.Sprintf("table[name=%s.%s,id=%d]", t.SchemaName, t.Name, t.ID)
 }
 
+// WithAddedColumn returns a copy of the table info with a visible column added to the end
+func (t *TableInfo) WithAddedColumn(name string, colType ColumnType, addedAt uint64) *TableInfo {
+	info := *t
+	numCols := len(t.ColumnNames)
+	info.ColumnNames = append(append(make([]string, 0, numCols+1), t.ColumnNames...), name)
+	info.ColumnTypes = append(append(make([]ColumnType, 0, numCols+1), t.ColumnTypes...), colType)
+	if t.ColsVisible != nil {
+		info.ColsVisible = append(append(make([]bool, 0, numCols+1), t.ColsVisible...), true)
+	}
+	info.ColsAddedAt = make([]uint64, numCols, numCols+1)
+	copy(info.ColsAddedAt, t.ColsAddedAt)
+	info.ColsAddedAt = append(info.ColsAddedAt, addedAt)
+	return &info
+}
+
+// ColAddedAfter returns true if the column was added with ALTER SOURCE ... ADD COLUMN after the table with the id was
+// created
+func (t *TableInfo) ColAddedAfter(colIndex int, tableID uint64) bool {
+	return t.ColsAddedAt != nil && t.ColsAddedAt[colIndex] > tableID
+}
+
 func (t *TableInfo) IsPrimaryKeyCol(colIndex int) bool {
 	if t.pKColsSet == nil {
 		t.calcPKColsSet()
@@ -275,6 +326,25 @@ func (s *Schema) DeleteTable(name string) {
 	delete(s.tables, name)
 }
 
+func (s *Schema) GetSink(name string) (*SinkInfo, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	sink, ok := s.sinks[name]
+	return sink, ok
+}
+
+func (s *Schema) PutSink(name string, sink *SinkInfo) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sinks[name] = sink
+}
+
+func (s *Schema) DeleteSink(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.sinks, name)
+}
+
 func (s *Schema) LenTables() int {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
@@ -323,6 +393,16 @@ func (i *SourceInfo) String() string {
 	return "source_" + i.TableInfo.String()
 }
 
+// UserTableInfo describes a table which is written to directly with INSERT, UPDATE and DELETE statements, rather than
+// being ingested from Kafka.
+type UserTableInfo struct {
+	*TableInfo
+}
+
+func (i *UserTableInfo) String() string {
+	return "table_" + i.TableInfo.String()
+}
+
 type Table interface {
 	GetTableInfo() *TableInfo
 }
@@ -337,13 +417,270 @@ func (i *MetaTableInfo) String() string {
 }
 
 type TopicInfo struct {
-	BrokerName     string
-	TopicName      string
+	BrokerName string
+	// TopicName is the topic the source consumes from. It's empty if the source consumes from TopicNames or
+	// TopicPattern instead.
+	TopicName string
+	// TopicNames are the topics the source consumes from, if it consumes from more than one
+	TopicNames []string
+	// TopicPattern is a regular expression, and the source consumes from every topic whose whole name matches it,
+	// including topics which are created after the source
+	TopicPattern   string
 	KeyEncoding    KafkaEncoding
 	ValueEncoding  KafkaEncoding
 	HeaderEncoding KafkaEncoding
 	ColSelectors   []selector.ColumnSelector
 	Properties     map[string]string
+	EventTime      *EventTimeInfo
+	Semantics      SourceSemantics
+	CSVOptions     *CSVOptions
+	ErrorPolicy    SourceErrorPolicy
+	// DeadLetterTopic is the topic, on the same broker, that messages which fail are sent to with
+	// SourceErrorPolicyDeadLetter. If it's empty they are written to the dead letters system table instead.
+	DeadLetterTopic string
+	// StartFrom is where in the topic the source starts consuming from when it's created. Nil means the earliest
+	// message.
+	StartFrom *StartFrom
+	// OffsetResets is the number of times the offsets of the source have been reset. It's part of the originator id
+	// used to detect duplicate messages, so messages which are consumed again after a reset aren't ignored.
+	OffsetResets uint32
+	// Paused is true if the source has been paused with ALTER SOURCE ... PAUSE. A paused source isn't started until it's
+	// resumed, including when the cluster restarts.
+	Paused bool
+	// TopicIndexes gives each topic of a source with multiple topics an index that's unique within the source. It's
+	// part of the originator id used to detect duplicate messages, as partitions of different topics have the same ids.
+	// The topics in TopicNames are given their indexes when the source is created, and topics which match TopicPattern
+	// are given the next index when a message is first ingested from them.
+	TopicIndexes map[string]uint32
+}
+
+// HasMultipleTopics returns true if the source consumes from more than one topic, or from the topics which match a
+// pattern
+func (t *TopicInfo) HasMultipleTopics() bool {
+	return len(t.TopicNames) > 0 || t.TopicPattern != ""
+}
+
+// CSVOptions describes how the CSV encoded headers, key or value of the messages of a topic are split into fields.
+type CSVOptions struct {
+	Delimiter rune
+	// Quote is the character which fields containing the delimiter are enclosed in, or zero if fields aren't quoted
+	Quote rune
+}
+
+var DefaultCSVOptions = CSVOptions{Delimiter: ',', Quote: '"'}
+
+// The names of the invisible columns which hold the topic, partition and offset of the message a row was ingested from,
+// for a source with SourceSemanticsAppend. The topic is only held if the source has multiple topics.
+const (
+	TopicColumnName       = "__gen_topic"
+	PartitionIDColumnName = "__gen_partition_id"
+	OffsetColumnName      = "__gen_offset"
+)
+
+// SourceSemantics determines how the messages of a topic are applied to the rows of a source.
+type SourceSemantics int
+
+const (
+	// SourceSemanticsUpsert means every message inserts or replaces the row with its key.
+	SourceSemanticsUpsert SourceSemantics = iota
+	// SourceSemanticsChangelog is like SourceSemanticsUpsert, except a tombstone (a message with a null value) deletes
+	// the row with its key. This is what a compacted topic, e.g. one produced by a CDC pipeline, expects.
+	SourceSemanticsChangelog
+	// SourceSemanticsAppend means every message is a new row. The source has no primary key of its own - rows are keyed
+	// on the partition and offset of the message, which are added as invisible columns.
+	SourceSemanticsAppend
+	SourceSemanticsUnknown
+)
+
+func (s SourceSemantics) String() string {
+	switch s {
+	case SourceSemanticsUpsert:
+		return "upsert"
+	case SourceSemanticsChangelog:
+		return "changelog"
+	case SourceSemanticsAppend:
+		return "append"
+	default:
+		return "unknown"
+	}
+}
+
+func SourceSemanticsFromString(str string) SourceSemantics {
+	switch strings.ToLower(str) {
+	case "upsert":
+		return SourceSemanticsUpsert
+	case "changelog":
+		return SourceSemanticsChangelog
+	case "append":
+		return SourceSemanticsAppend
+	default:
+		return SourceSemanticsUnknown
+	}
+}
+
+// SourceErrorPolicy determines what a source does with a message which can't be ingested, e.g. because it can't be
+// decoded or a selected value can't be coerced to the type of its column.
+type SourceErrorPolicy int
+
+const (
+	// SourceErrorPolicyFail stops the source, so no further messages are ingested until it's fixed.
+	SourceErrorPolicyFail SourceErrorPolicy = iota
+	// SourceErrorPolicySkip skips the message. The number of messages skipped is counted.
+	SourceErrorPolicySkip
+	// SourceErrorPolicyDeadLetter skips the message and records it, along with the error, in a dead letter topic or
+	// the dead letters system table.
+	SourceErrorPolicyDeadLetter
+	SourceErrorPolicyUnknown
+)
+
+func (s SourceErrorPolicy) String() string {
+	switch s {
+	case SourceErrorPolicyFail:
+		return "fail"
+	case SourceErrorPolicySkip:
+		return "skip"
+	case SourceErrorPolicyDeadLetter:
+		return "deadletter"
+	default:
+		return "unknown"
+	}
+}
+
+func SourceErrorPolicyFromString(str string) SourceErrorPolicy {
+	switch strings.ToLower(str) {
+	case "fail":
+		return SourceErrorPolicyFail
+	case "skip":
+		return SourceErrorPolicySkip
+	case "deadletter":
+		return SourceErrorPolicyDeadLetter
+	default:
+		return SourceErrorPolicyUnknown
+	}
+}
+
+// StartFrom is a position in a topic that a source can start consuming from
+type StartFrom struct {
+	Kind StartFromKind
+	// Timestamp is the time of the first message to consume with StartFromTimestamp
+	Timestamp time.Time
+	// Offsets maps partitions to the offset of the first message to consume with StartFromOffsets. Partitions which
+	// aren't present start from the earliest message.
+	Offsets map[int32]int64
+}
+
+type StartFromKind int
+
+const (
+	StartFromEarliest StartFromKind = iota
+	StartFromLatest
+	StartFromTimestamp
+	StartFromOffsets
+)
+
+const (
+	startFromTimestampPrefix = "timestamp:"
+	startFromOffsetsPrefix   = "offsets:"
+)
+
+func (s *StartFrom) String() string {
+	switch s.Kind {
+	case StartFromEarliest:
+		return "earliest"
+	case StartFromLatest:
+		return "latest"
+	case StartFromTimestamp:
+		return startFromTimestampPrefix + s.Timestamp.UTC().Format("2006-01-02 15:04:05.999999")
+	case StartFromOffsets:
+		partitions := make([]int, 0, len(s.Offsets))
+		for partition := range s.Offsets {
+			partitions = append(partitions, int(partition))
+		}
+		sort.Ints(partitions)
+		offsets := make([]string, len(partitions))
+		for i, partition := range partitions {
+			offsets[i] = fmt.Sprintf("%d=%d", partition, s.Offsets[int32(partition)])
+		}
+		return startFromOffsetsPrefix + strings.Join(offsets, ",")
+	default:
+		return "unknown"
+	}
+}
+
+// ParseStartFrom parses a position in a topic. It's one of "earliest", "latest", "timestamp:<timestamp>", where the
+// timestamp is in UTC, or "offsets:<partition>=<offset>,...".
+func ParseStartFrom(str string) (*StartFrom, error) {
+	lower := strings.ToLower(strings.TrimSpace(str))
+	switch {
+	case lower == "earliest":
+		return &StartFrom{Kind: StartFromEarliest}, nil
+	case lower == "latest":
+		return &StartFrom{Kind: StartFromLatest}, nil
+	case strings.HasPrefix(lower, startFromTimestampPrefix):
+		ts, err := ParseTimestamp(strings.TrimSpace(lower[len(startFromTimestampPrefix):]))
+		if err != nil {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid startFrom %q, invalid timestamp", str)
+		}
+		gt, err := ts.GoTime(time.UTC)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &StartFrom{Kind: StartFromTimestamp, Timestamp: gt}, nil
+	case strings.HasPrefix(lower, startFromOffsetsPrefix):
+		offsets := make(map[int32]int64)
+		for _, pair := range strings.Split(lower[len(startFromOffsetsPrefix):], ",") {
+			parts := strings.Split(pair, "=")
+			if len(parts) != 2 {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid startFrom %q, offsets must be <partition>=<offset>", str)
+			}
+			partition, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 32)
+			if err != nil || partition < 0 {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid startFrom %q, invalid partition %q", str, parts[0])
+			}
+			offset, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+			if err != nil || offset < 0 {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid startFrom %q, invalid offset %q", str, parts[1])
+			}
+			if _, ok := offsets[int32(partition)]; ok {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid startFrom %q, partition %d is repeated", str, partition)
+			}
+			offsets[int32(partition)] = offset
+		}
+		return &StartFrom{Kind: StartFromOffsets, Offsets: offsets}, nil
+	default:
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement,
+			"Invalid startFrom %q, must be one of \"earliest\", \"latest\", \"timestamp:<timestamp>\" or \"offsets:<partition>=<offset>,...\"", str)
+	}
+}
+
+// EventTimeInfo describes the event time column of a source. The source tracks a watermark for each partition of the
+// topic - the latest event time seen on the partition less the allowed lateness. Rows with an event time before the
+// lowest watermark are late, and are dropped. A partition which hasn't received a message for the idle timeout doesn't
+// hold the watermark back.
+type EventTimeInfo struct {
+	ColIndex        int
+	AllowedLateness time.Duration
+	IdleTimeout     time.Duration
+}
+
+// DefaultIdleTimeout is the idle timeout of the partitions of a source with an event time column, if none is given
+const DefaultIdleTimeout = time.Minute
+
+// IngestTimeColumnName is the name of the invisible column which holds the time a row was ingested, for a source which
+// has a retention but no event time column
+const IngestTimeColumnName = "__gen_ingest_time"
+
+// RetentionInfo describes how long the rows of a source or materialized view are kept. A row expires once the value of
+// its retention column is older than the retention period, and is deleted in the background on the shard that owns it.
+type RetentionInfo struct {
+	ColIndex int
+	Period   time.Duration
+	// Propagate is true if the deletion of an expired row is passed on to the materialized views and sinks which consume
+	// the table, as if the row had been deleted upstream. Otherwise, they keep the row.
+	Propagate bool
+	// IndexID is the ID of the retention index, which orders the rows by their retention column so the expired rows
+	// can be found without scanning the table, see table.EncodeRetentionKeyValue
+	IndexID uint64
 }
 
 type KafkaEncoding struct {
@@ -362,6 +699,7 @@ var (
 	KafkaEncodingInt64BE     = KafkaEncoding{Encoding: EncodingInt64BE}
 	KafkaEncodingInt16BE     = KafkaEncoding{Encoding: EncodingInt16BE}
 	KafkaEncodingStringBytes = KafkaEncoding{Encoding: EncodingStringBytes}
+	KafkaEncodingAvro        = KafkaEncoding{Encoding: EncodingAvro}
 )
 
 type Encoding int
@@ -378,6 +716,7 @@ const (
 	EncodingInt64BE
 	EncodingInt16BE
 	EncodingStringBytes
+	EncodingAvro // Avro in the Confluent wire format, with the schema fetched from a schema registry
 )
 
 // KafkaEncodingFromString decodes an encoding and an optional schema name from the string,
@@ -392,6 +731,35 @@ func KafkaEncodingFromString(str string) KafkaEncoding {
 	return KafkaEncoding{Encoding: enc, SchemaName: parts[1]}
 }
 
+func (e Encoding) String() string {
+	switch e {
+	case EncodingJSON:
+		return "json"
+	case EncodingProtobuf:
+		return "protobuf"
+	case EncodingRaw:
+		return "raw"
+	case EncodingCSV:
+		return "csv"
+	case EncodingFloat32BE:
+		return "float32be"
+	case EncodingFloat64BE:
+		return "float64be"
+	case EncodingInt32BE:
+		return "int32be"
+	case EncodingInt64BE:
+		return "int64be"
+	case EncodingInt16BE:
+		return "int16be"
+	case EncodingStringBytes:
+		return "stringbytes"
+	case EncodingAvro:
+		return "avro"
+	default:
+		return "unknown"
+	}
+}
+
 func EncodingFormatFromString(str string) Encoding {
 	str = strings.ToLower(str)
 	switch str {
@@ -415,6 +783,8 @@ func EncodingFormatFromString(str string) Encoding {
 		return EncodingInt16BE
 	case "stringbytes":
 		return EncodingStringBytes
+	case "avro":
+		return EncodingAvro
 	default:
 		return EncodingUnknown
 	}
@@ -435,8 +805,11 @@ func (i *MaterializedViewInfo) String() string {
 	return "mv_" + i.TableInfo.String()
 }
 
+// SinkInfo describes a sink, which publishes the changes to a materialized view to a Kafka topic
 type SinkInfo struct {
-	Name      string
-	Query     string
-	TopicInfo *TopicInfo
+	ID                   uint64
+	SchemaName           string
+	Name                 string
+	MaterializedViewName string
+	TopicInfo            *TopicInfo
 }
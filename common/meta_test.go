@@ -49,6 +49,16 @@ func TestColumnType_String(t *testing.T) {
 			fields: fields{Type: TypeTimestamp, FSP: 6},
 			want:   "timestamp(6)",
 		},
+		{
+			name:   "boolean",
+			fields: fields{Type: TypeBoolean},
+			want:   "boolean",
+		},
+		{
+			name:   "varbinary",
+			fields: fields{Type: TypeVarbinary},
+			want:   "varbinary",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -64,3 +74,39 @@ func TestColumnType_String(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStartFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     string
+		want    string
+		wantErr bool
+	}{
+		{name: "earliest", str: "earliest", want: "earliest"},
+		{name: "latest", str: "LATEST", want: "latest"},
+		{name: "timestamp", str: "timestamp:2022-01-01 10:00:00.5", want: "timestamp:2022-01-01 10:00:00.5"},
+		{name: "offsets", str: "offsets:1=200, 0=100", want: "offsets:0=100,1=200"},
+		{name: "unknown", str: "beginning", wantErr: true},
+		{name: "invalid timestamp", str: "timestamp:yesterday", wantErr: true},
+		{name: "invalid offsets", str: "offsets:0", wantErr: true},
+		{name: "negative offset", str: "offsets:0=-1", wantErr: true},
+		{name: "repeated partition", str: "offsets:0=1,0=2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStartFrom(tt.str)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseStartFrom(%q) expected error, got %v", tt.str, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseStartFrom(%q) error %v", tt.str, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("ParseStartFrom(%q) = %v, want %v", tt.str, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,9 @@ import (
 // Values in rows are typically encoded in little-endian order
 // Most CPU architectures are little-endian so those allows us to simply cast values in the cast of int types
 
+// Encoded rows are versioned by their number of columns. Columns are only ever added to the end of a table, with
+// ALTER SOURCE ... ADD COLUMN, so the columns missing from a row which was encoded before they were added decode as null.
+
 func EncodeRow(row *Row, colTypes []ColumnType, buffer []byte) ([]byte, error) {
 	for colIndex, colType := range colTypes {
 		var err error
@@ -28,6 +31,13 @@ func encodeRowCol(row *Row, colIndex int, colType ColumnType, buffer []byte) ([]
 			// We store as unsigned so convert signed to unsigned
 			valInt64 := row.GetInt64(colIndex)
 			buffer = AppendUint64ToBufferLE(buffer, uint64(valInt64))
+		case TypeBoolean:
+			// A boolean only needs a byte
+			if row.GetBool(colIndex) {
+				buffer = append(buffer, 1)
+			} else {
+				buffer = append(buffer, 0)
+			}
 		case TypeDecimal:
 			valDec := row.GetDecimal(colIndex)
 			var err error
@@ -41,6 +51,8 @@ func encodeRowCol(row *Row, colIndex int, colType ColumnType, buffer []byte) ([]
 		case TypeVarchar:
 			valString := row.GetString(colIndex)
 			buffer = AppendStringToBufferLE(buffer, valString)
+		case TypeVarbinary:
+			buffer = AppendBytesToBufferLE(buffer, row.GetBytes(colIndex))
 		case TypeTimestamp:
 			valTimestamp := row.GetTimestamp(colIndex)
 			var err error
@@ -64,6 +76,14 @@ func DecodeRowWithIgnoredCols(buffer []byte, colTypes []ColumnType, includeCol [
 	colIndex := 0
 	for i, colType := range colTypes {
 		include := includeCol == nil || includeCol[i]
+		if offset == len(buffer) {
+			// The row was encoded before the column was added
+			if include {
+				rows.AppendNullToColumn(colIndex)
+				colIndex++
+			}
+			continue
+		}
 		if buffer[offset] == 0 {
 			offset++
 			if include {
@@ -78,6 +98,12 @@ func DecodeRowWithIgnoredCols(buffer []byte, colTypes []ColumnType, includeCol [
 				if include {
 					rows.AppendInt64ToColumn(colIndex, int64(u))
 				}
+			case TypeBoolean:
+				val := buffer[offset] != 0
+				offset++
+				if include {
+					rows.AppendBoolToColumn(colIndex, val)
+				}
 			case TypeDecimal:
 				var val Decimal
 				var err error
@@ -100,6 +126,12 @@ func DecodeRowWithIgnoredCols(buffer []byte, colTypes []ColumnType, includeCol [
 				if include {
 					rows.AppendStringToColumn(colIndex, val)
 				}
+			case TypeVarbinary:
+				var val []byte
+				val, offset = ReadBytesFromBufferLE(buffer, offset)
+				if include {
+					rows.AppendBytesToColumn(colIndex, val)
+				}
 			case TypeTimestamp:
 				var (
 					val Timestamp
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeRow(t *testing.T) {
+	colTypes := []ColumnType{BigIntColumnType, VarcharColumnType, DoubleColumnType, NewDecimalColumnType(10, 2),
+		NewTimestampColumnType(6)}
+	rows := NewRows(colTypes, 2)
+	rows.AppendInt64ToColumn(0, 1)
+	rows.AppendStringToColumn(1, "foo")
+	rows.AppendFloat64ToColumn(2, 1.25)
+	dec, err := NewDecFromString("123.45")
+	require.NoError(t, err)
+	rows.AppendDecimalToColumn(3, *dec)
+	ts, err := ParseTimestamp("2022-01-01 01:02:03.000004")
+	require.NoError(t, err)
+	rows.AppendTimestampToColumn(4, ts)
+	rows.AppendInt64ToColumn(0, 2)
+	for i := 1; i < len(colTypes); i++ {
+		rows.AppendNullToColumn(i)
+	}
+
+	decoded := NewRows(colTypes, 2)
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		buff, err := EncodeRow(&row, colTypes, nil)
+		require.NoError(t, err)
+		err = DecodeRow(buff, colTypes, decoded)
+		require.NoError(t, err)
+	}
+	require.Equal(t, rows.String(), decoded.String())
+}
+
+func TestEncodeDecodeBoolean(t *testing.T) {
+	colTypes := []ColumnType{BooleanColumnType, BigIntColumnType}
+	rows := NewRows(colTypes, 3)
+	for _, b := range []bool{true, false} {
+		rows.AppendBoolToColumn(0, b)
+		rows.AppendInt64ToColumn(1, 7)
+	}
+	rows.AppendNullToColumn(0)
+	rows.AppendInt64ToColumn(1, 7)
+
+	decoded := NewRows(colTypes, 3)
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		buff, err := EncodeRow(&row, colTypes, nil)
+		require.NoError(t, err)
+		err = DecodeRow(buff, colTypes, decoded)
+		require.NoError(t, err)
+	}
+	row0, row1, row2 := decoded.GetRow(0), decoded.GetRow(1), decoded.GetRow(2)
+	require.True(t, row0.GetBool(0))
+	require.False(t, row1.GetBool(0))
+	require.True(t, row2.IsNull(0))
+	require.Equal(t, int64(7), row2.GetInt64(1))
+	require.Equal(t, "|true|7|", row0.String())
+}
+
+func TestEncodeDecodeVarbinary(t *testing.T) {
+	colTypes := []ColumnType{VarbinaryColumnType, BigIntColumnType}
+	rows := NewRows(colTypes, 3)
+	for _, b := range [][]byte{{0xff, 0xfe, 'a'}, {}} {
+		rows.AppendBytesToColumn(0, b)
+		rows.AppendInt64ToColumn(1, 7)
+	}
+	rows.AppendNullToColumn(0)
+	rows.AppendInt64ToColumn(1, 7)
+
+	decoded := NewRows(colTypes, 3)
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		buff, err := EncodeRow(&row, colTypes, nil)
+		require.NoError(t, err)
+		err = DecodeRow(buff, colTypes, decoded)
+		require.NoError(t, err)
+	}
+	row0, row1, row2 := decoded.GetRow(0), decoded.GetRow(1), decoded.GetRow(2)
+	require.Equal(t, []byte{0xff, 0xfe, 'a'}, row0.GetBytes(0))
+	require.Equal(t, 0, len(row1.GetBytes(0)))
+	require.False(t, row1.IsNull(0))
+	require.True(t, row2.IsNull(0))
+	require.Equal(t, int64(7), row2.GetInt64(1))
+	require.Equal(t, "|0xfffe61|7|", row0.String())
+}
+
+func TestDecodeRowWithAddedColumns(t *testing.T) {
+	colTypes := []ColumnType{BigIntColumnType, VarcharColumnType}
+	rows := NewRows(colTypes, 1)
+	rows.AppendInt64ToColumn(0, 1)
+	rows.AppendStringToColumn(1, "foo")
+	row := rows.GetRow(0)
+	buff, err := EncodeRow(&row, colTypes, nil)
+	require.NoError(t, err)
+
+	// The columns added since the row was encoded are null
+	addedColTypes := append(colTypes, DoubleColumnType, VarcharColumnType)
+	decoded := NewRows(addedColTypes, 1)
+	err = DecodeRow(buff, addedColTypes, decoded)
+	require.NoError(t, err)
+	decodedRow := decoded.GetRow(0)
+	require.Equal(t, int64(1), decodedRow.GetInt64(0))
+	require.Equal(t, "foo", decodedRow.GetString(1))
+	require.True(t, decodedRow.IsNull(2))
+	require.True(t, decodedRow.IsNull(3))
+
+	decoded = NewRows([]ColumnType{VarcharColumnType, DoubleColumnType}, 1)
+	err = DecodeRowWithIgnoredCols(buff, addedColTypes, []bool{false, true, true, false}, decoded)
+	require.NoError(t, err)
+	decodedRow = decoded.GetRow(0)
+	require.Equal(t, "foo", decodedRow.GetString(0))
+	require.True(t, decodedRow.IsNull(1))
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"strings"
@@ -92,10 +93,24 @@ func (r *Rows) AppendStringToColumn(colIndex int, val string) {
 	col.AppendString(val)
 }
 
+func (r *Rows) AppendBytesToColumn(colIndex int, val []byte) {
+	col := r.chunk.Column(colIndex)
+	col.AppendBytes(val)
+}
+
 func (r *Rows) AppendTimestampToColumn(colIndex int, val Timestamp) {
 	r.chunk.AppendTime(colIndex, val)
 }
 
+// AppendBoolToColumn appends a boolean. Booleans are held as 1 or 0, like the integer types, as they are in TiDB
+func (r *Rows) AppendBoolToColumn(colIndex int, val bool) {
+	var i int64
+	if val {
+		i = 1
+	}
+	r.AppendInt64ToColumn(colIndex, i)
+}
+
 func (r *Rows) AppendNullToColumn(colIndex int) {
 	col := r.chunk.Column(colIndex)
 	col.AppendNull()
@@ -126,6 +141,10 @@ func (r *Row) GetInt64(colIndex int) int64 {
 	return r.tRow.GetInt64(colIndex)
 }
 
+func (r *Row) GetBool(colIndex int) bool {
+	return r.tRow.GetInt64(colIndex) != 0
+}
+
 func (r *Row) GetFloat64(colIndex int) float64 {
 	return r.tRow.GetFloat64(colIndex)
 }
@@ -138,6 +157,10 @@ func (r *Row) GetString(colIndex int) string {
 	return r.tRow.GetString(colIndex)
 }
 
+func (r *Row) GetBytes(colIndex int) []byte {
+	return r.tRow.GetBytes(colIndex)
+}
+
 func (r *Row) GetTimestamp(colIndex int) Timestamp {
 	return r.tRow.GetTime(colIndex)
 }
@@ -185,6 +208,8 @@ func (r *Row) String() string {
 			case TypeTinyInt, TypeInt, TypeBigInt:
 				val := r.GetInt64(j)
 				sb.WriteString(strconv.Itoa(int(val)))
+			case TypeBoolean:
+				sb.WriteString(strconv.FormatBool(r.GetBool(j)))
 			case TypeDouble:
 				val := r.GetFloat64(j)
 				sb.WriteString(fmt.Sprintf("%f", val))
@@ -194,6 +219,8 @@ func (r *Row) String() string {
 			case TypeVarchar:
 				dec := r.GetString(j)
 				sb.WriteString(dec)
+			case TypeVarbinary:
+				sb.WriteString(FormatBytes(r.GetBytes(j)))
 			case TypeTimestamp:
 				val := r.GetTimestamp(j)
 				sb.WriteString(val.String())
@@ -207,6 +234,11 @@ func (r *Row) String() string {
 	return sb.String()
 }
 
+// FormatBytes formats a varbinary value as hex prefixed with 0x, like the binary-as-hex option of the MySQL client
+func FormatBytes(val []byte) string {
+	return "0x" + hex.EncodeToString(val)
+}
+
 // ToSimpleColNames converts a column name of the form schema.table.col to col
 func ToSimpleColNames(colNames []string) []string {
 	sCols := make([]string, len(colNames))
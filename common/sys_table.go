@@ -13,5 +13,7 @@ const (
 	ToDeleteTableID             = 9
 	LocalConfigTableID          = 10
 	ForwardDedupTableID         = 11
+	DeadLetterTableID           = 12
+	WatermarkTableID            = 13
 	UserTableIDBase             = 1000
 )
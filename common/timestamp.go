@@ -14,15 +14,21 @@ type Timestamp = types.Time
 
 // NewTimestampFromString parses a Timestamp from a string in MySQL datetime format.
 func NewTimestampFromString(str string) Timestamp {
-	ts, err := types.ParseTimestamp(&stmtctx.StatementContext{
-		TimeZone: time.UTC,
-	}, str)
+	ts, err := ParseTimestamp(str)
 	if err != nil {
 		panic(err)
 	}
 	return ts
 }
 
+// ParseTimestamp parses a Timestamp from a string in MySQL datetime format, returning an error if the string is not a
+// valid datetime.
+func ParseTimestamp(str string) (Timestamp, error) {
+	return types.ParseTimestamp(&stmtctx.StatementContext{
+		TimeZone: time.UTC,
+	}, str)
+}
+
 func NewTimestampFromGoTime(t time.Time) Timestamp {
 	return types.NewTime(types.FromGoTime(t.UTC()), mysql.TypeTimestamp, 6)
 }
@@ -22,6 +22,7 @@ const (
 	DefaultRaftRTTMs                     = 100
 	DefaultRaftHeartbeatRTT              = 30
 	DefaultRaftElectionRTT               = 300
+	DefaultRetentionCheckInterval        = 1 * time.Minute
 )
 
 type Config struct {
@@ -62,6 +63,7 @@ type Config struct {
 	RaftRTTMs                        int
 	RaftElectionRTT                  int
 	RaftHeartbeatRTT                 int
+	RetentionCheckInterval           time.Duration `help:"How often the rows of sources and materialized views with a retention are checked for expiry" default:"1m"`
 }
 
 func (c *Config) Validate() error { //nolint:gocyclo
@@ -176,6 +178,9 @@ func (c *Config) Validate() error { //nolint:gocyclo
 	if c.RaftElectionRTT < 2*c.RaftHeartbeatRTT {
 		return errors.NewInvalidConfigurationError("RaftElectionRTT must be > 2 * RaftHeartbeatRTT")
 	}
+	if c.RetentionCheckInterval < 100*time.Millisecond {
+		return errors.NewInvalidConfigurationError(fmt.Sprintf("RetentionCheckInterval must be >= %d", 100*time.Millisecond))
+	}
 	return nil
 }
 
@@ -192,6 +197,8 @@ const (
 type BrokerConfig struct {
 	ClientType BrokerClientType  `json:"client-type,omitempty"`
 	Properties map[string]string `json:"properties,omitempty"`
+	// SchemaRegistryURL is the URL of the schema registry which Avro encoded messages from the broker refer to
+	SchemaRegistryURL string `json:"schema-registry-url,omitempty"`
 }
 
 func NewDefaultConfig() *Config {
@@ -210,6 +217,7 @@ func NewDefaultConfig() *Config {
 		RaftRTTMs:                     DefaultRaftRTTMs,
 		RaftHeartbeatRTT:              DefaultRaftHeartbeatRTT,
 		RaftElectionRTT:               DefaultRaftElectionRTT,
+		RetentionCheckInterval:        DefaultRetentionCheckInterval,
 	}
 }
 
@@ -223,6 +231,7 @@ func NewTestConfig(fakeKafkaID int64) *Config {
 		RaftRTTMs:                     DefaultRaftRTTMs,
 		RaftHeartbeatRTT:              DefaultRaftHeartbeatRTT,
 		RaftElectionRTT:               DefaultRaftElectionRTT,
+		RetentionCheckInterval:        DefaultRetentionCheckInterval,
 		NodeID:                        0,
 		NumShards:                     10,
 		TestServer:                    true,
@@ -266,6 +266,12 @@ func invalidRaftElectionRTTNegative() Config {
 	return cnf
 }
 
+func invalidRetentionCheckInterval() Config {
+	cnf := confAllFields
+	cnf.RetentionCheckInterval = 100*time.Millisecond - 1
+	return cnf
+}
+
 func invalidRaftElectionRTTTooSmall() Config {
 	cnf := confAllFields
 	cnf.RaftElectionRTT = 1 + cnf.RaftHeartbeatRTT
@@ -310,6 +316,7 @@ var invalidConfigs = []configPair{
 	{"PDB0004 - Invalid configuration: RaftElectionRTT must be > 0", invalidRaftElectionRTTZero()},
 	{"PDB0004 - Invalid configuration: RaftElectionRTT must be > 0", invalidRaftElectionRTTNegative()},
 	{"PDB0004 - Invalid configuration: RaftElectionRTT must be > 2 * RaftHeartbeatRTT", invalidRaftElectionRTTTooSmall()},
+	{"PDB0004 - Invalid configuration: RetentionCheckInterval must be >= 100000000", invalidRetentionCheckInterval()},
 }
 
 func TestValidate(t *testing.T) {
@@ -356,4 +363,5 @@ var confAllFields = Config{
 	RaftRTTMs:                     100,
 	RaftHeartbeatRTT:              10,
 	RaftElectionRTT:               100,
+	RetentionCheckInterval:        30 * time.Second,
 }
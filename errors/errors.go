@@ -34,12 +34,27 @@ const (
 	IndexAlreadyExists
 
 	UnknownPerfCommand
+
+	UnknownSink
+	SinkAlreadyExists
+
+	SubscriptionClosed
+
+	UnknownTable
+	TableAlreadyExists
+	TableHasChildren
+
+	SubqueryReturnsMoreThanOneRow
 )
 
 func NewInternalError(seq int64) PranaError {
 	return NewPranaErrorf(InternalError, "Internal error - sequence %d please consult server logs for details", seq)
 }
 
+func NewSubqueryReturnsMoreThanOneRowError() PranaError {
+	return NewPranaErrorf(SubqueryReturnsMoreThanOneRow, "Subquery returns more than 1 row")
+}
+
 func NewSchemaNotInUseError() PranaError {
 	return NewPranaErrorf(SchemaNotInUse, "No schema in use")
 }
@@ -60,6 +75,10 @@ func NewUnknownSourceError(schemaName string, sourceName string) PranaError {
 	return NewPranaErrorf(UnknownSource, "Unknown source: %s.%s", schemaName, sourceName)
 }
 
+func NewUnknownTableError(schemaName string, tableName string) PranaError {
+	return NewPranaErrorf(UnknownTable, "Unknown table: %s.%s", schemaName, tableName)
+}
+
 func NewUnknownIndexError(schemaName string, tableName string, indexName string) PranaError {
 	return NewPranaErrorf(UnknownSource, "Unknown index: %s.%s.%s", schemaName, tableName, indexName)
 }
@@ -68,6 +87,10 @@ func NewUnknownMaterializedViewError(schemaName string, mvName string) PranaErro
 	return NewPranaErrorf(UnknownMaterializedView, "Unknown materialized view: %s.%s", schemaName, mvName)
 }
 
+func NewUnknownSinkError(schemaName string, sinkName string) PranaError {
+	return NewPranaErrorf(UnknownSink, "Unknown sink: %s.%s", schemaName, sinkName)
+}
+
 func NewUnknownSourceOrMaterializedViewError(schemaName string, tableName string) PranaError {
 	return NewPranaErrorf(UnknownSourceOrMaterializedView, "Unknown source or materialized view: %s.%s", schemaName, tableName)
 }
@@ -84,6 +107,14 @@ func NewSourceAlreadyExistsError(schemaName string, sourceName string) PranaErro
 	return NewPranaErrorf(SourceAlreadyExists, "Source already exists: %s.%s", schemaName, sourceName)
 }
 
+func NewTableAlreadyExistsError(schemaName string, tableName string) PranaError {
+	return NewPranaErrorf(TableAlreadyExists, "Table already exists: %s.%s", schemaName, tableName)
+}
+
+func NewSinkAlreadyExistsError(schemaName string, sinkName string) PranaError {
+	return NewPranaErrorf(SinkAlreadyExists, "Sink already exists: %s.%s", schemaName, sinkName)
+}
+
 func NewIndexAlreadyExistsError(schemaName string, tableName string, indexName string) PranaError {
 	return NewPranaErrorf(IndexAlreadyExists, "Index %s already exists on %s.%s", indexName, schemaName, tableName)
 }
@@ -96,10 +127,29 @@ func NewSourceHasChildrenError(schemaName string, sourceName string, childMVs []
 	return NewPranaErrorf(SourceHasChildren, "Cannot drop source %s.%s it has the following children %s", schemaName, sourceName, getChildString(schemaName, childMVs))
 }
 
+func NewTableHasChildrenError(schemaName string, tableName string, childMVs []string) PranaError {
+	return NewPranaErrorf(TableHasChildren, "Cannot drop table %s.%s it has the following children %s", schemaName, tableName, getChildString(schemaName, childMVs))
+}
+
 func NewMaterializedViewHasChildrenError(schemaName string, materializedViewName string, childMVs []string) PranaError {
 	return NewPranaErrorf(MaterializedViewHasChildren, "Cannot drop materialized view %s.%s it has the following children %s", schemaName, materializedViewName, getChildString(schemaName, childMVs))
 }
 
+func NewMaterializedViewHasChildrenReplaceError(schemaName string, materializedViewName string, childMVs []string,
+	indexes []string, sinks []string) PranaError {
+	var children []string
+	if len(childMVs) != 0 {
+		children = append(children, "materialized views "+getChildString(schemaName, childMVs))
+	}
+	if len(indexes) != 0 {
+		children = append(children, "indexes "+getChildString(schemaName+"."+materializedViewName, indexes))
+	}
+	if len(sinks) != 0 {
+		children = append(children, "sinks "+getChildString(schemaName, sinks))
+	}
+	return NewPranaErrorf(MaterializedViewHasChildren, "Cannot replace materialized view %s.%s it has the following children %s", schemaName, materializedViewName, strings.Join(children, "; "))
+}
+
 func NewUnknownLoadRunnerfCommandError(commandName string) PranaError {
 	return NewPranaErrorf(UnknownPerfCommand, "Unknown perf runner command %s", commandName)
 }
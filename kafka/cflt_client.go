@@ -4,42 +4,237 @@
 package kafka
 
 import (
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/common"
 	"github.com/squareup/pranadb/errors"
 )
 
 // Kafka Message Provider implementation that uses the standard Confluent golang client
 
-func NewMessageProviderFactory(topicName string, props map[string]string, groupID string) MessageProviderFactory {
+const offsetsTimeoutMs = 10000
+
+func NewMessageProviderFactory(topics []string, props map[string]string, groupID string) MessageProviderFactory {
 	return &ConfluentMessageProviderFactory{
-		topicName: topicName,
-		props:     props,
-		groupID:   groupID,
+		topics:  topics,
+		props:   props,
+		groupID: groupID,
 	}
 }
 
 type ConfluentMessageProviderFactory struct {
-	topicName string
-	props     map[string]string
-	groupID   string
+	topics  []string
+	props   map[string]string
+	groupID string
 }
 
 func (cmpf *ConfluentMessageProviderFactory) NewMessageProvider() (MessageProvider, error) {
 	log.Info("Creating ConfluentMessageProviderFactory")
 	kmp := &ConfluentMessageProvider{}
 	kmp.krpf = cmpf
-	kmp.topicName = cmpf.topicName
 	return kmp, nil
 }
 
+func (cmpf *ConfluentMessageProviderFactory) GetOffsets(startFrom *common.StartFrom) (map[TopicPartition]int64, error) {
+	consumer, err := cmpf.newConsumer()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer closeConsumer(consumer)
+	topicsMetadata, err := cmpf.getTopicsMetadata(consumer)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	offsets := make(map[TopicPartition]int64)
+	var times []kafka.TopicPartition
+	for _, topicMetadata := range topicsMetadata {
+		topicName := topicMetadata.Topic
+		partIDs := make(map[int32]struct{}, len(topicMetadata.Partitions))
+		for _, partition := range topicMetadata.Partitions {
+			partIDs[partition.ID] = struct{}{}
+			tp := TopicPartition{Topic: topicName, PartitionID: partition.ID}
+			low, high, err := consumer.QueryWatermarkOffsets(topicName, partition.ID, offsetsTimeoutMs)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			switch startFrom.Kind {
+			case common.StartFromEarliest:
+				offsets[tp] = low
+			case common.StartFromLatest:
+				offsets[tp] = high
+			case common.StartFromTimestamp:
+				// If there are no messages at or after the timestamp we start from the end of the partition
+				offsets[tp] = high
+				times = append(times, kafka.TopicPartition{
+					Topic:     &topicMetadata.Topic,
+					Partition: partition.ID,
+					Offset:    kafka.Offset(startFrom.Timestamp.UnixNano() / int64(time.Millisecond)),
+				})
+			case common.StartFromOffsets:
+				offset, ok := startFrom.Offsets[partition.ID]
+				if !ok {
+					offset = low
+				}
+				offsets[tp] = offset
+			}
+		}
+		for partID := range startFrom.Offsets {
+			if _, ok := partIDs[partID]; !ok {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid startFrom, topic %s has no partition %d", topicName, partID)
+			}
+		}
+	}
+	if len(times) > 0 {
+		tps, err := consumer.OffsetsForTimes(times, offsetsTimeoutMs)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, tp := range tps {
+			if tp.Error != nil {
+				return nil, errors.WithStack(tp.Error)
+			}
+			if tp.Offset >= 0 {
+				offsets[TopicPartition{Topic: *tp.Topic, PartitionID: tp.Partition}] = int64(tp.Offset)
+			}
+		}
+	}
+	return offsets, nil
+}
+
+// getTopicsMetadata returns the metadata of the topics which are subscribed to
+func (cmpf *ConfluentMessageProviderFactory) getTopicsMetadata(consumer *kafka.Consumer) ([]kafka.TopicMetadata, error) {
+	var topicsMetadata []kafka.TopicMetadata
+	for _, topic := range cmpf.topics {
+		if isTopicPattern(topic) {
+			// We need the names of all the topics to find the ones which match
+			metadata, err := consumer.GetMetadata(nil, true, offsetsTimeoutMs)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			names := make([]string, 0, len(metadata.Topics))
+			for name := range metadata.Topics {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			names, err = matchTopics(cmpf.topics, names)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			for _, name := range names {
+				topicsMetadata = append(topicsMetadata, metadata.Topics[name])
+			}
+			return topicsMetadata, nil
+		}
+	}
+	for _, topic := range cmpf.topics {
+		topic := topic
+		metadata, err := consumer.GetMetadata(&topic, false, offsetsTimeoutMs)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		topicMetadata, ok := metadata.Topics[topic]
+		if !ok {
+			return nil, errors.Errorf("no such topic %s", topic)
+		}
+		if topicMetadata.Error.Code() != kafka.ErrNoError {
+			return nil, errors.WithStack(topicMetadata.Error)
+		}
+		topicsMetadata = append(topicsMetadata, topicMetadata)
+	}
+	return topicsMetadata, nil
+}
+
+func (cmpf *ConfluentMessageProviderFactory) SetOffsets(offsets map[TopicPartition]int64) error {
+	consumer, err := cmpf.newConsumer()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer closeConsumer(consumer)
+	// The consumer isn't subscribed, so this commits the offsets on behalf of the group
+	_, err = consumer.CommitOffsets(toKafkaTopicPartitions(offsets))
+	return errors.WithStack(err)
+}
+
+func (cmpf *ConfluentMessageProviderFactory) GetCommittedOffsets() (map[TopicPartition]int64, error) {
+	consumer, err := cmpf.newConsumer()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer closeConsumer(consumer)
+	topicsMetadata, err := cmpf.getTopicsMetadata(consumer)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var partitions []kafka.TopicPartition
+	for _, topicMetadata := range topicsMetadata {
+		for _, partition := range topicMetadata.Partitions {
+			topicName := topicMetadata.Topic
+			partitions = append(partitions, kafka.TopicPartition{Topic: &topicName, Partition: partition.ID})
+		}
+	}
+	if len(partitions) == 0 {
+		return map[TopicPartition]int64{}, nil
+	}
+	committed, err := consumer.Committed(partitions, offsetsTimeoutMs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	offsets := make(map[TopicPartition]int64, len(committed))
+	for _, tp := range committed {
+		if tp.Error != nil {
+			return nil, errors.WithStack(tp.Error)
+		}
+		// A negative offset means the group hasn't committed one for the partition
+		if tp.Offset >= 0 {
+			offsets[TopicPartition{Topic: *tp.Topic, PartitionID: tp.Partition}] = int64(tp.Offset)
+		}
+	}
+	return offsets, nil
+}
+
+func toKafkaTopicPartitions(offsets map[TopicPartition]int64) []kafka.TopicPartition {
+	tps := make([]kafka.TopicPartition, 0, len(offsets))
+	for tp, offset := range offsets {
+		topicName := tp.Topic
+		tps = append(tps, kafka.TopicPartition{
+			Topic:     &topicName,
+			Partition: tp.PartitionID,
+			Offset:    kafka.Offset(offset),
+		})
+	}
+	return tps
+}
+
+func (cmpf *ConfluentMessageProviderFactory) newConsumer() (*kafka.Consumer, error) {
+	cm := &kafka.ConfigMap{
+		"group.id":             cmpf.groupID,
+		"auto.offset.reset":    "earliest",
+		"enable.auto.commit":   false,
+		"session.timeout.ms":   60000,
+		"max.poll.interval.ms": 5 * 60 * 1000,
+	}
+	for k, v := range cmpf.props {
+		if err := cm.SetKey(k, v); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	consumer, err := kafka.NewConsumer(cm)
+	return consumer, errors.WithStack(err)
+}
+
+func closeConsumer(consumer *kafka.Consumer) {
+	if err := consumer.Close(); err != nil {
+		log.Errorf("failed to close consumer %+v", err)
+	}
+}
+
 type ConfluentMessageProvider struct {
 	lock        sync.Mutex
 	consumer    *kafka.Consumer
-	topicName   string
 	krpf        *ConfluentMessageProviderFactory
 	rebalanceCB RebalanceCallback
 }
@@ -84,6 +279,7 @@ func (cmp *ConfluentMessageProvider) GetMessage(pollTimeout time.Duration) (*Mes
 		}
 		m := &Message{
 			PartInfo: PartInfo{
+				Topic:       *msg.TopicPartition.Topic,
 				PartitionID: msg.TopicPartition.Partition,
 				Offset:      int64(msg.TopicPartition.Offset),
 			},
@@ -100,23 +296,13 @@ func (cmp *ConfluentMessageProvider) GetMessage(pollTimeout time.Duration) (*Mes
 	}
 }
 
-func (cmp *ConfluentMessageProvider) CommitOffsets(offsetsMap map[int32]int64) error {
+func (cmp *ConfluentMessageProvider) CommitOffsets(offsetsMap map[TopicPartition]int64) error {
 	cmp.lock.Lock()
 	defer cmp.lock.Unlock()
 	if cmp.consumer == nil {
 		return nil
 	}
-	offsets := make([]kafka.TopicPartition, len(offsetsMap))
-	i := 0
-	for partID, offset := range offsetsMap {
-		offsets[i] = kafka.TopicPartition{
-			Topic:     &cmp.topicName,
-			Partition: partID,
-			Offset:    kafka.Offset(offset),
-		}
-		i++
-	}
-	_, err := cmp.consumer.CommitOffsets(offsets)
+	_, err := cmp.consumer.CommitOffsets(toKafkaTopicPartitions(offsetsMap))
 	return errors.WithStack(err)
 }
 
@@ -136,25 +322,107 @@ func (cmp *ConfluentMessageProvider) Start() error {
 	cmp.lock.Lock()
 	defer cmp.lock.Unlock()
 
+	consumer, err := cmp.krpf.newConsumer()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Topics which match a pattern are picked up by the client as they're created
+	if err := consumer.SubscribeTopics(cmp.krpf.topics, cmp.RebalanceOccurred); err != nil {
+		return errors.WithStack(err)
+	}
+	cmp.consumer = consumer
+	return nil
+}
+
+// Kafka Message Producer implementation that uses the standard Confluent golang client
+
+func NewMessageProducer(topicName string, props map[string]string) MessageProducer {
+	return &ConfluentMessageProducer{
+		topicName: topicName,
+		props:     props,
+	}
+}
+
+type ConfluentMessageProducer struct {
+	lock      sync.Mutex
+	producer  *kafka.Producer
+	topicName string
+	props     map[string]string
+}
+
+var _ MessageProducer = &ConfluentMessageProducer{}
+
+func (cmp *ConfluentMessageProducer) SendMessages(messages []*Message) error {
+	cmp.lock.Lock()
+	producer := cmp.producer
+	cmp.lock.Unlock()
+	if producer == nil {
+		return errors.Error("producer is not started")
+	}
+	deliveryChan := make(chan kafka.Event, len(messages))
+	for _, msg := range messages {
+		headers := make([]kafka.Header, len(msg.Headers))
+		for i, hdr := range msg.Headers {
+			headers[i] = kafka.Header{
+				Key:   hdr.Key,
+				Value: hdr.Value,
+			}
+		}
+		kmsg := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{
+				Topic:     &cmp.topicName,
+				Partition: kafka.PartitionAny,
+			},
+			Key:       msg.Key,
+			Value:     msg.Value,
+			Headers:   headers,
+			Timestamp: msg.TimeStamp,
+		}
+		if err := producer.Produce(kmsg, deliveryChan); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for range messages {
+		ev := <-deliveryChan
+		kmsg, ok := ev.(*kafka.Message)
+		if !ok {
+			return errors.Errorf("unexpected delivery event %+v", ev)
+		}
+		if kmsg.TopicPartition.Error != nil {
+			return errors.WithStack(kmsg.TopicPartition.Error)
+		}
+	}
+	return nil
+}
+
+func (cmp *ConfluentMessageProducer) Start() error {
+	cmp.lock.Lock()
+	defer cmp.lock.Unlock()
+
 	cm := &kafka.ConfigMap{
-		"group.id":             cmp.krpf.groupID,
-		"auto.offset.reset":    "earliest",
-		"enable.auto.commit":   false,
-		"session.timeout.ms":   60000,
-		"max.poll.interval.ms": 5 * 60 * 1000,
+		"acks":               "all",
+		"enable.idempotence": true,
 	}
-	for k, v := range cmp.krpf.props {
+	for k, v := range cmp.props {
 		if err := cm.SetKey(k, v); err != nil {
 			return errors.WithStack(err)
 		}
 	}
-	consumer, err := kafka.NewConsumer(cm)
+	producer, err := kafka.NewProducer(cm)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	if err := consumer.Subscribe(cmp.krpf.topicName, cmp.RebalanceOccurred); err != nil {
-		return errors.WithStack(err)
+	cmp.producer = producer
+	return nil
+}
+
+func (cmp *ConfluentMessageProducer) Close() error {
+	cmp.lock.Lock()
+	defer cmp.lock.Unlock()
+	if cmp.producer == nil {
+		return nil
 	}
-	cmp.consumer = consumer
+	cmp.producer.Close()
+	cmp.producer = nil
 	return nil
 }
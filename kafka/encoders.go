@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -19,6 +20,37 @@ type MessageEncoder interface {
 	EncodeMessage(row *common.Row, colTypes []common.ColumnType, keyCols []int, timestamp time.Time) (*Message, error)
 }
 
+// NewMessageEncoder returns the encoder for the key and value encodings of a topic that rows are written to, e.g. by
+// a sink
+func NewMessageEncoder(keyEncoding common.KafkaEncoding, valueEncoding common.KafkaEncoding,
+	registry protolib.Resolver) (MessageEncoder, error) {
+	switch valueEncoding.Encoding {
+	case common.EncodingJSON:
+		switch keyEncoding.Encoding {
+		case common.EncodingJSON:
+			return &JSONKeyJSONValueEncoder{}, nil
+		case common.EncodingStringBytes:
+			return &StringKeyTLJSONValueEncoder{}, nil
+		case common.EncodingInt64BE:
+			return &Int64BEKeyTLJSONValueEncoder{}, nil
+		case common.EncodingInt32BE:
+			return &Int32BEKeyTLJSONValueEncoder{}, nil
+		case common.EncodingInt16BE:
+			return &Int16BEKeyTLJSONValueEncoder{}, nil
+		case common.EncodingFloat64BE:
+			return &Float64BEKeyTLJSONValueEncoder{}, nil
+		case common.EncodingFloat32BE:
+			return &Float32BEKeyTLJSONValueEncoder{}, nil
+		}
+	case common.EncodingProtobuf:
+		if keyEncoding.Encoding == common.EncodingStringBytes {
+			return NewStringKeyProtobufValueEncoder(registry, valueEncoding.SchemaName)
+		}
+	}
+	return nil, errors.NewPranaErrorf(errors.UnknownTopicEncoding, "Unsupported key encoding %s with value encoding %s",
+		keyEncoding.Encoding, valueEncoding.Encoding)
+}
+
 // JSONKeyJSONValueEncoder encodes as top level JSON key, top level JSON value, no headers
 type JSONKeyJSONValueEncoder struct {
 }
@@ -55,6 +87,32 @@ func (s *JSONKeyJSONValueEncoder) EncodeMessage(row *common.Row, colTypes []comm
 	return message, nil
 }
 
+// JSONKeyTombstoneEncoder encodes as top level JSON key with a null value (a tombstone), no headers
+type JSONKeyTombstoneEncoder struct {
+}
+
+func (s *JSONKeyTombstoneEncoder) Name() string {
+	return "JSONKeyTombstoneEncoder"
+}
+
+func (s *JSONKeyTombstoneEncoder) EncodeMessage(row *common.Row, colTypes []common.ColumnType, keyCols []int, timestamp time.Time) (*Message, error) {
+	keyMap := map[string]interface{}{}
+	for i, keyCol := range keyCols {
+		colType := colTypes[keyCol]
+		colVal := getColVal(keyCol, colType, row)
+		keyMap[fmt.Sprintf("k%d", i)] = colVal
+	}
+	keyBytes, err := json.Marshal(keyMap)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	message := &Message{
+		TimeStamp: timestamp,
+		Key:       keyBytes,
+	}
+	return message, nil
+}
+
 // StringKeyTLJSONValueEncoder encodes as string key, top level JSON value, no headers
 type StringKeyTLJSONValueEncoder struct {
 }
@@ -85,6 +143,90 @@ func (s *StringKeyTLJSONValueEncoder) EncodeMessage(row *common.Row, colTypes []
 	}, nil
 }
 
+// StringKeyCSVValueEncoder encodes as string key, comma separated value with the columns in order, no headers. Null
+// columns are empty, and columns containing a comma or a double quote are quoted.
+type StringKeyCSVValueEncoder struct {
+}
+
+func (s *StringKeyCSVValueEncoder) Name() string {
+	return "StringKeyCSVValueEncoder"
+}
+
+func (s *StringKeyCSVValueEncoder) EncodeMessage(row *common.Row, colTypes []common.ColumnType, keyCols []int, timestamp time.Time) (*Message, error) {
+	if len(keyCols) != 1 {
+		return nil, errors.Error("must be only one pk col for binary key encoding")
+	}
+	keyColIndex := keyCols[0]
+	keyColType := colTypes[keyColIndex]
+	if keyColType != common.VarcharColumnType {
+		return nil, errors.Error("Key is not a varchar column")
+	}
+	keyBytes := []byte(row.GetString(keyColIndex))
+
+	fields := make([]string, len(colTypes))
+	for i, colType := range colTypes {
+		colVal := getColVal(i, colType, row)
+		if colVal == nil {
+			continue
+		}
+		var field string
+		if b, ok := colVal.([]byte); ok {
+			// Bytes are written as they are
+			field = string(b)
+		} else {
+			field = fmt.Sprintf("%v", colVal)
+		}
+		if strings.ContainsAny(field, ",\"") {
+			field = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+		fields[i] = field
+	}
+
+	return &Message{
+		Key:   keyBytes,
+		Value: []byte(strings.Join(fields, ",")),
+	}, nil
+}
+
+// StringKeyRawValueEncoder encodes as string key, and the bytes of the one non key column, which must be varchar or
+// varbinary, as the value. Null values are written as tombstones.
+type StringKeyRawValueEncoder struct {
+}
+
+func (s *StringKeyRawValueEncoder) Name() string {
+	return "StringKeyRawValueEncoder"
+}
+
+func (s *StringKeyRawValueEncoder) EncodeMessage(row *common.Row, colTypes []common.ColumnType, keyCols []int, timestamp time.Time) (*Message, error) {
+	if len(keyCols) != 1 || len(colTypes) != 2 {
+		return nil, errors.Error("must be one pk col and one value col for raw value encoding")
+	}
+	keyColIndex := keyCols[0]
+	keyColType := colTypes[keyColIndex]
+	if keyColType != common.VarcharColumnType {
+		return nil, errors.Error("Key is not a varchar column")
+	}
+	keyBytes := []byte(row.GetString(keyColIndex))
+
+	valColIndex := 1 - keyColIndex
+	var valBytes []byte
+	if !row.IsNull(valColIndex) {
+		switch colTypes[valColIndex].Type {
+		case common.TypeVarchar:
+			valBytes = []byte(row.GetString(valColIndex))
+		case common.TypeVarbinary:
+			valBytes = row.GetBytes(valColIndex)
+		default:
+			return nil, errors.Error("Value is not a varchar or varbinary column")
+		}
+	}
+
+	return &Message{
+		Key:   keyBytes,
+		Value: valBytes,
+	}, nil
+}
+
 // Int64BEKeyTLJSONValueEncoder encodes as int64BE key, top level JSON value, no headers
 type Int64BEKeyTLJSONValueEncoder struct {
 }
@@ -427,6 +569,12 @@ func protoSet(msg *dynamicpb.Message, fd pref.FieldDescriptor, v interface{}) {
 		if fd.Kind() == pref.BytesKind {
 			v = []byte(t)
 		}
+	case []byte:
+		if fd.Kind() == pref.StringKind {
+			v = string(t)
+		}
+	case bool:
+		// A bool is set as it is, so it can only be encoded to a bool field
 	default:
 		panic(fmt.Sprintf("unknown type %s", reflect.TypeOf(v)))
 	}
@@ -1,6 +1,7 @@
 package kafka
 
 import (
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -136,10 +137,11 @@ type Partition struct {
 
 type MessageQueue chan *Message
 
-func (p *Partition) push(message *Message) {
+func (p *Partition) push(topicName string, message *Message) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	message.PartInfo = PartInfo{
+		Topic:       topicName,
 		PartitionID: p.id,
 		Offset:      int64(len(p.messages)),
 	}
@@ -160,7 +162,7 @@ func (t *Topic) push(message *Message) error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	t.partitions[part].push(message)
+	t.partitions[part].push(t.Name, message)
 	return nil
 }
 
@@ -182,6 +184,11 @@ func (t *Topic) calcPartition(message *Message) (int, error) {
 }
 
 func (t *Topic) CreateSubscriber(groupID string, rebalanceCB RebalanceCallback) (*Subscriber, error) {
+	group := t.getOrCreateGroup(groupID)
+	return group.createSubscriber(t, group, rebalanceCB)
+}
+
+func (t *Topic) getOrCreateGroup(groupID string) *Group {
 	group, ok := t.getGroup(groupID)
 	if !ok {
 		t.lock.Lock()
@@ -192,12 +199,69 @@ func (t *Topic) CreateSubscriber(groupID string, rebalanceCB RebalanceCallback)
 		}
 		t.lock.Unlock()
 	}
-	return group.createSubscriber(t, group, rebalanceCB)
+	return group
+}
+
+// offsetsAt returns the offset of the first message at or after the position on each partition
+func (t *Topic) offsetsAt(startFrom *common.StartFrom) (map[int32]int64, error) {
+	if startFrom.Kind == common.StartFromOffsets {
+		for partID := range startFrom.Offsets {
+			if int(partID) >= len(t.partitions) {
+				return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid startFrom, topic %s has no partition %d", t.Name, partID)
+			}
+		}
+	}
+	offsets := make(map[int32]int64, len(t.partitions))
+	for _, part := range t.partitions {
+		part.lock.Lock()
+		var offset int64
+		switch startFrom.Kind {
+		case common.StartFromEarliest:
+			offset = 0
+		case common.StartFromLatest:
+			offset = int64(len(part.messages))
+		case common.StartFromTimestamp:
+			offset = int64(len(part.messages))
+			for i, msg := range part.messages {
+				if !msg.TimeStamp.Before(startFrom.Timestamp) {
+					offset = int64(i)
+					break
+				}
+			}
+		case common.StartFromOffsets:
+			offset = startFrom.Offsets[part.id]
+		}
+		part.lock.Unlock()
+		offsets[part.id] = offset
+	}
+	return offsets, nil
 }
 
 func (t *Topic) close() {
 }
 
+// Messages returns all the messages in the topic, in partition order
+func (t *Topic) Messages() []*Message {
+	var messages []*Message
+	for _, part := range t.partitions {
+		part.lock.Lock()
+		messages = append(messages, part.messages...)
+		part.lock.Unlock()
+	}
+	return messages
+}
+
+// TotalMessages returns the number of messages in the topic
+func (t *Topic) TotalMessages() int {
+	total := 0
+	for _, part := range t.partitions {
+		part.lock.Lock()
+		total += len(part.messages)
+		part.lock.Unlock()
+	}
+	return total
+}
+
 type Group struct {
 	id              string
 	subscribersLock sync.Mutex
@@ -288,6 +352,13 @@ func (g *Group) commitOffsets(offsets map[int32]int64) error {
 	return nil
 }
 
+// setOffsets sets the offsets the group consumes from next, unlike commitOffsets they can go backwards
+func (g *Group) setOffsets(offsets map[int32]int64) {
+	for partID, offset := range offsets {
+		g.offsets.Store(partID, offset-1)
+	}
+}
+
 func (g *Group) getQuiesceChannel() chan *quiesceResponse {
 	g.qcl.Lock()
 	defer g.qcl.Unlock()
@@ -375,9 +446,7 @@ func (g *Group) rebalance() error {
 	if len(g.subscribers) == 0 {
 		return nil
 	}
-	if len(g.subscribers) > len(g.topic.partitions) {
-		return errors.Error("too many subscribers")
-	}
+	// As in Kafka, if there are more subscribers than partitions some of them aren't assigned any partitions
 	for _, subscriber := range g.subscribers {
 		subscriber.partitions = []*Partition{}
 	}
@@ -386,17 +455,7 @@ func (g *Group) rebalance() error {
 		subscriber.partitions = append(subscriber.partitions, part)
 	}
 	for _, subscriber := range g.subscribers {
-		for _, part := range subscriber.partitions {
-			o, ok := g.offsets.Load(part.id)
-			var offset int64
-			if ok {
-				offset = o.(int64) //nolint:forcetypeassert
-			} else {
-				offset = -1
-			}
-			subscriber.nextOffsets[part.id] = offset + 1
-		}
-		subscriber.msgBuffer = nil
+		subscriber.rewind()
 	}
 	return nil
 }
@@ -478,7 +537,7 @@ func (c *Subscriber) GetMessage(pollTimeout time.Duration) (*Message, error) {
 	}
 
 	start := time.Now()
-	for time.Now().Sub(start) < pollTimeout {
+	for {
 
 		if len(c.msgBuffer) == 0 {
 			for _, part := range c.partitions {
@@ -501,9 +560,26 @@ func (c *Subscriber) GetMessage(pollTimeout time.Duration) (*Message, error) {
 			c.msgBuffer = c.msgBuffer[1:]
 			return msg, nil
 		}
+		if time.Now().Sub(start) >= pollTimeout {
+			return nil, nil
+		}
 		time.Sleep(1 * time.Millisecond)
 	}
-	return nil, nil
+}
+
+// rewind makes the subscriber consume its partitions from the offsets committed by its group
+func (c *Subscriber) rewind() {
+	for _, part := range c.partitions {
+		o, ok := c.group.offsets.Load(part.id)
+		var offset int64
+		if ok {
+			offset = o.(int64) //nolint:forcetypeassert
+		} else {
+			offset = -1
+		}
+		c.nextOffsets[part.id] = offset + 1
+	}
+	c.msgBuffer = nil
 }
 
 func (c *Subscriber) Unsubscribe() error {
@@ -511,50 +587,131 @@ func (c *Subscriber) Unsubscribe() error {
 	return c.group.unsubscribe(c)
 }
 
-func NewFakeMessageProviderFactory(topicName string, props map[string]string, groupName string) (MessageProviderFactory, error) {
-	sFakeKafkaID, ok := props[FakeKafkaIDPropName]
-	if !ok {
-		return nil, errors.Error("no fakeKafkaID property in broker configuration")
-	}
-	fakeKafkaID, err := strconv.ParseInt(sFakeKafkaID, 10, 64)
+func NewFakeMessageProviderFactory(topics []string, props map[string]string, groupName string) (MessageProviderFactory, error) {
+	fk, err := getFakeKafkaFromProps(props)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	fk, ok := GetFakeKafka(fakeKafkaID)
-	if !ok {
-		return nil, errors.Errorf("cannot find fake kafka with id %d", fakeKafkaID)
-	}
 	return &FakeMessageProviderFactory{
-		fk:        fk,
-		topicName: topicName,
-		props:     props,
-		groupID:   groupName,
+		fk:      fk,
+		topics:  topics,
+		props:   props,
+		groupID: groupName,
 	}, nil
 }
 
 type FakeMessageProviderFactory struct {
-	fk        *FakeKafka
-	topicName string
-	props     map[string]string
-	groupID   string
+	fk      *FakeKafka
+	topics  []string
+	props   map[string]string
+	groupID string
 }
 
 func (fmpf *FakeMessageProviderFactory) NewMessageProvider() (MessageProvider, error) {
-	topic, ok := fmpf.fk.GetTopic(fmpf.topicName)
-	if !ok {
-		return nil, errors.Errorf("no such topic %s", fmpf.topicName)
+	if _, err := fmpf.getTopics(); err != nil {
+		return nil, errors.WithStack(err)
 	}
 	return &FakeMessageProvider{
-		topic:   topic,
+		fmpf:    fmpf,
 		groupID: fmpf.groupID,
 	}, nil
 }
 
+// getTopics returns the topics which are subscribed to, in name order
+func (fmpf *FakeMessageProviderFactory) getTopics() ([]*Topic, error) {
+	for _, topicName := range fmpf.topics {
+		if isTopicPattern(topicName) {
+			continue
+		}
+		if _, ok := fmpf.fk.GetTopic(topicName); !ok {
+			return nil, errors.Errorf("no such topic %s", topicName)
+		}
+	}
+	names := fmpf.fk.GetTopicNames()
+	sort.Strings(names)
+	names, err := matchTopics(fmpf.topics, names)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	topics := make([]*Topic, 0, len(names))
+	for _, name := range names {
+		// The topic might have been deleted since we got the names
+		if topic, ok := fmpf.fk.GetTopic(name); ok {
+			topics = append(topics, topic)
+		}
+	}
+	return topics, nil
+}
+
+func (fmpf *FakeMessageProviderFactory) GetOffsets(startFrom *common.StartFrom) (map[TopicPartition]int64, error) {
+	topics, err := fmpf.getTopics()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	offsets := make(map[TopicPartition]int64)
+	for _, topic := range topics {
+		topicOffsets, err := topic.offsetsAt(startFrom)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for partID, offset := range topicOffsets {
+			offsets[TopicPartition{Topic: topic.Name, PartitionID: partID}] = offset
+		}
+	}
+	return offsets, nil
+}
+
+func (fmpf *FakeMessageProviderFactory) SetOffsets(offsets map[TopicPartition]int64) error {
+	for topicName, topicOffsets := range offsetsByTopic(offsets) {
+		topic, ok := fmpf.fk.GetTopic(topicName)
+		if !ok {
+			return errors.Errorf("no such topic %s", topicName)
+		}
+		topic.getOrCreateGroup(fmpf.groupID).setOffsets(topicOffsets)
+	}
+	return nil
+}
+
+func (fmpf *FakeMessageProviderFactory) GetCommittedOffsets() (map[TopicPartition]int64, error) {
+	topics, err := fmpf.getTopics()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	offsets := make(map[TopicPartition]int64)
+	for _, topic := range topics {
+		group, ok := topic.getGroup(fmpf.groupID)
+		if !ok {
+			continue
+		}
+		for partID, offset := range group.getOffsets() {
+			// The group keeps the last offset committed, rather than the next one to consume
+			offsets[TopicPartition{Topic: topic.Name, PartitionID: partID}] = offset + 1
+		}
+	}
+	return offsets, nil
+}
+
+func offsetsByTopic(offsets map[TopicPartition]int64) map[string]map[int32]int64 {
+	byTopic := make(map[string]map[int32]int64)
+	for tp, offset := range offsets {
+		topicOffsets, ok := byTopic[tp.Topic]
+		if !ok {
+			topicOffsets = make(map[int32]int64)
+			byTopic[tp.Topic] = topicOffsets
+		}
+		topicOffsets[tp.PartitionID] = offset
+	}
+	return byTopic
+}
+
+// FakeMessageProvider consumes from each of its topics with a separate subscriber
 type FakeMessageProvider struct {
-	subscriber  *Subscriber
-	topic       *Topic
+	fmpf        *FakeMessageProviderFactory
+	subscribers []*Subscriber
+	nextSub     int
 	groupID     string
 	lock        sync.Mutex
+	started     bool
 	rebalanceCB RebalanceCallback
 }
 
@@ -565,37 +722,170 @@ func (f *FakeMessageProvider) SetRebalanceCallback(callback RebalanceCallback) {
 func (f *FakeMessageProvider) GetMessage(pollTimeout time.Duration) (*Message, error) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
-	if f.subscriber == nil {
+	if !f.started {
 		// This is ok, we must start the message consumer before the we start the message provider
-		// so there is a window where the subscriber is not set.
+		// so there is a window where the subscribers are not set.
 		return nil, nil
 	}
-	return f.subscriber.GetMessage(pollTimeout)
+	// Topics which match a pattern can be created at any time
+	if err := f.subscribe(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	start := time.Now()
+	for {
+		// We start from the subscriber after the one that returned the last message, so a busy topic doesn't starve
+		// the others
+		for i := 0; i < len(f.subscribers); i++ {
+			index := (f.nextSub + i) % len(f.subscribers)
+			msg, err := f.subscribers[index].GetMessage(0)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if msg != nil {
+				f.nextSub = index + 1
+				return msg, nil
+			}
+		}
+		if time.Now().Sub(start) >= pollTimeout {
+			return nil, nil
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
 }
 
-func (f *FakeMessageProvider) CommitOffsets(offsets map[int32]int64) error {
-	if f.subscriber == nil {
+// subscribe creates a subscriber for each topic which isn't subscribed to yet
+func (f *FakeMessageProvider) subscribe() error {
+	topics, err := f.fmpf.getTopics()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, topic := range topics {
+		if f.getSubscriber(topic.Name) != nil {
+			continue
+		}
+		subscriber, err := topic.CreateSubscriber(f.groupID, f.rebalanceCallback(topic.Name))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		f.subscribers = append(f.subscribers, subscriber)
+	}
+	return nil
+}
+
+func (f *FakeMessageProvider) getSubscriber(topicName string) *Subscriber {
+	for _, subscriber := range f.subscribers {
+		if subscriber.topic.Name == topicName {
+			return subscriber
+		}
+	}
+	return nil
+}
+
+// rebalanceCallback returns the callback for the subscriber of the topic. The consumer drops all of the messages it
+// hasn't committed when a rebalance occurs, not just those of the topic being rebalanced, so the subscribers of the
+// other topics are rewound to the offsets their groups committed.
+func (f *FakeMessageProvider) rebalanceCallback(topicName string) RebalanceCallback {
+	return func() error {
+		for _, subscriber := range f.subscribers {
+			if subscriber.topic.Name != topicName {
+				subscriber.rewind()
+			}
+		}
+		if f.rebalanceCB != nil {
+			return f.rebalanceCB()
+		}
+		return nil
+	}
+}
+
+func (f *FakeMessageProvider) CommitOffsets(offsets map[TopicPartition]int64) error {
+	if !f.started {
 		return errors.Error("not started")
 	}
-	return f.subscriber.commitOffsets(offsets)
+	for topicName, topicOffsets := range offsetsByTopic(offsets) {
+		subscriber := f.getSubscriber(topicName)
+		if subscriber == nil {
+			return errors.Errorf("not subscribed to topic %s", topicName)
+		}
+		if err := subscriber.commitOffsets(topicOffsets); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
 }
 
 func (f *FakeMessageProvider) Start() error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
-	subscriber, err := f.topic.CreateSubscriber(f.groupID, f.rebalanceCB)
-	if err != nil {
+	if err := f.subscribe(); err != nil {
 		return errors.WithStack(err)
 	}
-	f.subscriber = subscriber
+	f.started = true
 	return nil
 }
 
 func (f *FakeMessageProvider) Stop() error {
-	f.subscriber.stopped.Set(true)
+	for _, subscriber := range f.subscribers {
+		subscriber.stopped.Set(true)
+	}
 	return nil
 }
 
 func (f *FakeMessageProvider) Close() error {
-	return f.subscriber.Unsubscribe()
+	for _, subscriber := range f.subscribers {
+		if err := subscriber.Unsubscribe(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func getFakeKafkaFromProps(props map[string]string) (*FakeKafka, error) {
+	sFakeKafkaID, ok := props[FakeKafkaIDPropName]
+	if !ok {
+		return nil, errors.Error("no fakeKafkaID property in broker configuration")
+	}
+	fakeKafkaID, err := strconv.ParseInt(sFakeKafkaID, 10, 64)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	fk, ok := GetFakeKafka(fakeKafkaID)
+	if !ok {
+		return nil, errors.Errorf("cannot find fake kafka with id %d", fakeKafkaID)
+	}
+	return fk, nil
+}
+
+func NewFakeMessageProducer(topicName string, props map[string]string) (MessageProducer, error) {
+	fk, err := getFakeKafkaFromProps(props)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &FakeMessageProducer{
+		fk:        fk,
+		topicName: topicName,
+	}, nil
+}
+
+// FakeMessageProducer sends messages to a topic in a FakeKafka
+type FakeMessageProducer struct {
+	fk        *FakeKafka
+	topicName string
+}
+
+func (f *FakeMessageProducer) SendMessages(messages []*Message) error {
+	for _, msg := range messages {
+		if err := f.fk.IngestMessage(f.topicName, msg); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (f *FakeMessageProducer) Start() error {
+	return nil
+}
+
+func (f *FakeMessageProducer) Close() error {
+	return nil
 }
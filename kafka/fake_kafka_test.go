@@ -8,6 +8,7 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/common"
 	"github.com/squareup/pranadb/common/commontest"
 	"github.com/stretchr/testify/require"
 )
@@ -139,6 +140,153 @@ func TestIngestConsumeTwoSubscribersOneGroup(t *testing.T) {
 	}
 }
 
+func TestGetAndSetOffsets(t *testing.T) {
+	fk := NewFakeKafka()
+	topic, err := fk.CreateTopic("topic1", 1)
+	require.NoError(t, err)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		msg := &Message{
+			TimeStamp: start.Add(time.Duration(i) * time.Second),
+			Key:       []byte(fmt.Sprintf("key-%d", i)),
+			Value:     []byte(fmt.Sprintf("value-%d", i)),
+		}
+		err := fk.IngestMessage(topic.Name, msg)
+		require.NoError(t, err)
+	}
+	mpf, err := NewFakeMessageProviderFactory([]string{topic.Name}, map[string]string{FakeKafkaIDPropName: fmt.Sprintf("%d", fk.ID)}, "group1")
+	require.NoError(t, err)
+
+	offsets, err := mpf.GetOffsets(&common.StartFrom{Kind: common.StartFromEarliest})
+	require.NoError(t, err)
+	tp := TopicPartition{Topic: topic.Name, PartitionID: 0}
+	require.Equal(t, map[TopicPartition]int64{tp: 0}, offsets)
+	offsets, err = mpf.GetOffsets(&common.StartFrom{Kind: common.StartFromLatest})
+	require.NoError(t, err)
+	require.Equal(t, map[TopicPartition]int64{tp: 10}, offsets)
+	offsets, err = mpf.GetOffsets(&common.StartFrom{Kind: common.StartFromTimestamp, Timestamp: start.Add(3500 * time.Millisecond)})
+	require.NoError(t, err)
+	require.Equal(t, map[TopicPartition]int64{tp: 4}, offsets)
+	offsets, err = mpf.GetOffsets(&common.StartFrom{Kind: common.StartFromTimestamp, Timestamp: start.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, map[TopicPartition]int64{tp: 10}, offsets)
+	_, err = mpf.GetOffsets(&common.StartFrom{Kind: common.StartFromOffsets, Offsets: map[int32]int64{1: 3}})
+	require.Error(t, err)
+
+	offsets, err = mpf.GetCommittedOffsets()
+	require.NoError(t, err)
+	require.Equal(t, 0, len(offsets))
+
+	// Consume and commit all the messages, then move the group back
+	sub, err := topic.CreateSubscriber("group1", nil)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		msg, err := sub.GetMessage(5 * time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, msg)
+	}
+	err = sub.commitOffsets(map[int32]int64{0: 10})
+	require.NoError(t, err)
+	offsets, err = mpf.GetCommittedOffsets()
+	require.NoError(t, err)
+	require.Equal(t, map[TopicPartition]int64{tp: 10}, offsets)
+	err = sub.Unsubscribe()
+	require.NoError(t, err)
+
+	err = mpf.SetOffsets(map[TopicPartition]int64{tp: 7})
+	require.NoError(t, err)
+	offsets, err = mpf.GetCommittedOffsets()
+	require.NoError(t, err)
+	require.Equal(t, map[TopicPartition]int64{tp: 7}, offsets)
+	sub, err = topic.CreateSubscriber("group1", nil)
+	require.NoError(t, err)
+	msg, err := sub.GetMessage(5 * time.Second)
+	require.NoError(t, err)
+	require.NotNil(t, msg)
+	require.Equal(t, "key-7", string(msg.Key))
+}
+
+func TestConsumeMultipleTopics(t *testing.T) {
+	fk := NewFakeKafka()
+	_, err := fk.CreateTopic("payments.us", 2)
+	require.NoError(t, err)
+	_, err = fk.CreateTopic("payments.eu", 3)
+	require.NoError(t, err)
+	_, err = fk.CreateTopic("refunds.us", 1)
+	require.NoError(t, err)
+	sendMessages(t, fk, 10, "payments.us")
+	sendMessages(t, fk, 10, "payments.eu")
+	sendMessages(t, fk, 10, "refunds.us")
+
+	props := map[string]string{FakeKafkaIDPropName: fmt.Sprintf("%d", fk.ID)}
+	mpf, err := NewFakeMessageProviderFactory([]string{TopicPattern(`payments\..*`)}, props, "group1")
+	require.NoError(t, err)
+	mp, err := mpf.NewMessageProvider()
+	require.NoError(t, err)
+	err = mp.Start()
+	require.NoError(t, err)
+	received := receiveMessages(t, mp, 20)
+	require.Equal(t, 10, received["payments.us"])
+	require.Equal(t, 10, received["payments.eu"])
+
+	// A topic which is created later is consumed too
+	_, err = fk.CreateTopic("payments.ap", 1)
+	require.NoError(t, err)
+	sendMessages(t, fk, 5, "payments.ap")
+	received = receiveMessages(t, mp, 5)
+	require.Equal(t, 5, received["payments.ap"])
+
+	err = mp.CommitOffsets(map[TopicPartition]int64{{Topic: "payments.ap", PartitionID: 0}: 5})
+	require.NoError(t, err)
+	err = mp.Stop()
+	require.NoError(t, err)
+	err = mp.Close()
+	require.NoError(t, err)
+
+	offsets, err := mpf.GetOffsets(&common.StartFrom{Kind: common.StartFromLatest})
+	require.NoError(t, err)
+	require.Equal(t, 6, len(offsets))
+	require.Equal(t, int64(5), offsets[TopicPartition{Topic: "payments.ap", PartitionID: 0}])
+	_, ok := offsets[TopicPartition{Topic: "refunds.us", PartitionID: 0}]
+	require.False(t, ok)
+
+	// A list of topics
+	mpf, err = NewFakeMessageProviderFactory([]string{"payments.us", "refunds.us"}, props, "group2")
+	require.NoError(t, err)
+	mp, err = mpf.NewMessageProvider()
+	require.NoError(t, err)
+	err = mp.Start()
+	require.NoError(t, err)
+	received = receiveMessages(t, mp, 20)
+	require.Equal(t, 10, received["payments.us"])
+	require.Equal(t, 10, received["refunds.us"])
+	err = mp.Stop()
+	require.NoError(t, err)
+	err = mp.Close()
+	require.NoError(t, err)
+
+	mpf, err = NewFakeMessageProviderFactory([]string{"payments.us", "payments.other"}, props, "group3")
+	require.NoError(t, err)
+	_, err = mpf.NewMessageProvider()
+	require.Error(t, err)
+}
+
+// receiveMessages gets the number of messages from the provider, and returns how many were received from each topic
+func receiveMessages(t *testing.T, mp MessageProvider, numMessages int) map[string]int {
+	t.Helper()
+	received := map[string]int{}
+	for i := 0; i < numMessages; i++ {
+		msg, err := mp.GetMessage(5 * time.Second)
+		require.NoError(t, err)
+		require.NotNil(t, msg)
+		received[msg.PartInfo.Topic]++
+	}
+	msg, err := mp.GetMessage(10 * time.Millisecond)
+	require.NoError(t, err)
+	require.Nil(t, msg)
+	return received
+}
+
 func newConsumer(groupID string, topic *Topic, msgCounter *int64, maxMessages int64) *consumer {
 	return &consumer{
 		groupID:     groupID,
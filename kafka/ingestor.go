@@ -15,15 +15,28 @@ var timestampBase = time.Date(2021, time.Month(4), 12, 9, 0, 0, 0, time.UTC)
 // IngestRows ingests rows given schema and source name - convenience method for use in tests
 func IngestRows(f *FakeKafka, sourceInfo *common.SourceInfo, colTypes []common.ColumnType, rows *common.Rows, encoder MessageEncoder) error {
 	topicName := sourceInfo.TopicInfo.TopicName
+	if topicName == "" {
+		return errors.Errorf("source %s consumes from more than one topic, so the topic must be given", sourceInfo.Name)
+	}
+	return IngestRowsToTopic(f, topicName, sourceInfo, colTypes, rows, encoder)
+}
+
+// IngestRowsToTopic ingests rows into one of the topics of the source - convenience method for use in tests
+func IngestRowsToTopic(f *FakeKafka, topicName string, sourceInfo *common.SourceInfo, colTypes []common.ColumnType, rows *common.Rows, encoder MessageEncoder) error {
 	topic, ok := f.GetTopic(topicName)
 	if !ok {
 		return errors.Errorf("cannot find topic %s", topicName)
 	}
+	keyCols := sourceInfo.PrimaryKeyCols
+	if sourceInfo.TopicInfo.Semantics == common.SourceSemanticsAppend {
+		// The key of an append source is generated on ingest, so it isn't in the message
+		keyCols = nil
+	}
 	timestamp := timestampBase
 	for i := 0; i < rows.RowCount(); i++ {
 		row := rows.GetRow(i)
 		// We give each
-		if err := IngestRow(topic, &row, colTypes, sourceInfo.PrimaryKeyCols, encoder, timestamp); err != nil {
+		if err := IngestRow(topic, &row, colTypes, keyCols, encoder, timestamp); err != nil {
 			return errors.WithStack(err)
 		}
 		timestamp = timestamp.Add(1 * time.Microsecond)
@@ -49,10 +62,15 @@ func getColVal(colIndex int, colType common.ColumnType, row *common.Row) interfa
 	switch colType.Type {
 	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
 		colVal = row.GetInt64(colIndex)
+	case common.TypeBoolean:
+		colVal = row.GetBool(colIndex)
 	case common.TypeDouble:
 		colVal = row.GetFloat64(colIndex)
 	case common.TypeVarchar:
 		colVal = row.GetString(colIndex)
+	case common.TypeVarbinary:
+		// JSON encodes bytes as base64, protobuf as bytes
+		colVal = row.GetBytes(colIndex)
 	case common.TypeDecimal:
 		dec := row.GetDecimal(colIndex)
 		colVal = dec.String()
@@ -1,22 +1,48 @@
 package kafka
 
-import "time"
+import (
+	"regexp"
+	"strings"
+	"time"
 
-type ClientFactory func(topicName string, props map[string]string, groupID string) MessageProviderFactory
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+// ClientFactory creates a MessageProviderFactory which consumes from the topics. As with librdkafka, a topic which
+// starts with "^" is a regular expression, and every topic whose name matches it is consumed, including topics which
+// are created later.
+type ClientFactory func(topics []string, props map[string]string, groupID string) MessageProviderFactory
 
 type MessageProviderFactory interface {
 	NewMessageProvider() (MessageProvider, error)
+	// GetOffsets returns the offset of the first message at or after the position on each partition of the topics
+	GetOffsets(startFrom *common.StartFrom) (map[TopicPartition]int64, error)
+	// SetOffsets sets the offsets the consumer group consumes from next. It must only be called when there are no
+	// consumers in the group.
+	SetOffsets(offsets map[TopicPartition]int64) error
+	// GetCommittedOffsets returns the offsets the consumer group consumes from next on each partition of the topics.
+	// Partitions which the group hasn't committed an offset for are left out.
+	GetCommittedOffsets() (map[TopicPartition]int64, error)
 }
 
 type MessageProvider interface {
 	GetMessage(pollTimeout time.Duration) (*Message, error)
-	CommitOffsets(offsets map[int32]int64) error
+	CommitOffsets(offsets map[TopicPartition]int64) error
 	Stop() error
 	Start() error
 	Close() error
 	SetRebalanceCallback(callback RebalanceCallback)
 }
 
+// MessageProducer sends messages to a topic
+type MessageProducer interface {
+	// SendMessages sends the messages and waits until they have all been delivered
+	SendMessages(messages []*Message) error
+	Start() error
+	Close() error
+}
+
 type Message struct {
 	PartInfo  PartInfo
 	TimeStamp time.Time
@@ -33,6 +59,54 @@ type MessageHeader struct {
 }
 
 type PartInfo struct {
+	Topic       string
 	PartitionID int32
 	Offset      int64
 }
+
+// TopicPartition identifies a partition of a topic
+type TopicPartition struct {
+	Topic       string
+	PartitionID int32
+}
+
+// TopicPattern returns the topic to subscribe to, to consume from every topic whose whole name matches the regular
+// expression
+func TopicPattern(pattern string) string {
+	return "^(" + pattern + ")$"
+}
+
+func isTopicPattern(topic string) bool {
+	return strings.HasPrefix(topic, "^")
+}
+
+// matchTopics returns the topic names which are subscribed to by the topics, in the order of the names
+func matchTopics(topics []string, names []string) ([]string, error) {
+	var patterns []*regexp.Regexp
+	subscribed := make(map[string]struct{}, len(topics))
+	for _, topic := range topics {
+		if isTopicPattern(topic) {
+			re, err := regexp.Compile(topic)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			patterns = append(patterns, re)
+		} else {
+			subscribed[topic] = struct{}{}
+		}
+	}
+	var matched []string
+	for _, name := range names {
+		if _, ok := subscribed[name]; ok {
+			matched = append(matched, name)
+			continue
+		}
+		for _, re := range patterns {
+			if re.MatchString(name) {
+				matched = append(matched, name)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/squareup/pranadb/common"
 	"github.com/squareup/pranadb/errors"
 )
 
@@ -25,33 +26,48 @@ import (
 // DO NOT USE this client in production. We leave it here for use during development as it's easier to build on newer
 // Macbooks than the Confluent client.
 
-func NewMessageProviderFactory(topicName string, props map[string]string, groupID string) MessageProviderFactory {
+func NewMessageProviderFactory(topics []string, props map[string]string, groupID string) MessageProviderFactory {
 	return &SegmentMessageProviderFactory{
-		topicName: topicName,
-		props:     props,
-		groupID:   groupID,
+		topics:  topics,
+		props:   props,
+		groupID: groupID,
 	}
 }
 
 type SegmentMessageProviderFactory struct {
-	topicName string
-	props     map[string]string
-	groupID   string
+	topics  []string
+	props   map[string]string
+	groupID string
 }
 
 func (smpf *SegmentMessageProviderFactory) NewMessageProvider() (MessageProvider, error) {
 	log.Info("Creating SegmentMessageProviderFactory")
+	for _, topic := range smpf.topics {
+		if isTopicPattern(topic) {
+			return nil, errors.Error("topic patterns are not supported by the SegmentIO client")
+		}
+	}
 	mp := &SegmentKafkaMessageProvider{}
 	mp.krpf = smpf
-	mp.topicName = smpf.topicName
 	return mp, nil
 }
 
+func (smpf *SegmentMessageProviderFactory) GetOffsets(startFrom *common.StartFrom) (map[TopicPartition]int64, error) {
+	return nil, errors.Error("resetting offsets is not supported by the SegmentIO client")
+}
+
+func (smpf *SegmentMessageProviderFactory) SetOffsets(offsets map[TopicPartition]int64) error {
+	return errors.Error("resetting offsets is not supported by the SegmentIO client")
+}
+
+func (smpf *SegmentMessageProviderFactory) GetCommittedOffsets() (map[TopicPartition]int64, error) {
+	return nil, errors.Error("getting committed offsets is not supported by the SegmentIO client")
+}
+
 type SegmentKafkaMessageProvider struct {
-	lock      sync.Mutex
-	reader    *kafka.Reader
-	topicName string
-	krpf      *SegmentMessageProviderFactory
+	lock   sync.Mutex
+	reader *kafka.Reader
+	krpf   *SegmentMessageProviderFactory
 }
 
 var _ MessageProvider = &SegmentKafkaMessageProvider{}
@@ -86,6 +102,7 @@ func (smp *SegmentKafkaMessageProvider) GetMessage(pollTimeout time.Duration) (*
 	}
 	m := &Message{
 		PartInfo: PartInfo{
+			Topic:       msg.Topic,
 			PartitionID: int32(msg.Partition),
 			Offset:      msg.Offset,
 		},
@@ -97,17 +114,17 @@ func (smp *SegmentKafkaMessageProvider) GetMessage(pollTimeout time.Duration) (*
 	return m, nil
 }
 
-func (smp *SegmentKafkaMessageProvider) CommitOffsets(offsets map[int32]int64) error {
+func (smp *SegmentKafkaMessageProvider) CommitOffsets(offsets map[TopicPartition]int64) error {
 	smp.lock.Lock()
 	defer smp.lock.Unlock()
 	if smp.reader == nil {
 		return nil
 	}
 	kmsgs := make([]kafka.Message, 0, len(offsets))
-	for partition, offset := range offsets {
+	for tp, offset := range offsets {
 		kmsgs = append(kmsgs, kafka.Message{
-			Topic:     smp.topicName,
-			Partition: int(partition),
+			Topic:     tp.Topic,
+			Partition: int(tp.PartitionID),
 			// The offset passed to commit is 1 higher than the offset of the original message.
 			Offset: offset - 1,
 		})
@@ -134,9 +151,13 @@ func (smp *SegmentKafkaMessageProvider) Start() error {
 
 	cfg := &kafka.ReaderConfig{
 		GroupID:     smp.krpf.groupID,
-		Topic:       smp.krpf.topicName,
 		StartOffset: kafka.FirstOffset,
 	}
+	if len(smp.krpf.topics) == 1 {
+		cfg.Topic = smp.krpf.topics[0]
+	} else {
+		cfg.GroupTopics = smp.krpf.topics
+	}
 	for k, v := range smp.krpf.props {
 		if err := setProperty(cfg, k, v); err != nil {
 			return errors.WithStack(err)
@@ -147,6 +168,82 @@ func (smp *SegmentKafkaMessageProvider) Start() error {
 	return nil
 }
 
+// Kafka Message Producer implementation that uses the SegmentIO golang client
+
+func NewMessageProducer(topicName string, props map[string]string) MessageProducer {
+	return &SegmentKafkaMessageProducer{
+		topicName: topicName,
+		props:     props,
+	}
+}
+
+type SegmentKafkaMessageProducer struct {
+	lock      sync.Mutex
+	writer    *kafka.Writer
+	topicName string
+	props     map[string]string
+}
+
+var _ MessageProducer = &SegmentKafkaMessageProducer{}
+
+func (smp *SegmentKafkaMessageProducer) SendMessages(messages []*Message) error {
+	smp.lock.Lock()
+	writer := smp.writer
+	smp.lock.Unlock()
+	if writer == nil {
+		return errors.Error("producer is not started")
+	}
+	kmsgs := make([]kafka.Message, len(messages))
+	for i, msg := range messages {
+		headers := make([]kafka.Header, len(msg.Headers))
+		for j, hdr := range msg.Headers {
+			headers[j] = kafka.Header{
+				Key:   hdr.Key,
+				Value: hdr.Value,
+			}
+		}
+		kmsgs[i] = kafka.Message{
+			Key:     msg.Key,
+			Value:   msg.Value,
+			Headers: headers,
+			Time:    msg.TimeStamp,
+		}
+	}
+	return errors.WithStack(writer.WriteMessages(context.Background(), kmsgs...))
+}
+
+func (smp *SegmentKafkaMessageProducer) Start() error {
+	smp.lock.Lock()
+	defer smp.lock.Unlock()
+
+	writer := &kafka.Writer{
+		Topic:        smp.topicName,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	for k, v := range smp.props {
+		switch k {
+		case "bootstrap.servers":
+			writer.Addr = kafka.TCP(strings.Split(v, ",")...)
+		default:
+			return errors.NewInvalidConfigurationError(fmt.Sprintf("unsupported segmentio/kafka-go client option: %s", v))
+		}
+	}
+	smp.writer = writer
+	return nil
+}
+
+func (smp *SegmentKafkaMessageProducer) Close() error {
+	smp.lock.Lock()
+	defer smp.lock.Unlock()
+	if smp.writer == nil {
+		return nil
+	}
+	err := smp.writer.Close()
+	smp.writer = nil
+	return errors.WithStack(err)
+}
+
 func setProperty(cfg *kafka.ReaderConfig, k, v string) error {
 	switch k {
 	case "bootstrap.servers":
@@ -13,6 +13,8 @@ const (
 	TableKindSource           = "source"
 	TableKindMaterializedView = "materialized_view"
 	TableKindInternal         = "internal"
+	TableKindSink             = "sink"
+	TableKindUserTable        = "user_table"
 )
 
 // EncodeIndexInfoToRow encodes a common.IndexInfo into a database row.
@@ -57,6 +59,28 @@ func DecodeSourceInfoRow(row *common.Row) *common.SourceInfo {
 	return &info
 }
 
+// EncodeUserTableInfoToRow encodes a common.UserTableInfo into a database row.
+func EncodeUserTableInfoToRow(info *common.UserTableInfo) *common.Row {
+	rows := tableInfoRowsFactory.NewRows(1)
+	rows.AppendInt64ToColumn(0, int64(info.TableInfo.ID))
+	rows.AppendStringToColumn(1, TableKindUserTable)
+	rows.AppendStringToColumn(2, info.SchemaName)
+	rows.AppendStringToColumn(3, info.Name)
+	rows.AppendStringToColumn(4, jsonEncode(info.TableInfo))
+	rows.AppendNullToColumn(5)
+	rows.AppendNullToColumn(6)
+	rows.AppendNullToColumn(7)
+	row := rows.GetRow(0)
+	return &row
+}
+
+// DecodeUserTableInfoRow decodes a database row into a common.UserTableInfo.
+func DecodeUserTableInfoRow(row *common.Row) *common.UserTableInfo {
+	info := common.UserTableInfo{}
+	jsonDecode(row.GetString(4), &info.TableInfo)
+	return &info
+}
+
 // EncodeMaterializedViewInfoToRow encodes a common.MaterializedViewInfo into a database row.
 func EncodeMaterializedViewInfoToRow(info *common.MaterializedViewInfo) *common.Row {
 	rows := tableInfoRowsFactory.NewRows(1)
@@ -105,6 +129,33 @@ func DecodeInternalTableInfoRow(row *common.Row) *common.InternalTableInfo {
 	return &info
 }
 
+// EncodeSinkInfoToRow encodes a common.SinkInfo into a database row.
+func EncodeSinkInfoToRow(info *common.SinkInfo) *common.Row {
+	rows := tableInfoRowsFactory.NewRows(1)
+	rows.AppendInt64ToColumn(0, int64(info.ID))
+	rows.AppendStringToColumn(1, TableKindSink)
+	rows.AppendStringToColumn(2, info.SchemaName)
+	rows.AppendStringToColumn(3, info.Name)
+	rows.AppendNullToColumn(4)
+	rows.AppendStringToColumn(5, jsonEncode(info.TopicInfo))
+	rows.AppendNullToColumn(6)
+	rows.AppendStringToColumn(7, info.MaterializedViewName)
+	row := rows.GetRow(0)
+	return &row
+}
+
+// DecodeSinkInfoRow decodes a database row into a common.SinkInfo.
+func DecodeSinkInfoRow(row *common.Row) *common.SinkInfo {
+	info := common.SinkInfo{
+		ID:                   uint64(row.GetInt64(0)),
+		SchemaName:           row.GetString(2),
+		Name:                 row.GetString(3),
+		MaterializedViewName: row.GetString(7),
+	}
+	jsonDecode(row.GetString(5), &info.TopicInfo)
+	return &info
+}
+
 func jsonEncode(v interface{}) string {
 	s, err := json.Marshal(v)
 	if err != nil {
@@ -19,6 +19,8 @@ const (
 	TableDefTableName = "tables"
 	IndexDefTableName = "indexes"
 	ProtobufTableName = "protos"
+	// DeadLetterTableName is the name of the table that holds the messages that sources failed to ingest.
+	DeadLetterTableName = "dead_letters"
 )
 
 // TableDefTableInfo is a static definition of the table schema for the table schema table.
@@ -68,6 +70,29 @@ var ProtobufTableInfo = &common.MetaTableInfo{TableInfo: &common.TableInfo{
 	},
 }}
 
+// DeadLetterTableInfo is a static definition of the table schema for the dead letters table. A row is keyed on the
+// source and the topic, partition and offset of the message, so a message which is redelivered replaces its row.
+var DeadLetterTableInfo = &common.MetaTableInfo{TableInfo: &common.TableInfo{
+	ID:             common.DeadLetterTableID,
+	SchemaName:     SystemSchemaName,
+	Name:           DeadLetterTableName,
+	PrimaryKeyCols: []int{0, 1, 2, 3},
+	ColumnNames: []string{"source_id", "topic_name", "partition_id", "message_offset", "schema_name", "source_name",
+		"message_key", "message_value", "error", "failed_at"},
+	ColumnTypes: []common.ColumnType{
+		common.BigIntColumnType,
+		common.VarcharColumnType,
+		common.BigIntColumnType,
+		common.BigIntColumnType,
+		common.VarcharColumnType,
+		common.VarcharColumnType,
+		common.VarcharColumnType,
+		common.VarcharColumnType,
+		common.VarcharColumnType,
+		common.NewTimestampColumnType(6),
+	},
+}}
+
 type Controller struct {
 	lock     sync.RWMutex
 	schemas  map[string]*common.Schema
@@ -141,6 +166,21 @@ func (c *Controller) GetSource(schemaName string, name string) (*common.SourceIn
 	return source, ok
 }
 
+func (c *Controller) GetUserTable(schemaName string, name string) (*common.UserTableInfo, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	schema, ok := c.schemas[schemaName]
+	if !ok {
+		return nil, false
+	}
+	tb, ok := schema.GetTable(name)
+	if !ok {
+		return nil, false
+	}
+	userTable, ok := tb.(*common.UserTableInfo)
+	return userTable, ok
+}
+
 func (c *Controller) GetIndex(schemaName string, tableName string, indexName string) (*common.IndexInfo, bool) {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -160,6 +200,16 @@ func (c *Controller) GetIndex(schemaName string, tableName string, indexName str
 	return index, ok
 }
 
+func (c *Controller) GetSink(schemaName string, sinkName string) (*common.SinkInfo, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	schema, ok := c.schemas[schemaName]
+	if !ok {
+		return nil, false
+	}
+	return schema.GetSink(sinkName)
+}
+
 func (c *Controller) GetSchemaNames() []string {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
@@ -272,6 +322,26 @@ func (c *Controller) RegisterSource(sourceInfo *common.SourceInfo) error {
 	return nil
 }
 
+// ReplaceSource replaces a registered source with an altered definition of it. The definition of a source is shared, so
+// it's replaced rather than changed in place. It does not persist it.
+func (c *Controller) ReplaceSource(sourceInfo *common.SourceInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	schema, ok := c.schemas[sourceInfo.SchemaName]
+	if !ok {
+		return errors.NewUnknownSourceError(sourceInfo.SchemaName, sourceInfo.Name)
+	}
+	tb, ok := schema.GetTable(sourceInfo.Name)
+	if !ok {
+		return errors.NewUnknownSourceError(sourceInfo.SchemaName, sourceInfo.Name)
+	}
+	if existing, ok := tb.(*common.SourceInfo); !ok || existing.ID != sourceInfo.ID {
+		return errors.NewUnknownSourceError(sourceInfo.SchemaName, sourceInfo.Name)
+	}
+	schema.PutTable(sourceInfo.Name, sourceInfo)
+	return nil
+}
+
 func (c *Controller) PersistSource(sourceInfo *common.SourceInfo) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -282,10 +352,62 @@ func (c *Controller) PersistSource(sourceInfo *common.SourceInfo) error {
 	return c.cluster.WriteBatch(wb)
 }
 
+// RegisterUserTable adds a user table to the metadata controller, making it active. It does not persist it
+func (c *Controller) RegisterUserTable(tableInfo *common.UserTableInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	log.Debugf("Registering table %s with id %d", tableInfo.Name, tableInfo.ID)
+	if err := c.checkTableID(tableInfo.ID); err != nil {
+		return errors.WithStack(err)
+	}
+	schema := c.getOrCreateSchema(tableInfo.SchemaName)
+	err := c.existsTable(schema, tableInfo.Name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	schema.PutTable(tableInfo.Name, tableInfo)
+	c.tableIDs[tableInfo.ID] = struct{}{}
+	return nil
+}
+
+func (c *Controller) PersistUserTable(tableInfo *common.UserTableInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	wb := cluster.NewWriteBatch(cluster.SystemSchemaShardID)
+	if err := table.Upsert(TableDefTableInfo.TableInfo, EncodeUserTableInfoToRow(tableInfo), wb); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.cluster.WriteBatch(wb)
+}
+
 func (c *Controller) PersistMaterializedView(mvInfo *common.MaterializedViewInfo, internalTables []*common.InternalTableInfo) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	wb := cluster.NewWriteBatch(cluster.SystemSchemaShardID)
+	if err := addMaterializedViewToBatch(mvInfo, internalTables, wb); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.cluster.WriteBatch(wb)
+}
+
+// PersistReplacedMaterializedView adds a materialized view to storage and deletes the one it replaces in the same
+// batch, so after a failure there is always exactly one materialized view with the name
+func (c *Controller) PersistReplacedMaterializedView(oldMVInfo *common.MaterializedViewInfo, oldInternalTables []*common.InternalTableInfo,
+	mvInfo *common.MaterializedViewInfo, internalTables []*common.InternalTableInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	wb := cluster.NewWriteBatch(cluster.SystemSchemaShardID)
+	addDeleteTableWithIDToBatch(oldMVInfo.ID, wb)
+	for _, it := range oldInternalTables {
+		addDeleteTableWithIDToBatch(it.ID, wb)
+	}
+	if err := addMaterializedViewToBatch(mvInfo, internalTables, wb); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.cluster.WriteBatch(wb)
+}
+
+func addMaterializedViewToBatch(mvInfo *common.MaterializedViewInfo, internalTables []*common.InternalTableInfo, wb *cluster.WriteBatch) error {
 	if err := table.Upsert(TableDefTableInfo.TableInfo, EncodeMaterializedViewInfoToRow(mvInfo), wb); err != nil {
 		return errors.WithStack(err)
 	}
@@ -294,9 +416,57 @@ func (c *Controller) PersistMaterializedView(mvInfo *common.MaterializedViewInfo
 			return errors.WithStack(err)
 		}
 	}
+	return nil
+}
+
+// RegisterSink adds a sink to the metadata controller, making it active. It does not persist it
+func (c *Controller) RegisterSink(sinkInfo *common.SinkInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	log.Debugf("Registering sink %s with id %d", sinkInfo.Name, sinkInfo.ID)
+	if err := c.checkTableID(sinkInfo.ID); err != nil {
+		return errors.WithStack(err)
+	}
+	schema := c.getOrCreateSchema(sinkInfo.SchemaName)
+	if _, ok := schema.GetSink(sinkInfo.Name); ok {
+		return errors.Errorf("sink with Name %s already exists in Schema %s", sinkInfo.Name, schema.Name)
+	}
+	schema.PutSink(sinkInfo.Name, sinkInfo)
+	c.tableIDs[sinkInfo.ID] = struct{}{}
+	return nil
+}
+
+func (c *Controller) PersistSink(sinkInfo *common.SinkInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	wb := cluster.NewWriteBatch(cluster.SystemSchemaShardID)
+	if err := table.Upsert(TableDefTableInfo.TableInfo, EncodeSinkInfoToRow(sinkInfo), wb); err != nil {
+		return errors.WithStack(err)
+	}
 	return c.cluster.WriteBatch(wb)
 }
 
+// UnregisterSink removes the sink from memory but does not delete it from storage
+func (c *Controller) UnregisterSink(schemaName string, sinkName string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	schema, ok := c.schemas[schemaName]
+	if !ok {
+		return errors.Errorf("no such schema %s", schemaName)
+	}
+	sink, ok := schema.GetSink(sinkName)
+	if !ok {
+		return errors.Errorf("no such sink %s", sinkName)
+	}
+	delete(c.tableIDs, sink.ID)
+	schema.DeleteSink(sinkName)
+	return nil
+}
+
+func (c *Controller) DeleteSink(sinkID uint64) error {
+	return c.deleteTableWithID(sinkID)
+}
+
 func (c *Controller) checkTableID(tableID uint64) error {
 	if _, ok := c.tableIDs[tableID]; ok {
 		return errors.Errorf("cannot register. table with id %d already exists", tableID)
@@ -314,6 +484,22 @@ func (c *Controller) checkIndexID(indexID uint64) error {
 func (c *Controller) RegisterMaterializedView(mvInfo *common.MaterializedViewInfo, internalTables []*common.InternalTableInfo) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	return c.registerMaterializedView(mvInfo, internalTables)
+}
+
+// RegisterReplacedMaterializedView swaps the materialized view with the same name for the one which replaces it, in
+// one step, so queries on the name never find no materialized view. It does not persist it
+func (c *Controller) RegisterReplacedMaterializedView(oldInternalTables []string, mvInfo *common.MaterializedViewInfo,
+	internalTables []*common.InternalTableInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := c.unregisterMaterializedView(mvInfo.SchemaName, mvInfo.Name, oldInternalTables, false); err != nil {
+		return errors.WithStack(err)
+	}
+	return c.registerMaterializedView(mvInfo, internalTables)
+}
+
+func (c *Controller) registerMaterializedView(mvInfo *common.MaterializedViewInfo, internalTables []*common.InternalTableInfo) error {
 	log.Debugf("Registering MV %s with id %d", mvInfo.Name, mvInfo.ID)
 	if err := c.checkTableID(mvInfo.ID); err != nil {
 		return errors.WithStack(err)
@@ -373,6 +559,31 @@ func (c *Controller) DeleteSource(sourceID uint64) error {
 	return c.deleteTableWithID(sourceID)
 }
 
+// UnregisterUserTable removes the user table from memory but does not delete it from storage
+func (c *Controller) UnregisterUserTable(schemaName string, tableName string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	schema, ok := c.schemas[schemaName]
+	if !ok {
+		return errors.Errorf("no such schema %s", schemaName)
+	}
+	tbl, ok := schema.GetTable(tableName)
+	if !ok {
+		return errors.Errorf("no such table %s", tableName)
+	}
+	if _, ok := tbl.(*common.UserTableInfo); !ok {
+		return errors.Errorf("%s is not a user table", tbl)
+	}
+	delete(c.tableIDs, tbl.GetTableInfo().ID)
+	schema.DeleteTable(tableName)
+	c.DeleteSchemaIfEmpty(schema)
+	return nil
+}
+
+func (c *Controller) DeleteUserTable(tableID uint64) error {
+	return c.deleteTableWithID(tableID)
+}
+
 func (c *Controller) DeleteMaterializedView(mvInfo *common.MaterializedViewInfo, internalTableIDs []*common.InternalTableInfo) error {
 	if err := c.deleteTableWithID(mvInfo.ID); err != nil {
 		return errors.WithStack(err)
@@ -388,6 +599,10 @@ func (c *Controller) DeleteMaterializedView(mvInfo *common.MaterializedViewInfo,
 func (c *Controller) UnregisterMaterializedView(schemaName string, mvName string, internalTables []string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	return c.unregisterMaterializedView(schemaName, mvName, internalTables, true)
+}
+
+func (c *Controller) unregisterMaterializedView(schemaName string, mvName string, internalTables []string, deleteEmptySchema bool) error {
 	schema, ok := c.schemas[schemaName]
 	if !ok {
 		return errors.Errorf("no such schema %s", schemaName)
@@ -412,7 +627,9 @@ func (c *Controller) UnregisterMaterializedView(schemaName string, mvName string
 		delete(c.tableIDs, internalTbl.GetTableInfo().ID)
 		schema.DeleteTable(it)
 	}
-	c.DeleteSchemaIfEmpty(schema)
+	if deleteEmptySchema {
+		c.DeleteSchemaIfEmpty(schema)
+	}
 	return nil
 }
 
@@ -424,11 +641,15 @@ func (c *Controller) DeleteEntityWithID(tableID uint64) error {
 
 func (c *Controller) deleteTableWithID(tableID uint64) error {
 	wb := cluster.NewWriteBatch(cluster.SystemSchemaShardID)
+	addDeleteTableWithIDToBatch(tableID, wb)
+	return c.cluster.WriteBatch(wb)
+}
+
+func addDeleteTableWithIDToBatch(tableID uint64, wb *cluster.WriteBatch) {
 	var key []byte
 	key = table.EncodeTableKeyPrefix(common.SchemaTableID, cluster.SystemSchemaShardID, 24)
 	key = common.KeyEncodeInt64(key, int64(tableID))
 	wb.AddDelete(key)
-	return c.cluster.WriteBatch(wb)
 }
 
 func (c *Controller) deleteIndexWithID(indexID uint64) error {
@@ -445,6 +666,7 @@ func (c *Controller) registerSystemSchema() {
 	schema.PutTable(TableDefTableInfo.Name, TableDefTableInfo)
 	schema.PutTable(IndexDefTableInfo.Name, IndexDefTableInfo)
 	schema.PutTable(ProtobufTableInfo.Name, ProtobufTableInfo)
+	schema.PutTable(DeadLetterTableInfo.Name, DeadLetterTableInfo)
 }
 
 // DeleteSchemaIfEmpty - Schema are removed once they have no more tables
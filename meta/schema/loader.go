@@ -47,6 +47,7 @@ func (l *Loader) Start() error { //nolint:gocyclo
 	// MVs must be started in the load order so we maintain a slice
 	var mvsToStart []tableKey
 	var srcsToStart []*source.Source
+	var sinksToStart []*common.SinkInfo
 
 	for i := 0; i < tableRows.RowCount(); i++ {
 		tableRow := tableRows.GetRow(i)
@@ -63,6 +64,14 @@ func (l *Loader) Start() error { //nolint:gocyclo
 				return errors.WithStack(err)
 			}
 			srcsToStart = append(srcsToStart, src)
+		case meta.TableKindUserTable:
+			info := meta.DecodeUserTableInfoRow(&tableRow)
+			if err := l.meta.RegisterUserTable(info); err != nil {
+				return errors.WithStack(err)
+			}
+			if _, err := l.pushEngine.CreateUserTable(info); err != nil {
+				return errors.WithStack(err)
+			}
 		case meta.TableKindMaterializedView:
 			info := meta.DecodeMaterializedViewInfoRow(&tableRow)
 			tk := tableKey{info.SchemaName, info.Name}
@@ -83,6 +92,9 @@ func (l *Loader) Start() error { //nolint:gocyclo
 			}
 			mvt.sequences = append(mvt.sequences, info.ID)
 			mvt.internalTables = append(mvt.internalTables, info)
+		case meta.TableKindSink:
+			// Sinks are started after the MVs they publish
+			sinksToStart = append(sinksToStart, meta.DecodeSinkInfoRow(&tableRow))
 		default:
 			return errors.Errorf("unknown table kind %s", kind)
 		}
@@ -101,14 +113,18 @@ func (l *Loader) Start() error { //nolint:gocyclo
 		// It's important that we remove the first id from the seq generator as that's the MV id
 		// If we don't do this then the internal tables will end up with different ids than last time!
 		mvID := seqGen.GenerateSequence()
+		// The query is planned against the columns the MV was created with - columns might have been added to the
+		// sources it selects from since
 		mv, err := push.CreateMaterializedView(
 			l.pushEngine,
-			parplan.NewPlanner(schema),
-			schema, mvt.mvInfo.Name, mvt.mvInfo.Query, mvID,
+			parplan.NewPlannerAsOf(schema, mvID),
+			schema, mvt.mvInfo.Name, mvt.mvInfo.Query, mvID, mvt.mvInfo.KeyNullMarkers,
 			seqGen)
 		if err != nil {
 			return errors.WithStack(err)
 		}
+		// The retention isn't part of the query, so it comes from the stored table info
+		mv.Info.Retention = mvt.mvInfo.Retention
 		if err := mv.Connect(true, true); err != nil {
 			return errors.WithStack(err)
 		}
@@ -131,6 +147,15 @@ func (l *Loader) Start() error { //nolint:gocyclo
 		}
 	}
 
+	for _, info := range sinksToStart {
+		if err := l.pushEngine.CreateSink(info, false); err != nil {
+			return err
+		}
+		if err := l.meta.RegisterSink(info); err != nil {
+			return err
+		}
+	}
+
 	log.Info("Starting sources")
 
 	for _, src := range srcsToStart {
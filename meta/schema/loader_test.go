@@ -123,6 +123,19 @@ func TestLoader(t *testing.T) {
 				},
 			}},
 		},
+		{
+			name: "user tables",
+			ddl: []ddl{{
+				schema: "rates",
+				queries: []string{
+					`create table currencies(code varchar, rate decimal(10, 4), primary key (code))`,
+					`create materialized view high_rates as
+						select code, rate
+						from currencies
+						where rate > 1`,
+				},
+			}},
+		},
 	}
 	for _, test := range tests {
 		// nolint: scopelint
@@ -28,7 +28,19 @@ type iSSchemaInfo struct {
 	TablesInfos map[string]*common.TableInfo
 }
 
-func schemaToInfoSchema(schema *common.Schema) infoschema.InfoSchema {
+func pkColsVisible(tableInfo *common.TableInfo) bool {
+	if tableInfo.ColsVisible == nil {
+		return true
+	}
+	for _, pkCol := range tableInfo.PrimaryKeyCols {
+		if !tableInfo.ColsVisible[pkCol] {
+			return false
+		}
+	}
+	return true
+}
+
+func schemaToInfoSchema(schema *common.Schema, asOfTableID uint64) infoschema.InfoSchema {
 
 	tableInfos := schema.GetAllTableInfos()
 	schemaInfo := iSSchemaInfo{
@@ -51,6 +63,9 @@ func schemaToInfoSchema(schema *common.Schema) infoschema.InfoSchema {
 			if tableInfo.ColsVisible != nil && !tableInfo.ColsVisible[columnIndex] {
 				continue
 			}
+			if asOfTableID != 0 && tableInfo.ColAddedAfter(columnIndex, asOfTableID) {
+				continue
+			}
 			colType := common.ConvertPranaTypeToTiDBType(columnType)
 			col := &model.ColumnInfo{
 				State:     model.StatePublic,
@@ -73,8 +88,8 @@ func schemaToInfoSchema(schema *common.Schema) infoschema.InfoSchema {
 
 		// The TiDB planner doesn't seem to support PK with more than one column, so in this case we create a fake index
 		// which has all the PK cols in it, so the planner can generate an index scan with it, so we get fast lookups
-		// and table scans
-		if len(tableInfo.PrimaryKeyCols) > 1 {
+		// and table scans. We can't do this if any of the PK cols are invisible, e.g. for the hidden key cols of a join.
+		if len(tableInfo.PrimaryKeyCols) > 1 && pkColsVisible(tableInfo) {
 			var indexCols []*model.IndexColumn
 			for _, columnIndex := range tableInfo.PrimaryKeyCols {
 				col := &model.IndexColumn{
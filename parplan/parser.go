@@ -1,12 +1,15 @@
 package parplan
 
 import (
+	"regexp"
 	"sort"
+	"strings"
 
 	pc_parser "github.com/pingcap/parser"
 	"github.com/pingcap/parser/ast"
 	"github.com/pingcap/parser/charset"
 	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/tidb/expression"
 	driver "github.com/squareup/pranadb/tidb/types/parser_driver"
 )
 
@@ -22,6 +25,7 @@ type Parser struct {
 }
 
 func (p *Parser) Parse(sql string) (stmt AstHandle, err error) {
+	sql = rewriteWindowIntervals(sql)
 	stmtNodes, warns, err := p.parser.Parse(sql, charset.CharsetUTF8, "")
 	if err != nil {
 		return AstHandle{}, errors.WithStack(err)
@@ -56,6 +60,29 @@ type AstHandle struct {
 	stmt ast.StmtNode
 }
 
+// ReferencesColumns returns true if the statement refers to a column, e.g. the select expression of
+// "select count + 1" does but that of "select 1" doesn't
+func (a AstHandle) ReferencesColumns() bool {
+	vis := &colRefVisitor{}
+	a.stmt.Accept(vis)
+	return vis.found
+}
+
+type colRefVisitor struct {
+	found bool
+}
+
+func (c *colRefVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	return in, c.found
+}
+
+func (c *colRefVisitor) Leave(in ast.Node) (ast.Node, bool) {
+	if _, ok := in.(*ast.ColumnNameExpr); ok {
+		c.found = true
+	}
+	return in, true
+}
+
 type pmVisitor struct {
 	pms []ast.ParamMarkerExpr
 }
@@ -87,3 +114,60 @@ func (ps *pmSorter) Less(i, j int) bool {
 func (ps *pmSorter) Swap(i, j int) {
 	ps.pms[i], ps.pms[j] = ps.pms[j], ps.pms[i]
 }
+
+var windowFuncRegex = regexp.MustCompile(`(?i)\b([a-z_]+)\s*\(`)
+var intervalRegex = regexp.MustCompile(`(?i)\bINTERVAL\s+'?(\d+)'?\s+([a-z_]+)`)
+
+// rewriteWindowIntervals rewrites INTERVAL expressions in the arguments of window functions to strings, e.g.
+// TUMBLE(ts, INTERVAL 1 MINUTE) becomes TUMBLE(ts, '1 MINUTE'), as the parser only accepts INTERVAL expressions in
+// the arguments of a few built-in functions.
+func rewriteWindowIntervals(sql string) string {
+	var sb strings.Builder
+	pos := 0
+	for _, loc := range windowFuncRegex.FindAllStringSubmatchIndex(sql, -1) {
+		if loc[0] < pos {
+			continue
+		}
+		if _, ok := expression.WindowFuncNames[strings.ToLower(sql[loc[2]:loc[3]])]; !ok {
+			continue
+		}
+		end := closingParenIndex(sql, loc[1])
+		if end == -1 {
+			break
+		}
+		sb.WriteString(sql[pos:loc[1]])
+		sb.WriteString(intervalRegex.ReplaceAllString(sql[loc[1]:end], "'$1 $2'"))
+		pos = end
+	}
+	if pos == 0 {
+		return sql
+	}
+	sb.WriteString(sql[pos:])
+	return sb.String()
+}
+
+// closingParenIndex returns the index of the parenthesis closing the one opened just before start, ignoring any in
+// string literals, or -1 if there is none.
+func closingParenIndex(sql string, start int) int {
+	depth := 1
+	var quote byte
+	for i := start; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -2,6 +2,8 @@ package parplan
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestParse(t *testing.T) {
@@ -11,3 +13,20 @@ func TestParse(t *testing.T) {
 	_, _ = parser.Parse("select t1.col1, t1.col2, t2.col3 from table1 t1 inner join table2 t2 on t1.col1 = t2.col3 order by t1.col1")
 
 }
+
+func TestReferencesColumns(t *testing.T) {
+	parser := NewParser()
+	for sql, expected := range map[string]bool{
+		"select 1":                            false,
+		"select round(1.5 * 2, 4)":            false,
+		"select 'count'":                      false,
+		"select count + 1":                    true,
+		"select not enabled":                  true,
+		"select (select max(x) from table1)":  true,
+		"select concat('a', t1.col1) from t1": true,
+	} {
+		stmt, err := parser.Parse(sql)
+		require.NoError(t, err)
+		require.Equal(t, expected, stmt.ReferencesColumns(), sql)
+	}
+}
@@ -172,3 +172,33 @@ func TestSecondaryIndexMultiColumnRangeUsingIndexScanForPullQuery(t *testing.T)
 	require.Equal(t, "bar", is.Ranges[0].LowVal[2].GetString())
 	require.Equal(t, "", is.Ranges[0].HighVal[2].GetString())
 }
+
+func TestEquiJoinUsesHashJoinForPushQuery(t *testing.T) {
+	schema := createTestSchema()
+	planner := NewPlanner(schema)
+	physi, _, err := planner.QueryToPlan("select * from table1 join table2 on table1.col1 = table2.col1 and table1.col2 > table2.col2", false, false)
+	require.NoError(t, err)
+	join, ok := physi.(*planner2.PhysicalHashJoin)
+	require.True(t, ok)
+	require.Equal(t, planner2.InnerJoin, join.JoinType)
+	require.Equal(t, 2, len(join.Children()))
+	require.Equal(t, 1, len(join.LeftJoinKeys))
+	require.Equal(t, 1, join.LeftJoinKeys[0].Index)
+	require.Equal(t, 1, len(join.RightJoinKeys))
+	require.Equal(t, 1, join.RightJoinKeys[0].Index)
+	require.Equal(t, 1, len(join.OtherConditions))
+}
+
+func TestLeftOuterJoinKeepsLeftConditionsInJoinForPushQuery(t *testing.T) {
+	schema := createTestSchema()
+	planner := NewPlanner(schema)
+	physi, _, err := planner.QueryToPlan("select * from table1 left join table2 on table1.col1 = table2.col1 and table1.col2 > 10", false, false)
+	require.NoError(t, err)
+	join, ok := physi.(*planner2.PhysicalHashJoin)
+	require.True(t, ok)
+	require.Equal(t, planner2.LeftOuterJoin, join.JoinType)
+	require.Equal(t, 1, len(join.LeftJoinKeys))
+	require.Equal(t, 1, len(join.LeftConditions))
+	require.Equal(t, 0, len(join.RightConditions))
+	require.Equal(t, 0, len(join.OtherConditions))
+}
@@ -18,11 +18,19 @@ type Planner struct {
 	parser             *Parser
 	sessionCtx         *sessctx.SessCtx
 	schema             *common.Schema
+	asOfTableID        uint64
 	is                 infoschema.InfoSchema
 }
 
 func NewPlanner(schema *common.Schema) *Planner {
-	is := schemaToInfoSchema(schema)
+	return NewPlannerAsOf(schema, 0)
+}
+
+// NewPlannerAsOf creates a planner which doesn't see the columns added to tables after the table with the id was
+// created, so the query of a materialized view is planned against the columns it was created with. An id of zero means
+// all the columns are seen.
+func NewPlannerAsOf(schema *common.Schema, tableID uint64) *Planner {
+	is := schemaToInfoSchema(schema, tableID)
 	sessCtx := sessctx.NewSessionContext(is, schema.Name)
 	// TODO different rules for push and pull queries
 	pl := &Planner{
@@ -31,6 +39,7 @@ func NewPlanner(schema *common.Schema) *Planner {
 		parser:             NewParser(),
 		sessionCtx:         sessCtx,
 		schema:             schema,
+		asOfTableID:        tableID,
 		is:                 is,
 	}
 	return pl
@@ -45,7 +54,7 @@ func (p *Planner) SetPSArgs(args []interface{}) {
 }
 
 func (p *Planner) RefreshInfoSchema() {
-	p.is = schemaToInfoSchema(p.schema)
+	p.is = schemaToInfoSchema(p.schema, p.asOfTableID)
 	p.sessionCtx.SetInfoSchema(p.is)
 }
 
@@ -412,6 +412,171 @@ func (x *ClusterReadResponse) GetResponseBody() []byte {
 	return nil
 }
 
+type SourceStatusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SourceIds []uint64 `protobuf:"varint,1,rep,packed,name=source_ids,json=sourceIds,proto3" json:"source_ids,omitempty"`
+}
+
+func (x *SourceStatusRequest) Reset() {
+	*x = SourceStatusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SourceStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SourceStatusRequest) ProtoMessage() {}
+
+func (x *SourceStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SourceStatusRequest.ProtoReflect.Descriptor instead.
+func (*SourceStatusRequest) Descriptor() ([]byte, []int) {
+	return file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SourceStatusRequest) GetSourceIds() []uint64 {
+	if x != nil {
+		return x.SourceIds
+	}
+	return nil
+}
+
+type SourceStatusResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NodeId   int64               `protobuf:"varint,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Statuses []*SourceNodeStatus `protobuf:"bytes,2,rep,name=statuses,proto3" json:"statuses,omitempty"`
+}
+
+func (x *SourceStatusResponse) Reset() {
+	*x = SourceStatusResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SourceStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SourceStatusResponse) ProtoMessage() {}
+
+func (x *SourceStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SourceStatusResponse.ProtoReflect.Descriptor instead.
+func (*SourceStatusResponse) Descriptor() ([]byte, []int) {
+	return file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SourceStatusResponse) GetNodeId() int64 {
+	if x != nil {
+		return x.NodeId
+	}
+	return 0
+}
+
+func (x *SourceStatusResponse) GetStatuses() []*SourceNodeStatus {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+type SourceNodeStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SourceId   uint64  `protobuf:"varint,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	IngestRate float64 `protobuf:"fixed64,2,opt,name=ingest_rate,json=ingestRate,proto3" json:"ingest_rate,omitempty"`
+	LastError  string  `protobuf:"bytes,3,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+}
+
+func (x *SourceNodeStatus) Reset() {
+	*x = SourceNodeStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SourceNodeStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SourceNodeStatus) ProtoMessage() {}
+
+func (x *SourceNodeStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SourceNodeStatus.ProtoReflect.Descriptor instead.
+func (*SourceNodeStatus) Descriptor() ([]byte, []int) {
+	return file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SourceNodeStatus) GetSourceId() uint64 {
+	if x != nil {
+		return x.SourceId
+	}
+	return 0
+}
+
+func (x *SourceNodeStatus) GetIngestRate() float64 {
+	if x != nil {
+		return x.IngestRate
+	}
+	return 0
+}
+
+func (x *SourceNodeStatus) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
 var File_squareup_cash_pranadb_notifications_v1_notifications_proto protoreflect.FileDescriptor
 
 var file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDesc = []byte{
@@ -461,12 +626,31 @@ var file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDesc = []
 	0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x61, 0x64, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
 	0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x5f, 0x62, 0x6f, 0x64,
 	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x42, 0x6f, 0x64, 0x79, 0x42, 0x4b, 0x5a, 0x49, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
-	0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x71, 0x75, 0x61, 0x72, 0x65, 0x75, 0x70, 0x2f, 0x70, 0x72, 0x61,
-	0x6e, 0x61, 0x64, 0x62, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x73, 0x71, 0x75, 0x61,
-	0x72, 0x65, 0x75, 0x70, 0x2f, 0x63, 0x61, 0x73, 0x68, 0x2f, 0x70, 0x72, 0x61, 0x6e, 0x61, 0x64,
-	0x62, 0x2f, 0x76, 0x31, 0x2f, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f,
-	0x6e, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x42, 0x6f, 0x64, 0x79, 0x22, 0x34, 0x0a, 0x13, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1d, 0x0a, 0x0a,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x04,
+	0x52, 0x09, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x49, 0x64, 0x73, 0x22, 0x85, 0x01, 0x0a, 0x14,
+	0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x54, 0x0a,
+	0x08, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x38, 0x2e, 0x73, 0x71, 0x75, 0x61, 0x72, 0x65, 0x75, 0x70, 0x2e, 0x63, 0x61, 0x73, 0x68, 0x2e,
+	0x70, 0x72, 0x61, 0x6e, 0x61, 0x64, 0x62, 0x2e, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4e,
+	0x6f, 0x64, 0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x08, 0x73, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x65, 0x73, 0x22, 0x6f, 0x0a, 0x10, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x4e, 0x6f, 0x64,
+	0x65, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x73, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x65, 0x49, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x5f, 0x72,
+	0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a, 0x69, 0x6e, 0x67, 0x65, 0x73,
+	0x74, 0x52, 0x61, 0x74, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x45,
+	0x72, 0x72, 0x6f, 0x72, 0x42, 0x4b, 0x5a, 0x49, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x73, 0x71, 0x75, 0x61, 0x72, 0x65, 0x75, 0x70, 0x2f, 0x70, 0x72, 0x61, 0x6e,
+	0x61, 0x64, 0x62, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x73, 0x71, 0x75, 0x61, 0x72,
+	0x65, 0x75, 0x70, 0x2f, 0x63, 0x61, 0x73, 0x68, 0x2f, 0x70, 0x72, 0x61, 0x6e, 0x61, 0x64, 0x62,
+	0x2f, 0x76, 0x31, 0x2f, 0x6e, 0x6f, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -481,7 +665,7 @@ func file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDescGZIP
 	return file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDescData
 }
 
-var file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_squareup_cash_pranadb_notifications_v1_notifications_proto_goTypes = []interface{}{
 	(*DDLStatementInfo)(nil),       // 0: squareup.cash.pranadb.notifications.v1.DDLStatementInfo
 	(*SessionClosedMessage)(nil),   // 1: squareup.cash.pranadb.notifications.v1.SessionClosedMessage
@@ -490,13 +674,17 @@ var file_squareup_cash_pranadb_notifications_v1_notifications_proto_goTypes = []
 	(*ClusterProposeResponse)(nil), // 4: squareup.cash.pranadb.notifications.v1.ClusterProposeResponse
 	(*ClusterReadRequest)(nil),     // 5: squareup.cash.pranadb.notifications.v1.ClusterReadRequest
 	(*ClusterReadResponse)(nil),    // 6: squareup.cash.pranadb.notifications.v1.ClusterReadResponse
+	(*SourceStatusRequest)(nil),    // 7: squareup.cash.pranadb.notifications.v1.SourceStatusRequest
+	(*SourceStatusResponse)(nil),   // 8: squareup.cash.pranadb.notifications.v1.SourceStatusResponse
+	(*SourceNodeStatus)(nil),       // 9: squareup.cash.pranadb.notifications.v1.SourceNodeStatus
 }
 var file_squareup_cash_pranadb_notifications_v1_notifications_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	9, // 0: squareup.cash.pranadb.notifications.v1.SourceStatusResponse.statuses:type_name -> squareup.cash.pranadb.notifications.v1.SourceNodeStatus
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_squareup_cash_pranadb_notifications_v1_notifications_proto_init() }
@@ -589,6 +777,42 @@ func file_squareup_cash_pranadb_notifications_v1_notifications_proto_init() {
 				return nil
 			}
 		}
+		file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SourceStatusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SourceStatusResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_squareup_cash_pranadb_notifications_v1_notifications_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SourceNodeStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -596,7 +820,7 @@ func file_squareup_cash_pranadb_notifications_v1_notifications_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_squareup_cash_pranadb_notifications_v1_notifications_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
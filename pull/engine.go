@@ -16,6 +16,7 @@ import (
 	"github.com/squareup/pranadb/pull/exec"
 	"github.com/squareup/pranadb/remoting"
 	"github.com/squareup/pranadb/sess"
+	"github.com/squareup/pranadb/tidb/planner"
 )
 
 type Engine struct {
@@ -123,7 +124,7 @@ func (p *Engine) ExecutePreparedStatement(session *sess.Session, psID int64, arg
 	if err != nil {
 		return nil, err
 	}
-	return p.buildPullDAG(session, physicalPlan, false)
+	return p.buildQueryDAG(session, physicalPlan)
 }
 
 func (p *Engine) BuildPullQuery(session *sess.Session, query string) (exec.PullExecutor, error) {
@@ -144,7 +145,22 @@ func (p *Engine) BuildPullQuery(session *sess.Session, query string) (exec.PullE
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	return p.buildPullDAG(session, physicalPlan, false)
+	return p.buildQueryDAG(session, physicalPlan)
+}
+
+// buildQueryDAG builds the pull DAG for a query executed on this node. When the query has more than one remote
+// executor, e.g. for a join, each is given an id so the nodes executing the remote part know which one to execute.
+func (p *Engine) buildQueryDAG(session *sess.Session, plan planner.PhysicalPlan) (exec.PullExecutor, error) {
+	dag, err := p.buildPullDAG(session, plan, false)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i, remExecutor := range findRemoteExecutors(dag, nil) {
+		if i > 0 {
+			remExecutor.SetID(uint32(i))
+		}
+	}
+	return dag, nil
 }
 
 // ExecuteRemotePullQuery - executes a pull query received from another node
@@ -205,7 +221,7 @@ func (p *Engine) ExecuteRemotePullQuery(queryInfo *cluster.QueryExecutionInfo) (
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
-			remExecutor := p.findRemoteExecutor(dag)
+			remExecutor := p.findRemoteExecutor(dag, queryInfo.ExecutorID)
 			if remExecutor == nil {
 				return nil, errors.Error("cannot find remote executor")
 			}
@@ -227,7 +243,7 @@ func (p *Engine) ExecuteRemotePullQuery(queryInfo *cluster.QueryExecutionInfo) (
 			if err != nil {
 				return nil, errors.WithStack(err)
 			}
-			remExecutor := p.findRemoteExecutor(dag)
+			remExecutor := p.findRemoteExecutor(dag, queryInfo.ExecutorID)
 			if remExecutor == nil {
 				return nil, errors.Error("cannot find remote executor")
 			}
@@ -281,19 +297,26 @@ func (p *Engine) getCachedSession(sessionID string) (*sess.Session, bool) {
 	return s, true
 }
 
-func (p *Engine) findRemoteExecutor(executor exec.PullExecutor) *exec.RemoteExecutor {
+func (p *Engine) findRemoteExecutor(executor exec.PullExecutor, id uint32) *exec.RemoteExecutor {
 	// We only execute the part of the dag beyond the table reader - this is the remote part
+	remExecutors := findRemoteExecutors(executor, nil)
+	if int(id) >= len(remExecutors) {
+		return nil
+	}
+	return remExecutors[id]
+}
+
+// findRemoteExecutors returns the remote executors of the dag, in the order they are found depth first. The index of
+// a remote executor in the result is its id.
+func findRemoteExecutors(executor exec.PullExecutor, remExecutors []*exec.RemoteExecutor) []*exec.RemoteExecutor {
 	remExecutor, ok := executor.(*exec.RemoteExecutor)
 	if ok {
-		return remExecutor
+		return append(remExecutors, remExecutor)
 	}
 	for _, child := range executor.GetChildren() {
-		remExecutor := p.findRemoteExecutor(child)
-		if remExecutor != nil {
-			return remExecutor
-		}
+		remExecutors = findRemoteExecutors(child, remExecutors)
 	}
-	return nil
+	return remExecutors
 }
 
 func (p *Engine) NumCachedSessions() (int, error) {
@@ -307,7 +330,15 @@ func (p *Engine) NumCachedSessions() (int, error) {
 
 func (p *Engine) HandleMessage(notification remoting.ClusterMessage) (remoting.ClusterMessage, error) {
 	sessCloseMsg := notification.(*notifications.SessionClosedMessage) // nolint: forcetypeassert
-	p.sessionCache().Delete(sessCloseMsg.GetSessionId())
+	sessionID := sessCloseMsg.GetSessionId()
+	p.sessionCache().Delete(sessionID)
+	// A query with more than one remote executor has a session for each of them, with the id of the executor appended
+	p.sessionCache().Range(func(key, value interface{}) bool {
+		if strings.HasPrefix(key.(string), sessionID+"-") { //nolint: forcetypeassert
+			p.sessionCache().Delete(key)
+		}
+		return true
+	})
 	return nil, nil
 }
 
@@ -362,7 +393,13 @@ func (p *Engine) ExecuteQuery(schemaName string, query string) (rows *common.Row
 	}
 	sess := sess.NewSession("", nil)
 	sess.UseSchema(schema)
-	exec, err := p.BuildPullQuery(sess, query)
+	// No need to close session as no prepared statements
+	return p.ExecuteQueryInSession(sess, query)
+}
+
+// ExecuteQueryInSession executes the query in the session and returns all of the rows
+func (p *Engine) ExecuteQueryInSession(session *sess.Session, query string) (rows *common.Rows, err error) {
+	exec, err := p.BuildPullQuery(session, query)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -381,7 +418,6 @@ func (p *Engine) ExecuteQuery(schemaName string, query string) (rows *common.Row
 			break
 		}
 	}
-	// No need to close session as no prepared statements
 	return rows, nil
 }
 
@@ -0,0 +1,204 @@
+package exec
+
+import (
+	"github.com/cznic/mathutil"
+	"github.com/squareup/pranadb/aggfuncs"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+// PullAggregator - a simple in memory aggregation executor. A query with an aggregation has a partial aggregator on
+// each shard, which aggregates the rows of the shard, and a full aggregator on the node executing the query, which
+// merges the partial aggregations. The output of a partial aggregator has all the aggregate functions, including any
+// added for group by columns that aren't selected, followed by a varchar column for the extra state of each function
+// that requires it.
+type PullAggregator struct {
+	pullExecutorBase
+	aggFuncInfos   []*aggfuncs.AggregateFunctionInfo
+	aggFuncs       []aggfuncs.AggregateFunction
+	aggColTypes    []common.ColumnType
+	extraStateCols []int
+	valueCounts    bool // any of the functions require value counts, which are kept in memory
+	groupByCols    []int
+	partial        bool
+	rows           *common.Rows
+	rowIndex       int
+}
+
+// NewPullAggregator creates a PullAggregator. colNames and colTypes are the visible columns of the aggregation and
+// groupByCols are the columns of the input to group by. For a full aggregator the input is the output of the partial
+// aggregators, so these are the group by columns in the output of the partial aggregation.
+func NewPullAggregator(colNames []string, colTypes []common.ColumnType, aggFunctions []*aggfuncs.AggregateFunctionInfo,
+	groupByCols []int, partial bool) (*PullAggregator, error) {
+	aggFuncs, err := aggfuncs.CreateAggregateFunctions(aggFunctions)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aggColTypes := make([]common.ColumnType, len(aggFunctions))
+	for i, aggFunc := range aggFunctions {
+		aggColTypes[i] = aggFunc.ReturnType
+	}
+	partialColTypes := aggColTypes
+	extraStateCols := make([]int, len(aggFuncs))
+	valueCounts := false
+	for i, aggFunc := range aggFuncs {
+		extraStateCols[i] = -1
+		valueCounts = valueCounts || aggFunc.RequiresValueCounts()
+		if aggFunc.RequiresExtraState() {
+			if len(partialColTypes) == len(aggColTypes) {
+				partialColTypes = append([]common.ColumnType{}, aggColTypes...)
+			}
+			extraStateCols[i] = len(partialColTypes)
+			partialColTypes = append(partialColTypes, common.VarcharColumnType)
+		}
+	}
+	if partial {
+		// The extra columns aren't visible, so they have no names
+		partialColNames := make([]string, len(partialColTypes))
+		copy(partialColNames, colNames)
+		colNames = partialColNames
+		colTypes = partialColTypes
+	}
+	base := pullExecutorBase{
+		colNames:       colNames,
+		colTypes:       colTypes,
+		simpleColNames: common.ToSimpleColNames(colNames),
+		rowsFactory:    common.NewRowsFactory(colTypes),
+	}
+	return &PullAggregator{
+		pullExecutorBase: base,
+		aggFuncInfos:     aggFunctions,
+		aggFuncs:         aggFuncs,
+		aggColTypes:      aggColTypes,
+		extraStateCols:   extraStateCols,
+		valueCounts:      valueCounts,
+		groupByCols:      groupByCols,
+		partial:          partial,
+	}, nil
+}
+
+func (p *PullAggregator) GetRows(limit int) (*common.Rows, error) {
+	if limit < 1 {
+		return nil, errors.Errorf("invalid limit %d", limit)
+	}
+	if p.rows == nil {
+		rows, err := p.aggregate()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		p.rows = rows
+	}
+	rowsLeft := p.rows.RowCount() - p.rowIndex
+	rowsToGet := mathutil.Min(rowsLeft, limit)
+	res := p.rowsFactory.NewRows(rowsToGet)
+	for i := p.rowIndex; i < p.rowIndex+rowsToGet; i++ {
+		res.AppendRow(p.rows.GetRow(i))
+	}
+	p.rowIndex += rowsToGet
+	return res, nil
+}
+
+// aggregate reads all the rows from the child and returns the aggregated rows, in the order the groups were first seen
+func (p *PullAggregator) aggregate() (*common.Rows, error) {
+	var groups []*aggfuncs.AggState
+	groupsByKey := make(map[string]*aggfuncs.AggState)
+	inputColTypes := p.GetChildren()[0].ColTypes()
+	numAggCols := len(p.aggFuncs)
+	for {
+		batch, err := p.GetChildren()[0].GetRows(queryBatchSize)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for i := 0; i < batch.RowCount(); i++ {
+			row := batch.GetRow(i)
+			// The key is only held in memory, so we can always distinguish null from the zero value with null markers
+			key, err := common.EncodeIndexKeyCols(&row, p.groupByCols, inputColTypes, nil)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			aggState, ok := groupsByKey[string(key)]
+			if !ok {
+				if len(groups) == aggregationMaxRows {
+					return nil, errors.Errorf("query with aggregation cannot return more than %d rows", aggregationMaxRows)
+				}
+				aggState = aggfuncs.NewAggState(numAggCols)
+				if p.valueCounts {
+					aggState.SetValueCounts(aggfuncs.NewMemValueCounts(p.aggFuncs))
+				}
+				groupsByKey[string(key)] = aggState
+				groups = append(groups, aggState)
+			}
+			if p.partial {
+				err = aggfuncs.EvaluateAggregateFunctions(p.aggFuncs, aggState, &row, false)
+			} else {
+				err = p.mergeRow(&row, aggState)
+			}
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		if batch.RowCount() < queryBatchSize {
+			break
+		}
+	}
+	if len(groups) == 0 && len(p.groupByCols) == 0 && !p.partial {
+		// An aggregation without a group by always returns a row, even when there are no rows to aggregate
+		groups = append(groups, aggfuncs.NewAggState(numAggCols))
+	}
+	rows := p.rowsFactory.NewRows(len(groups))
+	for _, aggState := range groups {
+		p.setEmptyResults(aggState)
+		if err := aggState.AppendToRows(rows, p.colTypes[:mathutil.Min(numAggCols, len(p.colTypes))]); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if p.partial {
+			p.appendExtraState(aggState, rows)
+		}
+	}
+	return rows, nil
+}
+
+// mergeRow merges a row from a partial aggregation into the aggregate state
+func (p *PullAggregator) mergeRow(row *common.Row, aggState *aggfuncs.AggState) error {
+	toMerge := aggfuncs.NewAggState(len(p.aggFuncs))
+	if err := toMerge.InitWithRow(row, p.aggColTypes); err != nil {
+		return errors.WithStack(err)
+	}
+	for i, col := range p.extraStateCols {
+		if col != -1 && !row.IsNull(col) {
+			toMerge.SetExtraState(i, []byte(row.GetString(col)))
+		}
+	}
+	return aggfuncs.MergeAggregateFunctions(p.aggFuncs, toMerge, aggState, false)
+}
+
+// appendExtraState appends the extra state of the aggregate functions to the extra state columns of a partial
+// aggregation
+func (p *PullAggregator) appendExtraState(aggState *aggfuncs.AggState, rows *common.Rows) {
+	for i, col := range p.extraStateCols {
+		if col == -1 {
+			continue
+		}
+		extraState := aggState.GetExtraState(i)
+		if extraState == nil {
+			rows.AppendNullToColumn(col)
+		} else {
+			rows.AppendStringToColumn(col, string(extraState))
+		}
+	}
+}
+
+// setEmptyResults sets the results of the aggregate functions that haven't had any values, because there were no rows
+// or all their arguments were NULL. COUNT is zero and everything else is NULL.
+func (p *PullAggregator) setEmptyResults(aggState *aggfuncs.AggState) {
+	for i, info := range p.aggFuncInfos {
+		if aggState.IsSet(i) {
+			continue
+		}
+		if info.FuncType == aggfuncs.CountAggregateFunctionType {
+			aggState.SetInt64(i, 0)
+		} else {
+			aggState.SetNull(i)
+		}
+	}
+}
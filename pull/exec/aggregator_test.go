@@ -0,0 +1,117 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/squareup/pranadb/aggfuncs"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/common/commontest"
+	"github.com/stretchr/testify/require"
+)
+
+var aggColNames = []string{"location", "num", "max_temperature", "total_cost"}
+var aggColTypes = []common.ColumnType{common.VarcharColumnType, common.BigIntColumnType, common.DoubleColumnType, common.NewDecimalColumnType(10, 2)}
+
+func TestPullAggregatorGroupBy(t *testing.T) {
+	shard1Rows := [][]interface{}{
+		{1, "wincanton", 25.5, "132.45"},
+		{2, "london", 35.1, "9.32"},
+		{3, "wincanton", 20.6, "11.75"},
+	}
+	shard2Rows := [][]interface{}{
+		{4, "london", 28.3, "1.00"},
+		{5, "los angeles", 32.7, nil},
+		{6, "london", nil, "20.00"},
+	}
+	expectedRows := [][]interface{}{
+		{"wincanton", 2, 25.5, "144.20"},
+		{"london", 3, 35.1, "30.32"},
+		{"los angeles", 1, 32.7, nil},
+	}
+	aggFuncs := aggFunctionInfos()
+	full := fullAggregator(t, aggFuncs, []int{0},
+		partialAggregate(t, aggFuncs, []int{1}, shard1Rows), partialAggregate(t, aggFuncs, []int{1}, shard2Rows))
+
+	provided, err := full.GetRows(1000)
+	require.NoError(t, err)
+	commontest.AllRowsEqual(t, toRows(t, expectedRows, aggColTypes), provided, aggColTypes)
+
+	provided, err = full.GetRows(1000)
+	require.NoError(t, err)
+	require.Equal(t, 0, provided.RowCount())
+}
+
+func TestPullAggregatorGetRowsInPages(t *testing.T) {
+	inpRows := [][]interface{}{
+		{1, "wincanton", 25.5, "132.45"},
+		{2, "london", 35.1, "9.32"},
+		{3, "los angeles", 20.6, "11.75"},
+	}
+	aggFuncs := aggFunctionInfos()
+	full := fullAggregator(t, aggFuncs, []int{0}, partialAggregate(t, aggFuncs, []int{1}, inpRows))
+
+	provided, err := full.GetRows(2)
+	require.NoError(t, err)
+	require.Equal(t, 2, provided.RowCount())
+	provided, err = full.GetRows(2)
+	require.NoError(t, err)
+	require.Equal(t, 1, provided.RowCount())
+	row := provided.GetRow(0)
+	require.Equal(t, "los angeles", row.GetString(0))
+}
+
+func TestPullAggregatorNoRows(t *testing.T) {
+	aggFuncs := aggFunctionInfos()[1:]
+	colNames := aggColNames[1:]
+	colTypes := aggColTypes[1:]
+
+	// An aggregation without a group by returns a single row
+	partial, err := NewPullAggregator(colNames, colTypes, aggFuncs, nil, true)
+	require.NoError(t, err)
+	partial.AddChild(Empty)
+	partialRows, err := partial.GetRows(1000)
+	require.NoError(t, err)
+	require.Equal(t, 0, partialRows.RowCount())
+
+	full, err := NewPullAggregator(colNames, colTypes, aggFuncs, nil, false)
+	require.NoError(t, err)
+	full.AddChild(&StaticRows{pullExecutorBase: pullExecutorBase{colTypes: partial.ColTypes()}, rows: partialRows})
+	provided, err := full.GetRows(1000)
+	require.NoError(t, err)
+	expected := toRows(t, [][]interface{}{{0, nil, nil}}, colTypes)
+	commontest.AllRowsEqual(t, expected, provided, colTypes)
+}
+
+func aggFunctionInfos() []*aggfuncs.AggregateFunctionInfo {
+	return []*aggfuncs.AggregateFunctionInfo{
+		{FuncType: aggfuncs.FirstRowAggregateFunctionType, ArgExpr: colExpression(1), ReturnType: aggColTypes[0]},
+		{FuncType: aggfuncs.CountAggregateFunctionType, ArgExpr: common.NewConstantInt(common.BigIntColumnType, 1), ReturnType: aggColTypes[1]},
+		{FuncType: aggfuncs.MaxAggregateFunctionType, ArgExpr: colExpression(2), ReturnType: aggColTypes[2]},
+		{FuncType: aggfuncs.SumAggregateFunctionType, ArgExpr: colExpression(3), ReturnType: aggColTypes[3]},
+	}
+}
+
+func partialAggregate(t *testing.T, aggFuncs []*aggfuncs.AggregateFunctionInfo, groupByCols []int, inputRows [][]interface{}) *common.Rows {
+	t.Helper()
+	partial, err := NewPullAggregator(aggColNames, aggColTypes, aggFuncs, groupByCols, true)
+	require.NoError(t, err)
+	child, err := NewStaticRows(colNames, toRows(t, inputRows, colTypes))
+	require.NoError(t, err)
+	partial.AddChild(child)
+	rows, err := partial.GetRows(1000)
+	require.NoError(t, err)
+	return rows
+}
+
+func fullAggregator(t *testing.T, aggFuncs []*aggfuncs.AggregateFunctionInfo, groupByCols []int, partialRows ...*common.Rows) *PullAggregator {
+	t.Helper()
+	full, err := NewPullAggregator(aggColNames, aggColTypes, aggFuncs, groupByCols, false)
+	require.NoError(t, err)
+	partialColTypes := partialRows[0].ColumnTypes()
+	rows := common.NewRows(partialColTypes, 0)
+	for _, r := range partialRows {
+		rows.AppendAll(r)
+	}
+	full.AddChild(&StaticRows{pullExecutorBase: pullExecutorBase{colTypes: partialColTypes}, rows: rows})
+	return full
+}
@@ -7,8 +7,10 @@ import (
 type ExecutorType uint32
 
 const (
-	orderByMaxRows = 50000
-	queryBatchSize = 10000
+	orderByMaxRows     = 50000
+	aggregationMaxRows = 50000
+	joinMaxRows        = 50000
+	queryBatchSize     = 10000
 )
 
 type PullExecutor interface {
@@ -174,7 +174,7 @@ func (p *PullIndexReader) getRowsFromRange(limit int, currRange *rangeHolder) er
 				return err
 			}
 			// And any from the PK
-			if _, err = common.DecodeIndexOrPKCols(kvPair.Value, 0, true, p.pkColTypes, p.pkOutputCols, p.rows); err != nil {
+			if _, err = common.DecodeIndexOrPKCols(kvPair.Value, 0, !p.tableInfo.KeyNullMarkers, p.pkColTypes, p.pkOutputCols, p.rows); err != nil {
 				return err
 			}
 		} else {
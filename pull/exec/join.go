@@ -0,0 +1,407 @@
+package exec
+
+import (
+	"github.com/cznic/mathutil"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+type JoinType int
+
+const (
+	JoinTypeInner JoinType = iota
+	JoinTypeLeftOuter
+)
+
+// pullJoinBase has the state shared by the join executors. The rows of the left input are read a batch at a time, and
+// each batch is joined with the matching rows of the right input. The output row comprises the columns of the left
+// input followed by the columns of the right input. For a left outer join, left rows without any matches are output
+// with the columns of the right input set to null. Rows with a null join key never match. If singleMatch is true, e.g.
+// when the right input is a scalar subquery, it's an error for a left row to match more than one right row.
+type pullJoinBase struct {
+	pullExecutorBase
+	joinType        JoinType
+	leftJoinCols    []int
+	rightJoinCols   []int
+	leftColCount    int
+	otherConditions []*common.Expression
+	singleMatch     bool
+	joined          *common.Rows
+	joinedIndex     int
+	leftComplete    bool
+}
+
+func newPullJoinBase(joinType JoinType, colNames []string, colTypes []common.ColumnType, leftColCount int,
+	leftJoinCols []int, rightJoinCols []int, otherConditions []*common.Expression, singleMatch bool) (pullJoinBase, error) {
+	if len(leftJoinCols) != len(rightJoinCols) {
+		return pullJoinBase{}, errors.Error("join must have the same number of join columns on each side")
+	}
+	if err := checkJoinColTypes(colTypes[:leftColCount], leftJoinCols, colTypes[leftColCount:], rightJoinCols); err != nil {
+		return pullJoinBase{}, err
+	}
+	base := pullExecutorBase{
+		colNames:       colNames,
+		colTypes:       colTypes,
+		simpleColNames: common.ToSimpleColNames(colNames),
+		rowsFactory:    common.NewRowsFactory(colTypes),
+	}
+	return pullJoinBase{
+		pullExecutorBase: base,
+		joinType:         joinType,
+		leftJoinCols:     leftJoinCols,
+		rightJoinCols:    rightJoinCols,
+		leftColCount:     leftColCount,
+		otherConditions:  otherConditions,
+		singleMatch:      singleMatch,
+	}, nil
+}
+
+func checkJoinColTypes(leftColTypes []common.ColumnType, leftJoinCols []int, rightColTypes []common.ColumnType,
+	rightJoinCols []int) error {
+	for i, leftJoinCol := range leftJoinCols {
+		leftType := leftColTypes[leftJoinCol]
+		rightType := rightColTypes[rightJoinCols[i]]
+		// Join keys from both inputs must have the same key encoding
+		if isIntType(leftType) && isIntType(rightType) {
+			continue
+		}
+		if leftType.Type != rightType.Type ||
+			(leftType.Type == common.TypeDecimal && (leftType.DecPrecision != rightType.DecPrecision || leftType.DecScale != rightType.DecScale)) {
+			return errors.NewPranaErrorf(errors.InvalidStatement, "Cannot join columns of type %s and %s", leftType.String(), rightType.String())
+		}
+	}
+	return nil
+}
+
+func isIntType(colType common.ColumnType) bool {
+	return colType.Type == common.TypeTinyInt || colType.Type == common.TypeInt || colType.Type == common.TypeBigInt ||
+		colType.Type == common.TypeBoolean
+}
+
+// getRows returns up to limit joined rows. joinBatch is called to join each batch of rows from the left input.
+func (j *pullJoinBase) getRows(limit int, joinBatch func(batch *common.Rows) error) (*common.Rows, error) {
+	if limit < 1 {
+		return nil, errors.Errorf("invalid limit %d", limit)
+	}
+	for !j.leftComplete && (j.joined == nil || j.joined.RowCount()-j.joinedIndex < limit) {
+		batch, err := j.GetChildren()[0].GetRows(queryBatchSize)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if batch.RowCount() < queryBatchSize {
+			j.leftComplete = true
+		}
+		if j.joined == nil || j.joinedIndex == j.joined.RowCount() {
+			j.joined = j.rowsFactory.NewRows(batch.RowCount())
+			j.joinedIndex = 0
+		}
+		if err := joinBatch(batch); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if j.joined == nil {
+		return j.rowsFactory.NewRows(0), nil
+	}
+	rowsToGet := mathutil.Min(j.joined.RowCount()-j.joinedIndex, limit)
+	res := j.rowsFactory.NewRows(rowsToGet)
+	for i := j.joinedIndex; i < j.joinedIndex+rowsToGet; i++ {
+		res.AppendRow(j.joined.GetRow(i))
+	}
+	j.joinedIndex += rowsToGet
+	return res, nil
+}
+
+// joinRows joins a batch of rows from the left input with their matches, which are keyed by encoded join key
+func (j *pullJoinBase) joinRows(batch *common.Rows, matchesByKey map[string][]*common.Row) error {
+	leftColTypes := j.colTypes[:j.leftColCount]
+	for i := 0; i < batch.RowCount(); i++ {
+		leftRow := batch.GetRow(i)
+		key, err := encodeJoinKey(&leftRow, j.leftJoinCols, leftColTypes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		matched := false
+		if key != nil {
+			for _, rightRow := range matchesByKey[string(key)] {
+				ok, err := j.appendJoinedRow(&leftRow, rightRow)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if ok && matched && j.singleMatch {
+					return errors.NewSubqueryReturnsMoreThanOneRowError()
+				}
+				matched = matched || ok
+			}
+		}
+		if !matched && j.joinType == JoinTypeLeftOuter {
+			outIndex := j.appendColsFromRow(&leftRow, j.leftColCount, 0, j.joined)
+			appendNullCols(len(j.colTypes)-j.leftColCount, outIndex, j.joined)
+		}
+	}
+	return nil
+}
+
+// appendJoinedRow appends the joined row if it satisfies the other conditions of the join, and returns whether it did
+func (j *pullJoinBase) appendJoinedRow(leftRow *common.Row, rightRow *common.Row) (bool, error) {
+	out := j.joined
+	if len(j.otherConditions) > 0 {
+		// We only know whether to output the row once we've evaluated the conditions on it
+		out = j.rowsFactory.NewRows(1)
+	}
+	outIndex := j.appendColsFromRow(leftRow, j.leftColCount, 0, out)
+	j.appendColsFromRow(rightRow, len(j.colTypes)-j.leftColCount, outIndex, out)
+	if len(j.otherConditions) == 0 {
+		return true, nil
+	}
+	joined := out.GetRow(0)
+	for _, cond := range j.otherConditions {
+		accept, isNull, err := cond.EvalBoolean(&joined)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if isNull || !accept {
+			return false, nil
+		}
+	}
+	j.joined.AppendRow(joined)
+	return true, nil
+}
+
+func (j *pullJoinBase) appendColsFromRow(row *common.Row, numCols int, outIndex int, out *common.Rows) int {
+	for i := 0; i < numCols; i++ {
+		if row.IsNull(i) {
+			out.AppendNullToColumn(outIndex)
+		} else {
+			switch j.colTypes[outIndex].Type {
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+				out.AppendInt64ToColumn(outIndex, row.GetInt64(i))
+			case common.TypeDouble:
+				out.AppendFloat64ToColumn(outIndex, row.GetFloat64(i))
+			case common.TypeVarchar, common.TypeVarbinary:
+				out.AppendStringToColumn(outIndex, row.GetString(i))
+			case common.TypeTimestamp:
+				out.AppendTimestampToColumn(outIndex, row.GetTimestamp(i))
+			case common.TypeDecimal:
+				out.AppendDecimalToColumn(outIndex, row.GetDecimal(i))
+			default:
+				panic("unexpected column type")
+			}
+		}
+		outIndex++
+	}
+	return outIndex
+}
+
+func appendNullCols(numCols int, outIndex int, out *common.Rows) int {
+	for i := 0; i < numCols; i++ {
+		out.AppendNullToColumn(outIndex)
+		outIndex++
+	}
+	return outIndex
+}
+
+// encodeJoinKey returns nil if any of the join columns are null
+func encodeJoinKey(row *common.Row, joinCols []int, colTypes []common.ColumnType) ([]byte, error) {
+	if len(joinCols) == 0 {
+		// Without equality conditions every row matches every other row
+		return []byte{}, nil
+	}
+	for _, joinCol := range joinCols {
+		if row.IsNull(joinCol) {
+			return nil, nil
+		}
+	}
+	return common.EncodeKeyCols(row, joinCols, colTypes, nil)
+}
+
+// addMatches adds the rows to the matches keyed by encoded join key, and returns the number of rows added
+func addMatches(rows *common.Rows, joinCols []int, colTypes []common.ColumnType, matchesByKey map[string][]*common.Row) (int, error) {
+	added := 0
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		key, err := encodeJoinKey(&row, joinCols, colTypes)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if key == nil {
+			continue
+		}
+		matchesByKey[string(key)] = append(matchesByKey[string(key)], &row)
+		added++
+	}
+	return added, nil
+}
+
+// PullHashJoin - a simple in memory hash join. The first time rows are requested all the rows of the right input are
+// read into a hash table keyed by join key, then the rows of the left input are joined with their matches.
+type PullHashJoin struct {
+	pullJoinBase
+	matchesByKey map[string][]*common.Row
+}
+
+var _ PullExecutor = &PullHashJoin{}
+
+// NewPullHashJoin creates a PullHashJoin. colNames and colTypes are the columns of the left input followed by the
+// columns of the right input, and otherConditions are evaluated on the joined row.
+func NewPullHashJoin(joinType JoinType, colNames []string, colTypes []common.ColumnType, leftColCount int,
+	leftJoinCols []int, rightJoinCols []int, otherConditions []*common.Expression, singleMatch bool) (*PullHashJoin, error) {
+	base, err := newPullJoinBase(joinType, colNames, colTypes, leftColCount, leftJoinCols, rightJoinCols, otherConditions,
+		singleMatch)
+	if err != nil {
+		return nil, err
+	}
+	return &PullHashJoin{pullJoinBase: base}, nil
+}
+
+func (h *PullHashJoin) GetRows(limit int) (*common.Rows, error) {
+	if h.matchesByKey == nil {
+		if err := h.readRightRows(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return h.getRows(limit, func(batch *common.Rows) error {
+		return h.joinRows(batch, h.matchesByKey)
+	})
+}
+
+func (h *PullHashJoin) readRightRows() error {
+	h.matchesByKey = make(map[string][]*common.Row)
+	right := h.GetChildren()[1]
+	numRows := 0
+	for {
+		batch, err := right.GetRows(queryBatchSize)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		added, err := addMatches(batch, h.rightJoinCols, right.ColTypes(), h.matchesByKey)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		numRows += added
+		if numRows > joinMaxRows {
+			return errors.Errorf("query with join cannot have more than %d rows on the right of the join", joinMaxRows)
+		}
+		if batch.RowCount() < queryBatchSize {
+			return nil
+		}
+	}
+}
+
+// PullLookupJoin - an index nested loop join. For each batch of rows from the left input, the distinct join keys are
+// sent to the shards and the matching rows of the right input are looked up using the primary key or a secondary
+// index of the table on the right.
+type PullLookupJoin struct {
+	pullJoinBase
+	lookupCols     []int
+	lookupColTypes []common.ColumnType
+	keyShard       func(key *common.Row) (uint64, error)
+}
+
+var _ PullExecutor = &PullLookupJoin{}
+
+// NewPullLookupJoin creates a PullLookupJoin. The right input must be a RemoteExecutor. lookupCols are the columns of
+// the left input that are looked up, in the order of the columns of the key or index, and lookupColTypes are the types
+// of those columns in the table. keyShard returns the shard that owns a key, and if it is nil the keys are looked up on
+// all shards.
+func NewPullLookupJoin(joinType JoinType, colNames []string, colTypes []common.ColumnType, leftColCount int,
+	leftJoinCols []int, rightJoinCols []int, otherConditions []*common.Expression, singleMatch bool, lookupCols []int,
+	lookupColTypes []common.ColumnType, keyShard func(key *common.Row) (uint64, error)) (*PullLookupJoin, error) {
+	base, err := newPullJoinBase(joinType, colNames, colTypes, leftColCount, leftJoinCols, rightJoinCols, otherConditions,
+		singleMatch)
+	if err != nil {
+		return nil, err
+	}
+	return &PullLookupJoin{
+		pullJoinBase:   base,
+		lookupCols:     lookupCols,
+		lookupColTypes: lookupColTypes,
+		keyShard:       keyShard,
+	}, nil
+}
+
+func (l *PullLookupJoin) GetRows(limit int) (*common.Rows, error) {
+	return l.getRows(limit, l.joinBatch)
+}
+
+func (l *PullLookupJoin) joinBatch(batch *common.Rows) error {
+	right, ok := l.GetChildren()[1].(*RemoteExecutor)
+	if !ok {
+		return errors.Error("right input of lookup join must be a remote executor")
+	}
+	keysByShard, err := l.lookupKeys(batch, right.ShardIDs)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	matchesByKey := make(map[string][]*common.Row)
+	if len(keysByShard) > 0 {
+		rightRows, err := right.Lookup(keysByShard)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if _, err := addMatches(rightRows, l.rightJoinCols, right.ColTypes(), matchesByKey); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return l.joinRows(batch, matchesByKey)
+}
+
+// lookupKeys returns the distinct non null keys of the left rows, by the shard to look them up on
+func (l *PullLookupJoin) lookupKeys(batch *common.Rows, shardIDs []uint64) (map[uint64]*common.Rows, error) {
+	leftColTypes := l.colTypes[:l.leftColCount]
+	keys := common.NewRows(l.lookupColTypes, batch.RowCount())
+	seen := make(map[string]struct{}, batch.RowCount())
+	for i := 0; i < batch.RowCount(); i++ {
+		row := batch.GetRow(i)
+		key, err := encodeJoinKey(&row, l.lookupCols, leftColTypes)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if key == nil {
+			continue
+		}
+		if _, ok := seen[string(key)]; ok {
+			continue
+		}
+		seen[string(key)] = struct{}{}
+		for j, col := range l.lookupCols {
+			switch l.lookupColTypes[j].Type {
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+				keys.AppendInt64ToColumn(j, row.GetInt64(col))
+			case common.TypeDouble:
+				keys.AppendFloat64ToColumn(j, row.GetFloat64(col))
+			case common.TypeVarchar, common.TypeVarbinary:
+				keys.AppendStringToColumn(j, row.GetString(col))
+			case common.TypeTimestamp:
+				keys.AppendTimestampToColumn(j, row.GetTimestamp(col))
+			case common.TypeDecimal:
+				keys.AppendDecimalToColumn(j, row.GetDecimal(col))
+			default:
+				panic("unexpected column type")
+			}
+		}
+	}
+	keysByShard := make(map[uint64]*common.Rows)
+	if keys.RowCount() == 0 {
+		return keysByShard, nil
+	}
+	if l.keyShard == nil {
+		for _, shardID := range shardIDs {
+			keysByShard[shardID] = keys
+		}
+		return keysByShard, nil
+	}
+	for i := 0; i < keys.RowCount(); i++ {
+		key := keys.GetRow(i)
+		shardID, err := l.keyShard(&key)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		shardKeys, ok := keysByShard[shardID]
+		if !ok {
+			shardKeys = common.NewRows(l.lookupColTypes, 1)
+			keysByShard[shardID] = shardKeys
+		}
+		shardKeys.AppendRow(key)
+	}
+	return keysByShard, nil
+}
@@ -0,0 +1,150 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/common/commontest"
+	"github.com/stretchr/testify/require"
+)
+
+var joinLeftColNames = []string{"order_id", "customer_id"}
+var joinLeftColTypes = []common.ColumnType{common.BigIntColumnType, common.IntColumnType}
+var joinRightColNames = []string{"customer_id", "name"}
+var joinRightColTypes = []common.ColumnType{common.BigIntColumnType, common.VarcharColumnType}
+
+var joinLeftRows = [][]interface{}{
+	{10, 1},
+	{11, 2},
+	{12, 1},
+	{13, nil},
+	{14, 3},
+}
+
+var joinRightRows = [][]interface{}{
+	{1, "alice"},
+	{2, "bob"},
+	{2, "bobby"},
+	{4, "dave"},
+}
+
+func TestPullHashJoinInner(t *testing.T) {
+	join := hashJoin(t, JoinTypeInner, nil, false)
+	expected := [][]interface{}{
+		{10, 1, 1, "alice"},
+		{11, 2, 2, "bob"},
+		{11, 2, 2, "bobby"},
+		{12, 1, 1, "alice"},
+	}
+	provided, err := join.GetRows(1000)
+	require.NoError(t, err)
+	commontest.AllRowsEqual(t, toRows(t, expected, join.ColTypes()), provided, join.ColTypes())
+}
+
+func TestPullHashJoinLeftOuter(t *testing.T) {
+	join := hashJoin(t, JoinTypeLeftOuter, nil, false)
+	expected := [][]interface{}{
+		{10, 1, 1, "alice"},
+		{11, 2, 2, "bob"},
+		{11, 2, 2, "bobby"},
+		{12, 1, 1, "alice"},
+		{13, nil, nil, nil},
+		{14, 3, nil, nil},
+	}
+	provided, err := join.GetRows(1000)
+	require.NoError(t, err)
+	commontest.AllRowsEqual(t, toRows(t, expected, join.ColTypes()), provided, join.ColTypes())
+}
+
+func TestPullHashJoinOtherConditions(t *testing.T) {
+	// name <> 'bob'
+	cond, err := common.NewScalarFunctionExpression(common.TinyIntColumnType, "ne",
+		common.NewColumnExpression(3, common.VarcharColumnType), common.NewConstantVarchar(common.VarcharColumnType, "bob"))
+	require.NoError(t, err)
+	join := hashJoin(t, JoinTypeLeftOuter, []*common.Expression{cond}, false)
+	expected := [][]interface{}{
+		{10, 1, 1, "alice"},
+		{11, 2, 2, "bobby"},
+		{12, 1, 1, "alice"},
+		{13, nil, nil, nil},
+		{14, 3, nil, nil},
+	}
+	provided, err := join.GetRows(1000)
+	require.NoError(t, err)
+	commontest.AllRowsEqual(t, toRows(t, expected, join.ColTypes()), provided, join.ColTypes())
+}
+
+func TestPullHashJoinSingleMatch(t *testing.T) {
+	join := hashJoin(t, JoinTypeLeftOuter, nil, true)
+	_, err := join.GetRows(1000)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Subquery returns more than 1 row")
+
+	// It's only an error if more than one of the rows satisfies the other conditions
+	cond, err := common.NewScalarFunctionExpression(common.TinyIntColumnType, "ne",
+		common.NewColumnExpression(3, common.VarcharColumnType), common.NewConstantVarchar(common.VarcharColumnType, "bob"))
+	require.NoError(t, err)
+	join = hashJoin(t, JoinTypeLeftOuter, []*common.Expression{cond}, true)
+	provided, err := join.GetRows(1000)
+	require.NoError(t, err)
+	require.Equal(t, 5, provided.RowCount())
+}
+
+func TestPullHashJoinGetRowsInPages(t *testing.T) {
+	join := hashJoin(t, JoinTypeLeftOuter, nil, false)
+	provided, err := join.GetRows(4)
+	require.NoError(t, err)
+	require.Equal(t, 4, provided.RowCount())
+	provided, err = join.GetRows(4)
+	require.NoError(t, err)
+	require.Equal(t, 2, provided.RowCount())
+	row := provided.GetRow(1)
+	require.Equal(t, int64(14), row.GetInt64(0))
+	provided, err = join.GetRows(4)
+	require.NoError(t, err)
+	require.Equal(t, 0, provided.RowCount())
+}
+
+func TestPullHashJoinNoJoinCols(t *testing.T) {
+	// Without equality conditions each left row is joined with every right row
+	joinColNames := append(append([]string{}, joinLeftColNames...), joinRightColNames...)
+	joinColTypes := append(append([]common.ColumnType{}, joinLeftColTypes...), joinRightColTypes...)
+	join, err := NewPullHashJoin(JoinTypeLeftOuter, joinColNames, joinColTypes, len(joinLeftColTypes), nil, nil, nil, false)
+	require.NoError(t, err)
+	left, err := NewStaticRows(joinLeftColNames, toRows(t, joinLeftRows[:2], joinLeftColTypes))
+	require.NoError(t, err)
+	right, err := NewStaticRows(joinRightColNames, toRows(t, joinRightRows[:2], joinRightColTypes))
+	require.NoError(t, err)
+	ConnectPullExecutors([]PullExecutor{left, right}, join)
+	expected := [][]interface{}{
+		{10, 1, 1, "alice"},
+		{10, 1, 2, "bob"},
+		{11, 2, 1, "alice"},
+		{11, 2, 2, "bob"},
+	}
+	provided, err := join.GetRows(1000)
+	require.NoError(t, err)
+	commontest.AllRowsEqual(t, toRows(t, expected, join.ColTypes()), provided, join.ColTypes())
+}
+
+func TestPullHashJoinIncompatibleTypes(t *testing.T) {
+	joinColTypes := append(append([]common.ColumnType{}, joinLeftColTypes...), common.VarcharColumnType, common.VarcharColumnType)
+	_, err := NewPullHashJoin(JoinTypeInner, append(joinLeftColNames, joinRightColNames...), joinColTypes, 2,
+		[]int{1}, []int{0}, nil, false)
+	require.Error(t, err)
+}
+
+func hashJoin(t *testing.T, joinType JoinType, otherConditions []*common.Expression, singleMatch bool) *PullHashJoin {
+	t.Helper()
+	joinColNames := append(append([]string{}, joinLeftColNames...), joinRightColNames...)
+	joinColTypes := append(append([]common.ColumnType{}, joinLeftColTypes...), joinRightColTypes...)
+	join, err := NewPullHashJoin(joinType, joinColNames, joinColTypes, len(joinLeftColTypes), []int{1}, []int{0},
+		otherConditions, singleMatch)
+	require.NoError(t, err)
+	left, err := NewStaticRows(joinLeftColNames, toRows(t, joinLeftRows, joinLeftColTypes))
+	require.NoError(t, err)
+	right, err := NewStaticRows(joinRightColNames, toRows(t, joinRightRows, joinRightColTypes))
+	require.NoError(t, err)
+	ConnectPullExecutors([]PullExecutor{left, right}, join)
+	return join
+}
@@ -0,0 +1,105 @@
+package exec
+
+import (
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+)
+
+// PullLookupScan - reads the rows of a table for the inner side of an index lookup join. The keys to look up are sent
+// by the node executing the join, and are looked up using the primary key, or a prefix of the columns of a secondary
+// index if indexInfo is set. The keys are only decoded when rows are first requested, so the scan can be built as part
+// of a query that doesn't look anything up.
+type PullLookupScan struct {
+	pullExecutorBase
+	tableInfo  *common.TableInfo
+	indexInfo  *common.IndexInfo
+	colIndexes []int
+	storage    cluster.Cluster
+	shardID    uint64
+	keyTypes   []common.ColumnType
+	keys       []byte
+	scan       PullExecutor
+}
+
+var _ PullExecutor = &PullLookupScan{}
+
+func NewPullLookupScan(tableInfo *common.TableInfo, indexInfo *common.IndexInfo, colIndexes []int,
+	storage cluster.Cluster, shardID uint64, keyTypes []common.ColumnType, keys []byte) (*PullLookupScan, error) {
+	// We create a scan of the whole table to get the columns of the output
+	scan, err := newLookupScan(tableInfo, indexInfo, colIndexes, storage, shardID, nil)
+	if err != nil {
+		return nil, err
+	}
+	base := pullExecutorBase{
+		colNames:       scan.ColNames(),
+		colTypes:       scan.ColTypes(),
+		simpleColNames: scan.SimpleColNames(),
+		rowsFactory:    common.NewRowsFactory(scan.ColTypes()),
+		keyCols:        tableInfo.PrimaryKeyCols,
+	}
+	return &PullLookupScan{
+		pullExecutorBase: base,
+		tableInfo:        tableInfo,
+		indexInfo:        indexInfo,
+		colIndexes:       colIndexes,
+		storage:          storage,
+		shardID:          shardID,
+		keyTypes:         keyTypes,
+		keys:             keys,
+	}, nil
+}
+
+func newLookupScan(tableInfo *common.TableInfo, indexInfo *common.IndexInfo, colIndexes []int, storage cluster.Cluster,
+	shardID uint64, scanRanges []*ScanRange) (PullExecutor, error) {
+	if indexInfo == nil {
+		return NewPullTableScan(tableInfo, colIndexes, storage, shardID, scanRanges)
+	}
+	return NewPullIndexReader(tableInfo, indexInfo, colIndexes, storage, shardID, scanRanges)
+}
+
+func (l *PullLookupScan) GetRows(limit int) (*common.Rows, error) {
+	if limit < 1 {
+		return nil, errors.Errorf("invalid limit %d", limit)
+	}
+	if l.scan == nil {
+		if len(l.keys) == 0 {
+			return l.rowsFactory.NewRows(0), nil
+		}
+		scan, err := newLookupScan(l.tableInfo, l.indexInfo, l.colIndexes, l.storage, l.shardID, l.keyRanges())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		l.scan = scan
+	}
+	return l.scan.GetRows(limit)
+}
+
+// keyRanges returns a point range for each key
+func (l *PullLookupScan) keyRanges() []*ScanRange {
+	keys := common.NewRows(l.keyTypes, 0)
+	keys.Deserialize(l.keys)
+	scanRanges := make([]*ScanRange, keys.RowCount())
+	for i := 0; i < keys.RowCount(); i++ {
+		key := keys.GetRow(i)
+		vals := make([]interface{}, len(l.keyTypes))
+		for j, keyType := range l.keyTypes {
+			switch keyType.Type {
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+				vals[j] = key.GetInt64(j)
+			case common.TypeDouble:
+				vals[j] = key.GetFloat64(j)
+			case common.TypeVarchar, common.TypeVarbinary:
+				vals[j] = key.GetString(j)
+			case common.TypeTimestamp:
+				vals[j] = key.GetTimestamp(j)
+			case common.TypeDecimal:
+				vals[j] = key.GetDecimal(j)
+			default:
+				panic("unexpected column type")
+			}
+		}
+		scanRanges[i] = &ScanRange{LowVals: vals, HighVals: vals}
+	}
+	return scanRanges
+}
@@ -45,7 +45,7 @@ func (p *PullProjection) GetRows(limit int) (rows *common.Rows, err error) { //
 		for j, projColumn := range p.projColumns {
 			colType := p.colTypes[j]
 			switch colType.Type {
-			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 				val, null, err := projColumn.EvalInt64(&row)
 				if err != nil {
 					return nil, errors.WithStack(err)
@@ -65,7 +65,7 @@ func (p *PullProjection) GetRows(limit int) (rows *common.Rows, err error) { //
 				} else {
 					result.AppendDecimalToColumn(j, val)
 				}
-			case common.TypeVarchar:
+			case common.TypeVarchar, common.TypeVarbinary:
 				val, null, err := projColumn.EvalString(&row)
 				if err != nil {
 					return nil, errors.WithStack(err)
@@ -22,6 +22,7 @@ type RemoteExecutor struct {
 	RemoteDag         PullExecutor
 	ShardIDs          []uint64
 	pointGetQueryInfo *cluster.QueryExecutionInfo
+	id                uint32
 }
 
 func NewRemoteExecutor(remoteDAG PullExecutor, queryInfo *cluster.QueryExecutionInfo, colNames []string,
@@ -164,6 +165,59 @@ func (re *RemoteExecutor) GetRows(limit int) (rows *common.Rows, err error) {
 	return rows, nil
 }
 
+// SetID sets the id of the remote executor, which identifies it among the remote executors of the query when there is
+// more than one, e.g. for a join
+func (re *RemoteExecutor) SetID(id uint32) {
+	re.id = id
+	if re.pointGetQueryInfo != nil {
+		re.pointGetQueryInfo = re.createGetterQueryExecInfo(re.queryInfo, re.pointGetQueryInfo.ShardID)
+	} else {
+		re.createGetters()
+	}
+}
+
+// Lookup executes the remote part of the query on each shard with keys to look up, for the inner side of an index
+// lookup join, and returns all the rows
+func (re *RemoteExecutor) Lookup(keysByShard map[uint64]*common.Rows) (*common.Rows, error) {
+	channels := make([]chan cluster.RemoteQueryResult, 0, len(keysByShard))
+	for shardID, keys := range keysByShard {
+		qei := re.createGetterQueryExecInfo(re.queryInfo, shardID)
+		qei.LookupKeys = keys.Serialize()
+		ch := make(chan cluster.RemoteQueryResult, 1)
+		channels = append(channels, ch)
+		go func() {
+			rows := re.rowsFactory.NewRows(queryBatchSize)
+			for {
+				qei.Limit = queryBatchSize
+				batch, err := re.cluster.ExecuteRemotePullQuery(qei, re.rowsFactory)
+				if err != nil {
+					ch <- cluster.RemoteQueryResult{Err: err}
+					return
+				}
+				rows.AppendAll(batch)
+				if batch.RowCount() < queryBatchSize {
+					ch <- cluster.RemoteQueryResult{Rows: rows}
+					return
+				}
+			}
+		}()
+	}
+	rows := re.rowsFactory.NewRows(queryBatchSize)
+	var err error
+	for _, ch := range channels {
+		res := <-ch
+		if res.Err != nil {
+			err = res.Err
+			continue
+		}
+		rows.AppendAll(res.Rows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
 func (re *RemoteExecutor) createGetters() {
 	shardIDs := re.ShardIDs
 	re.clusterGetters = make([]*clusterGetter, len(shardIDs))
@@ -187,6 +241,11 @@ func (re *RemoteExecutor) createGetterQueryExecInfo(qei *cluster.QueryExecutionI
 	// session for each shard, as each shard wil have an independent current query running and needs an
 	// independent planner as they're not thread-safe
 	qeiCopy.SessionID = fmt.Sprintf("%s-%d", re.queryInfo.SessionID, shardID)
+	if re.id != 0 {
+		// And each remote executor of the query needs its own session on the shard
+		qeiCopy.SessionID = fmt.Sprintf("%s-%d", qeiCopy.SessionID, re.id)
+	}
 	qeiCopy.ShardID = shardID
+	qeiCopy.ExecutorID = re.id
 	return &qeiCopy
 }
@@ -6,7 +6,7 @@ import (
 )
 
 func calcScanRangeKeys(scanRanges []*ScanRange, indexID uint64, indexCols []int, tableInfo *common.TableInfo,
-	shardID uint64, isIndex bool) ([]*rangeHolder, error) {
+	shardID uint64, nullMarkers bool) ([]*rangeHolder, error) {
 	keyPrefix := table.EncodeTableKeyPrefix(indexID, shardID, 16)
 	if len(scanRanges) == 0 || (len(scanRanges) == 1 && scanRanges[0] == nil) {
 		return []*rangeHolder{{rangeStart: keyPrefix, rangeEnd: table.EncodeTableKeyPrefix(indexID+1, shardID, 16)}}, nil
@@ -20,8 +20,8 @@ func calcScanRangeKeys(scanRanges []*ScanRange, indexID uint64, indexCols []int,
 			lv := sr.LowVals[j]
 			hv := sr.HighVals[j]
 			if lv == nil && hv == nil {
-				// This represents a get of a null value from the index, it can't occur for a pk
-				if !isIndex {
+				// This represents a get of a null value, it can't occur for a pk without null markers
+				if !nullMarkers {
 					panic("get of null in pk index")
 				}
 				rangeStart = append(rangeStart, 0)
@@ -31,8 +31,9 @@ func calcScanRangeKeys(scanRanges []*ScanRange, indexID uint64, indexCols []int,
 				//rangeEnd = table.EncodeTableKeyPrefix(indexID, shardID, 16)
 			} else {
 				// This is a closed range
-				if isIndex {
-					// Only index keys have a marker byte which says whether the key element is null or not
+				if nullMarkers {
+					// Index keys, and the keys of tables with KeyNullMarkers, have a marker byte which says whether the
+					// key element is null or not
 					rangeEnd = append(rangeEnd, 1)
 				}
 				rangeEnd, err = common.EncodeKeyElement(hv, tableInfo.ColumnTypes[indexCols[j]], rangeEnd)
@@ -41,7 +42,7 @@ func calcScanRangeKeys(scanRanges []*ScanRange, indexID uint64, indexCols []int,
 				}
 			}
 			if lv != nil {
-				if isIndex {
+				if nullMarkers {
 					rangeStart = append(rangeStart, 1)
 				}
 				rangeStart, err = common.EncodeKeyElement(lv, tableInfo.ColumnTypes[indexCols[j]], rangeStart)
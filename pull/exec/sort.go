@@ -109,7 +109,7 @@ func (p *PullSort) sortRows(unsorted *common.Rows) (*common.Rows, error) {
 				return false
 			}
 			switch colType.Type {
-			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 				val1, null1, err1 := sortbyExpr.EvalInt64(&row1)
 				if err1 != nil {
 					err = err1
@@ -185,7 +185,7 @@ func (p *PullSort) sortRows(unsorted *common.Rows) (*common.Rows, error) {
 						return descending
 					}
 				}
-			case common.TypeVarchar:
+			case common.TypeVarchar, common.TypeVarbinary:
 				val1, null1, err1 := sortbyExpr.EvalString(&row1)
 				if err1 != nil {
 					err = err1
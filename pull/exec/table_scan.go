@@ -48,12 +48,13 @@ func NewPullTableScan(tableInfo *common.TableInfo, colIndexes []int, storage clu
 
 	rf := common.NewRowsFactory(resultColTypes)
 	base := pullExecutorBase{
-		colTypes:    tableInfo.ColumnTypes,
+		colTypes:    resultColTypes,
 		rowsFactory: rf,
 		keyCols:     tableInfo.PrimaryKeyCols,
 	}
 
-	rangeHolders, err := calcScanRangeKeys(scanRanges, tableInfo.ID, tableInfo.PrimaryKeyCols, tableInfo, shardID, false)
+	rangeHolders, err := calcScanRangeKeys(scanRanges, tableInfo.ID, tableInfo.PrimaryKeyCols, tableInfo, shardID,
+		tableInfo.KeyNullMarkers)
 	if err != nil {
 		return nil, err
 	}
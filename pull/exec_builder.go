@@ -2,21 +2,37 @@ package pull
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/pingcap/parser/model"
+	"github.com/squareup/pranadb/aggfuncs"
 	"github.com/squareup/pranadb/common"
 	"github.com/squareup/pranadb/errors"
 	"github.com/squareup/pranadb/pull/exec"
 	"github.com/squareup/pranadb/sess"
 	"github.com/squareup/pranadb/sharder"
+	"github.com/squareup/pranadb/tidb/expression"
 	"github.com/squareup/pranadb/tidb/planner"
 	"github.com/squareup/pranadb/tidb/planner/util"
 	"github.com/squareup/pranadb/tidb/util/ranger"
 )
 
-// nolint: gocyclo
+// lookupInfo describes how the table on the inner side of an index lookup join is looked up
+type lookupInfo struct {
+	indexInfo *common.IndexInfo // nil if the rows are looked up by primary key
+	keyTypes  []common.ColumnType
+}
+
 func (p *Engine) buildPullDAG(session *sess.Session, plan planner.PhysicalPlan, remote bool) (exec.PullExecutor, error) {
+	return p.buildPullDAGWithLookup(session, plan, remote, nil)
+}
+
+// buildPullDAGWithLookup builds the pull DAG for the plan. If lookup is set, the plan is the inner side of an index
+// lookup join and its table scan looks up the keys sent by the join.
+// nolint: gocyclo
+func (p *Engine) buildPullDAGWithLookup(session *sess.Session, plan planner.PhysicalPlan, remote bool,
+	lookup *lookupInfo) (exec.PullExecutor, error) {
 	cols := plan.Schema().Columns
 	colTypes := make([]common.ColumnType, 0, len(cols))
 	colNames := make([]string, 0, len(cols))
@@ -45,7 +61,12 @@ func (p *Engine) buildPullDAG(session *sess.Session, plan planner.PhysicalPlan,
 		}
 		executor = exec.NewPullSelect(colNames, colTypes, exprs)
 	case *planner.PhysicalTableScan:
-		if remote {
+		if remote && lookup != nil {
+			executor, err = p.createPullLookupScan(session, op.Table.Name.L, op.Columns, lookup)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		} else if remote {
 			tableName := op.Table.Name.L
 			executor, err = p.createPullTableScan(session.Schema, tableName, op.Ranges, op.Columns, session.QueryInfo.ShardID)
 			if err != nil {
@@ -64,7 +85,12 @@ func (p *Engine) buildPullDAG(session *sess.Session, plan planner.PhysicalPlan,
 				pointGetShardID)
 		}
 	case *planner.PhysicalIndexScan:
-		if remote {
+		if remote && lookup != nil {
+			executor, err = p.createPullLookupScan(session, op.Table.Name.L, op.Columns, lookup)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		} else if remote {
 			tableName := op.Table.Name.L
 			if op.Index.Primary {
 				// This is a fake index we created because the table has a composite PK and TiDB planner doesn't
@@ -99,13 +125,25 @@ func (p *Engine) buildPullDAG(session *sess.Session, plan planner.PhysicalPlan,
 		desc, sortByExprs := p.byItemsToDescAndSortExpression(op.ByItems)
 		sort := exec.NewPullSort(colNames, colTypes, desc, sortByExprs)
 		executor = exec.NewPullChain(limit, sort)
+	case *planner.PhysicalHashAgg:
+		if remote {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Nested aggregations are not supported in pull queries")
+		}
+		// The children of the aggregation are built as the remote part of the query
+		return p.buildPullAggregation(session, op, colNames, colTypes)
+	case *planner.PhysicalHashJoin:
+		if remote {
+			return nil, errors.Error("unexpected join in remote part of query")
+		}
+		// The join is executed on this node, and each of its inputs has its own remote part
+		return p.buildPullJoin(session, op)
 	default:
 		return nil, errors.Errorf("unexpected plan type %T", plan)
 	}
 
 	var childExecutors []exec.PullExecutor
 	for _, child := range plan.Children() {
-		childExecutor, err := p.buildPullDAG(session, child, remote)
+		childExecutor, err := p.buildPullDAGWithLookup(session, child, remote, lookup)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -118,6 +156,347 @@ func (p *Engine) buildPullDAG(session *sess.Session, plan planner.PhysicalPlan,
 	return executor, nil
 }
 
+// buildPullAggregation builds the executors for an aggregation. A partial aggregation over the rows of each shard is
+// executed remotely, and the partial aggregations are merged on this node.
+func (p *Engine) buildPullAggregation(session *sess.Session, op *planner.PhysicalHashAgg, colNames []string,
+	colTypes []common.ColumnType) (exec.PullExecutor, error) {
+	var aggFuncs []*aggfuncs.AggregateFunctionInfo
+	for _, aggFunc := range op.AggFuncs {
+		af, err := aggfuncs.NewAggregateFunctionInfo(aggFunc)
+		if err != nil {
+			return nil, err
+		}
+		aggFuncs = append(aggFuncs, af)
+	}
+
+	// These are the indexes of the group by cols in the input of the partial aggregation
+	var groupByCols []int
+
+	// These are the indexes of the group by cols in the output of the partial aggregation
+	var partialGroupByCols []int
+
+	for _, expr := range op.GroupByItems {
+		// Group by expressions are evaluated by a projection below the aggregation, so they are columns here
+		col, ok := expr.(*expression.Column)
+		if !ok {
+			return nil, errors.Error("group by expression not a column")
+		}
+		groupByCols = append(groupByCols, col.Index)
+		partialCol := aggfuncs.FirstRowOfColumn(op.AggFuncs, aggFuncs, col)
+		if partialCol == -1 {
+			// The group by col isn't selected, but the full aggregation needs it to merge the partial aggregations
+			partialCol = len(aggFuncs)
+			aggFuncs = append(aggFuncs, &aggfuncs.AggregateFunctionInfo{
+				FuncType:   aggfuncs.FirstRowAggregateFunctionType,
+				ArgExpr:    common.NewExpression(col),
+				ReturnType: common.ConvertTiDBTypeToPranaType(col.GetType()),
+			})
+		}
+		partialGroupByCols = append(partialGroupByCols, partialCol)
+	}
+
+	partialAgg, err := exec.NewPullAggregator(colNames, colTypes, aggFuncs, groupByCols, true)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	fullAgg, err := exec.NewPullAggregator(colNames, colTypes, aggFuncs, partialGroupByCols, false)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if containsJoin(op) {
+		// A join is executed on this node, so both the partial and the full aggregation are too
+		child, err := p.buildPullDAG(session, op.Children()[0], false)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		exec.ConnectPullExecutors([]exec.PullExecutor{child}, partialAgg)
+		exec.ConnectPullExecutors([]exec.PullExecutor{partialAgg}, fullAgg)
+		return fullAgg, nil
+	}
+
+	remoteDag, err := p.buildPullDAG(session, op.Children()[0], true)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	exec.ConnectPullExecutors([]exec.PullExecutor{remoteDag}, partialAgg)
+
+	var pointGetShardID int64 = -1
+	if scan := findTableScan(op); scan != nil {
+		pointGetShardID, err = p.getPointGetShardID(session, scan.Ranges, scan.Table.Name.L)
+		if err != nil {
+			return nil, err
+		}
+	}
+	remoteExecutor := exec.NewRemoteExecutor(partialAgg, session.QueryInfo, partialAgg.ColNames(), partialAgg.ColTypes(),
+		session.Schema.Name, p.cluster, pointGetShardID)
+	exec.ConnectPullExecutors([]exec.PullExecutor{remoteExecutor}, fullAgg)
+	return fullAgg, nil
+}
+
+// buildPullJoin builds the executors for a join. If the rows on the right of the join can be looked up by the join keys,
+// using the primary key or a secondary index of the table, the join looks up the keys of each batch of rows from the
+// left. Otherwise all the rows on the right are read into a hash table.
+func (p *Engine) buildPullJoin(session *sess.Session, op *planner.PhysicalHashJoin) (exec.PullExecutor, error) {
+	var joinType exec.JoinType
+	switch op.JoinType {
+	case planner.InnerJoin:
+		joinType = exec.JoinTypeInner
+	case planner.LeftOuterJoin:
+		joinType = exec.JoinTypeLeftOuter
+	default:
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Unsupported join type %s", op.JoinType)
+	}
+	if len(op.RightConditions) != 0 {
+		return nil, errors.NewInvalidStatementError("Unsupported join condition")
+	}
+	leftJoinCols := make([]int, len(op.LeftJoinKeys))
+	for i, col := range op.LeftJoinKeys {
+		leftJoinCols[i] = col.Index
+	}
+	rightJoinCols := make([]int, len(op.RightJoinKeys))
+	for i, col := range op.RightJoinKeys {
+		rightJoinCols[i] = col.Index
+	}
+	// Conditions on the left input are only left in the join for outer joins. The left columns come first in the
+	// joined row so they can be evaluated along with the other conditions.
+	var otherConditions []*common.Expression
+	for _, expr := range op.LeftConditions {
+		otherConditions = append(otherConditions, common.NewExpression(expr))
+	}
+	for _, expr := range op.OtherConditions {
+		otherConditions = append(otherConditions, common.NewExpression(expr))
+	}
+	joinedSchema := expression.MergeSchema(op.Children()[0].Schema(), op.Children()[1].Schema())
+	colNames := make([]string, len(joinedSchema.Columns))
+	colTypes := make([]common.ColumnType, len(joinedSchema.Columns))
+	for i, col := range joinedSchema.Columns {
+		colNames[i] = col.OrigName
+		colTypes[i] = common.ConvertTiDBTypeToPranaType(col.GetType())
+	}
+	leftColCount := len(op.Children()[0].Schema().Columns)
+
+	left, err := p.buildPullDAG(session, op.Children()[0], false)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	lookup, lookupCols, keyShard, err := p.lookupForJoin(session, op)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var join exec.PullExecutor
+	var right exec.PullExecutor
+	if lookup != nil {
+		rightPlan := op.Children()[1]
+		remoteDag, err := p.buildPullDAGWithLookup(session, rightPlan, true, lookup)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		right = exec.NewRemoteExecutor(remoteDag, session.QueryInfo, colNames[leftColCount:], colTypes[leftColCount:],
+			session.Schema.Name, p.cluster, -1)
+		join, err = exec.NewPullLookupJoin(joinType, colNames, colTypes, leftColCount, leftJoinCols, rightJoinCols,
+			otherConditions, op.ScalarSubquery, lookupCols, lookup.keyTypes, keyShard)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		right, err = p.buildPullDAG(session, op.Children()[1], false)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		join, err = exec.NewPullHashJoin(joinType, colNames, colTypes, leftColCount, leftJoinCols, rightJoinCols,
+			otherConditions, op.ScalarSubquery)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	exec.ConnectPullExecutors([]exec.PullExecutor{left, right}, join)
+
+	// The planner can prune the columns of the join itself, e.g. for outer joins, while the join always outputs
+	// all the columns of its inputs. In this case we put a projection on top of the join.
+	// If the planner has pruned all the columns, e.g. for COUNT(*), we don't project as rows can't have no columns.
+	if len(op.Schema().Columns) == len(joinedSchema.Columns) || len(op.Schema().Columns) == 0 {
+		return join, nil
+	}
+	var projColNames []string
+	var projColTypes []common.ColumnType
+	exprs := make([]*common.Expression, len(op.Schema().Columns))
+	for i, col := range op.Schema().Columns {
+		projCol := col.Clone().(*expression.Column) //nolint:forcetypeassert
+		projCol.Index = joinedSchema.ColumnIndex(col)
+		exprs[i] = common.NewExpression(projCol)
+		projColNames = append(projColNames, col.OrigName)
+		projColTypes = append(projColTypes, common.ConvertTiDBTypeToPranaType(col.GetType()))
+	}
+	projection, err := exec.NewPullProjection(projColNames, projColTypes, exprs)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	exec.ConnectPullExecutors([]exec.PullExecutor{join}, projection)
+	return projection, nil
+}
+
+// lookupForJoin works out whether the rows on the right of a join can be looked up by the join keys. This is the case
+// when the right of the join is a scan of a whole table, optionally with selections and projections over it, and the
+// join keys include all the primary key columns of the table or a prefix of the columns of a secondary index. It
+// returns nil if the rows can't be looked up. Otherwise it also returns the join columns on the left in the order of
+// the lookup key, and for lookups by the primary key of a source a function which returns the shard that owns a key.
+// nolint: gocyclo
+func (p *Engine) lookupForJoin(session *sess.Session, op *planner.PhysicalHashJoin) (*lookupInfo, []int,
+	func(key *common.Row) (uint64, error), error) {
+	if len(op.RightJoinKeys) == 0 {
+		return nil, nil, nil, nil
+	}
+	// For each join key we find the column of the table on the right it comes from, if it does
+	keyCols := make([]int, len(op.RightJoinKeys))
+	for i, col := range op.RightJoinKeys {
+		keyCols[i] = col.Index
+	}
+	plan := op.Children()[1]
+	var tableName string
+	var columns []*model.ColumnInfo
+	// The index reader outputs the columns in a different order to the table scan, so when the planner has chosen an
+	// index we can only look up rows using an index
+	lookupByPK := true
+	for columns == nil {
+		switch child := plan.(type) {
+		case *planner.PhysicalProjection:
+			for i, keyCol := range keyCols {
+				if keyCol == -1 {
+					continue
+				}
+				col, ok := child.Exprs[keyCol].(*expression.Column)
+				if ok {
+					keyCols[i] = col.Index
+				} else {
+					keyCols[i] = -1
+				}
+			}
+		case *planner.PhysicalSelection:
+		case *planner.PhysicalTableScan:
+			if !isFullRange(child.Ranges) {
+				// The scan is already restricted, so we scan it rather than look up the keys
+				return nil, nil, nil, nil
+			}
+			tableName = child.Table.Name.L
+			columns = child.Columns
+			continue
+		case *planner.PhysicalIndexScan:
+			if !isFullRange(child.Ranges) || child.Index.Primary {
+				return nil, nil, nil, nil
+			}
+			tableName = child.Table.Name.L
+			columns = child.Columns
+			lookupByPK = false
+			continue
+		default:
+			return nil, nil, nil, nil
+		}
+		plan = plan.Children()[0]
+	}
+	tbl, ok := session.Schema.GetTable(tableName)
+	if !ok {
+		return nil, nil, nil, errors.Errorf("unknown source or materialized view %s", tableName)
+	}
+	tableInfo := tbl.GetTableInfo()
+	// The join columns on the left, by the column of the table on the right they are joined to
+	leftColsByTableCol := make(map[int]int, len(keyCols))
+	for i, keyCol := range keyCols {
+		if keyCol == -1 {
+			continue
+		}
+		tableCol := columns[keyCol].Offset
+		if _, ok := leftColsByTableCol[tableCol]; !ok {
+			leftColsByTableCol[tableCol] = op.LeftJoinKeys[i].Index
+		}
+	}
+	lookupCols, keyTypes := lookupKeyCols(tableInfo, tableInfo.PrimaryKeyCols, leftColsByTableCol)
+	if lookupByPK && len(lookupCols) == len(tableInfo.PrimaryKeyCols) {
+		lookup := &lookupInfo{keyTypes: keyTypes}
+		var shardedByKey bool
+		switch tbl.(type) {
+		case *common.SourceInfo, *common.UserTableInfo:
+			shardedByKey = true
+		}
+		if !shardedByKey || len(keyTypes) != 1 || keyTypes[0].Type == common.TypeDecimal {
+			// We only know which shard owns the key for sources and user tables. We don't currently support optimised
+			// lookups for keys of type Decimal.
+			return lookup, lookupCols, nil, nil
+		}
+		keyShard := func(key *common.Row) (uint64, error) {
+			k, err := common.EncodeKeyCols(key, []int{0}, keyTypes, nil)
+			if err != nil {
+				return 0, err
+			}
+			return p.shrder.CalculateShard(sharder.ShardTypeHash, k)
+		}
+		return lookup, lookupCols, keyShard, nil
+	}
+	// Otherwise we use the secondary index with the longest prefix of columns that are joined on
+	indexNames := make([]string, 0, len(tableInfo.IndexInfos))
+	for name := range tableInfo.IndexInfos {
+		indexNames = append(indexNames, name)
+	}
+	sort.Strings(indexNames)
+	var lookup *lookupInfo
+	lookupCols = nil
+	for _, name := range indexNames {
+		indexInfo := tableInfo.IndexInfos[name]
+		indexLookupCols, indexKeyTypes := lookupKeyCols(tableInfo, indexInfo.IndexCols, leftColsByTableCol)
+		if len(indexLookupCols) > len(lookupCols) {
+			lookup = &lookupInfo{indexInfo: indexInfo, keyTypes: indexKeyTypes}
+			lookupCols = indexLookupCols
+		}
+	}
+	if lookup == nil {
+		return nil, nil, nil, nil
+	}
+	return lookup, lookupCols, nil, nil
+}
+
+func isFullRange(ranges []*ranger.Range) bool {
+	return len(ranges) == 0 || (len(ranges) == 1 && ranges[0].IsFullRange())
+}
+
+// lookupKeyCols returns the join columns on the left for the longest prefix of the key columns that are joined on, and
+// the types of those key columns
+func lookupKeyCols(tableInfo *common.TableInfo, keyCols []int, leftColsByTableCol map[int]int) ([]int, []common.ColumnType) {
+	var lookupCols []int
+	var keyTypes []common.ColumnType
+	for _, keyCol := range keyCols {
+		leftCol, ok := leftColsByTableCol[keyCol]
+		if !ok {
+			break
+		}
+		lookupCols = append(lookupCols, leftCol)
+		keyTypes = append(keyTypes, tableInfo.ColumnTypes[keyCol])
+	}
+	return lookupCols, keyTypes
+}
+
+// containsJoin returns whether there is a join below a plan
+func containsJoin(plan planner.PhysicalPlan) bool {
+	for _, child := range plan.Children() {
+		if _, ok := child.(*planner.PhysicalHashJoin); ok || containsJoin(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// findTableScan returns the table scan below a plan, if there is one
+func findTableScan(plan planner.PhysicalPlan) *planner.PhysicalTableScan {
+	if scan, ok := plan.(*planner.PhysicalTableScan); ok {
+		return scan
+	}
+	for _, child := range plan.Children() {
+		if scan := findTableScan(child); scan != nil {
+			return scan
+		}
+	}
+	return nil
+}
+
 func (p *Engine) getPointGetShardID(session *sess.Session, ranges []*ranger.Range, tableName string) (int64, error) {
 	var pointGetShardID int64 = -1
 	if len(ranges) == 1 {
@@ -165,6 +544,20 @@ func (p *Engine) createPullTableScan(schema *common.Schema, tableName string, ra
 	return exec.NewPullTableScan(tbl.GetTableInfo(), colIndexes, p.cluster, shardID, scanRanges)
 }
 
+func (p *Engine) createPullLookupScan(session *sess.Session, tableName string, columns []*model.ColumnInfo,
+	lookup *lookupInfo) (exec.PullExecutor, error) {
+	tbl, ok := session.Schema.GetTable(tableName)
+	if !ok {
+		return nil, errors.Errorf("unknown source or materialized view %s", tableName)
+	}
+	var colIndexes []int
+	for _, col := range columns {
+		colIndexes = append(colIndexes, col.Offset)
+	}
+	return exec.NewPullLookupScan(tbl.GetTableInfo(), lookup.indexInfo, colIndexes, p.cluster, session.QueryInfo.ShardID,
+		lookup.keyTypes, session.QueryInfo.LookupKeys)
+}
+
 func (p *Engine) createPullIndexScan(schema *common.Schema, tableName string, indexName string, ranges []*ranger.Range,
 	columnInfos []*model.ColumnInfo, shardID uint64) (exec.PullExecutor, error) {
 	tbl, ok := schema.GetTable(tableName)
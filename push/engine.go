@@ -38,7 +38,10 @@ type Engine struct {
 	started                   bool
 	schedulers                map[uint64]*sched.ShardScheduler
 	sources                   map[uint64]*source.Source
+	userTables                map[uint64]*UserTable
 	materializedViews         map[uint64]*MaterializedView
+	sinks                     map[uint64]*exec.SinkExecutor
+	changeLogs                map[uint64]*changeLog // The changes to materialized views which are subscribed to
 	remoteConsumers           sync.Map
 	localLeaderShards         []uint64
 	cluster                   cluster.Cluster
@@ -52,6 +55,8 @@ type Engine struct {
 	processBatchTimeHistogram metrics.Observer
 	globalRateLimiter         ratelimit.Limiter
 	failInject                failinject.Injector
+	watermarks                sync.Map // The watermarks received on each shard, see receivedWatermarks
+	retentionTimer            *time.Timer
 }
 
 var (
@@ -113,6 +118,7 @@ func (p *Engine) Start() error {
 	}
 
 	p.started = true
+	p.scheduleRetentionCheck()
 	return nil
 }
 
@@ -129,6 +135,9 @@ func (p *Engine) Stop() error {
 		return nil
 	}
 	p.readyToReceive.Set(false)
+	if p.retentionTimer != nil {
+		p.retentionTimer.Stop()
+	}
 	for _, src := range p.sources {
 		if err := src.Stop(); err != nil {
 			return errors.WithStack(err)
@@ -137,6 +146,14 @@ func (p *Engine) Stop() error {
 	for _, sh := range p.schedulers {
 		sh.Stop()
 	}
+	for _, sink := range p.sinks {
+		if err := sink.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, mv := range p.materializedViews {
+		p.removeChangeLog(mv)
+	}
 	p.createMaps() // Clear the internal state
 	p.started = false
 	return nil
@@ -206,10 +223,11 @@ func (p *Engine) GetMaterializedView(mvID uint64) (*MaterializedView, error) {
 func (p *Engine) RemoveMV(mvID uint64) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	_, ok := p.materializedViews[mvID]
+	mv, ok := p.materializedViews[mvID]
 	if !ok {
 		return errors.Errorf("cannot find materialized view with id %d", mvID)
 	}
+	p.removeChangeLog(mv)
 	delete(p.materializedViews, mvID)
 	return nil
 }
@@ -264,8 +282,15 @@ func (p *Engine) RemoveIndex(indexInfo *common.IndexInfo) error {
 }
 
 func (p *Engine) getTableExecutorForIndex(indexInfo *common.IndexInfo) (*exec.TableExecutor, error) {
-	// Find the table executor for the source / mv that we are creating the index on
+	// Find the table executor for the source / table / mv that we are creating the index on
 	var te *exec.TableExecutor
+	if tableInfo, ok := p.meta.GetUserTable(indexInfo.SchemaName, indexInfo.TableName); ok {
+		userTable, err := p.GetUserTable(tableInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+		return userTable.TableExecutor(), nil
+	}
 	srcInfo, ok := p.meta.GetSource(indexInfo.SchemaName, indexInfo.TableName)
 	if !ok {
 		mvInfo, ok := p.meta.GetMaterializedView(indexInfo.SchemaName, indexInfo.TableName)
@@ -346,6 +371,12 @@ func (p *Engine) removeScheduler(shardID uint64) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	delete(p.schedulers, shardID)
+	p.watermarks.Delete(shardID)
+}
+
+func (p *Engine) receivedWatermarks(shardID uint64) *receivedWatermarks {
+	watermarks, _ := p.watermarks.LoadOrStore(shardID, newReceivedWatermarks(shardID))
+	return watermarks.(*receivedWatermarks) //nolint:forcetypeassert
 }
 
 type receiveBatch struct {
@@ -405,6 +436,8 @@ func (p *Engine) HandleReceivedRows(receivingShardID uint64) error {
 func (p *Engine) processReceiveBatch(batch *receiveBatch) error {
 	ctx := exec.NewExecutionContext(batch.writeBatch, true)
 	ctx.BatchSequence = batch.batchSequence
+	watermarks := p.receivedWatermarks(batch.writeBatch.ShardID)
+	now := time.Now()
 	for entityID, rawRows := range batch.rawRows {
 		rcVal, ok := p.remoteConsumers.Load(entityID)
 		if !ok {
@@ -427,9 +460,9 @@ func (p *Engine) processReceiveBatch(batch *receiveBatch) error {
 
 		remoteConsumer := rcVal.(*RemoteConsumer) //nolint:forcetypeassert
 		rows := remoteConsumer.RowsFactory.NewRows(len(rawRows))
-		entries := make([]exec.RowsEntry, len(rawRows))
+		entries := make([]exec.RowsEntry, 0, len(rawRows))
 		rc := 0
-		for i, row := range rawRows {
+		for _, row := range rawRows {
 			lpvb, _ := common.ReadUint32FromBufferLE(row, 0)
 			pi := -1
 			if lpvb != 0 {
@@ -442,16 +475,38 @@ func (p *Engine) processReceiveBatch(batch *receiveBatch) error {
 			}
 			lcvb, _ := common.ReadUint32FromBufferLE(row, int(4+lpvb))
 			ci := -1
+			currEnd := 8 + lpvb + lcvb
 			if lcvb != 0 {
-				currBytes := row[8+lpvb:]
+				currBytes := row[8+lpvb : currEnd]
 				if err := common.DecodeRow(currBytes, remoteConsumer.ColTypes, rows); err != nil {
 					return errors.WithStack(err)
 				}
 				ci = rc
 				rc++
 			}
-			entries[i] = exec.NewRowsEntry(pi, ci)
+			if pi != -1 || ci != -1 {
+				// A watermark heartbeat has no rows, see util.EncodeWatermarkHeartbeat
+				entries = append(entries, exec.NewRowsEntry(pi, ci))
+			}
+			if len(row) > int(currEnd) {
+				// The row was sent with a watermark, see util.AppendWatermark
+				watermark, off, err := common.ReadTimestampFromBuffer(row, int(currEnd), 6)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				originatorID, off := common.ReadUint64FromBufferLE(row, off)
+				if len(row) > off && row[off] == 1 {
+					watermarks.remove(entityID, originatorID)
+				} else {
+					watermarks.update(entityID, originatorID, watermark, now)
+				}
+			}
 		}
+		watermark, err := watermarks.watermark(entityID, now, p.cluster, batch.writeBatch)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		ctx.Watermark = watermark
 		rowsBatch := exec.NewRowsBatch(rows, entries)
 		if err := remoteConsumer.RowsHandler.HandleRemoteRows(rowsBatch, ctx); err != nil {
 			return errors.WithStack(err)
@@ -599,10 +654,38 @@ func (p *Engine) CreateSource(sourceInfo *common.SourceInfo) (*source.Source, er
 	return src, nil
 }
 
+// AlterSource replaces the definition of a stopped source with an altered one, e.g. with an added column
+func (p *Engine) AlterSource(sourceInfo *common.SourceInfo) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	src, ok := p.sources[sourceInfo.ID]
+	if !ok {
+		return errors.Errorf("no such source %d", sourceInfo.ID)
+	}
+	if err := src.Alter(sourceInfo); err != nil {
+		return errors.WithStack(err)
+	}
+	// The table executor has any added column before rows are decoded with it, so any rows which were decoded without
+	// it can still be handled
+	colTypes := sourceInfo.ColumnTypes
+	rc := &RemoteConsumer{
+		RowsFactory: common.NewRowsFactory(colTypes),
+		ColTypes:    colTypes,
+		RowsHandler: src.TableExecutor(),
+	}
+	p.remoteConsumers.Store(sourceInfo.ID, rc)
+	return nil
+}
+
 func (p *Engine) createMaps() {
 	p.remoteConsumers = sync.Map{}
+	p.watermarks = sync.Map{}
 	p.sources = make(map[uint64]*source.Source)
+	p.userTables = make(map[uint64]*UserTable)
 	p.materializedViews = make(map[uint64]*MaterializedView)
+	p.sinks = make(map[uint64]*exec.SinkExecutor)
+	p.changeLogs = make(map[uint64]*changeLog)
 	p.schedulers = make(map[uint64]*sched.ShardScheduler)
 }
 
@@ -628,7 +711,8 @@ func (p *Engine) IsEmpty() bool {
 		numRecs++
 		return true
 	})
-	return len(p.sources) == 0 && len(p.materializedViews) == 0 && numRecs == 0
+	return len(p.sources) == 0 && len(p.userTables) == 0 && len(p.materializedViews) == 0 && len(p.sinks) == 0 &&
+		numRecs == 0
 }
 
 func (p *Engine) Limit() {
@@ -1,6 +1,8 @@
 package exec
 
 import (
+	"bytes"
+
 	"github.com/squareup/pranadb/aggfuncs"
 	"github.com/squareup/pranadb/cluster"
 	"github.com/squareup/pranadb/common"
@@ -8,6 +10,7 @@ import (
 	"github.com/squareup/pranadb/push/util"
 	"github.com/squareup/pranadb/sharder"
 	"github.com/squareup/pranadb/table"
+	"github.com/squareup/pranadb/tidb/expression"
 )
 
 type Aggregator struct {
@@ -18,51 +21,132 @@ type Aggregator struct {
 	groupByCols         []int // The group by column indexes in the child
 	storage             cluster.Cluster
 	sharder             *sharder.Sharder
+	window              *AggregatorWindow
+	storedColTypes      []common.ColumnType // the types of the rows in the aggregate tables, see extraStateCols
+	storedRowsFactory   *common.RowsFactory
+	rowCols             []bool // the columns of the stored rows which aren't extra state
+	// extraStateCols holds, for each agg function that requires extra state, the index of the column after the
+	// aggregate columns that the extra state is stored in, or -1 for the other functions. The extra state is stored in
+	// the aggregate tables and sent to the full aggregation, but it isn't sent on to the parent.
+	extraStateCols []int
+	// ValuesTableInfo is the table that holds the value counts of the functions that require them, see
+	// storedValueCounts. It's nil if none of the functions do.
+	ValuesTableInfo *common.TableInfo
 }
 
-type AggregateFunctionInfo struct {
-	FuncType   aggfuncs.AggFunctionType
-	Distinct   bool
-	ArgExpr    *common.Expression
-	ReturnType common.ColumnType
+// AggregatorWindow describes the window of a windowed aggregation, e.g. GROUP BY TUMBLE(event_time, INTERVAL 1 MINUTE).
+// The child provides the start of the latest window each row falls in, and the aggregation adds the row to every window
+// that contains it. The window start and end are key columns of the aggregate tables.
+//
+// If the window is over the event time column of a source, a window closes on a shard once the watermark of the source
+// passes the end of the window. The watermark is sent to every shard, including those that receive no rows, so a
+// window closes on all the shards together. When that happens the partial aggregation for the window is deleted from
+// the shard, and any later rows for the window are dropped. The full aggregation counts the shards which still have
+// the window open, so a window has closed when the count is zero. Windows over any other column never close, as
+// there's no watermark to say that all the rows for them have arrived.
+//
+// The latest watermark seen on a shard, the window clock, is stored in the partial aggregate table so that windows
+// stay closed after a restart or failover.
+type AggregatorWindow struct {
+	Spec *expression.WindowSpec
+	// WindowCol is the column in the child that holds the start of the latest window that the row falls in
+	WindowCol int
+	// WindowStartCol, WindowEndCol and OpenCol are invisible columns added to the output. OpenCol holds the number of
+	// shards that have the window open.
+	WindowStartCol int
+	WindowEndCol   int
+	OpenCol        int
+	// EventTimeCols are the output columns that select the event time column itself. In a windowed aggregation these
+	// take the window start, so that window functions in the select list give the window of the group.
+	EventTimeCols []int
+	// UseWatermark is true if the window is over the event time column of a source, so windows are closed by the
+	// watermark of the source
+	UseWatermark bool
 }
 
 type aggStateHolder struct {
 	aggState        *aggfuncs.AggState
 	initialRowBytes []byte
 	keyBytes        []byte
+	shardingKey     []byte // if set, chooses the shard the partial aggregation is sent to instead of the key
 	rowBytes        []byte
 	initialRow      *common.Row
 	row             *common.Row
+	closed          bool // the window of a partial aggregation has closed, so it is deleted
 }
 
-func NewAggregator(pkCols []int, aggFunctions []*AggregateFunctionInfo, partialAggTableInfo *common.TableInfo,
-	fullAggTableInfo *common.TableInfo, groupByCols []int, storage cluster.Cluster, sharder *sharder.Sharder) (*Aggregator, error) {
+// NewAggregator creates an Aggregator. hiddenCols are output columns that aren't visible to the parent, they must come
+// after the visible columns.
+func NewAggregator(pkCols []int, aggFunctions []*aggfuncs.AggregateFunctionInfo, partialAggTableInfo *common.TableInfo,
+	fullAggTableInfo *common.TableInfo, valuesTableInfo *common.TableInfo, groupByCols []int, hiddenCols []int,
+	window *AggregatorWindow, storage cluster.Cluster, sharder *sharder.Sharder) (*Aggregator, error) {
 
 	colTypes := make([]common.ColumnType, len(aggFunctions))
 	for i, aggFunc := range aggFunctions {
 		colTypes[i] = aggFunc.ReturnType
 	}
-	partialAggTableInfo.ColumnTypes = colTypes
-	fullAggTableInfo.ColumnTypes = colTypes
 	rf := common.NewRowsFactory(colTypes)
 	pushBase := pushExecutorBase{
 		colTypes:    colTypes,
 		keyCols:     pkCols,
 		rowsFactory: rf,
 	}
-	aggFuncs, err := createAggFunctions(aggFunctions, colTypes)
+	if window != nil {
+		// The window columns are added by the aggregation and come after the ones the planner knows about
+		hiddenCols = append(hiddenCols, window.WindowStartCol, window.WindowEndCol, window.OpenCol)
+	}
+	if len(hiddenCols) > 0 {
+		pushBase.colsVisible = make([]bool, len(colTypes))
+		for i := range colTypes {
+			pushBase.colsVisible[i] = true
+		}
+		for _, col := range hiddenCols {
+			pushBase.colsVisible[col] = false
+		}
+	}
+	aggFuncs, err := aggfuncs.CreateAggregateFunctions(aggFunctions)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	storedColTypes := colTypes
+	var rowCols []bool
+	var extraStateCols []int
+	for i, aggFunc := range aggFuncs {
+		if !aggFunc.RequiresExtraState() {
+			continue
+		}
+		if extraStateCols == nil {
+			storedColTypes = append([]common.ColumnType{}, colTypes...)
+			extraStateCols = make([]int, len(aggFuncs))
+			for j := range extraStateCols {
+				extraStateCols[j] = -1
+			}
+		}
+		extraStateCols[i] = len(storedColTypes)
+		storedColTypes = append(storedColTypes, common.VarcharColumnType)
+	}
+	if extraStateCols != nil {
+		rowCols = make([]bool, len(storedColTypes))
+		for i := range colTypes {
+			rowCols[i] = true
+		}
+	}
+	partialAggTableInfo.ColumnTypes = storedColTypes
+	fullAggTableInfo.ColumnTypes = storedColTypes
 	return &Aggregator{
 		pushExecutorBase:    pushBase,
 		aggFuncs:            aggFuncs,
 		PartialAggTableInfo: partialAggTableInfo,
 		FullAggTableInfo:    fullAggTableInfo,
+		ValuesTableInfo:     valuesTableInfo,
 		groupByCols:         groupByCols,
 		storage:             storage,
 		sharder:             sharder,
+		window:              window,
+		storedColTypes:      storedColTypes,
+		storedRowsFactory:   common.NewRowsFactory(storedColTypes),
+		rowCols:             rowCols,
+		extraStateCols:      extraStateCols,
 	}, nil
 }
 
@@ -72,16 +156,35 @@ func (a *Aggregator) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) erro
 	stateHolders := make(map[string]*aggStateHolder)
 	numRows := rowsBatch.Len()
 	readRows := a.rowsFactory.NewRows(numRows)
-	for i := 0; i < numRows; i++ {
+	if a.window != nil {
+		if err := a.calcWindowedPartialAggregations(rowsBatch, readRows, stateHolders, ctx); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < numRows && a.window == nil; i++ {
 		prevRow := rowsBatch.PreviousRow(i)
 		currentRow := rowsBatch.CurrentRow(i)
-		if err := a.calcPartialAggregations(prevRow, currentRow, readRows, stateHolders, ctx.WriteBatch.ShardID); err != nil {
+		if prevRow != nil && currentRow != nil {
+			sameGroup, err := a.inSameGroup(prevRow, currentRow)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if !sameGroup {
+				// The row has moved from one group to another, e.g. the joined row changed, so we remove it from the
+				// previous group and add it to the current one
+				if err := a.calcPartialAggregations(prevRow, nil, readRows, stateHolders, ctx); err != nil {
+					return err
+				}
+				prevRow = nil
+			}
+		}
+		if err := a.calcPartialAggregations(prevRow, currentRow, readRows, stateHolders, ctx); err != nil {
 			return err
 		}
 	}
 
 	// Store the results locally
-	if err := a.storeAggregateResults(stateHolders, ctx.WriteBatch); err != nil {
+	if err := a.storeAggregateResults(stateHolders, ctx); err != nil {
 		return errors.WithStack(err)
 	}
 
@@ -89,7 +192,11 @@ func (a *Aggregator) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) erro
 	for _, stateHolder := range stateHolders {
 		if stateHolder.aggState.IsChanged() {
 			// We ignore the first 16 bytes as this is shard-id|table-id
-			remoteShardID, err := a.sharder.CalculateShard(sharder.ShardTypeHash, stateHolder.keyBytes[16:])
+			shardingKey := stateHolder.keyBytes[16:]
+			if stateHolder.shardingKey != nil {
+				shardingKey = stateHolder.shardingKey
+			}
+			remoteShardID, err := a.sharder.CalculateShard(sharder.ShardTypeHash, shardingKey)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -97,7 +204,9 @@ func (a *Aggregator) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) erro
 			forwardKey := util.EncodeKeyForForwardAggregation(ctx.EnableDuplicateDetection, a.PartialAggTableInfo.ID,
 				ctx.WriteBatch.ShardID, ctx.BatchSequence, a.FullAggTableInfo.ID)
 			value := util.EncodePrevAndCurrentRow(stateHolder.initialRowBytes, stateHolder.rowBytes)
-			ctx.AddToForwardBatch(remoteShardID, forwardKey, value)
+			if err := ctx.AddToForwardBatch(remoteShardID, forwardKey, value); err != nil {
+				return errors.WithStack(err)
+			}
 		}
 	}
 	return nil
@@ -113,13 +222,13 @@ func (a *Aggregator) HandleRemoteRows(rowsBatch RowsBatch, ctx *ExecutionContext
 	for i := 0; i < numRows; i++ {
 		prevRow := rowsBatch.PreviousRow(i)
 		currRow := rowsBatch.CurrentRow(i)
-		if err := a.calcFullAggregation(prevRow, currRow, readRows, stateHolders, ctx.WriteBatch.ShardID, numCols); err != nil {
+		if err := a.calcFullAggregation(prevRow, currRow, readRows, stateHolders, ctx, numCols); err != nil {
 			return errors.WithStack(err)
 		}
 	}
 
 	// Store the results
-	if err := a.storeAggregateResults(stateHolders, ctx.WriteBatch); err != nil {
+	if err := a.storeAggregateResults(stateHolders, ctx); err != nil {
 		return errors.WithStack(err)
 	}
 
@@ -151,42 +260,241 @@ func (a *Aggregator) HandleRemoteRows(rowsBatch RowsBatch, ctx *ExecutionContext
 	return a.parent.HandleRows(NewRowsBatch(resultRows, entries), ctx)
 }
 
-func (a *Aggregator) calcPartialAggregations(prevRow *common.Row, currRow *common.Row, readRows *common.Rows, aggStateHolders map[string]*aggStateHolder, shardID uint64) error {
+func (a *Aggregator) calcPartialAggregations(prevRow *common.Row, currRow *common.Row, readRows *common.Rows, aggStateHolders map[string]*aggStateHolder, ctx *ExecutionContext) error {
 
 	// Create the key
-	keyBytes, err := a.createKeyFromPrevOrCurrRow(prevRow, currRow, shardID, a.GetChildren()[0].ColTypes(), a.groupByCols, a.PartialAggTableInfo.ID)
+	keyBytes, err := a.createKeyFromPrevOrCurrRow(prevRow, currRow, ctx.WriteBatch.ShardID, a.GetChildren()[0].ColTypes(), a.groupByCols, a.PartialAggTableInfo.ID)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
 	// Lookup existing aggregate state
-	stateHolder, err := a.loadAggregateState(keyBytes, readRows, aggStateHolders)
+	stateHolder, err := a.loadAggregateState(keyBytes, readRows, aggStateHolders, ctx)
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	if a.FullAggTableInfo.KeyNullMarkers {
+		// The rows of the materialized view are on the shard that owns the key without null markers, the same as the
+		// rows of a source, so point gets can find the shard from the key
+		row := currRow
+		if row == nil {
+			row = prevRow
+		}
+		stateHolder.shardingKey, err = common.EncodeKeyCols(row, a.groupByCols, a.GetChildren()[0].ColTypes(), nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
 
 	// Evaluate the agg functions on the state
 	if prevRow != nil {
-		if err := a.evaluateAggFunctions(stateHolder.aggState, prevRow, true); err != nil {
+		if err := aggfuncs.EvaluateAggregateFunctions(a.aggFuncs, stateHolder.aggState, prevRow, true); err != nil {
 			return err
 		}
 	}
 	if currRow != nil {
-		if err := a.evaluateAggFunctions(stateHolder.aggState, currRow, false); err != nil {
+		if err := aggfuncs.EvaluateAggregateFunctions(a.aggFuncs, stateHolder.aggState, currRow, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Aggregator) calcWindowedPartialAggregations(rowsBatch RowsBatch, readRows *common.Rows,
+	aggStateHolders map[string]*aggStateHolder, ctx *ExecutionContext) error {
+	clock, clockSet, err := a.windowClock(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	advanced := false
+	if a.window.UseWatermark && ctx.Watermark != nil && (!clockSet || ctx.Watermark.Compare(clock) > 0) {
+		clock, clockSet, advanced = *ctx.Watermark, true, true
+	}
+	rowsClockSet := clockSet
+	if ctx.Filling {
+		// Rows are replayed in key order when filling, so no windows are closed until the fill is complete
+		rowsClockSet = false
+	}
+	for i := 0; i < rowsBatch.Len(); i++ {
+		// A row can move from one window to another so we always remove the previous row from its windows and add
+		// the current row to its windows
+		if prevRow := rowsBatch.PreviousRow(i); prevRow != nil {
+			if err := a.calcWindowAggregations(prevRow, true, clock, rowsClockSet, readRows, aggStateHolders, ctx); err != nil {
+				return err
+			}
+		}
+		if currRow := rowsBatch.CurrentRow(i); currRow != nil {
+			if err := a.calcWindowAggregations(currRow, false, clock, rowsClockSet, readRows, aggStateHolders, ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if !advanced {
+		return nil
+	}
+	if err := a.setWindowClock(clock, ctx); err != nil {
+		return errors.WithStack(err)
+	}
+	if ctx.Filling {
+		return nil
+	}
+	return a.closeWindows(clock, readRows, aggStateHolders, ctx)
+}
+
+func (a *Aggregator) calcWindowAggregations(row *common.Row, reverse bool, clock common.Timestamp, clockSet bool,
+	readRows *common.Rows, aggStateHolders map[string]*aggStateHolder, ctx *ExecutionContext) error {
+	if row.IsNull(a.window.WindowCol) {
+		// A row with no event time isn't in any window
+		return nil
+	}
+	starts, err := a.window.Spec.WindowStarts(row.GetTimestamp(a.window.WindowCol))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, start := range starts {
+		end, err := a.window.Spec.WindowEnd(start)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if clockSet && end.Compare(clock) <= 0 {
+			// The window has already closed on this shard, so the row is too late for it
+			continue
+		}
+		keyBytes, err := a.createWindowKey(row, start, end, ctx.WriteBatch.ShardID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		stateHolder, err := a.loadAggregateState(keyBytes, readRows, aggStateHolders, ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		aggState := stateHolder.aggState
+		if err := aggfuncs.EvaluateAggregateFunctions(a.aggFuncs, aggState, row, reverse); err != nil {
 			return err
 		}
+		for _, col := range append([]int{a.window.WindowStartCol}, a.window.EventTimeCols...) {
+			if !aggState.IsSet(col) {
+				if err := aggState.SetTimestamp(col, start); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+		if !aggState.IsSet(a.window.WindowEndCol) {
+			if err := aggState.SetTimestamp(a.window.WindowEndCol, end); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		aggState.SetInt64(a.window.OpenCol, 1)
+	}
+	return nil
+}
+
+// closeWindows closes the windows on this shard that end at or before the clock. The partial aggregations for the
+// windows are deleted and sent to the full aggregation for the last time, with the window no longer open.
+func (a *Aggregator) closeWindows(clock common.Timestamp, readRows *common.Rows, aggStateHolders map[string]*aggStateHolder,
+	ctx *ExecutionContext) error {
+	// The window end comes first in the key, so we can find the windows in storage that have closed with a scan
+	startPrefix := table.EncodeTableKeyPrefix(a.PartialAggTableInfo.ID, ctx.WriteBatch.ShardID, 24)
+	endPrefix, err := common.KeyEncodeTimestamp(common.CopyByteSlice(startPrefix), clock)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// The window clock is stored at the start prefix itself, so we start the scan just after it
+	kvPairs, err := a.storage.LocalScan(append(startPrefix, 0), common.IncrementBytesBigEndian(endPrefix), -1)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, kvPair := range kvPairs {
+		if _, err := a.loadAggregateState(kvPair.Key, readRows, aggStateHolders, ctx); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, stateHolder := range aggStateHolders {
+		aggState := stateHolder.aggState
+		if !aggState.IsSet(a.window.WindowEndCol) {
+			// Deleted earlier in this batch
+			continue
+		}
+		end, err := aggState.GetTimestamp(a.window.WindowEndCol)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if end.Compare(clock) <= 0 {
+			aggState.SetInt64(a.window.OpenCol, 0)
+			stateHolder.closed = true
+			if a.ValuesTableInfo != nil {
+				if err := aggState.GetValueCounts().(*storedValueCounts).deleteAll(); err != nil { //nolint:forcetypeassert
+					return errors.WithStack(err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// windowClock returns the latest watermark seen on the shard. It's stored in the partial aggregate
+// table with just the table prefix as the key, which comes before the keys of all the windows.
+func (a *Aggregator) windowClock(ctx *ExecutionContext) (common.Timestamp, bool, error) {
+	key := a.windowClockKey(ctx.WriteBatch.ShardID)
+	// It might have been set earlier in this batch, e.g. by another input of a union
+	value, ok := ctx.pendingAggRows[common.ByteSliceToStringZeroCopy(key)]
+	if !ok {
+		var err error
+		value, err = a.storage.LocalGet(key)
+		if err != nil {
+			return common.Timestamp{}, false, errors.WithStack(err)
+		}
+	}
+	if value == nil {
+		return common.Timestamp{}, false, nil
+	}
+	clock, _, err := common.ReadTimestampFromBuffer(value, 0, 6)
+	if err != nil {
+		return common.Timestamp{}, false, errors.WithStack(err)
+	}
+	return clock, true, nil
+}
+
+func (a *Aggregator) setWindowClock(clock common.Timestamp, ctx *ExecutionContext) error {
+	value, err := common.AppendTimestampToBuffer(nil, clock)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	key := a.windowClockKey(ctx.WriteBatch.ShardID)
+	ctx.WriteBatch.AddPut(key, value)
+	if ctx.pendingAggRows == nil {
+		ctx.pendingAggRows = make(map[string][]byte)
 	}
+	ctx.pendingAggRows[string(key)] = value
 	return nil
 }
 
+func (a *Aggregator) windowClockKey(shardID uint64) []byte {
+	return table.EncodeTableKeyPrefix(a.PartialAggTableInfo.ID, shardID, 16)
+}
+
+func (a *Aggregator) createWindowKey(row *common.Row, start common.Timestamp, end common.Timestamp, shardID uint64) ([]byte, error) {
+	keyBytes := table.EncodeTableKeyPrefix(a.PartialAggTableInfo.ID, shardID, 41)
+	keyBytes, err := common.KeyEncodeTimestamp(keyBytes, end)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	keyBytes, err = common.KeyEncodeTimestamp(keyBytes, start)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return common.EncodeNullableKeyCols(row, a.groupByCols, a.GetChildren()[0].ColTypes(), a.FullAggTableInfo.KeyNullMarkers,
+		keyBytes)
+}
+
 func (a *Aggregator) calcFullAggregation(prevRow *common.Row, currRow *common.Row, readRows *common.Rows,
-	stateHolders map[string]*aggStateHolder, shardID uint64, numCols int) error {
+	stateHolders map[string]*aggStateHolder, ctx *ExecutionContext, numCols int) error {
 
-	key, err := a.createKeyFromPrevOrCurrRow(prevRow, currRow, shardID, a.colTypes, a.keyCols, a.FullAggTableInfo.ID)
+	key, err := a.createKeyFromPrevOrCurrRow(prevRow, currRow, ctx.WriteBatch.ShardID, a.colTypes, a.keyCols, a.FullAggTableInfo.ID)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	stateHolder, err := a.loadAggregateState(key, readRows, stateHolders)
+	stateHolder, err := a.loadAggregateState(key, readRows, stateHolders, ctx)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -195,69 +503,47 @@ func (a *Aggregator) calcFullAggregation(prevRow *common.Row, currRow *common.Ro
 	var prevMergeState *aggfuncs.AggState
 	if prevRow != nil {
 		prevMergeState = aggfuncs.NewAggState(numCols)
-		if err := a.initAggStateWithRow(prevRow, prevMergeState, numCols); err != nil {
+		if err := prevMergeState.InitWithRow(prevRow, a.colTypes); err != nil {
 			return errors.WithStack(err)
 		}
-		if err := a.mergeState(prevMergeState, currAggState, true); err != nil {
+		a.initExtraStateWithRow(prevRow, prevMergeState)
+		if err := aggfuncs.MergeAggregateFunctions(a.aggFuncs, prevMergeState, currAggState, true); err != nil {
 			return err
 		}
 	}
 	var currMergeState *aggfuncs.AggState
 	if currRow != nil {
 		currMergeState = aggfuncs.NewAggState(numCols)
-		if err := a.initAggStateWithRow(currRow, currMergeState, numCols); err != nil {
+		if err := currMergeState.InitWithRow(currRow, a.colTypes); err != nil {
 			return errors.WithStack(err)
 		}
-		if err := a.mergeState(currMergeState, currAggState, false); err != nil {
+		a.initExtraStateWithRow(currRow, currMergeState)
+		if err := aggfuncs.MergeAggregateFunctions(a.aggFuncs, currMergeState, currAggState, false); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (a *Aggregator) mergeState(toMerge *aggfuncs.AggState, currState *aggfuncs.AggState, reverse bool) error {
-	for index, aggFunc := range a.aggFuncs {
-		switch aggFunc.ValueType().Type {
-		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
-			if err := aggFunc.MergeInt64(toMerge, currState, index, reverse); err != nil {
-				return err
-			}
-		case common.TypeDecimal:
-			if err := aggFunc.MergeDecimal(toMerge, currState, index, reverse); err != nil {
-				return err
-			}
-		case common.TypeDouble:
-			if err := aggFunc.MergeFloat64(toMerge, currState, index, reverse); err != nil {
-				return err
-			}
-		case common.TypeVarchar:
-			if err := aggFunc.MergeString(toMerge, currState, index, reverse); err != nil {
-				return err
-			}
-		case common.TypeTimestamp:
-			if err := aggFunc.MergeTimestamp(toMerge, currState, index, reverse); err != nil {
-				return err
-			}
-		default:
-			return errors.Errorf("unexpected column type %d", aggFunc.ValueType())
-		}
-	}
-	return nil
-}
-
-func (a *Aggregator) loadAggregateState(keyBytes []byte, readRows *common.Rows, aggStateHolders map[string]*aggStateHolder) (*aggStateHolder, error) {
+func (a *Aggregator) loadAggregateState(keyBytes []byte, readRows *common.Rows, aggStateHolders map[string]*aggStateHolder,
+	ctx *ExecutionContext) (*aggStateHolder, error) {
 	sKey := common.ByteSliceToStringZeroCopy(keyBytes)
 	stateHolder, ok := aggStateHolders[sKey] // maybe already cached for this batch
 	if !ok {
-		// Nope - try and load the aggregate state from storage
-		rowBytes, err := a.storage.LocalGet(keyBytes)
-		if err != nil {
-			return nil, errors.WithStack(err)
+		// Nope - it might have been written earlier in this batch, e.g. by another input of a union or join, otherwise
+		// try and load the aggregate state from storage
+		rowBytes, ok := ctx.pendingAggRows[sKey]
+		if !ok {
+			var err error
+			rowBytes, err = a.storage.LocalGet(keyBytes)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
 		}
 		var currRow *common.Row
 		if rowBytes != nil {
 			// Doesn't matter if we use partial or full col types here as they are the same
-			if err := common.DecodeRow(rowBytes, a.PartialAggTableInfo.ColumnTypes, readRows); err != nil {
+			if err := common.DecodeRowWithIgnoredCols(rowBytes, a.storedColTypes, a.rowCols, readRows); err != nil {
 				return nil, errors.WithStack(err)
 			}
 			r := readRows.GetRow(readRows.RowCount() - 1)
@@ -265,14 +551,25 @@ func (a *Aggregator) loadAggregateState(keyBytes []byte, readRows *common.Rows,
 		}
 		numCols := len(a.colTypes)
 		aggState := aggfuncs.NewAggState(numCols)
+		if a.ValuesTableInfo != nil {
+			aggState.SetValueCounts(a.newStoredValueCounts(keyBytes, ctx))
+		}
 		stateHolder = &aggStateHolder{aggState: aggState}
 		stateHolder.keyBytes = keyBytes
 		aggStateHolders[sKey] = stateHolder
 		if currRow != nil {
 			// Initialise the agg state with the row from storage
-			if err := a.initAggStateWithRow(currRow, aggState, numCols); err != nil {
+			if err := aggState.InitWithRow(currRow, a.colTypes); err != nil {
 				return nil, errors.WithStack(err)
 			}
+			if a.extraStateCols != nil {
+				storedRows := a.storedRowsFactory.NewRows(1)
+				if err := common.DecodeRow(rowBytes, a.storedColTypes, storedRows); err != nil {
+					return nil, errors.WithStack(err)
+				}
+				storedRow := storedRows.GetRow(0)
+				a.initExtraStateWithRow(&storedRow, aggState)
+			}
 			stateHolder.initialRow = currRow
 		}
 
@@ -282,136 +579,68 @@ func (a *Aggregator) loadAggregateState(keyBytes []byte, readRows *common.Rows,
 	return stateHolder, nil
 }
 
-func (a *Aggregator) storeAggregateResults(stateHolders map[string]*aggStateHolder, writeBatch *cluster.WriteBatch) error {
+func (a *Aggregator) storeAggregateResults(stateHolders map[string]*aggStateHolder, ctx *ExecutionContext) error {
 	resultRows := a.rowsFactory.NewRows(len(stateHolders))
 	rowCount := 0
 	for _, stateHolder := range stateHolders {
 		aggState := stateHolder.aggState
 		if aggState.IsChanged() {
-			for i, colType := range a.colTypes {
-				if aggState.IsNull(i) {
-					resultRows.AppendNullToColumn(i)
-				} else {
-					switch colType.Type {
-					case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
-						resultRows.AppendInt64ToColumn(i, aggState.GetInt64(i))
-					case common.TypeDecimal:
-						resultRows.AppendDecimalToColumn(i, aggState.GetDecimal(i))
-					case common.TypeDouble:
-						resultRows.AppendFloat64ToColumn(i, aggState.GetFloat64(i))
-					case common.TypeVarchar:
-						str := aggState.GetString(i)
-						resultRows.AppendStringToColumn(i, str)
-					case common.TypeTimestamp:
-						ts, err := aggState.GetTimestamp(i)
-						if err != nil {
-							return errors.WithStack(err)
-						}
-						resultRows.AppendTimestampToColumn(i, ts)
-					default:
-						return errors.Errorf("unexpected column type %d", colType)
-					}
-					// TODO!! store extra data
-				}
+			if err := aggState.AppendToRows(resultRows, a.colTypes); err != nil {
+				return errors.WithStack(err)
 			}
 			row := resultRows.GetRow(rowCount)
 			stateHolder.row = &row
-			// Doesn't matter if we use partial or full col types here as they are the same
-			valueBuff, err := common.EncodeRow(&row, a.PartialAggTableInfo.ColumnTypes, make([]byte, 0))
+			rowBuff, err := common.EncodeRow(&row, a.colTypes, make([]byte, 0))
 			if err != nil {
 				return errors.WithStack(err)
 			}
-			writeBatch.AddPut(stateHolder.keyBytes, valueBuff)
-			stateHolder.rowBytes = valueBuff
+			valueBuff := a.appendExtraState(aggState, common.CopyByteSlice(rowBuff), true)
+			if ctx.pendingAggRows == nil {
+				ctx.pendingAggRows = make(map[string][]byte)
+			}
+			if stateHolder.closed {
+				if stateHolder.initialRowBytes != nil {
+					ctx.WriteBatch.AddDelete(stateHolder.keyBytes)
+				}
+				ctx.pendingAggRows[string(stateHolder.keyBytes)] = nil
+			} else {
+				ctx.WriteBatch.AddPut(stateHolder.keyBytes, valueBuff)
+				ctx.pendingAggRows[string(stateHolder.keyBytes)] = valueBuff
+			}
+			stateHolder.rowBytes = a.appendExtraState(aggState, rowBuff, false)
 			rowCount++
 		}
 	}
+	writePendingAggValues(ctx)
 	return nil
 }
 
-func (a *Aggregator) initAggStateWithRow(currRow *common.Row, aggState *aggfuncs.AggState, numCols int) error {
-	for i := 0; i < numCols; i++ {
-		colType := a.colTypes[i]
-		if currRow.IsNull(i) {
-			aggState.SetNull(i)
+// appendExtraState appends the extra state of the agg functions to an encoded row, as varchar columns. The extra state
+// of the functions that require value counts is the changes to their values, which are sent to the full aggregation
+// but not stored, as the values themselves are in the values table.
+func (a *Aggregator) appendExtraState(aggState *aggfuncs.AggState, buff []byte, stored bool) []byte {
+	for i, col := range a.extraStateCols {
+		if col == -1 {
+			continue
+		}
+		extraState := aggState.GetExtraState(i)
+		if extraState == nil || (stored && a.aggFuncs[i].RequiresValueCounts()) {
+			buff = append(buff, 0)
 		} else {
-			switch colType.Type {
-			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
-				aggState.SetInt64(i, currRow.GetInt64(i))
-			case common.TypeDecimal:
-				if err := aggState.SetDecimal(i, currRow.GetDecimal(i)); err != nil {
-					return errors.WithStack(err)
-				}
-			case common.TypeDouble:
-				aggState.SetFloat64(i, currRow.GetFloat64(i))
-			case common.TypeVarchar:
-				strVal := currRow.GetString(i)
-				aggState.SetString(i, strVal)
-			case common.TypeTimestamp:
-				if err := aggState.SetTimestamp(i, currRow.GetTimestamp(i)); err != nil {
-					return errors.WithStack(err)
-				}
-			default:
-				return errors.Errorf("unexpected column type %d", colType)
-			}
+			buff = append(buff, 1)
+			buff = common.AppendStringToBufferLE(buff, common.ByteSliceToStringZeroCopy(extraState))
 		}
 	}
-	return nil
+	return buff
 }
 
-func (a *Aggregator) evaluateAggFunctions(aggState *aggfuncs.AggState, row *common.Row, reverse bool) error {
-	for index, aggFunc := range a.aggFuncs {
-		switch aggFunc.ValueType().Type {
-		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
-			arg, null, err := aggFunc.ArgExpression().EvalInt64(row)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			err = aggFunc.EvalInt64(arg, null, aggState, index, reverse)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-		case common.TypeDecimal:
-			arg, null, err := aggFunc.ArgExpression().EvalDecimal(row)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			err = aggFunc.EvalDecimal(arg, null, aggState, index, reverse)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-		case common.TypeDouble:
-			arg, null, err := aggFunc.ArgExpression().EvalFloat64(row)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			err = aggFunc.EvalFloat64(arg, null, aggState, index, reverse)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-		case common.TypeVarchar:
-			arg, null, err := aggFunc.ArgExpression().EvalString(row)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			err = aggFunc.EvalString(arg, null, aggState, index, reverse)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-		case common.TypeTimestamp:
-			arg, null, err := aggFunc.ArgExpression().EvalTimestamp(row)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			err = aggFunc.EvalTimestamp(arg, null, aggState, index, reverse)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-		default:
-			return errors.Errorf("unexpected column type %d", aggFunc.ValueType())
+// initExtraStateWithRow sets the extra state of the agg functions from a row with the extra state columns
+func (a *Aggregator) initExtraStateWithRow(row *common.Row, aggState *aggfuncs.AggState) {
+	for i, col := range a.extraStateCols {
+		if col != -1 && !row.IsNull(col) {
+			aggState.SetExtraState(i, []byte(row.GetString(col)))
 		}
 	}
-	return nil
 }
 
 func (a *Aggregator) createKeyFromPrevOrCurrRow(prevRow *common.Row, currRow *common.Row, shardID uint64, colTypes []common.ColumnType, keyCols []int, tableID uint64) ([]byte, error) {
@@ -422,26 +651,25 @@ func (a *Aggregator) createKeyFromPrevOrCurrRow(prevRow *common.Row, currRow *co
 	} else {
 		row = prevRow
 	}
-	return common.EncodeKeyCols(row, keyCols, colTypes, keyBytes)
+	return common.EncodeNullableKeyCols(row, keyCols, colTypes, a.FullAggTableInfo.KeyNullMarkers, keyBytes)
 }
 
-func (a *Aggregator) createKey(row *common.Row, shardID uint64, colTypes []common.ColumnType, keyCols []int, tableID uint64) ([]byte, error) {
-	keyBytes := table.EncodeTableKeyPrefix(tableID, shardID, 25)
-	return common.EncodeKeyCols(row, keyCols, colTypes, keyBytes)
+func (a *Aggregator) inSameGroup(prevRow *common.Row, currRow *common.Row) (bool, error) {
+	colTypes := a.GetChildren()[0].ColTypes()
+	prevKey, err := common.EncodeNullableKeyCols(prevRow, a.groupByCols, colTypes, a.FullAggTableInfo.KeyNullMarkers, nil)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	currKey, err := common.EncodeNullableKeyCols(currRow, a.groupByCols, colTypes, a.FullAggTableInfo.KeyNullMarkers, nil)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return bytes.Equal(prevKey, currKey), nil
 }
 
-func createAggFunctions(aggFunctionInfos []*AggregateFunctionInfo, colTypes []common.ColumnType) ([]aggfuncs.AggregateFunction, error) {
-	aggFuncs := make([]aggfuncs.AggregateFunction, len(aggFunctionInfos))
-	for index, funcInfo := range aggFunctionInfos {
-		argExpr := funcInfo.ArgExpr
-		valueType := colTypes[index]
-		aggFunc, err := aggfuncs.NewAggregateFunction(argExpr, funcInfo.FuncType, valueType)
-		if err != nil {
-			return nil, errors.WithStack(err)
-		}
-		aggFuncs[index] = aggFunc
-	}
-	return aggFuncs, nil
+func (a *Aggregator) createKey(row *common.Row, shardID uint64, colTypes []common.ColumnType, keyCols []int, tableID uint64) ([]byte, error) {
+	keyBytes := table.EncodeTableKeyPrefix(tableID, shardID, 25)
+	return common.EncodeNullableKeyCols(row, keyCols, colTypes, a.FullAggTableInfo.KeyNullMarkers, keyBytes)
 }
 
 func (a *Aggregator) ReCalcSchemaFromChildren() error {
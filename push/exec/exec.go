@@ -3,6 +3,8 @@ package exec
 import (
 	"github.com/squareup/pranadb/cluster"
 	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/push/util"
 )
 
 type PushExecutor interface {
@@ -33,9 +35,34 @@ type ExecutionContext struct {
 	RemoteBatches            map[uint64]*cluster.WriteBatch
 	BatchSequence            uint64
 	EnableDuplicateDetection bool
-}
-
-func (e *ExecutionContext) AddToForwardBatch(shardID uint64, key []byte, value []byte) {
+	// Watermark is the event time before which no more rows are expected, if the rows come from a source with an event
+	// time column. It is forwarded along with any rows sent to other shards.
+	Watermark *common.Timestamp
+	// Filling is true when the rows are existing rows being replayed to fill a new materialized view. These are not in
+	// event time order.
+	Filling bool
+	// Rows written to join tables while handling this batch, keyed by join key prefix then by table key. A nil value
+	// means the row was deleted. These are not visible in storage until the batch is committed, but the other input of
+	// the join must see them.
+	pendingJoinRows map[string]map[string][]byte
+	// Aggregate state written while handling this batch, keyed by table key. An aggregation can be called more than
+	// once for the same batch, e.g. once for each input of a join.
+	pendingAggRows map[string][]byte
+	// Value counts written to the values tables of aggregations while handling this batch, keyed by the prefix of the
+	// group and function then by table key, see storedValueCounts. A nil value means the count was deleted.
+	pendingAggValues map[string]map[string][]byte
+	// The keys in pendingAggValues written by the current call of an aggregation, with their prefix
+	writtenAggValues map[string]string
+}
+
+func (e *ExecutionContext) AddToForwardBatch(shardID uint64, key []byte, value []byte) error {
+	if e.Watermark != nil {
+		var err error
+		value, err = util.AppendWatermark(value, *e.Watermark, e.WriteBatch.ShardID)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	if e.RemoteBatches == nil {
 		e.RemoteBatches = make(map[uint64]*cluster.WriteBatch)
 	}
@@ -45,6 +72,7 @@ func (e *ExecutionContext) AddToForwardBatch(shardID uint64, key []byte, value [
 		e.RemoteBatches[shardID] = remoteBatch
 	}
 	remoteBatch.AddPut(key, value)
+	return nil
 }
 
 type pushExecutorBase struct {
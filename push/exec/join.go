@@ -0,0 +1,710 @@
+package exec
+
+import (
+	"sort"
+
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/push/util"
+	"github.com/squareup/pranadb/sharder"
+	"github.com/squareup/pranadb/table"
+)
+
+type JoinType int
+
+const (
+	JoinTypeInner JoinType = iota
+	JoinTypeLeftOuter
+)
+
+// Join maintains an equi-join between two inputs incrementally.
+//
+// Rows arriving at either input are forwarded to the shard that owns their join key. There, each input keeps its rows
+// in its own join table keyed by join key then by the key of the input row. When a row changes on one input we update
+// its join table and look up the rows with the same join key in the join table of the other input to work out which
+// joined rows have changed.
+//
+// The output row comprises the columns of the left input, followed by the columns of the right input, followed by any
+// hidden key columns of the left then the right input. The key of the output row is the key of the left input row
+// plus the key of the right input row.
+//
+// For a left outer join, left rows without any matches are output with the columns of the right input set to null.
+// When a right row changes we count the matches each left row had before and after the change, so we can tell when a
+// left row gains its first match or loses its last one, and retract or re-emit the null padded row accordingly.
+type Join struct {
+	pushExecutorBase
+	JoinType        JoinType
+	LeftTableInfo   *common.TableInfo
+	RightTableInfo  *common.TableInfo
+	leftJoinCols    []int
+	rightJoinCols   []int
+	leftColCount    int // The number of columns from the left input excluding any hidden key columns
+	rightColCount   int // The number of columns from the right input excluding any hidden key columns
+	otherConditions []*common.Expression
+	storage         cluster.Cluster
+	sharder         *sharder.Sharder
+	leftInput       *JoinInput
+	rightInput      *JoinInput
+}
+
+func NewJoin(joinType JoinType, leftJoinCols []int, rightJoinCols []int, leftColCount int, rightColCount int,
+	otherConditions []*common.Expression, leftTableInfo *common.TableInfo, rightTableInfo *common.TableInfo,
+	storage cluster.Cluster, sharder *sharder.Sharder) (*Join, error) {
+	if len(leftJoinCols) == 0 || len(leftJoinCols) != len(rightJoinCols) {
+		return nil, errors.Error("join must have at least one equality condition")
+	}
+	return &Join{
+		pushExecutorBase: pushExecutorBase{},
+		JoinType:         joinType,
+		LeftTableInfo:    leftTableInfo,
+		RightTableInfo:   rightTableInfo,
+		leftJoinCols:     leftJoinCols,
+		rightJoinCols:    rightJoinCols,
+		leftColCount:     leftColCount,
+		rightColCount:    rightColCount,
+		otherConditions:  otherConditions,
+		storage:          storage,
+		sharder:          sharder,
+	}, nil
+}
+
+func (j *Join) ReCalcSchemaFromChildren() error {
+	if len(j.children) != 2 {
+		panic("join must have two children")
+	}
+	left := j.children[0]
+	right := j.children[1]
+
+	if err := checkJoinColTypes(left.ColTypes(), j.leftJoinCols, right.ColTypes(), j.rightJoinCols); err != nil {
+		return err
+	}
+
+	j.colTypes = nil
+	j.colsVisible = nil
+	j.keyCols = nil
+	j.appendCols(left, 0, j.leftColCount)
+	j.appendCols(right, 0, j.rightColCount)
+	leftHiddenStart := len(j.colTypes)
+	j.appendCols(left, j.leftColCount, len(left.ColTypes()))
+	rightHiddenStart := len(j.colTypes)
+	j.appendCols(right, j.rightColCount, len(right.ColTypes()))
+
+	for _, keyCol := range left.KeyCols() {
+		if keyCol < j.leftColCount {
+			j.keyCols = append(j.keyCols, keyCol)
+		} else {
+			j.keyCols = append(j.keyCols, leftHiddenStart+keyCol-j.leftColCount)
+		}
+	}
+	for _, keyCol := range right.KeyCols() {
+		if keyCol < j.rightColCount {
+			j.keyCols = append(j.keyCols, j.leftColCount+keyCol)
+		} else {
+			j.keyCols = append(j.keyCols, rightHiddenStart+keyCol-j.rightColCount)
+		}
+	}
+	j.rowsFactory = common.NewRowsFactory(j.colTypes)
+
+	// Like union all, we need to know which input rows came from, so we put an intermediate executor between each
+	// child and the join
+	j.leftInput = j.newJoinInput(left, j.LeftTableInfo, j.leftJoinCols)
+	j.rightInput = j.newJoinInput(right, j.RightTableInfo, j.rightJoinCols)
+	j.children = []PushExecutor{j.leftInput, j.rightInput}
+	return nil
+}
+
+func (j *Join) appendCols(child PushExecutor, start int, end int) {
+	childColTypes := child.ColTypes()
+	childColsVisible := child.ColsVisible()
+	for i := start; i < end; i++ {
+		j.colTypes = append(j.colTypes, childColTypes[i])
+		j.colsVisible = append(j.colsVisible, childColsVisible == nil || childColsVisible[i])
+	}
+}
+
+func (j *Join) newJoinInput(child PushExecutor, tableInfo *common.TableInfo, joinCols []int) *JoinInput {
+	childColTypes := child.ColTypes()
+	// The join table is keyed by the join columns followed by the key columns of the input
+	tableInfo.PrimaryKeyCols = append(append([]int{}, joinCols...), child.KeyCols()...)
+	tableInfo.ColumnTypes = childColTypes
+	input := &JoinInput{
+		pushExecutorBase: pushExecutorBase{
+			colNames:    child.ColNames(),
+			colTypes:    childColTypes,
+			keyCols:     child.KeyCols(),
+			colsVisible: child.ColsVisible(),
+			rowsFactory: common.NewRowsFactory(childColTypes),
+		},
+		join:      j,
+		TableInfo: tableInfo,
+		joinCols:  joinCols,
+	}
+	child.SetParent(input)
+	input.SetParent(j)
+	input.AddChild(child)
+	return input
+}
+
+func checkJoinColTypes(leftColTypes []common.ColumnType, leftJoinCols []int, rightColTypes []common.ColumnType,
+	rightJoinCols []int) error {
+	for i, leftJoinCol := range leftJoinCols {
+		leftType := leftColTypes[leftJoinCol]
+		rightType := rightColTypes[rightJoinCols[i]]
+		// Join keys from both inputs must have the same key encoding
+		if isIntType(leftType) && isIntType(rightType) {
+			continue
+		}
+		if leftType.Type != rightType.Type ||
+			(leftType.Type == common.TypeDecimal && (leftType.DecPrecision != rightType.DecPrecision || leftType.DecScale != rightType.DecScale)) {
+			return errors.NewPranaErrorf(errors.InvalidStatement, "Cannot join columns of type %s and %s", leftType.String(), rightType.String())
+		}
+	}
+	return nil
+}
+
+func isIntType(colType common.ColumnType) bool {
+	return colType.Type == common.TypeTinyInt || colType.Type == common.TypeInt || colType.Type == common.TypeBigInt ||
+		colType.Type == common.TypeBoolean
+}
+
+func (j *Join) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) error {
+	panic("should not be called")
+}
+
+// handleInputRows is called on the shard that owns the join key when rows are forwarded from one of the inputs
+func (j *Join) handleInputRows(input *JoinInput, rowsBatch RowsBatch, ctx *ExecutionContext) error {
+	other := j.rightInput
+	if input == j.rightInput {
+		other = j.leftInput
+	}
+	shardID := ctx.WriteBatch.ShardID
+	// The keys in the join table of this input written while handling these rows, mapped to their join key prefix
+	writtenKeys := make(map[string]string)
+
+	numRows := rowsBatch.Len()
+	readRows := other.rowsFactory.NewRows(numRows)
+	ownReadRows := input.rowsFactory.NewRows(numRows)
+	joinedRows := j.rowsFactory.NewRows(numRows)
+	changes := j.newJoinedChanges()
+	for i := 0; i < numRows; i++ {
+		prevRow := rowsBatch.PreviousRow(i)
+		currRow := rowsBatch.CurrentRow(i)
+		joinKey, err := input.joinKey(prevRow, currRow)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if joinKey == nil {
+			// Null join keys never match anything, but the left rows of a left outer join are still output
+			if j.JoinType == JoinTypeLeftOuter && input == j.leftInput {
+				if err := j.joinLeftRow(prevRow, currRow, nil, joinedRows, changes); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+			continue
+		}
+
+		prefix := input.joinKeyPrefix(joinKey, shardID)
+		var ownRows []*common.Row
+		if j.JoinType == JoinTypeLeftOuter && input == j.rightInput {
+			// We need to know which rows the right input had for the join key before this change, so we can tell
+			// whether left rows lose or gain their only match
+			ownRows, err = input.lookupMatches(ctx, prefix, ownReadRows)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		// Update the join table for this input
+		if prevRow != nil {
+			key, err := common.EncodeNullableKeyCols(prevRow, input.keyCols, input.colTypes, input.TableInfo.KeyNullMarkers,
+				common.CopyByteSlice(prefix))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			putPendingJoinRow(ctx, prefix, key, nil)
+			writtenKeys[string(key)] = string(prefix)
+		}
+		if currRow != nil {
+			key, err := common.EncodeNullableKeyCols(currRow, input.keyCols, input.colTypes, input.TableInfo.KeyNullMarkers,
+				common.CopyByteSlice(prefix))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			value, err := common.EncodeRow(currRow, input.colTypes, nil)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			putPendingJoinRow(ctx, prefix, key, value)
+			writtenKeys[string(key)] = string(prefix)
+		}
+
+		// And join with the matching rows from the other input
+		matches, err := other.lookupMatches(ctx, other.joinKeyPrefix(joinKey, shardID), readRows)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch {
+		case j.JoinType == JoinTypeLeftOuter && input == j.leftInput:
+			err = j.joinLeftRow(prevRow, currRow, matches, joinedRows, changes)
+		case j.JoinType == JoinTypeLeftOuter:
+			err = j.joinRightRowOuter(prevRow, currRow, matches, ownRows, joinedRows, changes)
+		default:
+			err = j.joinInnerRow(input, prevRow, currRow, matches, joinedRows, changes)
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// Now we know the final state of each changed row we can add it to the write batch
+	writePendingJoinRows(ctx, writtenKeys)
+	resultBatch := changes.toRowsBatch()
+	if resultBatch.Len() == 0 {
+		return nil
+	}
+	return j.parent.HandleRows(resultBatch, ctx)
+}
+
+// joinedChanges collects the changes to the output rows of the join while handling a batch. A change to the same
+// output row can be made more than once, e.g. when a left row with no matches moves from one join key to another the
+// null padded row is deleted and then added again. A delete and a put of the same key in the same write batch would not
+// be applied in order, so we combine all the changes to an output row into a single entry.
+type joinedChanges struct {
+	join    *Join
+	changes []joinedChange
+	indexes map[string]int
+}
+
+// joinedChange is a change to a single output row of the join. prev is nil for an insert and curr is nil for a delete.
+type joinedChange struct {
+	prev *common.Row
+	curr *common.Row
+}
+
+func (j *Join) newJoinedChanges() *joinedChanges {
+	return &joinedChanges{join: j, indexes: make(map[string]int)}
+}
+
+func (c *joinedChanges) add(prev *common.Row, curr *common.Row) error {
+	if prev == nil && curr == nil {
+		return nil
+	}
+	row := curr
+	if row == nil {
+		row = prev
+	}
+	// This must be the key the output row is stored with, so changes to the same stored row are combined
+	key, err := common.EncodeNullableKeyCols(row, c.join.keyCols, c.join.colTypes, c.join.LeftTableInfo.KeyNullMarkers, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	index, ok := c.indexes[string(key)]
+	if ok {
+		c.changes[index].curr = curr
+		return nil
+	}
+	c.indexes[string(key)] = len(c.changes)
+	c.changes = append(c.changes, joinedChange{prev: prev, curr: curr})
+	return nil
+}
+
+func (c *joinedChanges) toRowsBatch() RowsBatch {
+	resultBatch := NewCurrentRowsBatch(c.join.rowsFactory.NewRows(len(c.changes)))
+	for _, change := range c.changes {
+		if change.prev != nil || change.curr != nil {
+			resultBatch.AppendEntry(change.prev, change.curr)
+		}
+	}
+	return resultBatch
+}
+
+func (j *Join) joinInnerRow(input *JoinInput, prevRow *common.Row, currRow *common.Row, matches []*common.Row,
+	joinedRows *common.Rows, changes *joinedChanges) error {
+	for _, match := range matches {
+		prevJoined, currJoined, err := j.joinMatch(input, prevRow, currRow, match, joinedRows)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := changes.add(prevJoined, currJoined); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// joinLeftRow handles a change to a row of the left input of a left outer join. If the row has no matches which
+// satisfy the join conditions it is output padded with nulls.
+func (j *Join) joinLeftRow(prevRow *common.Row, currRow *common.Row, matches []*common.Row, joinedRows *common.Rows,
+	changes *joinedChanges) error {
+	prevMatched, currMatched := false, false
+	for _, match := range matches {
+		prevJoined, currJoined, err := j.joinMatch(j.leftInput, prevRow, currRow, match, joinedRows)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		prevMatched = prevMatched || prevJoined != nil
+		currMatched = currMatched || currJoined != nil
+		if err := changes.add(prevJoined, currJoined); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	var prevPadded, currPadded *common.Row
+	if prevRow != nil && !prevMatched {
+		prevPadded = j.padLeftRow(prevRow, joinedRows)
+	}
+	if currRow != nil && !currMatched {
+		currPadded = j.padLeftRow(currRow, joinedRows)
+	}
+	return changes.add(prevPadded, currPadded)
+}
+
+// joinRightRowOuter handles a change to a row of the right input of a left outer join. When a left row gains its
+// first match the null padded row previously output for it is retracted, and when it loses its last match the null
+// padded row is output again. rightRows are the rows of the right input for the join key before the change.
+func (j *Join) joinRightRowOuter(prevRow *common.Row, currRow *common.Row, leftRows []*common.Row,
+	rightRows []*common.Row, joinedRows *common.Rows, changes *joinedChanges) error {
+	for _, leftRow := range leftRows {
+		prevJoined, currJoined, err := j.joinMatch(j.rightInput, prevRow, currRow, leftRow, joinedRows)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		matchesBefore, err := j.countMatches(leftRow, rightRows, joinedRows)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		matchesAfter := matchesBefore
+		if prevJoined != nil {
+			matchesAfter--
+		}
+		if currJoined != nil {
+			matchesAfter++
+		}
+		if matchesBefore == 0 && matchesAfter > 0 {
+			if err := changes.add(j.padLeftRow(leftRow, joinedRows), nil); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if err := changes.add(prevJoined, currJoined); err != nil {
+			return errors.WithStack(err)
+		}
+		if matchesBefore > 0 && matchesAfter == 0 {
+			if err := changes.add(nil, j.padLeftRow(leftRow, joinedRows)); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// countMatches returns the number of right rows which satisfy the join conditions with the left row
+func (j *Join) countMatches(leftRow *common.Row, rightRows []*common.Row, joinedRows *common.Rows) (int, error) {
+	if len(j.otherConditions) == 0 {
+		return len(rightRows), nil
+	}
+	count := 0
+	for _, rightRow := range rightRows {
+		ok, err := j.evalOtherConditions(j.joinRows(j.leftInput, leftRow, rightRow, joinedRows))
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if ok {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// joinMatch joins the previous and current rows from the input with a matching row from the other input. The returned
+// joined rows are nil if the input row is nil or the joined row does not satisfy the other join conditions.
+func (j *Join) joinMatch(input *JoinInput, prevRow *common.Row, currRow *common.Row, match *common.Row,
+	joinedRows *common.Rows) (*common.Row, *common.Row, error) {
+	var prevJoined, currJoined *common.Row
+	if prevRow != nil {
+		prevJoined = j.joinRows(input, prevRow, match, joinedRows)
+		ok, err := j.evalOtherConditions(prevJoined)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if !ok {
+			prevJoined = nil
+		}
+	}
+	if currRow != nil {
+		currJoined = j.joinRows(input, currRow, match, joinedRows)
+		ok, err := j.evalOtherConditions(currJoined)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if !ok {
+			currJoined = nil
+		}
+	}
+	return prevJoined, currJoined, nil
+}
+
+func (j *Join) evalOtherConditions(row *common.Row) (bool, error) {
+	if row == nil {
+		return false, nil
+	}
+	for _, cond := range j.otherConditions {
+		accept, isNull, err := cond.EvalBoolean(row)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+		if isNull || !accept {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// joinRows creates the output row from a row from the input and a matching row from the other input
+func (j *Join) joinRows(input *JoinInput, row *common.Row, match *common.Row, joinedRows *common.Rows) *common.Row {
+	leftRow, rightRow := row, match
+	if input == j.rightInput {
+		leftRow, rightRow = match, row
+	}
+	leftColCount := len(j.leftInput.colTypes)
+	rightColCount := len(j.rightInput.colTypes)
+	outIndex := 0
+	outIndex = j.appendColsFromRow(leftRow, 0, j.leftColCount, outIndex, joinedRows)
+	outIndex = j.appendColsFromRow(rightRow, 0, j.rightColCount, outIndex, joinedRows)
+	outIndex = j.appendColsFromRow(leftRow, j.leftColCount, leftColCount, outIndex, joinedRows)
+	j.appendColsFromRow(rightRow, j.rightColCount, rightColCount, outIndex, joinedRows)
+	joined := joinedRows.GetRow(joinedRows.RowCount() - 1)
+	return &joined
+}
+
+// padLeftRow creates the output row for a row from the left input of a left outer join which has no matches
+func (j *Join) padLeftRow(leftRow *common.Row, joinedRows *common.Rows) *common.Row {
+	leftColCount := len(j.leftInput.colTypes)
+	rightColCount := len(j.rightInput.colTypes)
+	outIndex := 0
+	outIndex = j.appendColsFromRow(leftRow, 0, j.leftColCount, outIndex, joinedRows)
+	outIndex = appendNullCols(j.rightColCount, outIndex, joinedRows)
+	outIndex = j.appendColsFromRow(leftRow, j.leftColCount, leftColCount, outIndex, joinedRows)
+	appendNullCols(rightColCount-j.rightColCount, outIndex, joinedRows)
+	padded := joinedRows.GetRow(joinedRows.RowCount() - 1)
+	return &padded
+}
+
+func appendNullCols(numCols int, outIndex int, out *common.Rows) int {
+	for i := 0; i < numCols; i++ {
+		out.AppendNullToColumn(outIndex)
+		outIndex++
+	}
+	return outIndex
+}
+
+func (j *Join) appendColsFromRow(row *common.Row, start int, end int, outIndex int, out *common.Rows) int {
+	for i := start; i < end; i++ {
+		if row.IsNull(i) {
+			out.AppendNullToColumn(outIndex)
+		} else {
+			switch j.colTypes[outIndex].Type {
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+				out.AppendInt64ToColumn(outIndex, row.GetInt64(i))
+			case common.TypeDouble:
+				out.AppendFloat64ToColumn(outIndex, row.GetFloat64(i))
+			case common.TypeVarchar, common.TypeVarbinary:
+				out.AppendStringToColumn(outIndex, row.GetString(i))
+			case common.TypeTimestamp:
+				out.AppendTimestampToColumn(outIndex, row.GetTimestamp(i))
+			case common.TypeDecimal:
+				out.AppendDecimalToColumn(outIndex, row.GetDecimal(i))
+			default:
+				panic("unexpected column type")
+			}
+		}
+		outIndex++
+	}
+	return outIndex
+}
+
+// JoinInput sits between a child of the join and the join itself. It forwards rows from the child to the shard that
+// owns the join key, and receives those rows on that shard.
+type JoinInput struct {
+	pushExecutorBase
+	join      *Join
+	TableInfo *common.TableInfo
+	joinCols  []int
+}
+
+func (i *JoinInput) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) error {
+	numRows := rowsBatch.Len()
+	for r := 0; r < numRows; r++ {
+		prevRow := rowsBatch.PreviousRow(r)
+		currRow := rowsBatch.CurrentRow(r)
+		prevKey, prevNull, err := i.shardingKey(prevRow)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		currKey, currNull, err := i.shardingKey(currRow)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if prevKey != nil && currKey != nil && prevNull == currNull && string(prevKey) == string(currKey) {
+			// The join key hasn't changed so the update goes to a single shard
+			if err := i.forwardRows(prevKey, prevRow, currRow, ctx); err != nil {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+		// Otherwise the row moves from the shard that owns the previous join key to the shard that owns the current
+		// one, so we send a delete and an add
+		if prevKey != nil {
+			if err := i.forwardRows(prevKey, prevRow, nil, ctx); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if currKey != nil {
+			if err := i.forwardRows(currKey, nil, currRow, ctx); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// shardingKey returns the key used to choose the shard the row is forwarded to. This is the join key, unless the join
+// key is null. Rows with a null join key never match anything so are dropped, except for the left input of a left
+// outer join where they are still output - these are sent to the shard that owns the key of the row itself.
+func (i *JoinInput) shardingKey(row *common.Row) ([]byte, bool, error) {
+	if row == nil {
+		return nil, false, nil
+	}
+	joinKey, err := i.encodeJoinKey(row)
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	if joinKey != nil {
+		return joinKey, false, nil
+	}
+	if i.join.JoinType != JoinTypeLeftOuter || i != i.join.leftInput {
+		return nil, false, nil
+	}
+	key, err := common.EncodeNullableKeyCols(row, i.keyCols, i.colTypes, i.TableInfo.KeyNullMarkers, nil)
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	return key, true, nil
+}
+
+// HandleRemoteRows is called when rows are forwarded from another shard
+func (i *JoinInput) HandleRemoteRows(rowsBatch RowsBatch, ctx *ExecutionContext) error {
+	return i.join.handleInputRows(i, rowsBatch, ctx)
+}
+
+func (i *JoinInput) forwardRows(joinKey []byte, prevRow *common.Row, currRow *common.Row, ctx *ExecutionContext) error {
+	remoteShardID, err := i.join.sharder.CalculateShard(sharder.ShardTypeHash, joinKey)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var prevBytes, currBytes []byte
+	if prevRow != nil {
+		prevBytes, err = common.EncodeRow(prevRow, i.colTypes, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if currRow != nil {
+		currBytes, err = common.EncodeRow(currRow, i.colTypes, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	forwardKey := util.EncodeKeyForForwardJoin(ctx.EnableDuplicateDetection, i.TableInfo.ID, ctx.WriteBatch.ShardID,
+		ctx.BatchSequence)
+	return ctx.AddToForwardBatch(remoteShardID, forwardKey, util.EncodePrevAndCurrentRow(prevBytes, currBytes))
+}
+
+// encodeJoinKey returns nil if any of the join columns are null
+func (i *JoinInput) encodeJoinKey(row *common.Row) ([]byte, error) {
+	if row == nil {
+		return nil, nil
+	}
+	for _, joinCol := range i.joinCols {
+		if row.IsNull(joinCol) {
+			return nil, nil
+		}
+	}
+	return common.EncodeKeyCols(row, i.joinCols, i.colTypes, nil)
+}
+
+func (i *JoinInput) joinKey(prevRow *common.Row, currRow *common.Row) ([]byte, error) {
+	// Rows are always forwarded such that the previous and current rows have the same join key
+	if currRow != nil {
+		return i.encodeJoinKey(currRow)
+	}
+	return i.encodeJoinKey(prevRow)
+}
+
+func (i *JoinInput) joinKeyPrefix(joinKey []byte, shardID uint64) []byte {
+	prefix := table.EncodeTableKeyPrefix(i.TableInfo.ID, shardID, 16+len(joinKey))
+	return append(prefix, joinKey...)
+}
+
+// lookupMatches returns the rows in the join table for this input with the specified join key prefix, including any changes
+// made while handling the current batch
+func (i *JoinInput) lookupMatches(ctx *ExecutionContext, prefix []byte, readRows *common.Rows) ([]*common.Row, error) {
+	kvPairs, err := i.join.storage.LocalScan(prefix, common.IncrementBytesBigEndian(prefix), -1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	values := make(map[string][]byte, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		values[string(kvPair.Key)] = kvPair.Value
+	}
+	for key, value := range ctx.pendingJoinRows[string(prefix)] {
+		if value == nil {
+			delete(values, key)
+		} else {
+			values[key] = value
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	matches := make([]*common.Row, len(keys))
+	for index, key := range keys {
+		if err := common.DecodeRow(values[key], i.colTypes, readRows); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		row := readRows.GetRow(readRows.RowCount() - 1)
+		matches[index] = &row
+	}
+	return matches, nil
+}
+
+func putPendingJoinRow(ctx *ExecutionContext, prefix []byte, key []byte, value []byte) {
+	if ctx.pendingJoinRows == nil {
+		ctx.pendingJoinRows = make(map[string]map[string][]byte)
+	}
+	pending, ok := ctx.pendingJoinRows[string(prefix)]
+	if !ok {
+		pending = make(map[string][]byte)
+		ctx.pendingJoinRows[string(prefix)] = pending
+	}
+	pending[string(key)] = value
+}
+
+// writePendingJoinRows adds the final state of the written keys to the write batch. We only do this once per key as
+// a put followed by a delete of the same key in the same write batch would not be applied in order.
+func writePendingJoinRows(ctx *ExecutionContext, writtenKeys map[string]string) {
+	keys := make([]string, 0, len(writtenKeys))
+	for key := range writtenKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := ctx.pendingJoinRows[writtenKeys[key]][key]
+		if value == nil {
+			ctx.WriteBatch.AddDelete([]byte(key))
+		} else {
+			ctx.WriteBatch.AddPut([]byte(key), value)
+		}
+	}
+}
@@ -105,7 +105,7 @@ func (p *PushProjection) calcProjection(row *common.Row, result *common.Rows) er
 	for j, projColumn := range p.projColumns {
 		colType := p.colTypes[j]
 		switch colType.Type {
-		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 			val, null, err := projColumn.EvalInt64(row)
 			if err != nil {
 				return errors.WithStack(err)
@@ -125,7 +125,7 @@ func (p *PushProjection) calcProjection(row *common.Row, result *common.Rows) er
 			} else {
 				result.AppendDecimalToColumn(j, val)
 			}
-		case common.TypeVarchar:
+		case common.TypeVarchar, common.TypeVarbinary:
 			val, null, err := projColumn.EvalString(row)
 			if err != nil {
 				return errors.WithStack(err)
@@ -166,20 +166,28 @@ func (p *PushProjection) calcProjection(row *common.Row, result *common.Rows) er
 	appendStart := len(p.projColumns)
 	for index, colNumber := range p.invisibleKeyColsInChild {
 		j := appendStart + index
+		if row.IsNull(colNumber) {
+			// Key columns from the right of a left outer join are null in rows without a match
+			result.AppendNullToColumn(j)
+			continue
+		}
 		colType := p.colTypes[j]
 		switch colType.Type {
-		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 			val := row.GetInt64(colNumber)
 			result.AppendInt64ToColumn(j, val)
 		case common.TypeDecimal:
 			val := row.GetDecimal(colNumber)
 			result.AppendDecimalToColumn(j, val)
-		case common.TypeVarchar:
+		case common.TypeVarchar, common.TypeVarbinary:
 			val := row.GetString(colNumber)
 			result.AppendStringToColumn(j, val)
 		case common.TypeDouble:
 			val := row.GetFloat64(colNumber)
 			result.AppendFloat64ToColumn(j, val)
+		case common.TypeTimestamp:
+			val := row.GetTimestamp(colNumber)
+			result.AppendTimestampToColumn(j, val)
 		default:
 			return errors.Errorf("unexpected column type %d", colType)
 		}
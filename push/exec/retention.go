@@ -0,0 +1,188 @@
+package exec
+
+import (
+	"bytes"
+
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/push/util"
+	"github.com/squareup/pranadb/table"
+)
+
+const expireMaxBatchSize = 1000
+
+// ExpireRows deletes the rows of the table on the shard whose retention column is before the cutoff, and returns the
+// number of rows deleted. It must be called on the scheduler of the shard. The expired rows are found by scanning the
+// retention index up to the cutoff, so rows which haven't expired aren't read, and the scanned entries are then
+// deleted with a range delete. Rows with a null retention column aren't in the retention index and never expire.
+func (t *TableExecutor) ExpireRows(shardID uint64, cutoff common.Timestamp) (int, error) {
+	retention := t.TableInfo.Retention
+	if retention == nil {
+		return 0, nil
+	}
+	startPrefix := table.EncodeTableKeyPrefix(retention.IndexID, shardID, 24)
+	endPrefix, err := common.KeyEncodeTimestamp(table.EncodeTableKeyPrefix(retention.IndexID, shardID, 24), cutoff)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	numExpired := 0
+	for {
+		kvp, err := t.store.LocalScan(startPrefix, endPrefix, expireMaxBatchSize)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		if len(kvp) == 0 {
+			return numExpired, nil
+		}
+		expired := t.rowsFactory.NewRows(len(kvp))
+		keys := make([][]byte, 0, len(kvp))
+		for _, kv := range kvp {
+			// The value of the retention index entry is the PK of the row
+			key := table.EncodeTableKeyPrefix(t.TableInfo.ID, shardID, 16+len(kv.Value))
+			key = append(key, kv.Value...)
+			v, err := t.store.LocalGet(key)
+			if err != nil {
+				return 0, errors.WithStack(err)
+			}
+			if v == nil {
+				continue
+			}
+			if err := common.DecodeRow(v, t.colTypes, expired); err != nil {
+				return 0, errors.WithStack(err)
+			}
+			keys = append(keys, key)
+		}
+		scannedEnd := common.IncrementBytesBigEndian(kvp[len(kvp)-1].Key)
+		if err := t.deleteExpiredRows(shardID, keys, expired, retention.Propagate, startPrefix, scannedEnd); err != nil {
+			return 0, errors.WithStack(err)
+		}
+		numExpired += len(keys)
+		if len(kvp) < expireMaxBatchSize {
+			return numExpired, nil
+		}
+		startPrefix = scannedEnd
+	}
+}
+
+// deleteExpiredRows deletes the expired rows, and the entries of the retention index from indexStart to indexEnd
+func (t *TableExecutor) deleteExpiredRows(shardID uint64, keys [][]byte, rows *common.Rows, propagate bool,
+	indexStart []byte, indexEnd []byte) error {
+	entries := make([]RowsEntry, rows.RowCount())
+	for i := range entries {
+		entries[i] = NewRowsEntry(i, -1)
+	}
+	rowsBatch := NewRowsBatch(rows, entries)
+	wb := cluster.NewWriteBatch(shardID)
+	// The rows don't come from Kafka, so there's nothing to detect duplicates of
+	ctx := NewExecutionContext(wb, false)
+	if propagate {
+		// The rows are deleted as if they'd been deleted upstream
+		if err := t.HandleRows(rowsBatch, ctx); err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		if err := t.deleteRowsLocally(keys, rowsBatch, ctx); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	wb.AddDeleteRange(indexStart, indexEnd)
+	if err := util.SendForwardBatches(ctx.RemoteBatches, t.store); err != nil {
+		return errors.WithStack(err)
+	}
+	return t.store.WriteBatch(wb)
+}
+
+// deleteRowsLocally deletes the rows from the table and its indexes, but not from anything else which consumes the
+// table
+func (t *TableExecutor) deleteRowsLocally(keys [][]byte, rowsBatch RowsBatch, ctx *ExecutionContext) error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	for _, key := range keys {
+		ctx.WriteBatch.AddDelete(key)
+	}
+	for _, consumingNode := range t.consumingNodes {
+		if _, ok := consumingNode.(*IndexExecutor); ok {
+			if err := consumingNode.HandleRows(rowsBatch, ctx); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// retentionIndexChanges collects the changes a batch makes to the retention index of a table. The deletes of a write
+// batch are applied after its puts, so an entry which is changed more than once in the batch is only added to the
+// write batch in its final state.
+type retentionIndexChanges struct {
+	tableInfo *common.TableInfo
+	shardID   uint64
+	entries   map[string]*retentionIndexChange
+}
+
+type retentionIndexChange struct {
+	value []byte
+	put   bool
+	// stored is true if the entry was in storage before the batch, so it must be deleted
+	stored bool
+}
+
+func newRetentionIndexChanges(tableInfo *common.TableInfo, shardID uint64) *retentionIndexChanges {
+	return &retentionIndexChanges{
+		tableInfo: tableInfo,
+		shardID:   shardID,
+		entries:   make(map[string]*retentionIndexChange),
+	}
+}
+
+// update changes the retention index for the change of a row from the row at prevIndex to the row at currIndex, either
+// of which is -1 if there's no such row
+func (r *retentionIndexChanges) update(rows *common.Rows, prevIndex int, currIndex int) error {
+	var prevKey, currKey, currValue []byte
+	if prevIndex != -1 {
+		prevRow := rows.GetRow(prevIndex)
+		var err error
+		prevKey, _, err = table.EncodeRetentionKeyValue(r.tableInfo, r.shardID, &prevRow)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if currIndex != -1 {
+		currRow := rows.GetRow(currIndex)
+		var err error
+		currKey, currValue, err = table.EncodeRetentionKeyValue(r.tableInfo, r.shardID, &currRow)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if bytes.Equal(prevKey, currKey) {
+		return nil
+	}
+	if prevKey != nil {
+		if entry, ok := r.entries[string(prevKey)]; ok {
+			entry.put = false
+		} else {
+			// The previous row is in storage, and so is its entry
+			r.entries[string(prevKey)] = &retentionIndexChange{stored: true}
+		}
+	}
+	if currKey != nil {
+		if entry, ok := r.entries[string(currKey)]; ok {
+			entry.put = true
+			entry.value = currValue
+		} else {
+			r.entries[string(currKey)] = &retentionIndexChange{value: currValue, put: true}
+		}
+	}
+	return nil
+}
+
+func (r *retentionIndexChanges) addToBatch(wb *cluster.WriteBatch) {
+	for key, entry := range r.entries {
+		if entry.put {
+			wb.AddPut([]byte(key), entry.value)
+		} else if entry.stored {
+			wb.AddDelete([]byte(key))
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/cluster/fake"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/table"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireRows(t *testing.T) {
+	clust := fake.NewFakeCluster(0, 10)
+	tableInfo := &common.TableInfo{
+		ID:             1000,
+		Name:           "events",
+		PrimaryKeyCols: []int{0},
+		ColumnNames:    []string{"id", "event_time"},
+		ColumnTypes:    []common.ColumnType{common.BigIntColumnType, common.NewTimestampColumnType(6)},
+		Retention:      &common.RetentionInfo{ColIndex: 1, IndexID: 1001},
+	}
+	te := NewTableExecutor(tableInfo, clust)
+	shardID := cluster.DataShardIDBase
+	old := common.NewTimestampFromString("2021-01-01 00:00:00")
+	recent := common.NewTimestampFromString("2037-01-01 00:00:00")
+	cutoff := common.NewTimestampFromString("2030-01-01 00:00:00")
+
+	// Row 1 moves out of the expired range and row 4 is deleted, in the same batch they're written in
+	rows := te.RowsFactory().NewRows(10)
+	var entries []RowsEntry
+	appendRow := func(id int64, eventTime *common.Timestamp, deleted bool) {
+		rows.AppendInt64ToColumn(0, id)
+		if eventTime == nil {
+			rows.AppendNullToColumn(1)
+		} else {
+			rows.AppendTimestampToColumn(1, *eventTime)
+		}
+		if deleted {
+			entries = append(entries, NewRowsEntry(rows.RowCount()-1, -1))
+		} else {
+			entries = append(entries, NewRowsEntry(-1, rows.RowCount()-1))
+		}
+	}
+	appendRow(1, &old, false)
+	appendRow(2, &recent, false)
+	appendRow(3, nil, false)
+	appendRow(1, &recent, false)
+	appendRow(4, &old, false)
+	appendRow(4, &old, true)
+	ctx := NewExecutionContext(cluster.NewWriteBatch(shardID), false)
+	require.NoError(t, te.HandleRows(NewRowsBatch(rows, entries), ctx))
+	require.NoError(t, clust.WriteBatch(ctx.WriteBatch))
+	requireRetentionIndexSize(t, clust, tableInfo, shardID, 2)
+
+	numExpired, err := te.ExpireRows(shardID, cutoff)
+	require.NoError(t, err)
+	require.Equal(t, 0, numExpired)
+
+	// Row 2 moves into the expired range
+	rows = te.RowsFactory().NewRows(1)
+	entries = nil
+	appendRow(2, &old, false)
+	ctx = NewExecutionContext(cluster.NewWriteBatch(shardID), false)
+	require.NoError(t, te.HandleRows(NewRowsBatch(rows, entries), ctx))
+	require.NoError(t, clust.WriteBatch(ctx.WriteBatch))
+	requireRetentionIndexSize(t, clust, tableInfo, shardID, 2)
+
+	numExpired, err = te.ExpireRows(shardID, cutoff)
+	require.NoError(t, err)
+	require.Equal(t, 1, numExpired)
+	requireRetentionIndexSize(t, clust, tableInfo, shardID, 1)
+
+	tableStart := table.EncodeTableKeyPrefix(tableInfo.ID, shardID, 16)
+	kvp, err := clust.LocalScan(tableStart, common.IncrementBytesBigEndian(tableStart), -1)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(kvp))
+	remaining := te.RowsFactory().NewRows(2)
+	for _, kv := range kvp {
+		require.NoError(t, common.DecodeRow(kv.Value, tableInfo.ColumnTypes, remaining))
+	}
+	for i, expectedID := range []int64{1, 3} {
+		row := remaining.GetRow(i)
+		require.Equal(t, expectedID, row.GetInt64(0))
+	}
+}
+
+func requireRetentionIndexSize(t *testing.T, clust cluster.Cluster, tableInfo *common.TableInfo, shardID uint64, expected int) {
+	t.Helper()
+	start := table.EncodeTableKeyPrefix(tableInfo.Retention.IndexID, shardID, 16)
+	kvp, err := clust.LocalScan(start, common.IncrementBytesBigEndian(start), -1)
+	require.NoError(t, err)
+	require.Equal(t, expected, len(kvp))
+}
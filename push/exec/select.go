@@ -29,6 +29,8 @@ func (p *PushSelect) ReCalcSchemaFromChildren() error {
 	}
 	child := p.children[0]
 	p.calculateSchema(child.ColTypes(), child.KeyCols())
+	// The child can have invisible key columns, e.g. a scan of only some of the columns of a table
+	p.colsVisible = child.ColsVisible()
 	return nil
 }
 
@@ -38,63 +40,67 @@ func (p *PushSelect) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) erro
 	resultRows := p.rowsFactory.NewRows(numRows)
 	resultBatch := NewCurrentRowsBatch(resultRows)
 	for i := 0; i < numRows; i++ {
-
-		currRow := rowsBatch.CurrentRow(i)
-		prevRow := rowsBatch.PreviousRow(i)
-
-		if currRow != nil && prevRow == nil {
-			// A new row
-			ok, err := p.evalPredicates(currRow)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			if ok {
-				resultBatch.AppendEntry(nil, currRow)
-			}
-		} else if currRow != nil && prevRow != nil {
-			// A modified row
-			okCurr, err := p.evalPredicates(currRow)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			okPrev, err := p.evalPredicates(prevRow)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			if okCurr && okPrev {
-				// Both the current and previous version pass the condition so this remains a modify
-				resultBatch.AppendEntry(prevRow, currRow)
-			} else if !okCurr && okPrev {
-				// Previous value passed the filter but current value doesn't so this becomes a delete
-				resultBatch.AppendEntry(prevRow, nil)
-			} else if okCurr && !okPrev {
-				// Passes now but didn't pass before - becomes an add
-				resultBatch.AppendEntry(nil, currRow)
-			}
-		} else if currRow == nil && prevRow != nil {
-			// A deleted row - pass it through if it previously was passed
-			ok, err := p.evalPredicates(prevRow)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			if ok {
-				resultBatch.AppendEntry(prevRow, nil)
-			}
+		prevRow, currRow, err := p.FilterChange(rowsBatch.PreviousRow(i), rowsBatch.CurrentRow(i))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if prevRow != nil || currRow != nil {
+			resultBatch.AppendEntry(prevRow, currRow)
 		}
 	}
 	return p.parent.HandleRows(resultBatch, ctx)
 }
 
+// FilterChange applies the predicates to a change to a row and returns the change that passes the filter - a
+// modified row can become a new or a deleted row. Both rows are nil if the change doesn't pass the filter.
+func (p *PushSelect) FilterChange(prevRow *common.Row, currRow *common.Row) (*common.Row, *common.Row, error) {
+	if currRow != nil && prevRow == nil {
+		// A new row
+		ok, err := p.evalPredicates(currRow)
+		if err != nil || !ok {
+			return nil, nil, errors.WithStack(err)
+		}
+		return nil, currRow, nil
+	} else if currRow != nil && prevRow != nil {
+		// A modified row
+		okCurr, err := p.evalPredicates(currRow)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		okPrev, err := p.evalPredicates(prevRow)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		if okCurr && okPrev {
+			// Both the current and previous version pass the condition so this remains a modify
+			return prevRow, currRow, nil
+		} else if !okCurr && okPrev {
+			// Previous value passed the filter but current value doesn't so this becomes a delete
+			return prevRow, nil, nil
+		} else if okCurr && !okPrev {
+			// Passes now but didn't pass before - becomes an add
+			return nil, currRow, nil
+		}
+	} else if currRow == nil && prevRow != nil {
+		// A deleted row - pass it through if it previously was passed
+		ok, err := p.evalPredicates(prevRow)
+		if err != nil || !ok {
+			return nil, nil, errors.WithStack(err)
+		}
+		return prevRow, nil, nil
+	}
+	return nil, nil, nil
+}
+
 func (p *PushSelect) evalPredicates(row *common.Row) (bool, error) {
 	for _, predicate := range p.predicates {
 		accept, isNull, err := predicate.EvalBoolean(row)
 		if err != nil {
 			return false, errors.WithStack(err)
 		}
-		if isNull {
-			return false, errors.Error("null returned from evaluating select predicate")
-		}
-		if !accept {
+		// As in SQL, a predicate that evaluates to NULL does not accept the row, e.g. HAVING MAX(x) > 0 when all x
+		// are NULL
+		if isNull || !accept {
 			return false, nil
 		}
 	}
@@ -0,0 +1,137 @@
+package exec
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/kafka"
+)
+
+// SinkExecutor publishes the changes to a materialized view to a Kafka topic. An added or updated row is sent as a
+// message with the row as the value, and a deleted row is sent as a tombstone - a message with the key of the row and
+// no value.
+// The messages are sent before the batch that contains the changes is committed, so if the node fails the batch is
+// processed again and the messages are sent again. This gives at-least-once delivery.
+type SinkExecutor struct {
+	pushExecutorBase
+	SinkInfo  *common.SinkInfo
+	TableInfo *common.TableInfo // The table info of the materialized view
+	producer  kafka.MessageProducer
+	encoder   kafka.MessageEncoder
+	sinkCols  []int // The columns of the table that are published, nil if it's all the visible columns
+}
+
+func NewSinkExecutor(tableInfo *common.TableInfo, sinkInfo *common.SinkInfo, producer kafka.MessageProducer,
+	encoder kafka.MessageEncoder) *SinkExecutor {
+	// We publish the visible columns along with any hidden key columns, hidden columns always come after the visible
+	// ones
+	numVisible := len(tableInfo.ColumnTypes)
+	if tableInfo.ColsVisible != nil {
+		numVisible = 0
+		for _, visible := range tableInfo.ColsVisible {
+			if visible {
+				numVisible++
+			}
+		}
+	}
+	colTypes := tableInfo.ColumnTypes[:numVisible]
+	keyCols := tableInfo.PrimaryKeyCols
+	var sinkCols []int
+	for i, keyCol := range tableInfo.PrimaryKeyCols {
+		if keyCol < numVisible {
+			continue
+		}
+		if sinkCols == nil {
+			sinkCols = make([]int, numVisible)
+			for j := range sinkCols {
+				sinkCols[j] = j
+			}
+			keyCols = append([]int{}, tableInfo.PrimaryKeyCols...)
+			colTypes = append([]common.ColumnType{}, colTypes...)
+		}
+		keyCols[i] = len(sinkCols)
+		sinkCols = append(sinkCols, keyCol)
+		colTypes = append(colTypes, tableInfo.ColumnTypes[keyCol])
+	}
+	return &SinkExecutor{
+		pushExecutorBase: pushExecutorBase{
+			colTypes:    colTypes,
+			keyCols:     keyCols,
+			rowsFactory: common.NewRowsFactory(colTypes),
+		},
+		SinkInfo:  sinkInfo,
+		TableInfo: tableInfo,
+		producer:  producer,
+		encoder:   encoder,
+		sinkCols:  sinkCols,
+	}
+}
+
+func (s *SinkExecutor) ReCalcSchemaFromChildren() error {
+	return nil
+}
+
+func (s *SinkExecutor) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) error {
+	numEntries := rowsBatch.Len()
+	if numEntries == 0 {
+		return nil
+	}
+	var projected *common.Rows
+	if s.sinkCols != nil {
+		projected = s.rowsFactory.NewRows(numEntries)
+	}
+	timestamp := time.Now()
+	messages := make([]*kafka.Message, 0, numEntries)
+	for i := 0; i < numEntries; i++ {
+		row := rowsBatch.CurrentRow(i)
+		tombstone := row == nil
+		if tombstone {
+			// It's a delete - we send the key of the previous row with no value
+			row = rowsBatch.PreviousRow(i)
+		}
+		if projected != nil {
+			row = s.projectRow(row, projected)
+		}
+		message, err := s.encoder.EncodeMessage(row, s.colTypes, s.keyCols, timestamp)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if tombstone {
+			message.Value = nil
+		}
+		messages = append(messages, message)
+	}
+	return errors.WithStack(s.producer.SendMessages(messages))
+}
+
+// projectRow appends the published columns of the row to projected and returns the new row
+func (s *SinkExecutor) projectRow(row *common.Row, projected *common.Rows) *common.Row {
+	for i, col := range s.sinkCols {
+		if row.IsNull(col) {
+			projected.AppendNullToColumn(i)
+			continue
+		}
+		switch s.colTypes[i].Type {
+		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
+			projected.AppendInt64ToColumn(i, row.GetInt64(col))
+		case common.TypeDouble:
+			projected.AppendFloat64ToColumn(i, row.GetFloat64(col))
+		case common.TypeVarchar, common.TypeVarbinary:
+			projected.AppendStringToColumn(i, row.GetString(col))
+		case common.TypeDecimal:
+			projected.AppendDecimalToColumn(i, row.GetDecimal(col))
+		case common.TypeTimestamp:
+			projected.AppendTimestampToColumn(i, row.GetTimestamp(col))
+		default:
+			panic(fmt.Sprintf("unexpected column type %d", s.colTypes[i].Type))
+		}
+	}
+	newRow := projected.GetRow(projected.RowCount() - 1)
+	return &newRow
+}
+
+func (s *SinkExecutor) Close() error {
+	return s.producer.Close()
+}
@@ -28,6 +28,13 @@ type TableExecutor struct {
 	lastSequences      sync.Map
 	fillTableID        uint64
 	uncommittedBatches sync.Map
+	changeListener     ChangeListener
+}
+
+// ChangeListener is told about the changes made to a table. It is called before the batch that contains the changes
+// is committed.
+type ChangeListener interface {
+	HandleChanges(rowsBatch RowsBatch, ctx *ExecutionContext)
 }
 
 func NewTableExecutor(tableInfo *common.TableInfo, store cluster.Cluster) *TableExecutor {
@@ -65,6 +72,26 @@ func (t *TableExecutor) RemoveConsumingNode(consumerName string) {
 	delete(t.consumingNodes, consumerName)
 }
 
+// SetTableInfo changes the table info of the table, when a column is added to a source
+func (t *TableExecutor) SetTableInfo(tableInfo *common.TableInfo) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.TableInfo = tableInfo
+	t.colNames = tableInfo.ColumnNames
+	t.colTypes = tableInfo.ColumnTypes
+	t.keyCols = tableInfo.PrimaryKeyCols
+	t.colsVisible = tableInfo.ColsVisible
+	t.rowsFactory = common.NewRowsFactory(tableInfo.ColumnTypes)
+}
+
+// SetChangeListener sets the listener which is told about the changes made to the table, or removes it if listener is
+// nil
+func (t *TableExecutor) SetChangeListener(listener ChangeListener) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.changeListener = listener
+}
+
 func (t *TableExecutor) HandleRemoteRows(rowsBatch RowsBatch, ctx *ExecutionContext) error {
 	return t.HandleRows(rowsBatch, ctx)
 }
@@ -101,62 +128,102 @@ func (t *TableExecutor) HandleRows(rowsBatch RowsBatch, ctx *ExecutionContext) e
 
 	numEntries := rowsBatch.Len()
 	outRows := t.rowsFactory.NewRows(numEntries)
-	rc := 0
-	entries := make([]RowsEntry, numEntries)
+	entries := make([]RowsEntry, 0, numEntries)
+	// The index in outRows of the rows written earlier in this batch, by key - they aren't in storage until the batch
+	// is committed. -1 means the row was deleted.
+	written := make(map[string]int)
+	var retentionEntries *retentionIndexChanges
+	if t.TableInfo.Retention != nil {
+		retentionEntries = newRetentionIndexChanges(t.TableInfo, ctx.WriteBatch.ShardID)
+	}
 	for i := 0; i < numEntries; i++ {
 		prevRow := rowsBatch.PreviousRow(i)
 		currentRow := rowsBatch.CurrentRow(i)
 
+		keyRow := currentRow
+		if keyRow == nil {
+			keyRow = prevRow
+		}
+		keyBuff := table.EncodeTableKeyPrefix(t.TableInfo.ID, ctx.WriteBatch.ShardID, 32)
+		keyBuff, err := common.EncodeNullableKeyCols(keyRow, t.TableInfo.PrimaryKeyCols, t.colTypes,
+			t.TableInfo.KeyNullMarkers, keyBuff)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// We always take the previous row from storage - the previous row of an incoming row isn't known when it's
+		// ingested from Kafka, and for a tombstone only the key columns are known
+		pi, err := t.appendStoredRow(keyBuff, written, outRows)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		ci := -1
 		if currentRow != nil {
-			keyBuff := table.EncodeTableKeyPrefix(t.TableInfo.ID, ctx.WriteBatch.ShardID, 32)
-			keyBuff, err := common.EncodeKeyCols(currentRow, t.TableInfo.PrimaryKeyCols, t.TableInfo.ColumnTypes, keyBuff)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			v, err := t.store.LocalGet(keyBuff)
-			if err != nil {
+			if err := t.appendRow(currentRow, outRows); err != nil {
 				return errors.WithStack(err)
 			}
-			pi := -1
-			if v != nil {
-				// Row already exists in storage - this is the case where a new rows comes into a source for the same key
-				// we won't have previousRow provided for us in this case as Kafka does not provide this
-				if err := common.DecodeRow(v, t.colTypes, outRows); err != nil {
-					return errors.WithStack(err)
-				}
-				pi = rc
-				rc++
-			}
-			outRows.AppendRow(*currentRow)
-			ci := rc
-			rc++
-			entries[i].prevIndex = pi
-			entries[i].currIndex = ci
+			ci = outRows.RowCount() - 1
+			entries = append(entries, NewRowsEntry(pi, ci))
+			written[string(keyBuff)] = ci
+			row := outRows.GetRow(ci)
 			var valueBuff []byte
-			valueBuff, err = common.EncodeRow(currentRow, t.colTypes, valueBuff)
+			valueBuff, err = common.EncodeRow(&row, t.colTypes, valueBuff)
 			if err != nil {
 				return errors.WithStack(err)
 			}
 			ctx.WriteBatch.AddPut(keyBuff, valueBuff)
-		} else {
-			// It's a delete
-			keyBuff := table.EncodeTableKeyPrefix(t.TableInfo.ID, ctx.WriteBatch.ShardID, 32)
-			keyBuff, err := common.EncodeKeyCols(prevRow, t.TableInfo.PrimaryKeyCols, t.colTypes, keyBuff)
-			if err != nil {
+		} else if pi != -1 {
+			// It's a delete - there's nothing to do if the row doesn't exist
+			entries = append(entries, NewRowsEntry(pi, -1))
+			written[string(keyBuff)] = -1
+			ctx.WriteBatch.AddDelete(keyBuff)
+		}
+		if retentionEntries != nil {
+			if err := retentionEntries.update(outRows, pi, ci); err != nil {
 				return errors.WithStack(err)
 			}
-			outRows.AppendRow(*prevRow)
-			entries[i].prevIndex = rc
-			entries[i].currIndex = -1
-			rc++
-			ctx.WriteBatch.AddDelete(keyBuff)
 		}
 	}
+	if retentionEntries != nil {
+		retentionEntries.addToBatch(ctx.WriteBatch)
+	}
 	err := t.handleForwardAndCapture(NewRowsBatch(outRows, entries), ctx)
 	t.lock.RUnlock()
 	return errors.WithStack(err)
 }
 
+// appendRow appends the row to the rows. A row which was decoded before a column was added to the table doesn't have
+// the column, so it's appended with the column as null.
+func (t *TableExecutor) appendRow(row *common.Row, rows *common.Rows) error {
+	if row.ColCount() == len(t.colTypes) {
+		rows.AppendRow(*row)
+		return nil
+	}
+	buff, err := common.EncodeRow(row, row.ColumnTypes(), nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return common.DecodeRow(buff, t.colTypes, rows)
+}
+
+// appendStoredRow appends the current value of the row with the key to the rows, and returns its index, or -1 if there
+// is no such row
+func (t *TableExecutor) appendStoredRow(key []byte, written map[string]int, rows *common.Rows) (int, error) {
+	if index, ok := written[string(key)]; ok {
+		return index, nil
+	}
+	v, err := t.store.LocalGet(key)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if v == nil {
+		return -1, nil
+	}
+	if err := common.DecodeRow(v, t.colTypes, rows); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return rows.RowCount() - 1, nil
+}
+
 func (t *TableExecutor) handleForwardAndCapture(rowsBatch RowsBatch, ctx *ExecutionContext) error {
 	if err := t.ForwardToConsumingNodes(rowsBatch, ctx); err != nil {
 		return errors.WithStack(err)
@@ -166,6 +233,9 @@ func (t *TableExecutor) handleForwardAndCapture(rowsBatch RowsBatch, ctx *Execut
 			return errors.WithStack(err)
 		}
 	}
+	if t.changeListener != nil && rowsBatch.Len() != 0 {
+		t.changeListener.HandleChanges(rowsBatch, ctx)
+	}
 	return nil
 }
 
@@ -457,6 +527,7 @@ func (t *TableExecutor) sendFillBatchFromPairs(pe PushExecutor, shardID uint64,
 	wb := cluster.NewWriteBatch(shardID)
 	// We disable duplicate detection for the fill
 	ctx := NewExecutionContext(wb, false)
+	ctx.Filling = true
 	if err := pe.HandleRows(NewCurrentRowsBatch(rows), ctx); err != nil {
 		return errors.WithStack(err)
 	}
@@ -120,13 +120,13 @@ func (t *Scan) appendResultRow(row *common.Row, outRows *common.Rows) error {
 		} else {
 			colType := t.colTypes[i]
 			switch colType.Type {
-			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 				val := row.GetInt64(incomingColIndex)
 				outRows.AppendInt64ToColumn(i, val)
 			case common.TypeDouble:
 				val := row.GetFloat64(incomingColIndex)
 				outRows.AppendFloat64ToColumn(i, val)
-			case common.TypeVarchar:
+			case common.TypeVarchar, common.TypeVarbinary:
 				val := row.GetString(incomingColIndex)
 				outRows.AppendStringToColumn(i, val)
 			case common.TypeDecimal:
@@ -6,12 +6,16 @@ import (
 
 type UnionAll struct {
 	pushExecutorBase
-	genIDColIndex int
+	genIDColIndex  int
+	keyNullMarkers bool
 }
 
-func NewUnionAll() (*UnionAll, error) {
+// NewUnionAll creates a union all. If keyNullMarkers is set the keys of the children are encoded with null markers in
+// the generated ids, see common.TableInfo.KeyNullMarkers.
+func NewUnionAll(keyNullMarkers bool) (*UnionAll, error) {
 	return &UnionAll{
 		pushExecutorBase: pushExecutorBase{},
+		keyNullMarkers:   keyNullMarkers,
 	}, nil
 }
 
@@ -94,11 +98,11 @@ func (u *UnionAll) appendRow(index int, inRow *common.Row, out *common.Rows) err
 		} else {
 			colType := u.colTypes[i]
 			switch colType.Type {
-			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+			case common.TypeTinyInt, common.TypeInt, common.TypeBigInt, common.TypeBoolean:
 				out.AppendInt64ToColumn(i, inRow.GetInt64(i))
 			case common.TypeDouble:
 				out.AppendFloat64ToColumn(i, inRow.GetFloat64(i))
-			case common.TypeVarchar:
+			case common.TypeVarchar, common.TypeVarbinary:
 				out.AppendStringToColumn(i, inRow.GetString(i))
 			case common.TypeTimestamp:
 				out.AppendTimestampToColumn(i, inRow.GetTimestamp(i))
@@ -122,7 +126,7 @@ func (u *UnionAll) generateID(row *common.Row, index int) (string, error) {
 	// We create the key by prefixing with the index, then appending the key from the actual child
 	var key []byte
 	key = common.AppendUint32ToBufferLE(key, uint32(index))
-	key, err := common.EncodeKeyCols(row, actualChild.KeyCols(), actualChild.ColTypes(), key)
+	key, err := common.EncodeNullableKeyCols(row, actualChild.KeyCols(), actualChild.ColTypes(), u.keyNullMarkers, key)
 	if err != nil {
 		return "", err
 	}
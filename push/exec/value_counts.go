@@ -0,0 +1,164 @@
+package exec
+
+import (
+	"sort"
+
+	"github.com/squareup/pranadb/aggfuncs"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/table"
+)
+
+// storedValueCounts are the value counts of a group of a push aggregation, see aggfuncs.ValueCounts. Each value is a
+// row in the values table of the aggregation with the count as its value. The key is the aggregate table key of the
+// group without the shard id, followed by the index of the function and the value, encoded so that the first value of
+// the function is the first key with the prefix.
+//
+// Counts written while handling a batch are kept in the execution context until the end of the call to the
+// aggregation, when the final state of each is added to the write batch, see writePendingAggValues.
+type storedValueCounts struct {
+	agg    *Aggregator
+	ctx    *ExecutionContext
+	prefix []byte
+}
+
+func (a *Aggregator) newStoredValueCounts(keyBytes []byte, ctx *ExecutionContext) *storedValueCounts {
+	prefix := table.EncodeTableKeyPrefix(a.ValuesTableInfo.ID, ctx.WriteBatch.ShardID, len(keyBytes)+8)
+	// The first 8 bytes of the aggregate table key are the shard id, which is already in the prefix
+	prefix = append(prefix, keyBytes[8:]...)
+	return &storedValueCounts{agg: a, ctx: ctx, prefix: prefix}
+}
+
+func (s *storedValueCounts) Add(index int, value interface{}, delta int64) (int64, int64, error) {
+	funcPrefix := s.funcPrefix(index)
+	key, err := aggfuncs.EncodeValueKey(common.CopyByteSlice(funcPrefix), value, s.agg.aggFuncs[index].ArgType(),
+		aggfuncs.DescendingValues(s.agg.aggFuncs[index]))
+	if err != nil {
+		return 0, 0, errors.WithStack(err)
+	}
+	prevCount, err := s.count(funcPrefix, key)
+	if err != nil {
+		return 0, 0, errors.WithStack(err)
+	}
+	newCount := prevCount + delta
+	if newCount < 0 {
+		return 0, 0, errors.Errorf("count of value %v is negative", value)
+	}
+	var countBytes []byte
+	if newCount != 0 {
+		countBytes = common.AppendUint64ToBufferLE(nil, uint64(newCount))
+	}
+	putPendingAggValue(s.ctx, funcPrefix, key, countBytes)
+	return prevCount, newCount, nil
+}
+
+func (s *storedValueCounts) First(index int) (interface{}, error) {
+	funcPrefix := s.funcPrefix(index)
+	pending := s.ctx.pendingAggValues[string(funcPrefix)]
+	// Each of the first values in storage might have been removed while handling this batch, so we scan for one more
+	// value than there are pending changes
+	kvPairs, err := s.agg.storage.LocalScan(funcPrefix, common.IncrementBytesBigEndian(funcPrefix), len(pending)+1)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var first string
+	found := false
+	for _, kvPair := range kvPairs {
+		key := string(kvPair.Key)
+		if value, ok := pending[key]; ok && value == nil {
+			continue
+		}
+		first, found = key, true
+		break
+	}
+	for key, value := range pending {
+		if value != nil && (!found || key < first) {
+			first, found = key, true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	value, err := aggfuncs.DecodeValueKey([]byte(first[len(funcPrefix):]), s.agg.aggFuncs[index].ArgType(),
+		aggfuncs.DescendingValues(s.agg.aggFuncs[index]))
+	return value, errors.WithStack(err)
+}
+
+// deleteAll deletes the counts of all the values of the group, e.g. when its window has closed
+func (s *storedValueCounts) deleteAll() error {
+	for index, aggFunc := range s.agg.aggFuncs {
+		if !aggFunc.RequiresValueCounts() {
+			continue
+		}
+		funcPrefix := s.funcPrefix(index)
+		kvPairs, err := s.agg.storage.LocalScan(funcPrefix, common.IncrementBytesBigEndian(funcPrefix), -1)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, kvPair := range kvPairs {
+			putPendingAggValue(s.ctx, funcPrefix, kvPair.Key, nil)
+		}
+		for key := range s.ctx.pendingAggValues[string(funcPrefix)] {
+			putPendingAggValue(s.ctx, funcPrefix, []byte(key), nil)
+		}
+	}
+	return nil
+}
+
+func (s *storedValueCounts) count(funcPrefix []byte, key []byte) (int64, error) {
+	value, ok := s.ctx.pendingAggValues[string(funcPrefix)][string(key)]
+	if !ok {
+		var err error
+		value, err = s.agg.storage.LocalGet(key)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+	}
+	if value == nil {
+		return 0, nil
+	}
+	count, _ := common.ReadInt64FromBufferLE(value, 0)
+	return count, nil
+}
+
+func (s *storedValueCounts) funcPrefix(index int) []byte {
+	funcPrefix := make([]byte, 0, len(s.prefix)+17)
+	funcPrefix = append(funcPrefix, s.prefix...)
+	return append(funcPrefix, byte(index))
+}
+
+func putPendingAggValue(ctx *ExecutionContext, funcPrefix []byte, key []byte, value []byte) {
+	if ctx.pendingAggValues == nil {
+		ctx.pendingAggValues = make(map[string]map[string][]byte)
+	}
+	pending, ok := ctx.pendingAggValues[string(funcPrefix)]
+	if !ok {
+		pending = make(map[string][]byte)
+		ctx.pendingAggValues[string(funcPrefix)] = pending
+	}
+	pending[string(key)] = value
+	if ctx.writtenAggValues == nil {
+		ctx.writtenAggValues = make(map[string]string)
+	}
+	ctx.writtenAggValues[string(key)] = string(funcPrefix)
+}
+
+// writePendingAggValues adds the final state of the value counts written by the current call of an aggregation to the
+// write batch. Like writePendingJoinRows, we only do this once per key as a put followed by a delete of the same key
+// in the same write batch would not be applied in order.
+func writePendingAggValues(ctx *ExecutionContext) {
+	keys := make([]string, 0, len(ctx.writtenAggValues))
+	for key := range ctx.writtenAggValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := ctx.pendingAggValues[ctx.writtenAggValues[key]][key]
+		if value == nil {
+			ctx.WriteBatch.AddDelete([]byte(key))
+		} else {
+			ctx.WriteBatch.AddPut([]byte(key), value)
+		}
+	}
+	ctx.writtenAggValues = nil
+}
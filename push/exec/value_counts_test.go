@@ -0,0 +1,90 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/squareup/pranadb/aggfuncs"
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/cluster/fake"
+	"github.com/squareup/pranadb/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoredValueCounts(t *testing.T) {
+	clust := fake.NewFakeCluster(0, 10)
+	minFunc, err := aggfuncs.NewAggregateFunction(nil, aggfuncs.MinAggregateFunctionType, common.VarcharColumnType)
+	require.NoError(t, err)
+	maxFunc, err := aggfuncs.NewAggregateFunction(nil, aggfuncs.MaxAggregateFunctionType, common.VarcharColumnType)
+	require.NoError(t, err)
+	agg := &Aggregator{
+		aggFuncs:        []aggfuncs.AggregateFunction{minFunc, maxFunc},
+		ValuesTableInfo: &common.TableInfo{ID: 1001},
+		storage:         clust,
+	}
+	groupKey := []byte("0123456789abcdefgroup1")
+	otherGroupKey := []byte("0123456789abcdefgroup2")
+
+	// Values written in the batch are seen before they're stored
+	ctx := NewExecutionContext(cluster.NewWriteBatch(cluster.DataShardIDBase), false)
+	vc := agg.newStoredValueCounts(groupKey, ctx)
+	for _, value := range []string{"b", "a", "c", "b", "aa"} {
+		for index := range agg.aggFuncs {
+			_, _, err := vc.Add(index, value, 1)
+			require.NoError(t, err)
+		}
+	}
+	_, _, err = agg.newStoredValueCounts(otherGroupKey, ctx).Add(1, "z", 1)
+	require.NoError(t, err)
+	requireFirst(t, vc, 0, "a")
+	requireFirst(t, vc, 1, "c")
+	writePendingAggValues(ctx)
+	require.NoError(t, clust.WriteBatch(ctx.WriteBatch))
+
+	// Removing values in a later batch uses the stored values
+	ctx = NewExecutionContext(cluster.NewWriteBatch(cluster.DataShardIDBase), false)
+	vc = agg.newStoredValueCounts(groupKey, ctx)
+	prevCount, newCount, err := vc.Add(1, "b", -1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), prevCount)
+	require.Equal(t, int64(1), newCount)
+	_, _, err = vc.Add(1, "c", -1)
+	require.NoError(t, err)
+	requireFirst(t, vc, 1, "b")
+	_, _, err = vc.Add(1, "b", -1)
+	require.NoError(t, err)
+	requireFirst(t, vc, 1, "aa")
+	_, _, err = vc.Add(1, "d", 1)
+	require.NoError(t, err)
+	requireFirst(t, vc, 1, "d")
+	_, _, err = vc.Add(0, "a", -1)
+	require.NoError(t, err)
+	requireFirst(t, vc, 0, "aa")
+	writePendingAggValues(ctx)
+	require.NoError(t, clust.WriteBatch(ctx.WriteBatch))
+
+	ctx = NewExecutionContext(cluster.NewWriteBatch(cluster.DataShardIDBase), false)
+	vc = agg.newStoredValueCounts(groupKey, ctx)
+	requireFirst(t, vc, 0, "aa")
+	requireFirst(t, vc, 1, "d")
+
+	// Deleting all the values of a group leaves the other groups
+	require.NoError(t, vc.deleteAll())
+	writePendingAggValues(ctx)
+	require.NoError(t, clust.WriteBatch(ctx.WriteBatch))
+	ctx = NewExecutionContext(cluster.NewWriteBatch(cluster.DataShardIDBase), false)
+	vc = agg.newStoredValueCounts(groupKey, ctx)
+	first, err := vc.First(0)
+	require.NoError(t, err)
+	require.Nil(t, first)
+	first, err = vc.First(1)
+	require.NoError(t, err)
+	require.Nil(t, first)
+	requireFirst(t, agg.newStoredValueCounts(otherGroupKey, ctx), 1, "z")
+}
+
+func requireFirst(t *testing.T, vc *storedValueCounts, index int, expected interface{}) {
+	t.Helper()
+	first, err := vc.First(index)
+	require.NoError(t, err)
+	require.Equal(t, expected, first)
+}
@@ -2,7 +2,12 @@ package push
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/pingcap/parser/ast"
+	"github.com/pingcap/parser/mysql"
 	"github.com/squareup/pranadb/tidb/planner"
+	"github.com/squareup/pranadb/tidb/types"
 
 	"github.com/squareup/pranadb/errors"
 
@@ -23,7 +28,8 @@ func (m *MaterializedView) buildPushQueryExecution(pl *parplan.Planner, schema *
 		return nil, nil, errors.WithStack(err)
 	}
 	// Build initial dag from the plan
-	dag, internalTables, err := m.buildPushDAG(physicalPlan, 0, schema, mvName, seqGenerator)
+	internalTableSeq := 0
+	dag, internalTables, err := m.buildPushDAG(physicalPlan, &internalTableSeq, schema, mvName, seqGenerator)
 	if err != nil {
 		return nil, nil, errors.WithStack(err)
 	}
@@ -38,86 +44,125 @@ func (m *MaterializedView) buildPushQueryExecution(pl *parplan.Planner, schema *
 	for _, colName := range logicalPlan.OutputNames() {
 		colNames = append(colNames, colName.ColName.L)
 	}
+	// Invisible columns always come after the visible ones, and they need names too as they can be part of the key
+	hiddenColSeq := 0
+	for _, visible := range dag.ColsVisible() {
+		if !visible {
+			colNames = append(colNames, fmt.Sprintf("__gen_hid_id%d", hiddenColSeq))
+			hiddenColSeq++
+		}
+	}
 	dag.SetColNames(colNames)
 	return dag, internalTables, nil
 }
 
 // TODO: extract functions and break apart giant switch
 // nolint: gocyclo
-func (m *MaterializedView) buildPushDAG(plan planner.PhysicalPlan, aggSequence int, schema *common.Schema, mvName string,
+func (m *MaterializedView) buildPushDAG(plan planner.PhysicalPlan, internalTableSeq *int, schema *common.Schema, mvName string,
 	seqGenerator common.SeqGenerator) (exec.PushExecutor, []*common.InternalTableInfo, error) {
 	var internalTables []*common.InternalTableInfo
 	var executor exec.PushExecutor
 	var err error
+	// top is the executor returned to the parent - it's usually the same as executor
+	var top exec.PushExecutor
 	switch op := plan.(type) {
 	case *planner.PhysicalProjection:
+		projExprs, err := replaceWindowClosedFuncs(op.Exprs, op.Children()[0])
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
 		var exprs []*common.Expression
-		for _, expr := range op.Exprs {
+		for _, expr := range projExprs {
 			exprs = append(exprs, common.NewExpression(expr))
 		}
 		executor = exec.NewPushProjection(exprs)
 	case *planner.PhysicalSelection:
+		conditions, err := replaceWindowClosedFuncs(op.Conditions, op.Children()[0])
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
 		var exprs []*common.Expression
-		for _, expr := range op.Conditions {
+		for _, expr := range conditions {
 			exprs = append(exprs, common.NewExpression(expr))
 		}
 		executor = exec.NewPushSelect(exprs)
 	case *planner.PhysicalHashAgg:
-		var aggFuncs []*exec.AggregateFunctionInfo
+		window, err := findWindowGroupBy(op)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		var aggWindow *exec.AggregatorWindow
+		if window != nil {
+			aggWindow = &exec.AggregatorWindow{Spec: window.spec, WindowCol: window.col.Index,
+				UseWatermark: window.isSourceEventTime(schema)}
+		}
 
-		firstRowFuncs := 0
-		for _, aggFunc := range op.AggFuncs {
-			argExprs := aggFunc.Args
-			if len(argExprs) > 1 {
-				return nil, nil, errors.Error("more than one aggregate function arg")
-			}
-			var argExpr *common.Expression
-			if len(argExprs) == 1 {
-				argExpr = common.NewExpression(argExprs[0])
-			}
+		var aggFuncs []*aggfuncs.AggregateFunctionInfo
 
-			var funcType aggfuncs.AggFunctionType
-			switch aggFunc.Name {
-			case "sum":
-				funcType = aggfuncs.SumAggregateFunctionType
-			case "count":
-				funcType = aggfuncs.CountAggregateFunctionType
-			case "firstrow":
-				funcType = aggfuncs.FirstRowAggregateFunctionType
-				firstRowFuncs++
-			default:
-				return nil, nil, errors.Errorf("unexpected aggregate function %s", aggFunc.Name)
+		for _, aggFunc := range op.AggFuncs {
+			af, err := aggfuncs.NewAggregateFunctionInfo(aggFunc)
+			if err != nil {
+				return nil, nil, err
 			}
-			colType := common.ConvertTiDBTypeToPranaType(aggFunc.RetTp)
-			af := &exec.AggregateFunctionInfo{
-				FuncType:   funcType,
-				Distinct:   aggFunc.HasDistinct,
-				ArgExpr:    argExpr,
-				ReturnType: colType,
+			if window != nil && aggFunc.Name == "firstrow" && len(aggFunc.Args) == 1 && window.isEventTime(aggFunc.Args[0]) {
+				// The event time takes the window start, see exec.AggregatorWindow
+				aggWindow.EventTimeCols = append(aggWindow.EventTimeCols, len(aggFuncs))
+				af.ArgExpr = nil
 			}
 			aggFuncs = append(aggFuncs, af)
 		}
 
-		nonFirstRowFuncs := len(aggFuncs) - firstRowFuncs
-
 		// These are the indexes of the group by cols in the output of the aggregation
-		pkCols := make([]int, len(op.GroupByItems))
+		var pkCols []int
 
 		// These are the indexes of the group by cols in the input of the aggregation
-		groupByCols := make([]int, len(op.GroupByItems))
+		var groupByCols []int
+
+		// The group by cols that aren't in the output, e.g. when only an expression over a group by col is selected
+		var hiddenCols []int
 
 		for i, expr := range op.GroupByItems {
+			// Group by expressions are evaluated by a projection below the aggregation, so they are columns here
 			col, ok := expr.(*expression.Column)
 			if !ok {
 				return nil, nil, errors.Error("group by expression not a column")
 			}
-			groupByCols[i] = col.Index
-			pkCols[i] = i + nonFirstRowFuncs
+			if window != nil && i == window.groupByIndex {
+				// The window is added to the key by the aggregator
+				continue
+			}
+			groupByCols = append(groupByCols, col.Index)
+			pkCol := aggfuncs.FirstRowOfColumn(op.AggFuncs, aggFuncs, col)
+			if pkCol == -1 {
+				// The group by col isn't selected, but it's needed as part of the key so we add it as a hidden column
+				pkCol = len(aggFuncs)
+				hiddenCols = append(hiddenCols, pkCol)
+				aggFuncs = append(aggFuncs, &aggfuncs.AggregateFunctionInfo{
+					FuncType:   aggfuncs.FirstRowAggregateFunctionType,
+					ArgExpr:    common.NewExpression(col),
+					ReturnType: common.ConvertTiDBTypeToPranaType(col.GetType()),
+				})
+			}
+			pkCols = append(pkCols, pkCol)
+		}
+
+		if window != nil {
+			// The window start and end are added to the output, along with the count of shards that have the window
+			// open
+			aggWindow.WindowStartCol = len(aggFuncs)
+			aggWindow.WindowEndCol = len(aggFuncs) + 1
+			aggWindow.OpenCol = len(aggFuncs) + 2
+			windowColType := common.ConvertTiDBTypeToPranaType(window.fn.GetType())
+			aggFuncs = append(aggFuncs,
+				&aggfuncs.AggregateFunctionInfo{FuncType: aggfuncs.FirstRowAggregateFunctionType, ReturnType: windowColType},
+				&aggfuncs.AggregateFunctionInfo{FuncType: aggfuncs.FirstRowAggregateFunctionType, ReturnType: windowColType},
+				&aggfuncs.AggregateFunctionInfo{FuncType: aggfuncs.CountAggregateFunctionType, ReturnType: common.BigIntColumnType})
+			pkCols = append(pkCols, aggWindow.WindowStartCol, aggWindow.WindowEndCol)
 		}
 
 		partialTableID := seqGenerator.GenerateSequence()
-		partialTableName := fmt.Sprintf("%s-partial-aggtable-%d", mvName, aggSequence)
-		aggSequence++
+		partialTableName := fmt.Sprintf("%s-partial-aggtable-%d", mvName, *internalTableSeq)
+		*internalTableSeq++
 		partialTableInfo := &common.TableInfo{
 			ID:             partialTableID,
 			SchemaName:     schema.Name,
@@ -125,10 +170,11 @@ func (m *MaterializedView) buildPushDAG(plan planner.PhysicalPlan, aggSequence i
 			PrimaryKeyCols: pkCols,
 			IndexInfos:     nil, // TODO
 			Internal:       true,
+			KeyNullMarkers: m.keyNullMarkers,
 		}
 		fullTableID := seqGenerator.GenerateSequence()
-		fullTableName := fmt.Sprintf("%s-full-aggtable-%d", mvName, aggSequence)
-		aggSequence++
+		fullTableName := fmt.Sprintf("%s-full-aggtable-%d", mvName, *internalTableSeq)
+		*internalTableSeq++
 		fullTableInfo := &common.TableInfo{
 			ID:             fullTableID,
 			SchemaName:     schema.Name,
@@ -136,6 +182,7 @@ func (m *MaterializedView) buildPushDAG(plan planner.PhysicalPlan, aggSequence i
 			PrimaryKeyCols: pkCols,
 			IndexInfos:     nil, // TODO
 			Internal:       true,
+			KeyNullMarkers: m.keyNullMarkers,
 		}
 		partialAggInfo := &common.InternalTableInfo{
 			TableInfo:            partialTableInfo,
@@ -147,12 +194,106 @@ func (m *MaterializedView) buildPushDAG(plan planner.PhysicalPlan, aggSequence i
 			MaterializedViewName: mvName,
 		}
 		internalTables = append(internalTables, fullAggInfo)
-		executor, err = exec.NewAggregator(pkCols, aggFuncs, partialTableInfo, fullTableInfo, groupByCols, m.cluster, m.sharder)
+		var valuesTableInfo *common.TableInfo
+		if requiresValuesTable(op) {
+			valuesTableID := seqGenerator.GenerateSequence()
+			valuesTableName := fmt.Sprintf("%s-values-aggtable-%d", mvName, *internalTableSeq)
+			*internalTableSeq++
+			valuesTableInfo = &common.TableInfo{
+				ID:             valuesTableID,
+				SchemaName:     schema.Name,
+				Name:           valuesTableName,
+				ColumnTypes:    []common.ColumnType{common.BigIntColumnType},
+				Internal:       true,
+				KeyNullMarkers: m.keyNullMarkers,
+			}
+			internalTables = append(internalTables, &common.InternalTableInfo{
+				TableInfo:            valuesTableInfo,
+				MaterializedViewName: mvName,
+			})
+		}
+		executor, err = exec.NewAggregator(pkCols, aggFuncs, partialTableInfo, fullTableInfo, valuesTableInfo, groupByCols,
+			hiddenCols, aggWindow, m.cluster, m.sharder)
 		if err != nil {
 			return nil, nil, errors.WithStack(err)
 		}
+	case *planner.PhysicalHashJoin:
+		var joinType exec.JoinType
+		switch op.JoinType {
+		case planner.InnerJoin:
+			joinType = exec.JoinTypeInner
+		case planner.LeftOuterJoin:
+			joinType = exec.JoinTypeLeftOuter
+		default:
+			return nil, nil, errors.NewPranaErrorf(errors.InvalidStatement, "Unsupported join type %s", op.JoinType)
+		}
+		if len(op.EqualConditions) == 0 {
+			return nil, nil, errors.NewInvalidStatementError("Join must have at least one equality condition")
+		}
+		if len(op.RightConditions) != 0 {
+			return nil, nil, errors.NewInvalidStatementError("Unsupported join condition")
+		}
+		if op.ScalarSubquery && !joinsOnPrimaryKey(op, schema) {
+			// The rows aren't known until they arrive, when it's too late to reject them
+			return nil, nil, errors.NewInvalidStatementError("Scalar subqueries in materialized views must " +
+				"aggregate or select by the primary key, so they cannot return more than one row")
+		}
+		leftTables := scannedTableNames(op.Children()[0])
+		for tableName := range scannedTableNames(op.Children()[1]) {
+			if _, ok := leftTables[tableName]; ok {
+				return nil, nil, errors.NewPranaErrorf(errors.InvalidStatement, "Cannot join %s to itself", tableName)
+			}
+		}
+		leftJoinCols := make([]int, len(op.LeftJoinKeys))
+		for i, col := range op.LeftJoinKeys {
+			leftJoinCols[i] = col.Index
+		}
+		rightJoinCols := make([]int, len(op.RightJoinKeys))
+		for i, col := range op.RightJoinKeys {
+			rightJoinCols[i] = col.Index
+		}
+		// Conditions on the left input are only left in the join for outer joins. The left columns come first in the
+		// joined row so they can be evaluated along with the other conditions.
+		var otherConditions []*common.Expression
+		for _, expr := range op.LeftConditions {
+			otherConditions = append(otherConditions, common.NewExpression(expr))
+		}
+		for _, expr := range op.OtherConditions {
+			otherConditions = append(otherConditions, common.NewExpression(expr))
+		}
+		leftTableInfo := &common.TableInfo{
+			ID:             seqGenerator.GenerateSequence(),
+			SchemaName:     schema.Name,
+			Name:           fmt.Sprintf("%s-left-jointable-%d", mvName, *internalTableSeq),
+			Internal:       true,
+			KeyNullMarkers: m.keyNullMarkers,
+		}
+		*internalTableSeq++
+		rightTableInfo := &common.TableInfo{
+			ID:             seqGenerator.GenerateSequence(),
+			SchemaName:     schema.Name,
+			Name:           fmt.Sprintf("%s-right-jointable-%d", mvName, *internalTableSeq),
+			Internal:       true,
+			KeyNullMarkers: m.keyNullMarkers,
+		}
+		*internalTableSeq++
+		internalTables = append(internalTables,
+			&common.InternalTableInfo{TableInfo: leftTableInfo, MaterializedViewName: mvName},
+			&common.InternalTableInfo{TableInfo: rightTableInfo, MaterializedViewName: mvName})
+		leftColCount := len(op.Children()[0].Schema().Columns)
+		rightColCount := len(op.Children()[1].Schema().Columns)
+		executor, err = exec.NewJoin(joinType, leftJoinCols, rightJoinCols, leftColCount, rightColCount, otherConditions,
+			leftTableInfo, rightTableInfo, m.cluster, m.sharder)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		// The planner can prune the columns of the join itself, e.g. for outer joins, while the join always outputs
+		// all the columns of its inputs. In this case we put a projection on top of the join.
+		if len(op.Schema().Columns) != leftColCount+rightColCount {
+			top = exec.NewPushProjection(joinProjectionExprs(op))
+		}
 	case *planner.PhysicalUnionAll:
-		executor, err = exec.NewUnionAll()
+		executor, err = exec.NewUnionAll(m.keyNullMarkers)
 		if err != nil {
 			return nil, nil, errors.WithStack(err)
 		}
@@ -182,10 +323,13 @@ func (m *MaterializedView) buildPushDAG(plan planner.PhysicalPlan, aggSequence i
 	default:
 		return nil, nil, errors.Errorf("unexpected plan type %T", plan)
 	}
+	if top == nil {
+		top = executor
+	}
 
 	var childExecutors []exec.PushExecutor
 	for _, child := range plan.Children() {
-		childExecutor, it, err := m.buildPushDAG(child, aggSequence, schema, mvName, seqGenerator)
+		childExecutor, it, err := m.buildPushDAG(child, internalTableSeq, schema, mvName, seqGenerator)
 		if err != nil {
 			return nil, nil, errors.WithStack(err)
 		}
@@ -195,7 +339,237 @@ func (m *MaterializedView) buildPushDAG(plan planner.PhysicalPlan, aggSequence i
 		}
 	}
 	exec.ConnectPushExecutors(childExecutors, executor)
-	return executor, internalTables, nil
+	if top != executor {
+		exec.ConnectPushExecutors([]exec.PushExecutor{executor}, top)
+	}
+	return top, internalTables, nil
+}
+
+func joinProjectionExprs(join *planner.PhysicalHashJoin) []*common.Expression {
+	joinedSchema := expression.MergeSchema(join.Children()[0].Schema(), join.Children()[1].Schema())
+	exprs := make([]*common.Expression, len(join.Schema().Columns))
+	for i, col := range join.Schema().Columns {
+		projCol := col.Clone().(*expression.Column) //nolint:forcetypeassert
+		projCol.Index = joinedSchema.ColumnIndex(col)
+		exprs[i] = common.NewExpression(projCol)
+	}
+	return exprs
+}
+
+// windowGroupBy is a TUMBLE or HOP group by item of an aggregation. The planner evaluates group by expressions in a
+// projection below the aggregation, so the group by item is a column that gives the start of the latest window for the
+// row.
+type windowGroupBy struct {
+	groupByIndex int
+	col          *expression.Column
+	fn           *expression.ScalarFunction
+	spec         *expression.WindowSpec
+	proj         *planner.PhysicalProjection
+}
+
+func findWindowGroupBy(agg *planner.PhysicalHashAgg) (*windowGroupBy, error) {
+	proj, ok := agg.Children()[0].(*planner.PhysicalProjection)
+	if !ok {
+		return nil, nil
+	}
+	var window *windowGroupBy
+	for i, expr := range agg.GroupByItems {
+		col, ok := expr.(*expression.Column)
+		if !ok {
+			continue
+		}
+		fn, ok := proj.Exprs[col.Index].(*expression.ScalarFunction)
+		if !ok || (fn.FuncName.L != expression.Tumble && fn.FuncName.L != expression.Hop) {
+			continue
+		}
+		if window != nil {
+			return nil, errors.NewInvalidStatementError("Only one window can be used in GROUP BY")
+		}
+		spec, err := expression.NewWindowSpec(fn.FuncName.L, fn.GetArgs())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		window = &windowGroupBy{groupByIndex: i, col: col, fn: fn, spec: spec, proj: proj}
+	}
+	return window, nil
+}
+
+// isEventTime returns true if the aggregation input column is the event time column of the window
+func (w *windowGroupBy) isEventTime(expr expression.Expression) bool {
+	col, ok := expr.(*expression.Column)
+	if !ok {
+		return false
+	}
+	return w.proj.Exprs[col.Index].Equal(nil, w.fn.GetArgs()[0])
+}
+
+// isSourceEventTime returns true if the window is over the event time column of a source
+func (w *windowGroupBy) isSourceEventTime(schema *common.Schema) bool {
+	col, ok := w.fn.GetArgs()[0].(*expression.Column)
+	if !ok {
+		return false
+	}
+	// The original name is <schema>.<table>.<column>
+	parts := strings.Split(col.OrigName, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	tbl, ok := schema.GetTable(parts[1])
+	if !ok {
+		return false
+	}
+	sourceInfo, ok := tbl.(*common.SourceInfo)
+	if !ok || sourceInfo.TopicInfo.EventTime == nil {
+		return false
+	}
+	return sourceInfo.ColumnNames[sourceInfo.TopicInfo.EventTime.ColIndex] == parts[2]
+}
+
+// replaceWindowClosedFuncs replaces TUMBLE_CLOSED and HOP_CLOSED with a check that no shard has the window of the
+// windowed aggregation below open
+func replaceWindowClosedFuncs(exprs []expression.Expression, child planner.PhysicalPlan) ([]expression.Expression, error) {
+	res := make([]expression.Expression, len(exprs))
+	for i, expr := range exprs {
+		res[i] = expr
+		fn, ok := expr.(*expression.ScalarFunction)
+		if !ok || (fn.FuncName.L != expression.TumbleClosed && fn.FuncName.L != expression.HopClosed) {
+			continue
+		}
+		agg, err := findWindowedAgg(child)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if agg == nil {
+			return nil, errors.NewPranaErrorf(errors.InvalidStatement, "%s can only be used with a windowed aggregation",
+				fn.FuncName.O)
+		}
+		openCol := &expression.Column{
+			// See exec.AggregatorWindow
+			Index:   len(agg.AggFuncs) + 2,
+			RetType: types.NewFieldType(mysql.TypeLonglong),
+		}
+		res[i] = expression.NewFunctionInternal(fn.GetCtx(), ast.EQ, fn.GetType(), openCol, expression.NewZero())
+	}
+	return res, nil
+}
+
+func findWindowedAgg(plan planner.PhysicalPlan) (*planner.PhysicalHashAgg, error) {
+	switch op := plan.(type) {
+	case *planner.PhysicalSelection:
+		return findWindowedAgg(op.Children()[0])
+	case *planner.PhysicalHashAgg:
+		window, err := findWindowGroupBy(op)
+		if err != nil || window == nil {
+			return nil, err
+		}
+		return op, nil
+	}
+	return nil, nil
+}
+
+// NumTableIDsRequired returns how many table ids are needed to create a materialized view for the query - one for the
+// materialized view itself plus one for each of its internal tables
+func NumTableIDsRequired(pl *parplan.Planner, query string) (int, error) {
+	physicalPlan, _, err := pl.QueryToPlan(query, false, false)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return 1 + numInternalTables(physicalPlan), nil
+}
+
+func numInternalTables(plan planner.PhysicalPlan) int {
+	num := 0
+	switch op := plan.(type) {
+	case *planner.PhysicalHashAgg:
+		// An aggregation has a partial and a full aggregation table, and a values table if it needs one
+		num = 2
+		if requiresValuesTable(op) {
+			num++
+		}
+	case *planner.PhysicalHashJoin:
+		// A join has a table for each input
+		num = 2
+	}
+	for _, child := range plan.Children() {
+		num += numInternalTables(child)
+	}
+	return num
+}
+
+// requiresValuesTable returns true if any of the aggregate functions keep the counts of the values they have seen, in
+// the values table of the aggregation, see aggfuncs.ValueCounts
+func requiresValuesTable(op *planner.PhysicalHashAgg) bool {
+	for _, aggFunc := range op.AggFuncs {
+		if aggFunc.Name == ast.AggFuncMin || aggFunc.Name == ast.AggFuncMax || aggFunc.HasDistinct {
+			return true
+		}
+	}
+	return false
+}
+
+// joinsOnPrimaryKey returns true if the right of the join is a table, optionally with selections and projections over
+// it, and the join keys include all the primary key columns of the table, so a row on the left joins to at most one row
+// on the right
+func joinsOnPrimaryKey(op *planner.PhysicalHashJoin, schema *common.Schema) bool {
+	// For each join key we find the column of the table on the right it comes from, if it does
+	keyCols := make([]int, len(op.RightJoinKeys))
+	for i, col := range op.RightJoinKeys {
+		keyCols[i] = col.Index
+	}
+	plan := op.Children()[1]
+	for {
+		switch child := plan.(type) {
+		case *planner.PhysicalProjection:
+			for i, keyCol := range keyCols {
+				if keyCol == -1 {
+					continue
+				}
+				if col, ok := child.Exprs[keyCol].(*expression.Column); ok {
+					keyCols[i] = col.Index
+				} else {
+					keyCols[i] = -1
+				}
+			}
+		case *planner.PhysicalSelection:
+		case *planner.PhysicalTableScan:
+			tbl, ok := schema.GetTable(child.Table.Name.L)
+			if !ok {
+				return false
+			}
+			joined := make(map[int]struct{}, len(keyCols))
+			for _, keyCol := range keyCols {
+				if keyCol != -1 {
+					joined[child.Columns[keyCol].Offset] = struct{}{}
+				}
+			}
+			for _, pkCol := range tbl.GetTableInfo().PrimaryKeyCols {
+				if _, ok := joined[pkCol]; !ok {
+					return false
+				}
+			}
+			return true
+		default:
+			return false
+		}
+		plan = plan.Children()[0]
+	}
+}
+
+// scannedTableNames returns the names of the sources and materialized views scanned by the plan
+func scannedTableNames(plan planner.PhysicalPlan) map[string]struct{} {
+	tableNames := make(map[string]struct{})
+	switch op := plan.(type) {
+	case *planner.PhysicalTableScan:
+		tableNames[op.Table.Name.L] = struct{}{}
+	case *planner.PhysicalIndexScan:
+		tableNames[op.Table.Name.L] = struct{}{}
+	}
+	for _, child := range plan.Children() {
+		for tableName := range scannedTableNames(child) {
+			tableNames[tableName] = struct{}{}
+		}
+	}
+	return tableNames
 }
 
 // The schema provided by the planner may not be the ones we need. We need to provide information
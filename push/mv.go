@@ -18,17 +18,29 @@ type MaterializedView struct {
 	cluster        cluster.Cluster
 	InternalTables []*common.InternalTableInfo
 	sharder        *sharder.Sharder
+	// keyNullMarkers is set for the tables of materialized views created since key columns could have null markers,
+	// see common.TableInfo.KeyNullMarkers
+	keyNullMarkers bool
+	// replacing is set while the materialized view is filled to replace another with the same name, so both can
+	// consume from the same feeders until the other is disconnected
+	replacing bool
 }
 
-// CreateMaterializedView creates the materialized view but does not register it in memory
+// replacementConsumerSuffix is added to the name a replacing materialized view consumes its feeders with. It can't be
+// part of an identifier, so it can't be the name of another materialized view
+const replacementConsumerSuffix = "-replacement"
+
+// CreateMaterializedView creates the materialized view but does not register it in memory. keyNullMarkers is true for
+// new materialized views, and comes from the stored table info when they're loaded.
 func CreateMaterializedView(pe *Engine, pl *parplan.Planner, schema *common.Schema, mvName string, query string,
-	tableID uint64, seqGenerator common.SeqGenerator) (*MaterializedView, error) {
+	tableID uint64, keyNullMarkers bool, seqGenerator common.SeqGenerator) (*MaterializedView, error) {
 
 	mv := MaterializedView{
-		pe:      pe,
-		schema:  schema,
-		cluster: pe.cluster,
-		sharder: pe.sharder,
+		pe:             pe,
+		schema:         schema,
+		cluster:        pe.cluster,
+		sharder:        pe.sharder,
+		keyNullMarkers: keyNullMarkers,
 	}
 	dag, internalTables, err := mv.buildPushQueryExecution(pl, schema, query, mvName, seqGenerator)
 	if err != nil {
@@ -43,6 +55,7 @@ func CreateMaterializedView(pe *Engine, pl *parplan.Planner, schema *common.Sche
 		ColumnTypes:    dag.ColTypes(),
 		ColsVisible:    dag.ColsVisible(),
 		IndexInfos:     nil,
+		KeyNullMarkers: keyNullMarkers,
 	}
 	mvInfo := common.MaterializedViewInfo{
 		Query:     query,
@@ -61,6 +74,55 @@ func (m *MaterializedView) Connect(addConsuming bool, registerRemote bool) error
 	return m.connect(m.tableExecutor, addConsuming, registerRemote)
 }
 
+// SetReplacing marks the materialized view as the replacement of the materialized view with the same name. It must be
+// called before it's connected or filled
+func (m *MaterializedView) SetReplacing() {
+	m.replacing = true
+}
+
+// FinishReplace makes the materialized view consume from its feeders with its own name. The materialized view it
+// replaces must have been disconnected first
+func (m *MaterializedView) FinishReplace() error {
+	if !m.replacing {
+		return nil
+	}
+	tes, tss, err := m.getFeedingExecutors(m.tableExecutor)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i, te := range tes {
+		te.RemoveConsumingNode(m.consumerName())
+		te.AddConsumingNode(m.Info.Name, tss[i])
+	}
+	m.replacing = false
+	return nil
+}
+
+// ConsumesFrom returns true if the materialized view scans the table with the name
+func (m *MaterializedView) ConsumesFrom(tableName string) bool {
+	return consumesFrom(m.tableExecutor, tableName)
+}
+
+func consumesFrom(executor exec.PushExecutor, tableName string) bool {
+	if ts, ok := executor.(*exec.Scan); ok && ts.TableName == tableName {
+		return true
+	}
+	for _, child := range executor.GetChildren() {
+		if consumesFrom(child, tableName) {
+			return true
+		}
+	}
+	return false
+}
+
+// consumerName is the name the materialized view is added to its feeders with
+func (m *MaterializedView) consumerName() string {
+	if m.replacing {
+		return m.Info.Name + replacementConsumerSuffix
+	}
+	return m.Info.Name
+}
+
 func (m *MaterializedView) Disconnect() error {
 	return m.disconnectOrDeleteDataForMV(m.schema, m.tableExecutor, true, false)
 }
@@ -71,6 +133,11 @@ func (m *MaterializedView) Drop() error {
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	if retention := m.Info.Retention; retention != nil {
+		if err := m.deleteTableData(retention.IndexID); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	return m.deleteTableData(m.Info.ID)
 }
 
@@ -90,7 +157,15 @@ func (m *MaterializedView) disconnectOrDeleteDataForMV(schema *common.Schema, no
 				if err != nil {
 					return errors.WithStack(err)
 				}
-				source.RemoveConsumingExecutor(m.Info.Name)
+				source.RemoveConsumingExecutor(m.consumerName())
+			}
+		case *common.UserTableInfo:
+			if disconnect {
+				userTable, err := m.pe.GetUserTable(tbl.ID)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				userTable.RemoveConsumingExecutor(m.consumerName())
 			}
 		case *common.MaterializedViewInfo:
 			if disconnect {
@@ -98,7 +173,7 @@ func (m *MaterializedView) disconnectOrDeleteDataForMV(schema *common.Schema, no
 				if err != nil {
 					return errors.WithStack(err)
 				}
-				mv.removeConsumingExecutor(m.Info.Name)
+				mv.removeConsumingExecutor(m.consumerName())
 			}
 		default:
 			return errors.Errorf("cannot disconnect %s: invalid table type", tbl)
@@ -119,6 +194,25 @@ func (m *MaterializedView) disconnectOrDeleteDataForMV(schema *common.Schema, no
 			if err != nil {
 				return errors.WithStack(err)
 			}
+			if op.ValuesTableInfo != nil {
+				err = m.deleteTableData(op.ValuesTableInfo.ID)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+			}
+		}
+	case *exec.JoinInput:
+		if disconnect {
+			err := m.pe.UnregisterRemoteConsumer(op.TableInfo.ID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if deleteData {
+			err := m.deleteTableData(op.TableInfo.ID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
 		}
 	}
 
@@ -170,13 +264,19 @@ func (m *MaterializedView) connect(executor exec.PushExecutor, addConsuming bool
 				if err != nil {
 					return errors.WithStack(err)
 				}
-				source.AddConsumingExecutor(m.Info.Name, executor)
+				source.AddConsumingExecutor(m.consumerName(), executor)
+			case *common.UserTableInfo:
+				userTable, err := m.pe.GetUserTable(tbl.ID)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				userTable.AddConsumingExecutor(m.consumerName(), executor)
 			case *common.MaterializedViewInfo:
 				mv, err := m.pe.GetMaterializedView(tbl.ID)
 				if err != nil {
 					return errors.WithStack(err)
 				}
-				mv.addConsumingExecutor(m.Info.Name, executor)
+				mv.addConsumingExecutor(m.consumerName(), executor)
 			default:
 				return errors.Errorf("table scan on %s is not supported", reflect.TypeOf(tbl))
 			}
@@ -195,6 +295,20 @@ func (m *MaterializedView) connect(executor exec.PushExecutor, addConsuming bool
 				return errors.WithStack(err)
 			}
 		}
+	case *exec.JoinInput:
+		if registerRemote {
+			colTypes := op.TableInfo.ColumnTypes
+			rf := common.NewRowsFactory(colTypes)
+			rc := &RemoteConsumer{
+				RowsFactory: rf,
+				ColTypes:    colTypes,
+				RowsHandler: op,
+			}
+			err := m.pe.RegisterRemoteConsumer(op.TableInfo.ID, rc)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
 	}
 	return nil
 }
@@ -220,7 +334,7 @@ func (m *MaterializedView) Fill() error {
 		// Execute in parallel
 		te := tableExec
 		go func() {
-			err := te.FillTo(ts, m.Info.Name, m.Info.ID, schedulers, m.pe.failInject)
+			err := te.FillTo(ts, m.consumerName(), m.Info.ID, schedulers, m.pe.failInject)
 			ch <- err
 		}()
 	}
@@ -253,6 +367,12 @@ func (m *MaterializedView) getFeedingExecutors(ex exec.PushExecutor) ([]*exec.Ta
 				return nil, nil, errors.WithStack(err)
 			}
 			tes = append(tes, source.TableExecutor())
+		case *common.UserTableInfo:
+			userTable, err := m.pe.GetUserTable(tbl.ID)
+			if err != nil {
+				return nil, nil, errors.WithStack(err)
+			}
+			tes = append(tes, userTable.TableExecutor())
 		case *common.MaterializedViewInfo:
 			mv, err := m.pe.GetMaterializedView(tbl.ID)
 			if err != nil {
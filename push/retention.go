@@ -0,0 +1,90 @@
+package push
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/push/exec"
+)
+
+// ExpireRows deletes the expired rows of the sources and materialized views which have a retention, on each shard that
+// this node processes. The rows of a shard are deleted on its push scheduler rather than in the shard state machine:
+// the deletes of a propagating retention have to go through the push DAG, like any other change, and running on the
+// scheduler orders expiry with respect to the other changes made to the shard. The deletes are written in a
+// replicated write batch, so the replicas of the shard delete the same rows.
+func (p *Engine) ExpireRows() error {
+	tableExecutors := p.getTableExecutorsWithRetention()
+	if len(tableExecutors) == 0 {
+		return nil
+	}
+	schedulers, err := p.GetLocalLeaderSchedulers()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	now := time.Now()
+	chans := make([]chan error, 0, len(schedulers))
+	for shardID, scheduler := range schedulers {
+		theShardID := shardID
+		ch := scheduler.ScheduleAction(func() error {
+			for _, te := range tableExecutors {
+				cutoff := common.NewTimestampFromGoTime(now.Add(-te.TableInfo.Retention.Period))
+				numExpired, err := te.ExpireRows(theShardID, cutoff)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if numExpired != 0 {
+					log.Debugf("expired %d rows of %s on shard %d", numExpired, te.TableInfo, theShardID)
+				}
+			}
+			return nil
+		})
+		chans = append(chans, ch)
+	}
+	for _, ch := range chans {
+		err, ok := <-ch
+		if !ok {
+			return errors.Error("channel was closed")
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (p *Engine) getTableExecutorsWithRetention() []*exec.TableExecutor {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	var tableExecutors []*exec.TableExecutor
+	for _, src := range p.sources {
+		if te := src.TableExecutor(); te.TableInfo.Retention != nil {
+			tableExecutors = append(tableExecutors, te)
+		}
+	}
+	for _, mv := range p.materializedViews {
+		if te := mv.TableExecutor(); te.TableInfo.Retention != nil {
+			tableExecutors = append(tableExecutors, te)
+		}
+	}
+	return tableExecutors
+}
+
+func (p *Engine) scheduleRetentionCheck() {
+	p.retentionTimer = time.AfterFunc(p.cfg.RetentionCheckInterval, p.checkRetention)
+}
+
+func (p *Engine) checkRetention() {
+	if p.readyToReceive.Get() {
+		if err := p.ExpireRows(); err != nil {
+			log.Errorf("failed to expire rows: %+v", err)
+		}
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.started {
+		return
+	}
+	p.scheduleRetentionCheck()
+}
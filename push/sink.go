@@ -0,0 +1,82 @@
+package push
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/kafka"
+	"github.com/squareup/pranadb/push/exec"
+	"github.com/squareup/pranadb/push/source"
+)
+
+// CreateSink creates a sink which publishes the changes to a materialized view to a Kafka topic. If fill is true the
+// current contents of the materialized view are published first.
+func (p *Engine) CreateSink(sinkInfo *common.SinkInfo, fill bool) error {
+	te, err := p.getTableExecutorForSink(sinkInfo)
+	if err != nil {
+		return err
+	}
+	producer, err := p.createMessageProducer(sinkInfo.TopicInfo)
+	if err != nil {
+		return err
+	}
+	encoder, err := kafka.NewMessageEncoder(sinkInfo.TopicInfo.KeyEncoding, sinkInfo.TopicInfo.ValueEncoding, p.protoRegistry)
+	if err != nil {
+		return err
+	}
+	if err := producer.Start(); err != nil {
+		return errors.WithStack(err)
+	}
+	sinkExec := exec.NewSinkExecutor(te.TableInfo, sinkInfo, producer, encoder)
+	if fill {
+		schedulers, err := p.GetLocalLeaderSchedulers()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		// Publish the current contents of the MV then attach the sink
+		if err := te.FillTo(sinkExec, sinkInfo.Name, sinkInfo.ID, schedulers, p.failInject); err != nil {
+			if err2 := producer.Close(); err2 != nil {
+				log.Errorf("failed to close producer %+v", err2)
+			}
+			return err
+		}
+	} else {
+		te.AddConsumingNode(sinkInfo.Name, sinkExec)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.sinks[sinkInfo.ID] = sinkExec
+	return nil
+}
+
+func (p *Engine) RemoveSink(sinkInfo *common.SinkInfo) error {
+	te, err := p.getTableExecutorForSink(sinkInfo)
+	if err != nil {
+		return err
+	}
+	te.RemoveConsumingNode(sinkInfo.Name)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	sinkExec, ok := p.sinks[sinkInfo.ID]
+	if !ok {
+		return errors.Errorf("no such sink %d", sinkInfo.ID)
+	}
+	delete(p.sinks, sinkInfo.ID)
+	return sinkExec.Close()
+}
+
+func (p *Engine) getTableExecutorForSink(sinkInfo *common.SinkInfo) (*exec.TableExecutor, error) {
+	mvInfo, ok := p.meta.GetMaterializedView(sinkInfo.SchemaName, sinkInfo.MaterializedViewName)
+	if !ok {
+		return nil, errors.NewUnknownMaterializedViewError(sinkInfo.SchemaName, sinkInfo.MaterializedViewName)
+	}
+	mv, err := p.GetMaterializedView(mvInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+	return mv.TableExecutor(), nil
+}
+
+func (p *Engine) createMessageProducer(ti *common.TopicInfo) (kafka.MessageProducer, error) {
+	return source.NewMessageProducer(p.cfg, ti.BrokerName, ti.TopicName, ti.Properties)
+}
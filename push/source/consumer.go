@@ -18,12 +18,12 @@ type MessageConsumer struct {
 	running         common.AtomicBool
 	messageParser   *MessageParser
 	msgBatch        []*kafka.Message
-	offsetsToCommit map[int32]int64
+	offsetsToCommit map[kafka.TopicPartition]int64
 }
 
 func NewMessageConsumer(msgProvider kafka.MessageProvider, pollTimeout time.Duration, maxMessages int,
 	source *Source) (*MessageConsumer, error) {
-	messageParser, err := NewMessageParser(source.sourceInfo, source.protoRegistry)
+	messageParser, err := NewMessageParser(source.sourceInfo, source.protoRegistry, source.schemaRegistry)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -34,7 +34,7 @@ func NewMessageConsumer(msgProvider kafka.MessageProvider, pollTimeout time.Dura
 		source:          source,
 		loopCh:          make(chan struct{}, 1),
 		messageParser:   messageParser,
-		offsetsToCommit: make(map[int32]int64),
+		offsetsToCommit: make(map[kafka.TopicPartition]int64),
 	}
 
 	msgProvider.SetRebalanceCallback(mc.rebalanceOccurring)
@@ -79,7 +79,8 @@ func (m *MessageConsumer) rebalanceOccurring() error {
 	// This wil be called on the message loop when the consumer calls in to getMessage, so we can simply ignore
 	// the current unprocessed batch of messages
 	m.msgBatch = nil
-	m.offsetsToCommit = make(map[int32]int64)
+	m.offsetsToCommit = make(map[kafka.TopicPartition]int64)
+	m.source.partitionsRevoked()
 	return nil
 }
 
@@ -116,12 +117,12 @@ func (m *MessageConsumer) pollLoop() {
 	}
 }
 
-func (m *MessageConsumer) getBatch(pollTimeout time.Duration, maxRecords int) ([]*kafka.Message, map[int32]int64, error) {
+func (m *MessageConsumer) getBatch(pollTimeout time.Duration, maxRecords int) ([]*kafka.Message, map[kafka.TopicPartition]int64, error) {
 	start := time.Now()
 	remaining := pollTimeout
 
 	m.msgBatch = nil
-	m.offsetsToCommit = make(map[int32]int64)
+	m.offsetsToCommit = make(map[kafka.TopicPartition]int64)
 
 	// The golang Kafka consumer API returns single messages, not batches, but it's more efficient for us to
 	// process in batches. So we attempt to return more than one message at a time.
@@ -133,8 +134,8 @@ func (m *MessageConsumer) getBatch(pollTimeout time.Duration, maxRecords int) ([
 		if msg == nil {
 			break
 		}
-		partID := msg.PartInfo.PartitionID
-		m.offsetsToCommit[partID] = msg.PartInfo.Offset + 1
+		tp := kafka.TopicPartition{Topic: msg.PartInfo.Topic, PartitionID: msg.PartInfo.PartitionID}
+		m.offsetsToCommit[tp] = msg.PartInfo.Offset + 1
 		m.msgBatch = append(m.msgBatch, msg)
 		remaining = pollTimeout - time.Now().Sub(start)
 		if remaining <= 0 {
@@ -0,0 +1,125 @@
+package source
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/kafka"
+	"github.com/squareup/pranadb/meta"
+	"github.com/squareup/pranadb/sharder"
+	"github.com/squareup/pranadb/table"
+)
+
+// Headers added to a message when it is sent to a dead letter topic
+const (
+	DeadLetterErrorHeader     = "prana_error"
+	DeadLetterSourceHeader    = "prana_source"
+	DeadLetterTopicHeader     = "prana_topic"
+	DeadLetterPartitionHeader = "prana_partition"
+	DeadLetterOffsetHeader    = "prana_offset"
+)
+
+// handleFailedMessages applies the error policy of the source to messages which couldn't be parsed
+func (s *Source) handleFailedMessages(failed []*FailedMessage) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	s.failedMessagesCounter.Add(float64(len(failed)))
+	atomic.AddInt64(&s.failedMessagesCount, int64(len(failed)))
+	for _, fm := range failed {
+		log.Warnf("source %s.%s failed to parse message from topic %s at partition %d offset %d: %v",
+			s.sourceInfo.SchemaName, s.sourceInfo.Name, fm.Message.PartInfo.Topic, fm.Message.PartInfo.PartitionID,
+			fm.Message.PartInfo.Offset, fm.Err)
+	}
+	if s.sourceInfo.TopicInfo.ErrorPolicy != common.SourceErrorPolicyDeadLetter {
+		return nil
+	}
+	if s.deadLetterProducer != nil {
+		return s.sendDeadLetters(failed)
+	}
+	return s.storeDeadLetters(failed)
+}
+
+// sendDeadLetters sends the failed messages to the dead letter topic. The original key, value, headers and timestamp
+// are kept, and headers describing the failure are added.
+func (s *Source) sendDeadLetters(failed []*FailedMessage) error {
+	sourceName := s.sourceInfo.SchemaName + "." + s.sourceInfo.Name
+	messages := make([]*kafka.Message, len(failed))
+	for i, fm := range failed {
+		headers := make([]kafka.MessageHeader, 0, len(fm.Message.Headers)+5)
+		headers = append(headers, fm.Message.Headers...)
+		headers = append(headers,
+			kafka.MessageHeader{Key: DeadLetterErrorHeader, Value: []byte(fm.Err.Error())},
+			kafka.MessageHeader{Key: DeadLetterSourceHeader, Value: []byte(sourceName)},
+			kafka.MessageHeader{Key: DeadLetterTopicHeader, Value: []byte(fm.Message.PartInfo.Topic)},
+			kafka.MessageHeader{Key: DeadLetterPartitionHeader, Value: []byte(strconv.Itoa(int(fm.Message.PartInfo.PartitionID)))},
+			kafka.MessageHeader{Key: DeadLetterOffsetHeader, Value: []byte(strconv.FormatInt(fm.Message.PartInfo.Offset, 10))},
+		)
+		messages[i] = &kafka.Message{
+			TimeStamp: fm.Message.TimeStamp,
+			Key:       fm.Message.Key,
+			Value:     fm.Message.Value,
+			Headers:   headers,
+		}
+	}
+	return errors.WithStack(s.deadLetterProducer.SendMessages(messages))
+}
+
+// storeDeadLetters writes the failed messages to the dead letters system table
+func (s *Source) storeDeadLetters(failed []*FailedMessage) error {
+	tableInfo := meta.DeadLetterTableInfo.TableInfo
+	rows := common.NewRows(tableInfo.ColumnTypes, len(failed))
+	failedAt := common.NewTimestampFromGoTime(time.Now())
+	for _, fm := range failed {
+		rows.AppendInt64ToColumn(0, int64(s.sourceInfo.ID))
+		rows.AppendStringToColumn(1, fm.Message.PartInfo.Topic)
+		rows.AppendInt64ToColumn(2, int64(fm.Message.PartInfo.PartitionID))
+		rows.AppendInt64ToColumn(3, fm.Message.PartInfo.Offset)
+		rows.AppendStringToColumn(4, s.sourceInfo.SchemaName)
+		rows.AppendStringToColumn(5, s.sourceInfo.Name)
+		appendBytesAsString(rows, 6, fm.Message.Key)
+		appendBytesAsString(rows, 7, fm.Message.Value)
+		rows.AppendStringToColumn(8, fm.Err.Error())
+		rows.AppendTimestampToColumn(9, failedAt)
+	}
+	batches := make(map[uint64]*cluster.WriteBatch)
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		key, err := common.EncodeKeyCols(&row, tableInfo.PrimaryKeyCols, tableInfo.ColumnTypes, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		shardID, err := s.sharder.CalculateShard(sharder.ShardTypeHash, key)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		wb, ok := batches[shardID]
+		if !ok {
+			wb = cluster.NewWriteBatch(shardID)
+			batches[shardID] = wb
+		}
+		if err := table.Upsert(tableInfo, &row, wb); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, wb := range batches {
+		if err := s.cluster.WriteBatch(wb); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func appendBytesAsString(rows *common.Rows, colIndex int, bytes []byte) {
+	if bytes == nil {
+		rows.AppendNullToColumn(colIndex)
+		return
+	}
+	rows.AppendStringToColumn(colIndex, strings.ToValidUTF8(string(bytes), "�"))
+}
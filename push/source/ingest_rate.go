@@ -0,0 +1,44 @@
+package source
+
+import (
+	"sync"
+	"time"
+)
+
+// ingestRateWindowSecs is the number of seconds the ingest rate of a source is averaged over
+const ingestRateWindowSecs = 60
+
+// ingestRate counts the rows ingested in each second of a sliding window, so the rate can be calculated without
+// keeping every batch
+type ingestRate struct {
+	lock    sync.Mutex
+	counts  [ingestRateWindowSecs]int64
+	seconds [ingestRateWindowSecs]int64 // The unix time of the second each count is for
+}
+
+func (r *ingestRate) add(now time.Time, rows int64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	sec := now.Unix()
+	i := sec % ingestRateWindowSecs
+	if r.seconds[i] != sec {
+		// The count is for a second which has left the window
+		r.seconds[i] = sec
+		r.counts[i] = 0
+	}
+	r.counts[i] += rows
+}
+
+// rate returns the average number of rows ingested per second over the window
+func (r *ingestRate) rate(now time.Time) float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	sec := now.Unix()
+	var total int64
+	for i, s := range r.seconds {
+		if s > sec-ingestRateWindowSecs && s <= sec {
+			total += r.counts[i]
+		}
+	}
+	return float64(total) / ingestRateWindowSecs
+}
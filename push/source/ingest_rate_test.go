@@ -0,0 +1,32 @@
+package source
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestRate(t *testing.T) {
+	var r ingestRate
+	now := time.Unix(1000, 0)
+	require.Equal(t, 0.0, r.rate(now))
+
+	r.add(now, 30)
+	r.add(now, 30)
+	r.add(now.Add(10*time.Second), 60)
+	require.Equal(t, 2.0, r.rate(now.Add(10*time.Second)))
+
+	// The first rows leave the window after a minute
+	require.Equal(t, 1.0, r.rate(now.Add(60*time.Second)))
+	require.Equal(t, 0.0, r.rate(now.Add(70*time.Second)))
+}
+
+func TestIngestRateReusesSeconds(t *testing.T) {
+	var r ingestRate
+	now := time.Unix(1000, 0)
+	r.add(now, 60)
+	// The same slot is used for a second a whole window later, and the old count is dropped
+	r.add(now.Add(ingestRateWindowSecs*time.Second), 120)
+	require.Equal(t, 2.0, r.rate(now.Add(ingestRateWindowSecs*time.Second)))
+}
@@ -11,10 +11,13 @@ import (
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/dynamicpb"
 	"reflect"
+	"strings"
+	"time"
 )
 
 var (
 	jsonDecoder         = &JSONDecoder{}
+	rawDecoder          = &RawDecoder{}
 	kafkaDecoderFloat   = newKafkaDecoder(common.KafkaEncodingFloat32BE)
 	kafkaDecoderDouble  = newKafkaDecoder(common.KafkaEncodingFloat64BE)
 	kafkaDecoderInteger = newKafkaDecoder(common.KafkaEncodingInt32BE)
@@ -27,14 +30,18 @@ type MessageParser struct {
 	sourceInfo       *common.SourceInfo
 	rowsFactory      *common.RowsFactory
 	colEvals         []evaluable
+	colIndexes       []int // The index of the column each selector selects
+	colVals          []interface{}
 	headerDecoder    Decoder
 	keyDecoder       Decoder
 	valueDecoder     Decoder
 	evalContext      *evalContext
 	protobufRegistry protolib.Resolver
+	ingestTimeCol    int
 }
 
-func NewMessageParser(sourceInfo *common.SourceInfo, registry protolib.Resolver) (*MessageParser, error) {
+func NewMessageParser(sourceInfo *common.SourceInfo, registry protolib.Resolver,
+	schemaRegistry *SchemaRegistry) (*MessageParser, error) {
 	selectors := sourceInfo.TopicInfo.ColSelectors
 	selectEvals := make([]evaluable, len(selectors))
 	// We pre-compute whether the selectors need headers, key and value so we don't unnecessary parse them if they
@@ -59,8 +66,10 @@ func NewMessageParser(sourceInfo *common.SourceInfo, registry protolib.Resolver)
 				decodeHeader = true
 			case "key":
 				decodeKey = true
-			case "timestamp":
-				// timestamp selector, no decoding required
+			case "value":
+				decodeValue = true
+			case "timestamp", "topic":
+				// timestamp and topic selectors, no decoding required
 			default:
 				panic(fmt.Sprintf("invalid selector %q", selector))
 			}
@@ -75,27 +84,34 @@ func NewMessageParser(sourceInfo *common.SourceInfo, registry protolib.Resolver)
 		protobufRegistry: registry,
 		sourceInfo:       sourceInfo,
 		colEvals:         selectEvals,
+		colIndexes:       selectedCols(sourceInfo.TableInfo, len(selectEvals)),
+		colVals:          make([]interface{}, len(selectEvals)),
 		headerDecoder:    headerDecoder,
 		keyDecoder:       keyDecoder,
 		valueDecoder:     valueDecoder,
 		evalContext: &evalContext{
-			meta: make(map[string]interface{}, 3),
+			meta: make(map[string]interface{}, 5),
 		},
+		ingestTimeCol: -1,
+	}
+	if retention := sourceInfo.Retention; retention != nil &&
+		sourceInfo.ColumnNames[retention.ColIndex] == common.IngestTimeColumnName {
+		mp.ingestTimeCol = retention.ColIndex
 	}
 	if decodeHeader {
-		mp.headerDecoder, err = getDecoder(registry, topic.HeaderEncoding)
+		mp.headerDecoder, err = getDecoder(registry, schemaRegistry, topic.CSVOptions, topic.HeaderEncoding)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 	}
 	if decodeKey {
-		mp.keyDecoder, err = getDecoder(registry, topic.KeyEncoding)
+		mp.keyDecoder, err = getDecoder(registry, schemaRegistry, topic.CSVOptions, topic.KeyEncoding)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 	}
 	if decodeValue {
-		mp.valueDecoder, err = getDecoder(registry, topic.ValueEncoding)
+		mp.valueDecoder, err = getDecoder(registry, schemaRegistry, topic.CSVOptions, topic.ValueEncoding)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
@@ -103,17 +119,73 @@ func NewMessageParser(sourceInfo *common.SourceInfo, registry protolib.Resolver)
 	return mp, nil
 }
 
+// selectedCols returns the indexes of the columns selected by the column selectors. These are the visible columns - the
+// hidden ones come after the columns the source was created with, but columns added later come after them.
+func selectedCols(tableInfo *common.TableInfo, numSelectors int) []int {
+	cols := make([]int, 0, numSelectors)
+	for i := 0; i < len(tableInfo.ColumnTypes) && len(cols) < numSelectors; i++ {
+		if tableInfo.ColsVisible == nil || tableInfo.ColsVisible[i] {
+			cols = append(cols, i)
+		}
+	}
+	return cols
+}
+
+// FailedMessage is a message which couldn't be parsed, and the reason why
+type FailedMessage struct {
+	Message *kafka.Message
+	Err     error
+}
+
+// ParseMessages parses the messages into rows. If any message can't be parsed an error is returned.
 func (m *MessageParser) ParseMessages(messages []*kafka.Message) (*common.Rows, error) {
+	rows, _, failed := m.ParseValidMessages(messages)
+	if len(failed) != 0 {
+		return nil, failed[0].Err
+	}
+	return rows, nil
+}
+
+// ParseValidMessages parses the messages which can be parsed into rows. It returns the rows, the messages that the rows
+// were parsed from, in the same order, and the messages which couldn't be parsed.
+func (m *MessageParser) ParseValidMessages(messages []*kafka.Message) (*common.Rows, []*kafka.Message, []*FailedMessage) {
 	rows := m.rowsFactory.NewRows(len(messages))
+	parsed := make([]*kafka.Message, 0, len(messages))
+	var failed []*FailedMessage
+	ingestTime := common.NewTimestampFromGoTime(time.Now())
 	for _, msg := range messages {
 		if err := m.decodeMessage(msg); err != nil {
-			return nil, errors.WithStack(err)
+			failed = append(failed, &FailedMessage{Message: msg, Err: errors.WithStack(err)})
+			continue
 		}
-		if err := m.evalColumns(rows); err != nil {
-			return nil, errors.WithStack(err)
+		if err := m.evalColumns(); err != nil {
+			failed = append(failed, &FailedMessage{Message: msg, Err: errors.WithStack(err)})
+			continue
+		}
+		// The values are only appended once they've all been evaluated, so a message which fails doesn't leave a
+		// partial row
+		for i, val := range m.colVals {
+			colIndex := m.colIndexes[i]
+			if err := appendValue(rows, colIndex, m.sourceInfo.ColumnTypes[colIndex], val); err != nil {
+				panic(err)
+			}
 		}
+		if m.sourceInfo.TopicInfo.Semantics == common.SourceSemanticsAppend {
+			// The partition and offset are the last key columns
+			pkCols := m.sourceInfo.PrimaryKeyCols
+			partitionIDCol := pkCols[len(pkCols)-2]
+			if m.sourceInfo.TopicInfo.HasMultipleTopics() {
+				rows.AppendStringToColumn(partitionIDCol-1, msg.PartInfo.Topic)
+			}
+			rows.AppendInt64ToColumn(partitionIDCol, int64(msg.PartInfo.PartitionID))
+			rows.AppendInt64ToColumn(partitionIDCol+1, msg.PartInfo.Offset)
+		}
+		if m.ingestTimeCol != -1 {
+			rows.AppendTimestampToColumn(m.ingestTimeCol, ingestTime)
+		}
+		parsed = append(parsed, msg)
 	}
-	return rows, nil
+	return rows, parsed, failed
 }
 
 func (m *MessageParser) decodeMessage(message *kafka.Message) error {
@@ -153,70 +225,41 @@ func (m *MessageParser) decodeMessage(message *kafka.Message) error {
 
 	m.evalContext.meta["header"] = hdrs
 	m.evalContext.meta["key"] = km
+	m.evalContext.meta["value"] = vm
 	m.evalContext.meta["timestamp"] = message.TimeStamp
+	m.evalContext.meta["topic"] = message.PartInfo.Topic
 	m.evalContext.value = vm
 
 	return nil
 }
 
-func (m *MessageParser) evalColumns(rows *common.Rows) error {
+func (m *MessageParser) evalColumns() error {
 	for i, eval := range m.colEvals {
-		colType := m.sourceInfo.ColumnTypes[i]
 		val, err := eval(m.evalContext.meta, m.evalContext.value)
 		if err != nil {
 			return errors.WithStack(err)
 		}
-		if val == nil {
-			rows.AppendNullToColumn(i)
-			continue
-		}
-		switch colType.Type {
-		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
-			ival, err := CoerceInt64(val)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			rows.AppendInt64ToColumn(i, ival)
-		case common.TypeDouble:
-			fval, err := CoerceFloat64(val)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			rows.AppendFloat64ToColumn(i, fval)
-		case common.TypeVarchar:
-			sval, err := CoerceString(val)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			rows.AppendStringToColumn(i, sval)
-		case common.TypeDecimal:
-			dval, err := CoerceDecimal(val)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			rows.AppendDecimalToColumn(i, *dval)
-		case common.TypeTimestamp:
-			tsVal, err := CoerceTimestamp(val)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			tsVal.SetFsp(colType.FSP)
-			if err := common.RoundTimestampToFSP(&tsVal, colType.FSP); err != nil {
-				return err
-			}
-			rows.AppendTimestampToColumn(i, tsVal)
-		default:
-			return errors.Errorf("unsupported col type %d", colType.Type)
+		m.colVals[i], err = CoerceValue(m.sourceInfo.ColumnTypes[m.colIndexes[i]], val)
+		if err != nil {
+			return errors.WithStack(err)
 		}
 	}
 	return nil
 }
 
-func getDecoder(registry protolib.Resolver, encoding common.KafkaEncoding) (Decoder, error) {
+func getDecoder(registry protolib.Resolver, schemaRegistry *SchemaRegistry, csvOptions *common.CSVOptions,
+	encoding common.KafkaEncoding) (Decoder, error) {
 	var decoder Decoder
 	switch encoding.Encoding {
 	case common.EncodingJSON:
 		decoder = jsonDecoder
+	case common.EncodingRaw:
+		decoder = rawDecoder
+	case common.EncodingCSV:
+		if csvOptions == nil {
+			csvOptions = &common.DefaultCSVOptions
+		}
+		decoder = &CSVDecoder{delimiter: csvOptions.Delimiter, quote: csvOptions.Quote}
 	case common.EncodingFloat64BE:
 		decoder = kafkaDecoderDouble
 	case common.EncodingFloat32BE:
@@ -239,6 +282,11 @@ func getDecoder(registry protolib.Resolver, encoding common.KafkaEncoding) (Deco
 			return nil, errors.Errorf("expected to find MessageDescriptor at %q, but was %q", encoding.SchemaName, reflect.TypeOf(msgDesc))
 		}
 		decoder = &ProtobufDecoder{desc: msgDesc}
+	case common.EncodingAvro:
+		if schemaRegistry == nil {
+			return nil, errors.Error("avro encoding requires a schema registry")
+		}
+		decoder = &AvroDecoder{schemaRegistry: schemaRegistry}
 	default:
 		panic(fmt.Sprintf("unsupported encoding %+v", encoding))
 	}
@@ -268,6 +316,69 @@ func (j *JSONDecoder) Decode(bytes []byte) (interface{}, error) {
 	return m, nil
 }
 
+// RawDecoder leaves the bytes as they are
+type RawDecoder struct {
+}
+
+func (r *RawDecoder) Decode(bytes []byte) (interface{}, error) {
+	return bytes, nil
+}
+
+// CSVDecoder decodes a line of delimiter separated fields into a slice of strings, so fields can be selected by index.
+// A field which starts with the quote character is quoted - it ends at the next unpaired quote, and a pair of quotes
+// within it is a single quote. An empty unquoted field is null.
+type CSVDecoder struct {
+	delimiter rune
+	quote     rune
+}
+
+func (c *CSVDecoder) Decode(bytes []byte) (interface{}, error) {
+	line := []rune(strings.TrimRight(string(bytes), "\r\n"))
+	var fields []interface{}
+	sb := strings.Builder{}
+	pos := 0
+	for {
+		if c.quote != 0 && pos < len(line) && line[pos] == c.quote {
+			sb.Reset()
+			closed := false
+			for pos++; pos < len(line); pos++ {
+				if line[pos] == c.quote {
+					if pos+1 < len(line) && line[pos+1] == c.quote {
+						pos++
+					} else {
+						pos++
+						closed = true
+						break
+					}
+				}
+				sb.WriteRune(line[pos])
+			}
+			if !closed {
+				return nil, errors.Errorf("unterminated quoted field in csv %q", string(bytes))
+			}
+			if pos < len(line) && line[pos] != c.delimiter {
+				return nil, errors.Errorf("unexpected character %q after quoted field in csv %q", line[pos], string(bytes))
+			}
+			fields = append(fields, sb.String())
+		} else {
+			start := pos
+			for pos < len(line) && line[pos] != c.delimiter {
+				pos++
+			}
+			if pos == start {
+				fields = append(fields, nil)
+			} else {
+				fields = append(fields, string(line[start:pos]))
+			}
+		}
+		if pos >= len(line) {
+			return fields, nil
+		}
+		// Skip the delimiter
+		pos++
+	}
+}
+
 func newKafkaDecoder(encoding common.KafkaEncoding) *KafkaDecoder {
 	return &KafkaDecoder{encoding: encoding}
 }
@@ -314,6 +425,28 @@ func (p *ProtobufDecoder) Decode(bytes []byte) (interface{}, error) {
 	return msg, errors.WithStack(err)
 }
 
+// AvroDecoder decodes Avro messages in the Confluent wire format - a zero magic byte, then the ID of the writer schema
+// as a big-endian int32, then the Avro binary encoded datum
+type AvroDecoder struct {
+	schemaRegistry *SchemaRegistry
+}
+
+func (a *AvroDecoder) Decode(bytes []byte) (interface{}, error) {
+	if len(bytes) < 5 || bytes[0] != 0 {
+		return nil, errors.Error("avro message is not in the Confluent wire format")
+	}
+	schemaID, _ := common.ReadUint32FromBufferBE(bytes, 1)
+	schema, err := a.schemaRegistry.getSchema(int32(schemaID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	native, _, err := schema.codec.NativeFromBinary(bytes[5:])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return schema.normalize(schema.root, "", native)
+}
+
 type evaluable func(meta map[string]interface{}, v interface{}) (interface{}, error)
 type evalContext struct {
 	meta  map[string]interface{}
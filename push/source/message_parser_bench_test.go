@@ -40,7 +40,7 @@ func setupMessageParserJSON() *MessageParser {
 		TableInfo: tableInfo,
 		TopicInfo: topicInfo,
 	}
-	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry)
+	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry, nil)
 	if err != nil {
 		panic(err)
 	}
@@ -1,10 +1,15 @@
 package source
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/linkedin/goavro/v2"
 	"github.com/squareup/pranadb/command/parser/selector"
 	"github.com/squareup/pranadb/common"
 	"github.com/squareup/pranadb/errors"
@@ -229,6 +234,23 @@ func TestParseMessageTimestamp(t *testing.T) {
 		vf)
 }
 
+func TestParseMessageBoolean(t *testing.T) {
+	theColNames := []string{"col0", "col1", "col2", "col3", "col4"}
+	theColTypes := []common.ColumnType{common.BigIntColumnType, common.BooleanColumnType, common.BooleanColumnType,
+		common.BooleanColumnType, common.BooleanColumnType}
+	// Booleans can come from JSON booleans, strings or numbers
+	vf := func(t *testing.T, row *common.Row) { //nolint:thelper
+		require.True(t, row.GetBool(1))
+		require.False(t, row.GetBool(2))
+		require.True(t, row.GetBool(3))
+		require.False(t, row.GetBool(4))
+	}
+	testParseMessage(t, theColNames, theColTypes,
+		common.KafkaEncodingJSON, common.KafkaEncodingJSON, common.KafkaEncodingJSON,
+		nil, []byte(`{"kf1":1234}`), []byte(`{"vf1":true,"vf2":false,"vf3":"true","vf4":0}`),
+		[]string{"meta(\"key\").kf1", "vf1", "vf2", "vf3", "vf4"}, time.Now(), vf)
+}
+
 func TestParseMessagesJSONHeaders(t *testing.T) {
 	theColNames := []string{"col0", "col1", "col2", "col3"}
 	theColTypes := []common.ColumnType{common.BigIntColumnType, common.BigIntColumnType, common.VarcharColumnType, common.DoubleColumnType}
@@ -403,6 +425,365 @@ func compileSelectors(raw []string) ([]selector.ColumnSelector, error) {
 }
 
 //nolint:unparam
+func TestParseMessagesAppendSemantics(t *testing.T) {
+	selectors, err := compileSelectors([]string{"v0", "v1"})
+	require.NoError(t, err)
+	sourceInfo := &common.SourceInfo{
+		TableInfo: &common.TableInfo{
+			ID:             0,
+			SchemaName:     "test",
+			Name:           "test_table",
+			PrimaryKeyCols: []int{2, 3},
+			ColumnNames:    []string{"col0", "col1", common.PartitionIDColumnName, common.OffsetColumnName},
+			ColumnTypes: []common.ColumnType{common.VarcharColumnType, common.BigIntColumnType, common.BigIntColumnType,
+				common.BigIntColumnType},
+			ColsVisible: []bool{true, true, false, false},
+		},
+		TopicInfo: &common.TopicInfo{
+			BrokerName:     "test_broker",
+			TopicName:      "test_topic",
+			HeaderEncoding: common.KafkaEncodingJSON,
+			KeyEncoding:    common.KafkaEncodingJSON,
+			ValueEncoding:  common.KafkaEncodingJSON,
+			ColSelectors:   selectors,
+			Semantics:      common.SourceSemanticsAppend,
+		},
+	}
+	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry, nil)
+	require.NoError(t, err)
+
+	value := []byte(`{"v0":"foo","v1":23}`)
+	messages := []*kafka.Message{
+		{PartInfo: kafka.PartInfo{PartitionID: 3, Offset: 100}, Value: value},
+		{PartInfo: kafka.PartInfo{PartitionID: 3, Offset: 101}, Value: value},
+		{PartInfo: kafka.PartInfo{PartitionID: 7, Offset: 100}, Value: value},
+	}
+	rows, err := mp.ParseMessages(messages)
+	require.NoError(t, err)
+	require.Equal(t, 3, rows.RowCount())
+	for i, msg := range messages {
+		row := rows.GetRow(i)
+		require.Equal(t, "foo", row.GetString(0))
+		require.Equal(t, int64(23), row.GetInt64(1))
+		require.Equal(t, int64(msg.PartInfo.PartitionID), row.GetInt64(2))
+		require.Equal(t, msg.PartInfo.Offset, row.GetInt64(3))
+	}
+}
+
+func TestParseMessagesIngestTime(t *testing.T) {
+	selectors, err := compileSelectors([]string{"meta(\"key\").k0", "v1"})
+	require.NoError(t, err)
+	sourceInfo := &common.SourceInfo{
+		TableInfo: &common.TableInfo{
+			ID:             0,
+			SchemaName:     "test",
+			Name:           "test_table",
+			PrimaryKeyCols: []int{0},
+			ColumnNames:    []string{"col0", "col1", common.IngestTimeColumnName},
+			ColumnTypes: []common.ColumnType{common.BigIntColumnType, common.VarcharColumnType,
+				common.NewTimestampColumnType(6)},
+			ColsVisible: []bool{true, true, false},
+			Retention:   &common.RetentionInfo{ColIndex: 2, Period: time.Hour, Propagate: true},
+		},
+		TopicInfo: &common.TopicInfo{
+			BrokerName:     "test_broker",
+			TopicName:      "test_topic",
+			HeaderEncoding: common.KafkaEncodingJSON,
+			KeyEncoding:    common.KafkaEncodingJSON,
+			ValueEncoding:  common.KafkaEncodingJSON,
+			ColSelectors:   selectors,
+		},
+	}
+	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry, nil)
+	require.NoError(t, err)
+
+	before := common.NewTimestampFromGoTime(time.Now())
+	messages := []*kafka.Message{
+		{Key: []byte(`{"k0":1}`), Value: []byte(`{"v1":"foo"}`), TimeStamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Key: []byte(`{"k0":2}`), Value: []byte(`{"v1":"bar"}`), TimeStamp: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	rows, err := mp.ParseMessages(messages)
+	require.NoError(t, err)
+	after := common.NewTimestampFromGoTime(time.Now())
+	require.Equal(t, 2, rows.RowCount())
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		require.Equal(t, int64(i+1), row.GetInt64(0))
+		// The ingest time is when the message was parsed, not the timestamp of the message
+		ingestTime := row.GetTimestamp(2)
+		require.True(t, ingestTime.Compare(before) >= 0)
+		require.True(t, ingestTime.Compare(after) <= 0)
+	}
+}
+
+func TestParseMessageCSV(t *testing.T) {
+	vf := func(t *testing.T, row *common.Row) { //nolint:thelper
+		verifyJSONExpectedValues(t, row)
+	}
+	testParseMessage(t, colNames, colTypes,
+		common.KafkaEncodingJSON, common.KafkaEncodingCSV, common.KafkaEncodingCSV,
+		nil, []byte("x,1234"), []byte("4321,23.12,foo,12345678.99\r\n"),
+		[]string{"meta(\"key\")[1]", "[0]", "[1]", "[2]", "[3]"}, time.Now(), vf)
+}
+
+func TestDecodeCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		options common.CSVOptions
+		line    string
+		want    []interface{}
+		wantErr bool
+	}{
+		{name: "simple", options: common.DefaultCSVOptions, line: "a,b,c", want: []interface{}{"a", "b", "c"}},
+		{name: "empty is null", options: common.DefaultCSVOptions, line: ",b,", want: []interface{}{nil, "b", nil}},
+		{name: "quoted", options: common.DefaultCSVOptions, line: `"a,b","say ""hi""",""`,
+			want: []interface{}{"a,b", `say "hi"`, ""}},
+		{name: "unterminated quote", options: common.DefaultCSVOptions, line: `a,"b`, wantErr: true},
+		{name: "text after quote", options: common.DefaultCSVOptions, line: `"a"b,c`, wantErr: true},
+		{name: "custom delimiter and quote", options: common.CSVOptions{Delimiter: '|', Quote: '\''},
+			line: `a|'b|c'|d,e`, want: []interface{}{"a", "b|c", "d,e"}},
+		{name: "no quoting", options: common.CSVOptions{Delimiter: '\t'}, line: "\"a\"\tb",
+			want: []interface{}{`"a"`, "b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := &CSVDecoder{delimiter: test.options.Delimiter, quote: test.options.Quote}
+			fields, err := decoder.Decode([]byte(test.line))
+			if test.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, test.want, fields)
+			}
+		})
+	}
+}
+
+func TestParseMessageRawValue(t *testing.T) {
+	theColNames := []string{"col0", "col1"}
+	theColTypes := []common.ColumnType{common.BigIntColumnType, common.VarcharColumnType}
+	vf := func(t *testing.T, row *common.Row) { //nolint:thelper
+		require.Equal(t, int64(1234), row.GetInt64(0))
+		require.Equal(t, "some raw\x00bytes", row.GetString(1))
+	}
+	testParseMessage(t, theColNames, theColTypes,
+		common.KafkaEncodingJSON, common.KafkaEncodingJSON, common.KafkaEncodingRaw,
+		nil, []byte(`{"kf1":1234}`), []byte("some raw\x00bytes"),
+		[]string{"meta(\"key\").kf1", "meta(\"value\")"}, time.Now(), vf)
+}
+
+func TestParseMessageRawValueVarbinary(t *testing.T) {
+	theColNames := []string{"col0", "col1"}
+	theColTypes := []common.ColumnType{common.BigIntColumnType, common.VarbinaryColumnType}
+	vf := func(t *testing.T, row *common.Row) { //nolint:thelper
+		require.Equal(t, int64(1234), row.GetInt64(0))
+		require.Equal(t, []byte{0xff, 0xfe, 0x00, 'a'}, row.GetBytes(1))
+	}
+	testParseMessage(t, theColNames, theColTypes,
+		common.KafkaEncodingJSON, common.KafkaEncodingJSON, common.KafkaEncodingRaw,
+		nil, []byte(`{"kf1":1234}`), []byte{0xff, 0xfe, 0x00, 'a'},
+		[]string{"meta(\"key\").kf1", "meta(\"value\")"}, time.Now(), vf)
+}
+
+func TestParseMessageRawValueNotUTF8(t *testing.T) {
+	selectors, err := compileSelectors([]string{"meta(\"key\").kf1", "meta(\"value\")"})
+	require.NoError(t, err)
+	sourceInfo := &common.SourceInfo{
+		TableInfo: &common.TableInfo{
+			SchemaName:     "test",
+			Name:           "test_table",
+			PrimaryKeyCols: []int{0},
+			ColumnNames:    []string{"col0", "col1"},
+			ColumnTypes:    []common.ColumnType{common.BigIntColumnType, common.VarcharColumnType},
+		},
+		TopicInfo: &common.TopicInfo{
+			HeaderEncoding: common.KafkaEncodingJSON,
+			KeyEncoding:    common.KafkaEncodingJSON,
+			ValueEncoding:  common.KafkaEncodingRaw,
+			ColSelectors:   selectors,
+		},
+	}
+	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry, nil)
+	require.NoError(t, err)
+
+	messages := []*kafka.Message{
+		{PartInfo: kafka.PartInfo{Offset: 0}, Key: []byte(`{"kf1":1}`), Value: []byte("valid")},
+		{PartInfo: kafka.PartInfo{Offset: 1}, Key: []byte(`{"kf1":2}`), Value: []byte{0xff, 0xfe, 'a'}},
+	}
+	rows, parsed, failed := mp.ParseValidMessages(messages)
+	require.Equal(t, 1, rows.RowCount())
+	require.Equal(t, []*kafka.Message{messages[0]}, parsed)
+	row := rows.GetRow(0)
+	require.Equal(t, "valid", row.GetString(1))
+	require.Equal(t, 1, len(failed))
+	require.Equal(t, messages[1], failed[0].Message)
+	require.Contains(t, failed[0].Err.Error(), "not valid UTF-8")
+}
+
+func TestParseValidMessages(t *testing.T) {
+	selectors, err := compileSelectors([]string{"k", "v"})
+	require.NoError(t, err)
+	sourceInfo := &common.SourceInfo{
+		TableInfo: &common.TableInfo{
+			SchemaName:     "test",
+			Name:           "test_table",
+			PrimaryKeyCols: []int{0},
+			ColumnNames:    []string{"col0", "col1"},
+			ColumnTypes:    []common.ColumnType{common.BigIntColumnType, common.BigIntColumnType},
+		},
+		TopicInfo: &common.TopicInfo{
+			HeaderEncoding: common.KafkaEncodingJSON,
+			KeyEncoding:    common.KafkaEncodingJSON,
+			ValueEncoding:  common.KafkaEncodingJSON,
+			ColSelectors:   selectors,
+		},
+	}
+	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry, nil)
+	require.NoError(t, err)
+
+	values := []string{`{"k":1,"v":10}`, `{"k":2,"v":"not a number"}`, `{"k":3,"v":30}`, `not json`, `{"k":5,"v":50}`}
+	messages := make([]*kafka.Message, len(values))
+	for i, v := range values {
+		messages[i] = &kafka.Message{PartInfo: kafka.PartInfo{Offset: int64(i)}, Value: []byte(v)}
+	}
+	rows, parsed, failed := mp.ParseValidMessages(messages)
+
+	// The rows must line up with the messages they were parsed from, and a failed message mustn't leave a partial row
+	require.Equal(t, 3, rows.RowCount())
+	require.Equal(t, []*kafka.Message{messages[0], messages[2], messages[4]}, parsed)
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		require.Equal(t, parsed[i].PartInfo.Offset+1, row.GetInt64(0))
+		require.Equal(t, (parsed[i].PartInfo.Offset+1)*10, row.GetInt64(1))
+	}
+	require.Equal(t, 2, len(failed))
+	require.Equal(t, messages[1], failed[0].Message)
+	require.Error(t, failed[0].Err)
+	require.Equal(t, messages[3], failed[1].Message)
+	require.Error(t, failed[1].Err)
+
+	_, err = mp.ParseMessages(messages)
+	require.Error(t, err)
+}
+
+const avroTestSchema = `{
+  "type": "record",
+  "name": "Payment",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "customer", "type": {
+      "type": "record",
+      "name": "Customer",
+      "fields": [
+        {"name": "name", "type": "string"},
+        {"name": "country", "type": ["null", "string"]}
+      ]
+    }},
+    {"name": "previous_customer", "type": ["null", "Customer"]},
+    {"name": "amount", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+    {"name": "created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "fraud_score", "type": ["null", "double"]}
+  ]
+}`
+
+func TestParseMessageAvro(t *testing.T) {
+	schemaID := 7
+	codec, err := goavro.NewCodec(avroTestSchema)
+	require.NoError(t, err)
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/schemas/ids/%d", schemaID) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		resp, err := json.Marshal(map[string]string{"schema": avroTestSchema})
+		require.NoError(t, err)
+		_, err = w.Write(resp)
+		require.NoError(t, err)
+	}))
+	defer registry.Close()
+
+	created := time.Date(2021, 9, 3, 14, 23, 11, 123000000, time.UTC)
+	native := map[string]interface{}{
+		"id": int64(1234),
+		"customer": map[string]interface{}{
+			"name":    "alice",
+			"country": goavro.Union("string", "UK"),
+		},
+		"previous_customer": goavro.Union("com.example.Customer", map[string]interface{}{
+			"name":    "bob",
+			"country": nil,
+		}),
+		"amount":      big.NewRat(1234567899, 100),
+		"created":     created,
+		"tags":        []interface{}{"a", "foo"},
+		"fraud_score": goavro.Union("double", 0.25),
+	}
+	// Confluent wire format - magic byte, schema id then the avro binary
+	value := common.AppendUint32ToBufferBE([]byte{0}, uint32(schemaID))
+	value, err = codec.BinaryFromNative(value, native)
+	require.NoError(t, err)
+
+	theColNames := []string{"col0", "col1", "col2", "col3", "col4", "col5", "col6", "col7", "col8"}
+	tsColType := common.NewTimestampColumnType(3)
+	theColTypes := []common.ColumnType{common.BigIntColumnType, common.VarcharColumnType, common.VarcharColumnType,
+		common.VarcharColumnType, common.VarcharColumnType, dt, tsColType, common.VarcharColumnType, common.DoubleColumnType}
+	vf := func(t *testing.T, row *common.Row) {
+		t.Helper()
+		require.Equal(t, int64(1234), row.GetInt64(0))
+		require.Equal(t, "alice", row.GetString(1))
+		require.Equal(t, "UK", row.GetString(2))
+		require.Equal(t, "bob", row.GetString(3))
+		require.True(t, row.IsNull(4))
+		dec := row.GetDecimal(5)
+		require.Equal(t, "12345678.99", dec.String())
+		expected := common.NewTimestampFromGoTime(created)
+		expected.SetFsp(3)
+		require.Equal(t, expected, row.GetTimestamp(6))
+		require.Equal(t, "foo", row.GetString(7))
+		require.Equal(t, 0.25, row.GetFloat64(8))
+	}
+	selectors, err := compileSelectors([]string{"id", "customer.name", "customer.country", "previous_customer.name",
+		"previous_customer.country", "amount", "created", "tags[1]", "fraud_score"})
+	require.NoError(t, err)
+	sourceInfo := &common.SourceInfo{
+		TableInfo: &common.TableInfo{
+			SchemaName:     "test",
+			Name:           "test_table",
+			PrimaryKeyCols: []int{0},
+			ColumnNames:    theColNames,
+			ColumnTypes:    theColTypes,
+		},
+		TopicInfo: &common.TopicInfo{
+			BrokerName:     "test_broker",
+			TopicName:      "test_topic",
+			HeaderEncoding: common.KafkaEncodingJSON,
+			KeyEncoding:    common.KafkaEncodingJSON,
+			ValueEncoding:  common.KafkaEncodingAvro,
+			ColSelectors:   selectors,
+		},
+	}
+	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry, NewSchemaRegistry(registry.URL))
+	require.NoError(t, err)
+	rows, err := mp.ParseMessages([]*kafka.Message{{Value: value}})
+	require.NoError(t, err)
+	require.Equal(t, 1, rows.RowCount())
+	row := rows.GetRow(0)
+	vf(t, &row)
+
+	// A schema which isn't in the registry
+	unknown := common.AppendUint32ToBufferBE([]byte{0}, uint32(schemaID+1))
+	unknown, err = codec.BinaryFromNative(unknown, native)
+	require.NoError(t, err)
+	_, err = mp.ParseMessages([]*kafka.Message{{Value: unknown}})
+	require.Error(t, err)
+
+	// Not in the Confluent wire format
+	_, err = mp.ParseMessages([]*kafka.Message{{Value: []byte(`{"id":1234}`)}})
+	require.Error(t, err)
+}
+
 func testParseMessage(t *testing.T, colNames []string, colTypes []common.ColumnType, headerEncoding common.KafkaEncoding, keyEncoding common.KafkaEncoding,
 	valueEncoding common.KafkaEncoding, headers []kafka.MessageHeader, keyBytes []byte, valueBytes []byte, colSelectors []string, timestamp time.Time,
 	vf verifyExpectedValuesFunc) {
@@ -431,7 +812,7 @@ func testParseMessage(t *testing.T, colNames []string, colTypes []common.ColumnT
 		TableInfo: tableInfo,
 		TopicInfo: topicInfo,
 	}
-	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry)
+	mp, err := NewMessageParser(sourceInfo, protolib.EmptyRegistry, nil)
 	require.NoError(t, err)
 
 	msg := &kafka.Message{
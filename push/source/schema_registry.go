@@ -0,0 +1,290 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/squareup/pranadb/errors"
+)
+
+const schemaRegistryTimeout = 10 * time.Second
+
+// SchemaRegistry fetches Avro writer schemas by ID from a Confluent compatible schema registry. Schemas are immutable
+// once registered, so they are cached for the lifetime of the registry.
+type SchemaRegistry struct {
+	url     string
+	client  *http.Client
+	lock    sync.RWMutex
+	schemas map[int32]*avroSchema
+}
+
+func NewSchemaRegistry(url string) *SchemaRegistry {
+	return &SchemaRegistry{
+		url:     strings.TrimSuffix(url, "/"),
+		client:  &http.Client{Timeout: schemaRegistryTimeout},
+		schemas: make(map[int32]*avroSchema),
+	}
+}
+
+func (s *SchemaRegistry) getSchema(schemaID int32) (*avroSchema, error) {
+	s.lock.RLock()
+	schema, ok := s.schemas[schemaID]
+	s.lock.RUnlock()
+	if ok {
+		return schema, nil
+	}
+	schemaStr, err := s.fetchSchema(schemaID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	schema, err = newAvroSchema(schemaStr)
+	if err != nil {
+		return nil, errors.Errorf("invalid avro schema with id %d: %v", schemaID, err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.schemas[schemaID] = schema
+	return schema, nil
+}
+
+func (s *SchemaRegistry) fetchSchema(schemaID int32) (string, error) {
+	resp, err := s.client.Get(fmt.Sprintf("%s/schemas/ids/%d", s.url, schemaID))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("failed to fetch avro schema with id %d from schema registry %s: %s %s", schemaID,
+			s.url, resp.Status, string(body))
+	}
+	var schemaResp struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.Unmarshal(body, &schemaResp); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return schemaResp.Schema, nil
+}
+
+// avroSchema is a writer schema and the codec which decodes it
+type avroSchema struct {
+	codec *goavro.Codec
+	root  interface{}
+	named map[string]interface{} // The named types of the schema by full name
+}
+
+func newAvroSchema(schemaStr string) (*avroSchema, error) {
+	codec, err := goavro.NewCodec(schemaStr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var root interface{}
+	if err := json.Unmarshal([]byte(schemaStr), &root); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	schema := &avroSchema{codec: codec, root: root, named: make(map[string]interface{})}
+	schema.addNamedTypes(root, "")
+	return schema, nil
+}
+
+func (a *avroSchema) addNamedTypes(schema interface{}, namespace string) {
+	switch s := schema.(type) {
+	case []interface{}:
+		for _, branch := range s {
+			a.addNamedTypes(branch, namespace)
+		}
+	case map[string]interface{}:
+		switch s["type"] {
+		case "record", "error", "enum", "fixed":
+			fullName, ns := avroFullName(s, namespace)
+			a.named[fullName] = s
+			if fields, ok := s["fields"].([]interface{}); ok {
+				for _, field := range fields {
+					if f, ok := field.(map[string]interface{}); ok {
+						a.addNamedTypes(f["type"], ns)
+					}
+				}
+			}
+		case "array":
+			a.addNamedTypes(s["items"], namespace)
+		case "map":
+			a.addNamedTypes(s["values"], namespace)
+		}
+	}
+}
+
+// avroFullName returns the full name of a named type, and the namespace of the types it encloses
+func avroFullName(schema map[string]interface{}, enclosingNamespace string) (string, string) {
+	name, _ := schema["name"].(string)
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return name, name[:i]
+	}
+	namespace := enclosingNamespace
+	if ns, ok := schema["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name, ""
+	}
+	return namespace + "." + name, namespace
+}
+
+// normalize converts a value decoded by goavro into the plain maps, slices and scalars that column selectors and type
+// coercion work with. goavro wraps non-null union values in a map keyed by the type name of the branch, decodes
+// decimals as *big.Rat and bytes as []byte, so these are unwrapped and converted using the schema.
+func (a *avroSchema) normalize(schema interface{}, namespace string, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch s := schema.(type) {
+	case string:
+		switch s {
+		case "null", "boolean", "int", "long", "float", "double", "string":
+			return val, nil
+		case "bytes":
+			return bytesToString(val), nil
+		}
+		named, ok := a.named[s]
+		if !ok {
+			named, ok = a.named[namespace+"."+s]
+		}
+		if !ok {
+			return nil, errors.Errorf("unknown avro type %s", s)
+		}
+		return a.normalize(named, namespace, val)
+	case []interface{}:
+		wrapped, ok := val.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return nil, errors.Errorf("unexpected avro union value %v", val)
+		}
+		for typeName, branchVal := range wrapped {
+			for _, branch := range s {
+				if a.isUnionBranch(branch, namespace, typeName) {
+					return a.normalize(branch, namespace, branchVal)
+				}
+			}
+			return nil, errors.Errorf("unknown avro union branch %s", typeName)
+		}
+	case map[string]interface{}:
+		return a.normalizeComplex(s, namespace, val)
+	}
+	return nil, errors.Errorf("invalid avro schema %v", schema)
+}
+
+func (a *avroSchema) normalizeComplex(schema map[string]interface{}, namespace string, val interface{}) (interface{}, error) {
+	switch schema["logicalType"] {
+	case "decimal":
+		if rat, ok := val.(*big.Rat); ok {
+			scale, _ := schema["scale"].(float64)
+			return rat.FloatString(int(scale)), nil
+		}
+	case "time-millis":
+		if d, ok := val.(time.Duration); ok {
+			return int64(d / time.Millisecond), nil
+		}
+	case "time-micros":
+		if d, ok := val.(time.Duration); ok {
+			return int64(d / time.Microsecond), nil
+		}
+	}
+	switch schema["type"] {
+	case "record", "error":
+		record, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("unexpected avro record value %v", val)
+		}
+		_, ns := avroFullName(schema, namespace)
+		fields, _ := schema["fields"].([]interface{})
+		for _, field := range fields {
+			f, ok := field.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := f["name"].(string)
+			fieldVal, err := a.normalize(f["type"], ns, record[name])
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			record[name] = fieldVal
+		}
+		return record, nil
+	case "array":
+		arr, ok := val.([]interface{})
+		if !ok {
+			return nil, errors.Errorf("unexpected avro array value %v", val)
+		}
+		for i, item := range arr {
+			itemVal, err := a.normalize(schema["items"], namespace, item)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			arr[i] = itemVal
+		}
+		return arr, nil
+	case "map":
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("unexpected avro map value %v", val)
+		}
+		for k, v := range m {
+			mapVal, err := a.normalize(schema["values"], namespace, v)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			m[k] = mapVal
+		}
+		return m, nil
+	case "fixed":
+		return bytesToString(val), nil
+	case "enum":
+		return val, nil
+	default:
+		return a.normalize(schema["type"], namespace, val)
+	}
+}
+
+// isUnionBranch returns true if typeName is the name that goavro uses to identify the branch of a union
+func (a *avroSchema) isUnionBranch(branch interface{}, namespace string, typeName string) bool {
+	switch b := branch.(type) {
+	case string:
+		if _, ok := a.named[b]; !ok && typeName == namespace+"."+b {
+			return true
+		}
+		return typeName == b
+	case map[string]interface{}:
+		switch t := b["type"]; t {
+		case "record", "error", "enum", "fixed":
+			fullName, _ := avroFullName(b, namespace)
+			return typeName == fullName
+		case "array", "map":
+			return typeName == t
+		default:
+			// A primitive with a logical type is named after both, unless goavro doesn't support the logical type
+			if logicalType, ok := b["logicalType"].(string); ok {
+				if baseType, ok := t.(string); ok && typeName == baseType+"."+logicalType {
+					return true
+				}
+			}
+			return a.isUnionBranch(t, namespace, typeName)
+		}
+	}
+	return false
+}
+
+func bytesToString(val interface{}) interface{} {
+	if b, ok := val.([]byte); ok {
+		return string(b)
+	}
+	return val
+}
@@ -3,6 +3,7 @@ package source
 import (
 	"fmt"
 	"github.com/squareup/pranadb/push/util"
+	"math"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -19,9 +20,11 @@ import (
 	"github.com/squareup/pranadb/conf"
 	"github.com/squareup/pranadb/errors"
 	"github.com/squareup/pranadb/kafka"
+	"github.com/squareup/pranadb/meta"
 	"github.com/squareup/pranadb/protolib"
 	"github.com/squareup/pranadb/push/exec"
 	"github.com/squareup/pranadb/sharder"
+	"github.com/squareup/pranadb/table"
 )
 
 const (
@@ -33,6 +36,14 @@ const (
 	numConsumersPerSourcePropName = "prana.source.numconsumers"
 	pollTimeoutPropName           = "prana.source.polltimeoutms"
 	maxPollMessagesPropName       = "prana.source.maxpollmessages"
+	watermarkHeartbeatInterval    = time.Second
+	watermarkKeepAliveInterval    = 10 * time.Second
+	topicIndexLockTimeout         = 30 * time.Second
+	topicIndexLockRetryDelay      = 100 * time.Millisecond
+	// MaxTopicIndex is the highest index a topic of a source with multiple topics can have, and MaxOffsetResets the
+	// most times its offsets can be reset, as they share the upper 32 bits of the originator partition
+	MaxTopicIndex   = math.MaxUint16
+	MaxOffsetResets = math.MaxUint16
 )
 
 type RowProcessor interface {
@@ -48,12 +59,14 @@ type Source struct {
 	sharder                 *sharder.Sharder
 	cluster                 cluster.Cluster
 	protoRegistry           protolib.Resolver
+	schemaRegistry          *SchemaRegistry
 	msgProvFact             kafka.MessageProviderFactory
 	msgConsumers            []*MessageConsumer
 	queryExec               common.SimpleQueryExec
 	lock                    sync.Mutex
 	lastRestartDelay        time.Duration
 	started                 bool
+	paused                  bool
 	numConsumersPerSource   int
 	pollTimeoutMs           int
 	maxPollMessages         int
@@ -66,6 +79,23 @@ type Source struct {
 	ingestDurationHistogram metrics.Observer
 	ingestRowSizeHistogram  metrics.Observer
 	globalRateLimiter       IngestLimiter
+	lateRowsCounter         metrics.Counter
+	lateRowsCount           int64
+	watermarkLock           sync.Mutex
+	partitionEventTimes     map[kafka.TopicPartition]common.Timestamp // The latest event time seen on each partition
+	partitionLastReceived   map[kafka.TopicPartition]time.Time        // When a message was last received on each partition
+	currentWatermark        *common.Timestamp
+	sentWatermark           *common.Timestamp // The watermark last sent to all the shards
+	sentIdle                bool
+	sentAt                  time.Time
+	stopHeartbeats          chan struct{}
+	deadLetterProducer      kafka.MessageProducer
+	failedMessagesCounter   metrics.Counter
+	failedMessagesCount     int64
+	lastError               string
+	ingestRate              ingestRate
+	topicIndexesLock        sync.Mutex
+	topicIndexes            map[string]uint32
 }
 
 var (
@@ -89,6 +119,14 @@ var (
 		Name: "pranadb_ingest_row_size",
 		Help: "histogram measuring size of ingested rows in bytes",
 	}, []string{"source"})
+	lateRowsVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pranadb_late_rows_dropped_total",
+		Help: "counter for number of rows dropped because they arrived after the watermark, segmented by source name",
+	}, []string{"source"})
+	failedMessagesVec = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pranadb_messages_failed_total",
+		Help: "counter for number of messages which failed to parse and were skipped or dead lettered, segmented by source name",
+	}, []string{"source"})
 )
 
 func NewSource(sourceInfo *common.SourceInfo, tableExec *exec.TableExecutor, sharder *sharder.Sharder,
@@ -108,17 +146,29 @@ func NewSource(sourceInfo *common.SourceInfo, tableExec *exec.TableExecutor, sha
 	if !ok {
 		return nil, errors.NewPranaErrorf(errors.UnknownBrokerName, "Unknown broker. Name: %s", ti.BrokerName)
 	}
-	props := copyAndAddAll(brokerConf.Properties, ti.Properties)
+	var schemaRegistry *SchemaRegistry
+	if brokerConf.SchemaRegistryURL != "" {
+		schemaRegistry = NewSchemaRegistry(brokerConf.SchemaRegistryURL)
+	} else {
+		for _, enc := range []common.KafkaEncoding{ti.HeaderEncoding, ti.KeyEncoding, ti.ValueEncoding} {
+			if enc.Encoding == common.EncodingAvro {
+				return nil, errors.NewPranaErrorf(errors.UnknownTopicEncoding,
+					"Avro encoding requires a schema registry URL to be configured for broker %s", ti.BrokerName)
+			}
+		}
+	}
+	props := CopyAndAddAll(brokerConf.Properties, ti.Properties)
 	groupID := GenerateGroupID(cfg.ClusterID, sourceInfo)
+	topics := subscribedTopics(ti)
 	switch brokerConf.ClientType {
 	case conf.BrokerClientFake:
 		var err error
-		msgProvFact, err = kafka.NewFakeMessageProviderFactory(ti.TopicName, props, groupID)
+		msgProvFact, err = kafka.NewFakeMessageProviderFactory(topics, props, groupID)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 	case conf.BrokerClientDefault:
-		msgProvFact = kafka.NewMessageProviderFactory(ti.TopicName, props, groupID)
+		msgProvFact = kafka.NewMessageProviderFactory(topics, props, groupID)
 	default:
 		return nil, errors.NewPranaErrorf(errors.UnsupportedBrokerClientType, "Unsupported broker client type %d", brokerConf.ClientType)
 	}
@@ -139,14 +189,25 @@ func NewSource(sourceInfo *common.SourceInfo, tableExec *exec.TableExecutor, sha
 	bytesIngestedCounter := bytesIngestedVec.WithLabelValues(sourceInfo.Name)
 	ingestDurationHistogram := ingestBatchTimeVec.WithLabelValues(sourceInfo.Name)
 	ingestRowSizeHistogram := ingestRowSizeVec.WithLabelValues(sourceInfo.Name)
+	lateRowsCounter := lateRowsVec.WithLabelValues(sourceInfo.Name)
+	failedMessagesCounter := failedMessagesVec.WithLabelValues(sourceInfo.Name)
+	var deadLetterProducer kafka.MessageProducer
+	if ti.ErrorPolicy == common.SourceErrorPolicyDeadLetter && ti.DeadLetterTopic != "" {
+		deadLetterProducer, err = NewMessageProducer(cfg, ti.BrokerName, ti.DeadLetterTopic, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
 	source := &Source{
 		sourceInfo:              sourceInfo,
 		tableExecutor:           tableExec,
 		sharder:                 sharder,
 		cluster:                 cluster,
 		protoRegistry:           registry,
+		schemaRegistry:          schemaRegistry,
 		msgProvFact:             msgProvFact,
 		queryExec:               queryExec,
+		paused:                  ti.Paused,
 		numConsumersPerSource:   numConsumers,
 		pollTimeoutMs:           pollTimeoutMs,
 		maxPollMessages:         maxPollMessages,
@@ -157,16 +218,36 @@ func NewSource(sourceInfo *common.SourceInfo, tableExec *exec.TableExecutor, sha
 		ingestDurationHistogram: ingestDurationHistogram,
 		ingestRowSizeHistogram:  ingestRowSizeHistogram,
 		globalRateLimiter:       globalRateLimiter,
+		lateRowsCounter:         lateRowsCounter,
+		partitionEventTimes:     make(map[kafka.TopicPartition]common.Timestamp),
+		partitionLastReceived:   make(map[kafka.TopicPartition]time.Time),
+		deadLetterProducer:      deadLetterProducer,
+		failedMessagesCounter:   failedMessagesCounter,
+		topicIndexes:            make(map[string]uint32, len(ti.TopicIndexes)),
+	}
+	for topic, index := range ti.TopicIndexes {
+		source.topicIndexes[topic] = index
 	}
 	source.commitOffsets.Set(true)
 	return source, nil
 }
 
 func (s *Source) Start() error {
-	log.Infof("Starting source %s.%s", s.sourceInfo.SchemaName, s.sourceInfo.Name)
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	if s.paused {
+		log.Infof("Not starting source %s.%s as it is paused", s.sourceInfo.SchemaName, s.sourceInfo.Name)
+		return nil
+	}
+	log.Infof("Starting source %s.%s", s.sourceInfo.SchemaName, s.sourceInfo.Name)
+	if err := s.start(); err != nil {
+		s.setLastError(err)
+		return errors.WithStack(err)
+	}
+	return nil
+}
 
+func (s *Source) start() error {
 	if s.started {
 		return nil
 	}
@@ -175,6 +256,12 @@ func (s *Source) Start() error {
 		panic("more than zero consumers!")
 	}
 
+	if s.deadLetterProducer != nil {
+		if err := s.deadLetterProducer.Start(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
 	for i := 0; i < s.numConsumersPerSource; i++ {
 		msgProvider, err := s.msgProvFact.NewMessageProvider()
 		if err != nil {
@@ -188,6 +275,11 @@ func (s *Source) Start() error {
 		s.msgConsumers = append(s.msgConsumers, consumer)
 	}
 
+	if s.sourceInfo.TopicInfo.EventTime != nil {
+		s.stopHeartbeats = make(chan struct{})
+		go s.heartbeatLoop(s.stopHeartbeats)
+	}
+
 	s.started = true
 	return nil
 }
@@ -204,6 +296,42 @@ func (s *Source) IsRunning() bool {
 	return s.started
 }
 
+// Pause stops the source and marks it as paused, so it isn't started again, e.g. after a consumer error, until it's
+// resumed
+func (s *Source) Pause() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	log.Infof("Pausing source %s.%s", s.sourceInfo.SchemaName, s.sourceInfo.Name)
+	s.paused = true
+	return s.stop()
+}
+
+// Resume clears the paused mark and starts the source. A source which stopped because of an error is started too.
+func (s *Source) Resume() error {
+	s.lock.Lock()
+	s.paused = false
+	s.lock.Unlock()
+	return s.Start()
+}
+
+func (s *Source) IsPaused() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.paused
+}
+
+// GetLastError returns the message of the last error which stopped the source on this node, or prevented it from
+// starting. It's empty if there hasn't been one since the node started.
+func (s *Source) GetLastError() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastError
+}
+
+func (s *Source) setLastError(err error) {
+	s.lastError = err.Error()
+}
+
 func (s *Source) Drop() error {
 	// Delete the deduplication ids for the source
 	log.Printf("dropping source %s %d", s.sourceInfo.Name, s.sourceInfo.ID)
@@ -214,6 +342,23 @@ func (s *Source) Drop() error {
 		return errors.WithStack(err)
 	}
 
+	// Delete the dead letters for the source
+	deadLetterPrefix := common.AppendUint64ToBufferBE(nil, common.DeadLetterTableID)
+	deadLetterStartPrefix := common.KeyEncodeInt64(deadLetterPrefix, int64(s.sourceInfo.ID))
+	deadLetterEndPrefix := common.KeyEncodeInt64(deadLetterPrefix, int64(s.sourceInfo.ID+1))
+	if err := s.cluster.DeleteAllDataInRangeForAllShardsLocally(deadLetterStartPrefix, deadLetterEndPrefix); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if retention := s.sourceInfo.Retention; retention != nil {
+		// Delete the retention index
+		indexStartPrefix := common.AppendUint64ToBufferBE(nil, retention.IndexID)
+		indexEndPrefix := common.AppendUint64ToBufferBE(nil, retention.IndexID+1)
+		if err := s.cluster.DeleteAllDataInRangeForAllShardsLocally(indexStartPrefix, indexEndPrefix); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
 	// Delete the table data
 	tableStartPrefix := common.AppendUint64ToBufferBE(nil, s.sourceInfo.ID)
 	tableEndPrefix := common.AppendUint64ToBufferBE(nil, s.sourceInfo.ID+1)
@@ -245,6 +390,7 @@ func (s *Source) consumerError(err error, clientError bool) {
 		//panic("Got consumer error but souce is not started")
 	}
 	log.Errorf("Failure in consumer, source will be stopped: %+v. ", err)
+	s.setLastError(err)
 	if err2 := s.stop(); err2 != nil {
 		return
 	}
@@ -283,6 +429,15 @@ func (s *Source) stop() error {
 		}
 	}
 	s.msgConsumers = nil
+	if s.stopHeartbeats != nil {
+		close(s.stopHeartbeats)
+		s.stopHeartbeats = nil
+	}
+	if s.deadLetterProducer != nil {
+		if err := s.deadLetterProducer.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 	s.started = false
 	return nil
 }
@@ -291,9 +446,19 @@ func (s *Source) ingestMessages(messages []*kafka.Message, mp *MessageParser) er
 
 	start := time.Now()
 
-	rows, err := mp.ParseMessages(messages)
-	if err != nil {
-		return errors.WithStack(err)
+	var rows *common.Rows
+	if s.sourceInfo.TopicInfo.ErrorPolicy == common.SourceErrorPolicyFail {
+		var err error
+		rows, err = mp.ParseMessages(messages)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		var failed []*FailedMessage
+		rows, messages, failed = mp.ParseValidMessages(messages)
+		if err := s.handleFailedMessages(failed); err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
 	// TODO where Source has no key - need to create one
@@ -306,8 +471,17 @@ func (s *Source) ingestMessages(messages []*kafka.Message, mp *MessageParser) er
 
 	forwardBatches := make(map[uint64]*cluster.WriteBatch)
 
+	late, watermark, err := s.updateWatermark(rows, messages, time.Now())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
 	totBatchSizeBytes := 0
+	ingestedCount := 0
 	for i := 0; i < rows.RowCount(); i++ {
+		if late[i] {
+			continue
+		}
 		// We throttle the global ingest to prevent the node getting overloaded - it's easy otherwise to saturate the
 		// disk throughput which can make the node unstable
 		s.globalRateLimiter.Limit()
@@ -331,8 +505,11 @@ func (s *Source) ingestMessages(messages []*kafka.Message, mp *MessageParser) er
 		}
 
 		kMsg := messages[i]
-		forwardKey := util.EncodeKeyForForwardIngest(tableID, uint64(kMsg.PartInfo.PartitionID),
-			uint64(kMsg.PartInfo.Offset), tableID)
+		originatorPartition, err := s.originatorPartition(kMsg.PartInfo)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		forwardKey := util.EncodeKeyForForwardIngest(tableID, originatorPartition, uint64(kMsg.PartInfo.Offset), tableID)
 
 		valueBuff := make([]byte, 0, 32)
 		var encodedRow []byte
@@ -341,13 +518,34 @@ func (s *Source) ingestMessages(messages []*kafka.Message, mp *MessageParser) er
 			return err
 		}
 
-		forwardBatch.AddPut(forwardKey, util.EncodePrevAndCurrentRow(nil, encodedRow))
+		var forwardValue []byte
+		if kMsg.Value == nil && s.sourceInfo.TopicInfo.Semantics == common.SourceSemanticsChangelog {
+			// A tombstone deletes the row with its key
+			forwardValue = util.EncodePrevAndCurrentRow(encodedRow, nil)
+		} else {
+			forwardValue = util.EncodePrevAndCurrentRow(nil, encodedRow)
+		}
+		if watermark != nil {
+			forwardValue, err = util.AppendWatermark(forwardValue, *watermark, uint64(s.cluster.GetNodeID()))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		forwardBatch.AddPut(forwardKey, forwardValue)
+		ingestedCount++
 
 		l := len(valueBuff)
 		totBatchSizeBytes += l
 		s.ingestRowSizeHistogram.Observe(float64(l))
 	}
 
+	if watermark != nil && s.shouldSendWatermark(*watermark, false, time.Now()) {
+		// The shards which no rows were sent to must still see the new watermark, or their windows wouldn't close
+		if err := s.addWatermarkHeartbeats(forwardBatches, *watermark, false); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
 	if err := util.SendForwardBatches(forwardBatches, s.cluster); err != nil {
 		log.Errorf("failed to send ingest forward batches %+v", err)
 		return err
@@ -355,18 +553,327 @@ func (s *Source) ingestMessages(messages []*kafka.Message, mp *MessageParser) er
 
 	ingestTimeNanos := time.Now().Sub(start).Nanoseconds()
 	s.ingestDurationHistogram.Observe(float64(ingestTimeNanos))
-	s.rowsIngestedCounter.Add(float64(rows.RowCount()))
+	s.rowsIngestedCounter.Add(float64(ingestedCount))
+	s.ingestRate.add(time.Now(), int64(ingestedCount))
 	s.batchesIngestedCounter.Add(1)
 	s.bytesIngestedCounter.Add(float64(totBatchSizeBytes))
 
 	return nil
 }
 
+// originatorPartition returns the partition part of the originator id used to detect duplicate messages. Each partition
+// is a stream with increasing offsets as far as duplicate detection is concerned.
+func (s *Source) originatorPartition(partInfo kafka.PartInfo) (uint64, error) {
+	topicInfo := s.sourceInfo.TopicInfo
+	// The number of offset resets is combined with the partition, so after a reset the messages are a new stream, and
+	// messages consumed again aren't ignored
+	stream := topicInfo.OffsetResets
+	if topicInfo.HasMultipleTopics() {
+		// Partitions of different topics have the same ids, so the index of the topic is combined with them too
+		topicIndex, err := s.topicIndex(partInfo.Topic)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+		stream = stream<<16 | topicIndex
+	}
+	return uint64(stream)<<32 | uint64(uint32(partInfo.PartitionID)), nil
+}
+
+// topicIndex returns the index of a topic of a source with multiple topics
+func (s *Source) topicIndex(topic string) (uint32, error) {
+	s.topicIndexesLock.Lock()
+	defer s.topicIndexesLock.Unlock()
+	index, ok := s.topicIndexes[topic]
+	if ok {
+		return index, nil
+	}
+	index, err := s.assignTopicIndex(topic)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	s.topicIndexes[topic] = index
+	return index, nil
+}
+
+// assignTopicIndex returns the persisted index of a topic the source hasn't seen before - the topic might have been
+// given an index on another node, or the source might have been created before it. If the topic doesn't have an index
+// yet it's given the next one. The indexes are read and updated under a cluster lock so every node agrees on them.
+func (s *Source) assignTopicIndex(topic string) (uint32, error) {
+	lockName := fmt.Sprintf("$topic_indexes/%d", s.sourceInfo.ID)
+	if err := s.getLock(lockName); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer func() {
+		if _, err := s.cluster.ReleaseLock(lockName); err != nil {
+			log.Errorf("failed to release topic index lock %s %+v", lockName, err)
+		}
+	}()
+	sourceInfo, err := LoadSourceInfo(s.queryExec, s.sourceInfo.ID)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	topicInfo := *sourceInfo.TopicInfo
+	if index, ok := topicInfo.TopicIndexes[topic]; ok {
+		return index, nil
+	}
+	index := uint32(len(topicInfo.TopicIndexes))
+	if index > MaxTopicIndex {
+		return 0, errors.Errorf("source %s.%s can't consume from more than %d topics", s.sourceInfo.SchemaName,
+			s.sourceInfo.Name, MaxTopicIndex+1)
+	}
+	topicIndexes := make(map[string]uint32, len(topicInfo.TopicIndexes)+1)
+	for name, i := range topicInfo.TopicIndexes {
+		topicIndexes[name] = i
+	}
+	topicIndexes[topic] = index
+	topicInfo.TopicIndexes = topicIndexes
+	sourceInfo.TopicInfo = &topicInfo
+	wb := cluster.NewWriteBatch(cluster.SystemSchemaShardID)
+	if err := table.Upsert(meta.TableDefTableInfo.TableInfo, meta.EncodeSourceInfoToRow(sourceInfo), wb); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if err := s.cluster.WriteBatch(wb); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	log.Infof("topic %s of source %s.%s has index %d", topic, s.sourceInfo.SchemaName, s.sourceInfo.Name, index)
+	return index, nil
+}
+
+func (s *Source) getLock(lockName string) error {
+	start := time.Now()
+	for {
+		ok, err := s.cluster.GetLock(lockName)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().Sub(start) > topicIndexLockTimeout {
+			return errors.Errorf("timed out waiting to get lock %s", lockName)
+		}
+		time.Sleep(topicIndexLockRetryDelay)
+	}
+}
+
+// LoadSourceInfo loads the persisted definition of a source. It can differ from the one registered with the meta
+// controller, as topics of a source with a topic pattern are given their indexes when they're first consumed from.
+func LoadSourceInfo(queryExec common.SimpleQueryExec, sourceID uint64) (*common.SourceInfo, error) {
+	rows, err := queryExec.ExecuteQuery("sys", fmt.Sprintf(
+		"select id, kind, schema_name, name, table_info, topic_info, query, mv_name from tables where id = %d", sourceID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if rows.RowCount() != 1 {
+		return nil, errors.Errorf("cannot find persisted source with id %d", sourceID)
+	}
+	row := rows.GetRow(0)
+	return meta.DecodeSourceInfoRow(&row), nil
+}
+
+// updateWatermark updates the latest event time of each partition with the rows, and returns which of the rows are
+// late along with the watermark. A row is late if its event time is before the watermark when it arrives. The watermark
+// is nil if the source has no event time column.
+func (s *Source) updateWatermark(rows *common.Rows, messages []*kafka.Message, now time.Time) ([]bool, *common.Timestamp, error) {
+	late := make([]bool, rows.RowCount())
+	eventTime := s.sourceInfo.TopicInfo.EventTime
+	if eventTime == nil {
+		return late, nil, nil
+	}
+	s.watermarkLock.Lock()
+	defer s.watermarkLock.Unlock()
+	watermark := s.currentWatermark
+	lateCount := 0
+	for i := 0; i < rows.RowCount(); i++ {
+		partInfo := messages[i].PartInfo
+		tp := kafka.TopicPartition{Topic: partInfo.Topic, PartitionID: partInfo.PartitionID}
+		s.partitionLastReceived[tp] = now
+		row := rows.GetRow(i)
+		if row.IsNull(eventTime.ColIndex) {
+			continue
+		}
+		ts := row.GetTimestamp(eventTime.ColIndex)
+		if watermark != nil && ts.Compare(*watermark) < 0 {
+			late[i] = true
+			lateCount++
+			continue
+		}
+		if latest, ok := s.partitionEventTimes[tp]; !ok || ts.Compare(latest) > 0 {
+			s.partitionEventTimes[tp] = ts
+		}
+	}
+	if lateCount > 0 {
+		s.lateRowsCounter.Add(float64(lateCount))
+		atomic.AddInt64(&s.lateRowsCount, int64(lateCount))
+	}
+	if _, err := s.updateCurrentWatermark(now); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return late, s.currentWatermark, nil
+}
+
+// partitionsRevoked is called when the partitions assigned to the consumers of the source change. Partitions that are
+// no longer consumed here would otherwise hold the watermark back.
+func (s *Source) partitionsRevoked() {
+	s.watermarkLock.Lock()
+	defer s.watermarkLock.Unlock()
+	s.partitionEventTimes = make(map[kafka.TopicPartition]common.Timestamp)
+	s.partitionLastReceived = make(map[kafka.TopicPartition]time.Time)
+}
+
+// updateCurrentWatermark moves the watermark on to the lowest latest event time of the partitions which aren't idle,
+// less the allowed lateness, and returns true if all the partitions are idle. The watermark never goes backwards, e.g.
+// when an idle partition receives messages again.
+func (s *Source) updateCurrentWatermark(now time.Time) (bool, error) {
+	eventTime := s.sourceInfo.TopicInfo.EventTime
+	var lowest *common.Timestamp
+	for tp, latest := range s.partitionEventTimes {
+		if now.Sub(s.partitionLastReceived[tp]) >= eventTime.IdleTimeout {
+			continue
+		}
+		if lowest == nil || latest.Compare(*lowest) < 0 {
+			l := latest
+			lowest = &l
+		}
+	}
+	if lowest == nil {
+		return true, nil
+	}
+	gt, err := lowest.GoTime(time.UTC)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	watermark := common.NewTimestampFromGoTime(gt.Add(-eventTime.AllowedLateness))
+	if s.currentWatermark == nil || watermark.Compare(*s.currentWatermark) > 0 {
+		s.currentWatermark = &watermark
+	}
+	return false, nil
+}
+
+// shouldSendWatermark returns true if the watermark, or whether the source is idle, has changed since it was last sent
+// to all the shards, and records it as sent. It's also sent again if it hasn't been sent for
+// watermarkKeepAliveInterval, as the shards stop waiting for a source they haven't heard from for a while.
+func (s *Source) shouldSendWatermark(watermark common.Timestamp, idle bool, now time.Time) bool {
+	s.watermarkLock.Lock()
+	defer s.watermarkLock.Unlock()
+	if s.sentWatermark != nil && watermark.Compare(*s.sentWatermark) <= 0 && idle == s.sentIdle &&
+		now.Sub(s.sentAt) < watermarkKeepAliveInterval {
+		return false
+	}
+	s.sentWatermark = &watermark
+	s.sentIdle = idle
+	s.sentAt = now
+	return true
+}
+
+// addWatermarkHeartbeats adds a watermark with no rows to the forward batch of each shard which doesn't have one
+func (s *Source) addWatermarkHeartbeats(forwardBatches map[uint64]*cluster.WriteBatch, watermark common.Timestamp,
+	idle bool) error {
+	forwardKey := util.EncodeKeyForForwardWatermark(s.sourceInfo.TableInfo.ID)
+	for _, shardID := range s.cluster.GetAllShardIDs() {
+		if _, ok := forwardBatches[shardID]; ok {
+			continue
+		}
+		value, err := util.EncodeWatermarkHeartbeat(watermark, uint64(s.cluster.GetNodeID()), idle)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		forwardBatch := cluster.NewWriteBatch(shardID)
+		forwardBatch.AddPut(forwardKey, value)
+		forwardBatches[shardID] = forwardBatch
+	}
+	return nil
+}
+
+// heartbeatLoop periodically sends the watermark to all the shards if it has changed, which it can do without any
+// messages being received when a partition goes idle, or if it's due to be sent again to keep the source alive on the
+// shards
+func (s *Source) heartbeatLoop(stop chan struct{}) {
+	ticker := time.NewTicker(watermarkHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := s.sendWatermarkHeartbeat(); err != nil {
+				log.Warnf("failed to send watermark heartbeat for source %s.%s %+v", s.sourceInfo.SchemaName,
+					s.sourceInfo.Name, err)
+			}
+		}
+	}
+}
+
+func (s *Source) sendWatermarkHeartbeat() error {
+	now := time.Now()
+	s.watermarkLock.Lock()
+	idle, err := s.updateCurrentWatermark(now)
+	watermark := s.currentWatermark
+	s.watermarkLock.Unlock()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if watermark == nil || !s.shouldSendWatermark(*watermark, idle, now) {
+		return nil
+	}
+	forwardBatches := make(map[uint64]*cluster.WriteBatch)
+	if err := s.addWatermarkHeartbeats(forwardBatches, *watermark, idle); err != nil {
+		return errors.WithStack(err)
+	}
+	return util.SendForwardBatches(forwardBatches, s.cluster)
+}
+
+// GetLateRowsCount returns the number of rows dropped because they arrived after the watermark
+func (s *Source) GetLateRowsCount() int64 {
+	return atomic.LoadInt64(&s.lateRowsCount)
+}
+
+// GetStartOffsets returns the offset of the first message at or after the position on each partition of the topics
+func (s *Source) GetStartOffsets(startFrom *common.StartFrom) (map[kafka.TopicPartition]int64, error) {
+	return s.msgProvFact.GetOffsets(startFrom)
+}
+
+// SetOffsets sets the offsets the source consumes from next on each partition of the topics. The source must be
+// stopped on every node of the cluster.
+func (s *Source) SetOffsets(offsets map[kafka.TopicPartition]int64) error {
+	return s.msgProvFact.SetOffsets(offsets)
+}
+
+// Alter replaces the definition of the source with an altered one, e.g. with an added column. The definition is shared
+// with the meta controller, so it's replaced rather than changed in place. The source must be stopped on every node of
+// the cluster.
+func (s *Source) Alter(sourceInfo *common.SourceInfo) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.started {
+		return errors.Errorf("source %s.%s is running", s.sourceInfo.SchemaName, s.sourceInfo.Name)
+	}
+	s.sourceInfo = sourceInfo
+	s.tableExecutor.SetTableInfo(sourceInfo.TableInfo)
+	return nil
+}
+
+// GetCommittedOffsets returns the offsets the source consumes from next on each partition of the topics, as committed
+// by the consumers on all the nodes of the cluster. Partitions which no offset has been committed for are left out.
+func (s *Source) GetCommittedOffsets() (map[kafka.TopicPartition]int64, error) {
+	return s.msgProvFact.GetCommittedOffsets()
+}
+
+// GetIngestRate returns the number of rows per second ingested by the source on this node, over the last minute
+func (s *Source) GetIngestRate() float64 {
+	return s.ingestRate.rate(time.Now())
+}
+
+// GetFailedMessagesCount returns the number of messages which failed to parse and were skipped or dead lettered
+func (s *Source) GetFailedMessagesCount() int64 {
+	return atomic.LoadInt64(&s.failedMessagesCount)
+}
+
 func (s *Source) TableExecutor() *exec.TableExecutor {
 	return s.tableExecutor
 }
 
-func copyAndAddAll(p1 map[string]string, p2 map[string]string) map[string]string {
+func CopyAndAddAll(p1 map[string]string, p2 map[string]string) map[string]string {
 	m := make(map[string]string, len(p1)+len(p2))
 	for k, v := range p2 {
 		m[k] = v
@@ -378,6 +885,38 @@ func copyAndAddAll(p1 map[string]string, p2 map[string]string) map[string]string
 	return m
 }
 
+// NewMessageProducer creates a producer which sends messages to the topic on the broker
+func NewMessageProducer(cfg *conf.Config, brokerName string, topicName string, topicProps map[string]string) (kafka.MessageProducer, error) {
+	if cfg.KafkaBrokers == nil {
+		return nil, errors.NewPranaError(errors.MissingKafkaBrokers, "No Kafka brokers configured")
+	}
+	brokerConf, ok := cfg.KafkaBrokers[brokerName]
+	if !ok {
+		return nil, errors.NewPranaErrorf(errors.UnknownBrokerName, "Unknown broker. Name: %s", brokerName)
+	}
+	props := CopyAndAddAll(brokerConf.Properties, topicProps)
+	switch brokerConf.ClientType {
+	case conf.BrokerClientFake:
+		return kafka.NewFakeMessageProducer(topicName, props)
+	case conf.BrokerClientDefault:
+		return kafka.NewMessageProducer(topicName, props), nil
+	default:
+		return nil, errors.NewPranaErrorf(errors.UnsupportedBrokerClientType, "Unsupported broker client type %d", brokerConf.ClientType)
+	}
+}
+
+// subscribedTopics returns the topics the consumers of the source subscribe to
+func subscribedTopics(topicInfo *common.TopicInfo) []string {
+	switch {
+	case topicInfo.TopicPattern != "":
+		return []string{kafka.TopicPattern(topicInfo.TopicPattern)}
+	case len(topicInfo.TopicNames) > 0:
+		return topicInfo.TopicNames
+	default:
+		return []string{topicInfo.TopicName}
+	}
+}
+
 func GenerateGroupID(clusterID uint64, sourceInfo *common.SourceInfo) string {
 	return fmt.Sprintf("prana-source-%d-%s-%s-%d", clusterID, sourceInfo.SchemaName, sourceInfo.Name, sourceInfo.ID)
 }
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/squareup/pranadb/errors"
 
@@ -94,16 +95,60 @@ func CoerceFloat64(val interface{}) (float64, error) {
 	}
 }
 
+func CoerceBool(val interface{}) (bool, error) {
+	switch v := val.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case int32:
+		return v != 0, nil
+	case uint64:
+		return v != 0, nil
+	case uint32:
+		return v != 0, nil
+	case uint16:
+		return v != 0, nil
+	case int16:
+		return v != 0, nil
+	case int:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	case float32:
+		return v != 0, nil
+	case string:
+		r, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, errors.Errorf("string value %s cannot be coerced to bool %v", v, err)
+		}
+		return r, nil
+	default:
+		return false, coerceFailedErr(v, "bool")
+	}
+}
+
 func CoerceString(val interface{}) (string, error) {
 	switch v := val.(type) {
 	case string:
 		return v, nil
+	case []byte:
+		// Bytes must be valid UTF-8 to be stored in a varchar column - we don't replace invalid bytes. Any bytes can be
+		// stored in a varbinary column.
+		if !utf8.Valid(v) {
+			return "", errors.Errorf("raw value of %d bytes is not valid UTF-8 so cannot be coerced to string", len(v))
+		}
+		return string(v), nil
 	case int64, int32, uint64, int16, uint32, uint16, int:
 		return fmt.Sprintf("%d", v), nil
 	case float64, float32:
 		return fmt.Sprintf("%f", v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
 	case common.Decimal:
 		return v.String(), nil
+	case *common.Decimal:
+		return v.String(), nil
 	case protoreflect.Enum:
 		return string(v.Descriptor().Values().ByNumber(v.Number()).Name()), nil
 	default:
@@ -111,6 +156,17 @@ func CoerceString(val interface{}) (string, error) {
 	}
 }
 
+func CoerceBytes(val interface{}) ([]byte, error) {
+	switch v := val.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, coerceFailedErr(v, "bytes")
+	}
+}
+
 func CoerceDecimal(val interface{}) (*common.Decimal, error) {
 	switch v := val.(type) {
 	case *common.Decimal:
@@ -145,7 +201,11 @@ func CoerceTimestamp(val interface{}) (common.Timestamp, error) {
 	case time.Time:
 		return common.NewTimestampFromGoTime(v), nil
 	case string:
-		return common.NewTimestampFromString(v), nil
+		ts, err := common.ParseTimestamp(v)
+		if err != nil {
+			return common.Timestamp{}, coerceFailedErr(v, "timestamp")
+		}
+		return ts, nil
 	case float64:
 		return CoerceTimestamp(uint64(v))
 	case uint64:
@@ -161,6 +221,80 @@ func CoerceTimestamp(val interface{}) (common.Timestamp, error) {
 	}
 }
 
+// AppendCoercedValue coerces the value to the column type and appends it to the column. A nil value is appended as
+// null.
+func AppendCoercedValue(rows *common.Rows, colIndex int, colType common.ColumnType, val interface{}) error {
+	coerced, err := CoerceValue(colType, val)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return appendValue(rows, colIndex, colType, coerced)
+}
+
+// CoerceValue coerces the value to the Go type which holds values of the column type. A nil value stays nil.
+func CoerceValue(colType common.ColumnType, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+	switch colType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+		return CoerceInt64(val)
+	case common.TypeDouble:
+		return CoerceFloat64(val)
+	case common.TypeBoolean:
+		return CoerceBool(val)
+	case common.TypeVarchar:
+		return CoerceString(val)
+	case common.TypeVarbinary:
+		return CoerceBytes(val)
+	case common.TypeDecimal:
+		dval, err := CoerceDecimal(val)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return *dval, nil
+	case common.TypeTimestamp:
+		tsVal, err := CoerceTimestamp(val)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		tsVal.SetFsp(colType.FSP)
+		if err := common.RoundTimestampToFSP(&tsVal, colType.FSP); err != nil {
+			return nil, err
+		}
+		return tsVal, nil
+	default:
+		return nil, errors.Errorf("unsupported col type %d", colType.Type)
+	}
+}
+
+// appendValue appends a value returned by CoerceValue to the column
+func appendValue(rows *common.Rows, colIndex int, colType common.ColumnType, val interface{}) error {
+	if val == nil {
+		rows.AppendNullToColumn(colIndex)
+		return nil
+	}
+	switch colType.Type {
+	case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+		rows.AppendInt64ToColumn(colIndex, val.(int64))
+	case common.TypeDouble:
+		rows.AppendFloat64ToColumn(colIndex, val.(float64))
+	case common.TypeBoolean:
+		rows.AppendBoolToColumn(colIndex, val.(bool))
+	case common.TypeVarchar:
+		rows.AppendStringToColumn(colIndex, val.(string))
+	case common.TypeVarbinary:
+		rows.AppendBytesToColumn(colIndex, val.([]byte))
+	case common.TypeDecimal:
+		rows.AppendDecimalToColumn(colIndex, val.(common.Decimal))
+	case common.TypeTimestamp:
+		rows.AppendTimestampToColumn(colIndex, val.(common.Timestamp))
+	default:
+		return errors.Errorf("unsupported col type %d", colType.Type)
+	}
+	return nil
+}
+
 func coerceFailedErr(v interface{}, t string) error {
 	return errors.Errorf("cannot coerce value %v, type %s to %s", v, reflect.TypeOf(v), t)
 }
@@ -0,0 +1,40 @@
+package push
+
+import (
+	"fmt"
+
+	"github.com/squareup/pranadb/protos/squareup/cash/pranadb/v1/notifications"
+	"github.com/squareup/pranadb/remoting"
+)
+
+// GetSourceStatusHandler returns the handler of the requests for the status of sources on this node. The ingest rate
+// and the last error of a source are only known on the nodes which consume it, so show source status broadcasts the
+// request to all nodes.
+func (p *Engine) GetSourceStatusHandler() remoting.ClusterMessageHandler {
+	return &sourceStatusHandler{p: p}
+}
+
+type sourceStatusHandler struct {
+	p *Engine
+}
+
+func (s *sourceStatusHandler) HandleMessage(notification remoting.ClusterMessage) (remoting.ClusterMessage, error) {
+	req, ok := notification.(*notifications.SourceStatusRequest)
+	if !ok {
+		panic(fmt.Sprintf("not a *notifications.SourceStatusRequest %v", req))
+	}
+	resp := &notifications.SourceStatusResponse{NodeId: int64(s.p.cluster.GetNodeID())}
+	for _, sourceID := range req.SourceIds {
+		src, err := s.p.GetSource(sourceID)
+		if err != nil {
+			// The source might not have been created on this node yet, or it's being dropped
+			continue
+		}
+		resp.Statuses = append(resp.Statuses, &notifications.SourceNodeStatus{
+			SourceId:   sourceID,
+			IngestRate: src.GetIngestRate(),
+			LastError:  src.GetLastError(),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,283 @@
+package push
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/parplan"
+	"github.com/squareup/pranadb/push/exec"
+	"github.com/squareup/pranadb/tidb/planner"
+)
+
+// The maximum number of changes to a materialized view that are kept in memory on each node for subscriptions to
+// resume from
+const changeLogMaxEntries = 10000
+
+// Change is a change to a row of a materialized view which has been committed on this node
+type Change struct {
+	Seq      uint64
+	PrevRow  *common.Row // nil if the row was added
+	CurrRow  *common.Row // nil if the row was deleted
+	Position string      // The position to resume a subscription from to receive the changes after this one
+}
+
+type changeLogEntry struct {
+	prevRow *common.Row
+	currRow *common.Row
+}
+
+// changeLog keeps the most recent changes to a materialized view that have been committed on this node, in the
+// order they were committed. It is created when the materialized view is first subscribed to on the node. Each
+// changeLog has its own id so a position from a previous changeLog, e.g. from before the node was restarted, is not
+// resumed from.
+type changeLog struct {
+	lock      sync.Mutex
+	id        int64
+	tableInfo *common.TableInfo
+	entries   []changeLogEntry
+	firstSeq  uint64        // The seq of entries[0]
+	changed   chan struct{} // Closed, and replaced, when entries are added or the changeLog is closed
+	closed    bool
+}
+
+func newChangeLog(tableInfo *common.TableInfo) *changeLog {
+	return &changeLog{
+		id:        time.Now().UnixNano(),
+		tableInfo: tableInfo,
+		firstSeq:  1,
+		changed:   make(chan struct{}),
+	}
+}
+
+func (c *changeLog) HandleChanges(rowsBatch exec.RowsBatch, ctx *exec.ExecutionContext) {
+	numEntries := rowsBatch.Len()
+	entries := make([]changeLogEntry, numEntries)
+	for i := 0; i < numEntries; i++ {
+		entries[i].prevRow = rowsBatch.PreviousRow(i)
+		entries[i].currRow = rowsBatch.CurrentRow(i)
+	}
+	// The changes are only added once the batch has been committed - if it fails it will be processed again
+	ctx.WriteBatch.AddCommittedCallback(func() error {
+		c.append(entries)
+		return nil
+	})
+}
+
+func (c *changeLog) append(entries []changeLogEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return
+	}
+	c.entries = append(c.entries, entries...)
+	if excess := len(c.entries) - changeLogMaxEntries; excess > 0 {
+		c.entries = append([]changeLogEntry(nil), c.entries[excess:]...)
+		c.firstSeq += uint64(excess)
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *changeLog) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.changed)
+}
+
+func (c *changeLog) position(seq uint64) string {
+	return fmt.Sprintf("%d:%d", c.id, seq)
+}
+
+// Subscription receives the changes to a materialized view which are committed on this node
+type Subscription struct {
+	changeLog *changeLog
+	filter    *exec.PushSelect
+	nextSeq   uint64
+	resumed   bool
+	position  string
+}
+
+// Resumed returns true if the subscription resumed from the position it was created with, rather than starting
+// from the changes committed after it was created
+func (s *Subscription) Resumed() bool {
+	return s.resumed
+}
+
+// Position returns the position to resume from to receive the changes after the ones received so far
+func (s *Subscription) Position() string {
+	return s.position
+}
+
+// NextChanges waits for changes after the ones received so far and returns them. It returns nil if done is closed
+// before there are any. An error is returned if the materialized view is dropped, or the node is stopped, or if the
+// subscription has fallen so far behind that the changes have been discarded.
+func (s *Subscription) NextChanges(done <-chan struct{}) ([]Change, error) {
+	for {
+		entries, changed, err := s.nextEntries()
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			select {
+			case <-changed:
+				continue
+			case <-done:
+				return nil, nil
+			}
+		}
+		var changes []Change
+		for _, entry := range entries {
+			seq := s.nextSeq
+			s.nextSeq++
+			s.position = s.changeLog.position(seq)
+			prevRow, currRow, err := s.filter.FilterChange(entry.prevRow, entry.currRow)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			if prevRow == nil && currRow == nil {
+				continue
+			}
+			changes = append(changes, Change{Seq: seq, PrevRow: prevRow, CurrRow: currRow, Position: s.position})
+		}
+		if len(changes) != 0 {
+			return changes, nil
+		}
+	}
+}
+
+func (s *Subscription) nextEntries() ([]changeLogEntry, chan struct{}, error) {
+	c := s.changeLog
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return nil, nil, errors.NewPranaErrorf(errors.SubscriptionClosed,
+			"Materialized view %s.%s is no longer available to subscribe to", c.tableInfo.SchemaName, c.tableInfo.Name)
+	}
+	if s.nextSeq < c.firstSeq {
+		return nil, nil, errors.NewPranaErrorf(errors.SubscriptionClosed,
+			"Subscription to %s.%s has fallen too far behind", c.tableInfo.SchemaName, c.tableInfo.Name)
+	}
+	return c.entries[s.nextSeq-c.firstSeq:], c.changed, nil
+}
+
+// Subscribe creates a subscription to the changes to a materialized view which are committed on this node. filter is
+// an optional SQL expression over the columns of the materialized view, only changes to rows which match it are
+// received. If position is the position of a change from an earlier subscription, and the changes after it are still
+// available, the subscription resumes from there, otherwise it receives the changes committed from now on.
+func (p *Engine) Subscribe(schema *common.Schema, mvName string, filter string, position string) (*Subscription, error) {
+	mvInfo, ok := schema.GetTable(mvName)
+	if !ok {
+		return nil, errors.NewUnknownMaterializedViewError(schema.Name, mvName)
+	}
+	if _, ok := mvInfo.(*common.MaterializedViewInfo); !ok {
+		return nil, errors.NewUnknownMaterializedViewError(schema.Name, mvName)
+	}
+	predicates, err := compileSubscriptionFilter(schema, mvName, filter)
+	if err != nil {
+		return nil, err
+	}
+	pushSelect := exec.NewPushSelect(predicates)
+	changeLog, err := p.getOrCreateChangeLog(mvInfo.GetTableInfo().ID)
+	if err != nil {
+		return nil, err
+	}
+
+	changeLog.lock.Lock()
+	defer changeLog.lock.Unlock()
+	sub := &Subscription{changeLog: changeLog, filter: pushSelect}
+	nextSeq := changeLog.firstSeq + uint64(len(changeLog.entries))
+	if seq, ok := changeLog.parsePosition(position); ok && seq+1 >= changeLog.firstSeq && seq < nextSeq {
+		sub.nextSeq = seq + 1
+		sub.resumed = true
+	} else {
+		sub.nextSeq = nextSeq
+	}
+	sub.position = changeLog.position(sub.nextSeq - 1)
+	return sub, nil
+}
+
+// parsePosition returns the seq of a position if it's a position in this changeLog
+func (c *changeLog) parsePosition(position string) (uint64, bool) {
+	parts := strings.Split(position, ":")
+	if len(parts) != 2 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id != c.id {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
+
+func (p *Engine) getOrCreateChangeLog(mvID uint64) (*changeLog, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	mv, ok := p.materializedViews[mvID]
+	if !ok {
+		return nil, errors.Errorf("no such materialized view %d", mvID)
+	}
+	changeLog, ok := p.changeLogs[mvID]
+	if !ok {
+		changeLog = newChangeLog(mv.Info.TableInfo)
+		mv.TableExecutor().SetChangeListener(changeLog)
+		p.changeLogs[mvID] = changeLog
+	}
+	return changeLog, nil
+}
+
+// removeChangeLog closes the changeLog of a materialized view, if it has one, which ends the subscriptions to it
+func (p *Engine) removeChangeLog(mv *MaterializedView) {
+	changeLog, ok := p.changeLogs[mv.Info.TableInfo.ID]
+	if !ok {
+		return
+	}
+	mv.TableExecutor().SetChangeListener(nil)
+	changeLog.close()
+	delete(p.changeLogs, mv.Info.TableInfo.ID)
+}
+
+// compileSubscriptionFilter plans the filter as the WHERE clause of a query on the materialized view and returns its
+// conditions
+func compileSubscriptionFilter(schema *common.Schema, mvName string, filter string) ([]*common.Expression, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+	pl := parplan.NewPlanner(schema)
+	query := fmt.Sprintf("select * from %s where %s", mvName, filter)
+	physicalPlan, _, err := pl.QueryToPlan(query, false, false)
+	if err != nil {
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid filter %s: %v", filter, err)
+	}
+	for {
+		proj, ok := physicalPlan.(*planner.PhysicalProjection)
+		if !ok {
+			break
+		}
+		physicalPlan = proj.Children()[0]
+	}
+	selection, ok := physicalPlan.(*planner.PhysicalSelection)
+	if !ok {
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid filter %s", filter)
+	}
+	if _, ok := selection.Children()[0].(*planner.PhysicalTableScan); !ok {
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid filter %s", filter)
+	}
+	predicates := make([]*common.Expression, len(selection.Conditions))
+	for i, condition := range selection.Conditions {
+		predicates[i] = common.NewExpression(condition)
+	}
+	return predicates, nil
+}
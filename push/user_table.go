@@ -0,0 +1,298 @@
+package push
+
+import (
+	"bytes"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/push/exec"
+	"github.com/squareup/pranadb/push/util"
+	"github.com/squareup/pranadb/sharder"
+	"github.com/squareup/pranadb/table"
+)
+
+// UserTable is a table which is written to directly with INSERT, UPDATE and DELETE statements. Written rows are sharded
+// on the primary key and forwarded to the owning shard, where they are handled by the table executor in the same way
+// as rows ingested by a source. Updates are applied to the row as it is stored on the owning shard, see
+// HandleRemoteRows.
+type UserTable struct {
+	lock          sync.Mutex
+	tableInfo     *common.UserTableInfo
+	tableExecutor *exec.TableExecutor
+	sharder       *sharder.Sharder
+	cluster       cluster.Cluster
+}
+
+func (p *Engine) CreateUserTable(tableInfo *common.UserTableInfo) (*UserTable, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.userTables[tableInfo.ID]; ok {
+		return nil, errors.Errorf("table with id %d already exists", tableInfo.ID)
+	}
+	userTable := &UserTable{
+		tableInfo:     tableInfo,
+		tableExecutor: exec.NewTableExecutor(tableInfo.TableInfo, p.cluster),
+		sharder:       p.sharder,
+		cluster:       p.cluster,
+	}
+	colTypes := tableInfo.ColumnTypes
+	rc := &RemoteConsumer{
+		RowsFactory: common.NewRowsFactory(colTypes),
+		ColTypes:    colTypes,
+		RowsHandler: userTable,
+	}
+	p.remoteConsumers.Store(tableInfo.ID, rc)
+	p.userTables[tableInfo.ID] = userTable
+	return userTable, nil
+}
+
+func (p *Engine) GetUserTable(tableID uint64) (*UserTable, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	userTable, ok := p.userTables[tableID]
+	if !ok {
+		return nil, errors.Errorf("no such table %d", tableID)
+	}
+	return userTable, nil
+}
+
+func (p *Engine) RemoveUserTable(tableInfo *common.UserTableInfo) (*UserTable, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	userTable, ok := p.userTables[tableInfo.ID]
+	if !ok {
+		return nil, errors.Errorf("no such table %d", tableInfo.ID)
+	}
+	delete(p.userTables, tableInfo.ID)
+	p.remoteConsumers.Delete(tableInfo.ID)
+	return userTable, nil
+}
+
+// Upsert writes the rows to the table, replacing any existing rows with the same key
+func (u *UserTable) Upsert(rows *common.Rows) error {
+	return u.forwardRows(nil, rows)
+}
+
+// Update updates the rows of the table which had the values of prevRows when they were read to the values of rows.
+// Only the columns which differ between the two are written, to the row as it is when the update is applied. A row
+// which no longer exists isn't written.
+func (u *UserTable) Update(prevRows *common.Rows, rows *common.Rows) error {
+	return u.forwardRows(prevRows, rows)
+}
+
+// Delete deletes the rows with the same keys as the rows from the table
+func (u *UserTable) Delete(rows *common.Rows) error {
+	return u.forwardRows(rows, nil)
+}
+
+// forwardRows forwards the changes of rows from prevRows to currRows to the shards that own them. Either of prevRows
+// and currRows is nil for an upsert or a delete.
+func (u *UserTable) forwardRows(prevRows *common.Rows, currRows *common.Rows) error {
+	info := u.tableInfo.TableInfo
+	rows := currRows
+	if rows == nil {
+		rows = prevRows
+	}
+	keys := make([][]byte, rows.RowCount())
+	// If a key is written more than once we only keep the last row for it - the table executor can't see the
+	// previous value of a row written earlier in the same batch
+	lastIndexes := make(map[string]int, rows.RowCount())
+	for i := 0; i < rows.RowCount(); i++ {
+		row := rows.GetRow(i)
+		key, err := common.EncodeKeyCols(&row, info.PrimaryKeyCols, info.ColumnTypes, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		keys[i] = key
+		lastIndexes[string(key)] = i
+	}
+	forwardBatches := make(map[uint64]*cluster.WriteBatch)
+	for i := 0; i < rows.RowCount(); i++ {
+		if lastIndexes[string(keys[i])] != i {
+			continue
+		}
+		destShardID, err := u.sharder.CalculateShard(sharder.ShardTypeHash, keys[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		forwardBatch, ok := forwardBatches[destShardID]
+		if !ok {
+			forwardBatch = cluster.NewWriteBatch(destShardID)
+			forwardBatches[destShardID] = forwardBatch
+		}
+		var prevValue, currValue []byte
+		if prevRows != nil {
+			row := prevRows.GetRow(i)
+			if prevValue, err = common.EncodeRow(&row, info.ColumnTypes, nil); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if currRows != nil {
+			row := currRows.GetRow(i)
+			if currValue, err = common.EncodeRow(&row, info.ColumnTypes, nil); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		forwardBatch.AddPut(util.EncodeKeyForForwardWrite(info.ID), util.EncodePrevAndCurrentRow(prevValue, currValue))
+	}
+	return util.SendForwardBatches(forwardBatches, u.cluster)
+}
+
+// HandleRemoteRows handles the rows forwarded to the owning shard by Upsert, Update and Delete. An update has both a
+// previous and a current row. It's applied to the row as it's stored now, rather than as it was read, so a concurrent
+// write to the columns the update doesn't change isn't lost, and a row which has been deleted isn't written again.
+func (u *UserTable) HandleRemoteRows(rowsBatch exec.RowsBatch, ctx *exec.ExecutionContext) error {
+	info := u.tableInfo.TableInfo
+	numEntries := rowsBatch.Len()
+	rows := u.tableExecutor.RowsFactory().NewRows(numEntries)
+	entries := make([]exec.RowsEntry, 0, numEntries)
+	// The index in rows of the rows written earlier in this batch, by key - they aren't in storage until the batch is
+	// committed. -1 means the row was deleted.
+	written := make(map[string]int)
+	for i := 0; i < numEntries; i++ {
+		prevRow := rowsBatch.PreviousRow(i)
+		currentRow := rowsBatch.CurrentRow(i)
+		keyRow := currentRow
+		if keyRow == nil {
+			keyRow = prevRow
+		}
+		key := table.EncodeTableKeyPrefix(info.ID, ctx.WriteBatch.ShardID, 32)
+		key, err := common.EncodeNullableKeyCols(keyRow, info.PrimaryKeyCols, info.ColumnTypes, info.KeyNullMarkers, key)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch {
+		case currentRow == nil:
+			rows.AppendRow(*prevRow)
+			entries = append(entries, exec.NewRowsEntry(rows.RowCount()-1, -1))
+			written[string(key)] = -1
+			continue
+		case prevRow != nil:
+			storedRow, err := u.getRow(key, written, rows)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			if storedRow == nil {
+				// The row has been deleted since it was read
+				continue
+			}
+			if err := appendUpdatedRow(prevRow, currentRow, storedRow, info.ColumnTypes, rows); err != nil {
+				return errors.WithStack(err)
+			}
+		default:
+			rows.AppendRow(*currentRow)
+		}
+		entries = append(entries, exec.NewRowsEntry(-1, rows.RowCount()-1))
+		written[string(key)] = rows.RowCount() - 1
+	}
+	return u.tableExecutor.HandleRemoteRows(exec.NewRowsBatch(rows, entries), ctx)
+}
+
+// getRow returns the current value of the row with the key, or nil if there is no such row
+func (u *UserTable) getRow(key []byte, written map[string]int, rows *common.Rows) (*common.Row, error) {
+	if index, ok := written[string(key)]; ok {
+		if index == -1 {
+			return nil, nil
+		}
+		row := rows.GetRow(index)
+		return &row, nil
+	}
+	v, err := u.cluster.LocalGet(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	storedRows := u.tableExecutor.RowsFactory().NewRows(1)
+	if err := common.DecodeRow(v, u.tableInfo.ColumnTypes, storedRows); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	row := storedRows.GetRow(0)
+	return &row, nil
+}
+
+// appendUpdatedRow appends the stored row with the columns which differ between the previous and the current row of an
+// update set to their current values
+func appendUpdatedRow(prevRow *common.Row, currentRow *common.Row, storedRow *common.Row, colTypes []common.ColumnType,
+	rows *common.Rows) error {
+	for colIndex, colType := range colTypes {
+		changed, err := colChanged(prevRow, currentRow, colIndex, colTypes)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		row := storedRow
+		if changed {
+			row = currentRow
+		}
+		if row.IsNull(colIndex) {
+			rows.AppendNullToColumn(colIndex)
+			continue
+		}
+		switch colType.Type {
+		case common.TypeTinyInt, common.TypeInt, common.TypeBigInt:
+			rows.AppendInt64ToColumn(colIndex, row.GetInt64(colIndex))
+		case common.TypeBoolean:
+			rows.AppendBoolToColumn(colIndex, row.GetBool(colIndex))
+		case common.TypeDouble:
+			rows.AppendFloat64ToColumn(colIndex, row.GetFloat64(colIndex))
+		case common.TypeVarchar:
+			rows.AppendStringToColumn(colIndex, row.GetString(colIndex))
+		case common.TypeVarbinary:
+			rows.AppendBytesToColumn(colIndex, row.GetBytes(colIndex))
+		case common.TypeDecimal:
+			rows.AppendDecimalToColumn(colIndex, row.GetDecimal(colIndex))
+		case common.TypeTimestamp:
+			rows.AppendTimestampToColumn(colIndex, row.GetTimestamp(colIndex))
+		default:
+			return errors.Errorf("unexpected column type %d", colType.Type)
+		}
+	}
+	return nil
+}
+
+// colChanged returns true if the value of the column differs between the rows. The key encodings of the values are
+// compared, as they are the same only if the values are.
+func colChanged(prevRow *common.Row, currentRow *common.Row, colIndex int, colTypes []common.ColumnType) (bool, error) {
+	colIndexes := []int{colIndex}
+	prevBuff, err := common.EncodeNullableKeyCols(prevRow, colIndexes, colTypes, true, nil)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	currBuff, err := common.EncodeNullableKeyCols(currentRow, colIndexes, colTypes, true, nil)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return !bytes.Equal(prevBuff, currBuff), nil
+}
+
+func (u *UserTable) Drop() error {
+	log.Debugf("dropping table %s %d", u.tableInfo.Name, u.tableInfo.ID)
+	tableStartPrefix := common.AppendUint64ToBufferBE(nil, u.tableInfo.ID)
+	tableEndPrefix := common.AppendUint64ToBufferBE(nil, u.tableInfo.ID+1)
+	return u.cluster.DeleteAllDataInRangeForAllShardsLocally(tableStartPrefix, tableEndPrefix)
+}
+
+func (u *UserTable) AddConsumingExecutor(mvName string, executor exec.PushExecutor) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.tableExecutor.AddConsumingNode(mvName, executor)
+}
+
+func (u *UserTable) RemoveConsumingExecutor(mvName string) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.tableExecutor.RemoveConsumingNode(mvName)
+}
+
+func (u *UserTable) GetConsumingMVs() []string {
+	return u.tableExecutor.GetConsumingMvNames()
+}
+
+func (u *UserTable) TableExecutor() *exec.TableExecutor {
+	return u.tableExecutor
+}
@@ -59,6 +59,54 @@ func EncodeKeyForForwardAggregation(enableDupDetection bool, partialAggTableID u
 	return buff
 }
 
+func EncodeKeyForForwardJoin(enableDupDetection bool, joinTableID uint64, sendingShardID uint64,
+	batchSequence uint64) []byte {
+
+	buff := make([]byte, 0, 33)
+
+	// First byte is whether duplicate detection is enabled or not
+	if enableDupDetection {
+		buff = append(buff, 1)
+	} else {
+		buff = append(buff, 0)
+	}
+
+	// The next 24 bytes is the dedup key and comprises [originator_id (16 bytes), sequence (8 bytes)]
+	// Originator id for forward of a join input is [join_table_id (8 bytes), sending_shard_id (8 bytes) ]
+	// Sequence in this case is the batch number
+	buff = common.AppendUint64ToBufferBE(buff, joinTableID)
+	buff = common.AppendUint64ToBufferBE(buff, sendingShardID)
+	buff = common.AppendUint64ToBufferBE(buff, batchSequence)
+	// The join table id is also the remote consumer id
+	buff = common.AppendUint64ToBufferBE(buff, joinTableID)
+
+	return buff
+}
+
+// EncodeKeyForForwardWrite encodes the key for forwarding a row written by an INSERT, UPDATE or DELETE statement to
+// the shard which owns it. Statements are not retried so duplicate detection is disabled.
+func EncodeKeyForForwardWrite(tableID uint64) []byte {
+	buff := make([]byte, 0, 33)
+	buff = append(buff, 0)
+	// The dedup key is ignored when duplicate detection is disabled, but it must still be present
+	buff = append(buff, make([]byte, 24)...)
+	// The table id is the remote consumer id
+	buff = common.AppendUint64ToBufferBE(buff, tableID)
+	return buff
+}
+
+// EncodeKeyForForwardWatermark encodes the key for forwarding a watermark heartbeat from a source to a shard. A
+// heartbeat only moves the watermark forward, so it doesn't matter if it's received more than once, and duplicate
+// detection is disabled.
+func EncodeKeyForForwardWatermark(sourceID uint64) []byte {
+	buff := make([]byte, 0, 33)
+	buff = append(buff, 0)
+	buff = append(buff, make([]byte, 24)...)
+	// The source id is the remote consumer id
+	buff = common.AppendUint64ToBufferBE(buff, sourceID)
+	return buff
+}
+
 func EncodePrevAndCurrentRow(prevValueBuff []byte, currValueBuff []byte) []byte {
 	lpvb := len(prevValueBuff)
 	lcvb := len(currValueBuff)
@@ -70,6 +118,31 @@ func EncodePrevAndCurrentRow(prevValueBuff []byte, currValueBuff []byte) []byte
 	return buff
 }
 
+// AppendWatermark appends a watermark to an encoded previous and current row, along with the id of the originator of
+// the watermark. The receiving shard keeps the latest watermark from each originator and uses the lowest of them.
+func AppendWatermark(buff []byte, watermark common.Timestamp, originatorID uint64) ([]byte, error) {
+	buff, err := common.AppendTimestampToBuffer(buff, watermark)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return common.AppendUint64ToBufferLE(buff, originatorID), nil
+}
+
+// EncodeWatermarkHeartbeat encodes a watermark with no rows, which is sent to the shards that the rows of a batch
+// weren't sent to, and periodically while the partitions are idle, so the windows on those shards still close. If
+// idle is true the originator has no partitions which aren't idle, and the receiving shard ignores its watermark until
+// it sends another.
+func EncodeWatermarkHeartbeat(watermark common.Timestamp, originatorID uint64, idle bool) ([]byte, error) {
+	buff, err := AppendWatermark(EncodePrevAndCurrentRow(nil, nil), watermark, originatorID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if idle {
+		buff = append(buff, 1)
+	}
+	return buff, nil
+}
+
 func SendForwardBatches(forwardBatches map[uint64]*cluster.WriteBatch, clust cluster.Cluster) error {
 	lb := len(forwardBatches)
 	chs := make([]chan error, 0, lb)
@@ -0,0 +1,112 @@
+package push
+
+import (
+	"time"
+
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/common"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/table"
+)
+
+// originatorTimeout is how long a shard waits to hear from an originator before it no longer holds the watermark
+// back. Sources send their watermark at least every source.watermarkKeepAliveInterval, so an originator that has
+// been silent for longer than this has stopped, e.g. its node was restarted or its partitions were assigned elsewhere.
+const originatorTimeout = 30 * time.Second
+
+// receivedWatermarks holds the latest watermark received from each originator for each remote consumer on a shard,
+// and the watermark of each remote consumer, which is stored in the watermark table so that it's known again after a
+// restart, before any originator has sent one. It's only accessed from the scheduler of the shard.
+type receivedWatermarks struct {
+	shardID     uint64
+	originators map[uint64]map[uint64]originatorWatermark
+	watermarks  map[uint64]*common.Timestamp
+}
+
+type originatorWatermark struct {
+	watermark common.Timestamp
+	received  time.Time
+}
+
+func newReceivedWatermarks(shardID uint64) *receivedWatermarks {
+	return &receivedWatermarks{
+		shardID:     shardID,
+		originators: make(map[uint64]map[uint64]originatorWatermark),
+		watermarks:  make(map[uint64]*common.Timestamp),
+	}
+}
+
+func (r *receivedWatermarks) update(entityID uint64, originatorID uint64, watermark common.Timestamp, now time.Time) {
+	originators, ok := r.originators[entityID]
+	if !ok {
+		originators = make(map[uint64]originatorWatermark)
+		r.originators[entityID] = originators
+	}
+	if prev, ok := originators[originatorID]; ok && watermark.Compare(prev.watermark) < 0 {
+		watermark = prev.watermark
+	}
+	originators[originatorID] = originatorWatermark{watermark: watermark, received: now}
+}
+
+// remove forgets the watermark of an originator which has gone idle, so it doesn't hold the watermark back
+func (r *receivedWatermarks) remove(entityID uint64, originatorID uint64) {
+	delete(r.originators[entityID], originatorID)
+}
+
+// watermark returns the watermark of the remote consumer - the lowest watermark received from any originator that
+// hasn't timed out, or nil if none is known. It never goes backwards, e.g. when an originator with a lower watermark
+// starts sending, and the stored watermark is loaded the first time it's needed. If it advances, it's stored with the
+// write batch.
+func (r *receivedWatermarks) watermark(entityID uint64, now time.Time, storage cluster.Cluster,
+	writeBatch *cluster.WriteBatch) (*common.Timestamp, error) {
+	stored, ok := r.watermarks[entityID]
+	if !ok {
+		var err error
+		stored, err = r.loadWatermark(entityID, storage)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		r.watermarks[entityID] = stored
+	}
+	var lowest *common.Timestamp
+	for originatorID, ow := range r.originators[entityID] {
+		if now.Sub(ow.received) >= originatorTimeout {
+			delete(r.originators[entityID], originatorID)
+			continue
+		}
+		if lowest == nil || ow.watermark.Compare(*lowest) < 0 {
+			wm := ow.watermark
+			lowest = &wm
+		}
+	}
+	if lowest == nil || (stored != nil && lowest.Compare(*stored) <= 0) {
+		return stored, nil
+	}
+	value, err := common.AppendTimestampToBuffer(nil, *lowest)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	writeBatch.AddPut(r.watermarkKey(entityID), value)
+	r.watermarks[entityID] = lowest
+	return lowest, nil
+}
+
+func (r *receivedWatermarks) loadWatermark(entityID uint64, storage cluster.Cluster) (*common.Timestamp, error) {
+	value, err := storage.LocalGet(r.watermarkKey(entityID))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	watermark, _, err := common.ReadTimestampFromBuffer(value, 0, 6)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &watermark, nil
+}
+
+func (r *receivedWatermarks) watermarkKey(entityID uint64) []byte {
+	key := table.EncodeTableKeyPrefix(common.WatermarkTableID, r.shardID, 24)
+	return common.AppendUint64ToBufferBE(key, entityID)
+}
@@ -0,0 +1,78 @@
+package push
+
+import (
+	"testing"
+	"time"
+
+	"github.com/squareup/pranadb/cluster"
+	"github.com/squareup/pranadb/cluster/fake"
+	"github.com/squareup/pranadb/common"
+	"github.com/stretchr/testify/require"
+)
+
+const testShardID = cluster.DataShardIDBase
+
+func TestWatermarkIsLowestOfOriginators(t *testing.T) {
+	clust := fake.NewFakeCluster(0, 10)
+	watermarks := newReceivedWatermarks(testShardID)
+	now := time.Now()
+	watermarks.update(1000, 1, common.NewTimestampFromString("2021-06-01 10:02:00"), now)
+	watermarks.update(1000, 2, common.NewTimestampFromString("2021-06-01 10:01:00"), now)
+	requireWatermark(t, watermarks, 1000, now, clust, "2021-06-01 10:01:00")
+
+	// An idle originator no longer holds the watermark back
+	watermarks.remove(1000, 2)
+	requireWatermark(t, watermarks, 1000, now, clust, "2021-06-01 10:02:00")
+}
+
+func TestWatermarkDoesNotGoBackwards(t *testing.T) {
+	clust := fake.NewFakeCluster(0, 10)
+	watermarks := newReceivedWatermarks(testShardID)
+	now := time.Now()
+	watermarks.update(1000, 1, common.NewTimestampFromString("2021-06-01 10:02:00"), now)
+	requireWatermark(t, watermarks, 1000, now, clust, "2021-06-01 10:02:00")
+	watermarks.update(1000, 1, common.NewTimestampFromString("2021-06-01 10:01:00"), now)
+	watermarks.update(1000, 2, common.NewTimestampFromString("2021-06-01 10:00:00"), now)
+	requireWatermark(t, watermarks, 1000, now, clust, "2021-06-01 10:02:00")
+}
+
+func TestWatermarkOriginatorTimesOut(t *testing.T) {
+	clust := fake.NewFakeCluster(0, 10)
+	watermarks := newReceivedWatermarks(testShardID)
+	now := time.Now()
+	watermarks.update(1000, 1, common.NewTimestampFromString("2021-06-01 10:00:00"), now)
+	watermarks.update(1000, 2, common.NewTimestampFromString("2021-06-01 10:02:00"), now.Add(originatorTimeout/2))
+	requireWatermark(t, watermarks, 1000, now, clust, "2021-06-01 10:00:00")
+	requireWatermark(t, watermarks, 1000, now.Add(originatorTimeout), clust, "2021-06-01 10:02:00")
+}
+
+func TestWatermarkIsStored(t *testing.T) {
+	clust := fake.NewFakeCluster(0, 10)
+	watermarks := newReceivedWatermarks(testShardID)
+	now := time.Now()
+	wb := cluster.NewWriteBatch(testShardID)
+	watermarks.update(1000, 1, common.NewTimestampFromString("2021-06-01 10:02:00"), now)
+	_, err := watermarks.watermark(1000, now, clust, wb)
+	require.NoError(t, err)
+	require.NoError(t, clust.WriteBatch(wb))
+
+	// After a restart the watermark is known before any originator has sent one
+	watermarks = newReceivedWatermarks(testShardID)
+	requireWatermark(t, watermarks, 1000, now, clust, "2021-06-01 10:02:00")
+	watermarks.update(1000, 2, common.NewTimestampFromString("2021-06-01 10:01:00"), now)
+	requireWatermark(t, watermarks, 1000, now, clust, "2021-06-01 10:02:00")
+
+	// Other entities don't have one
+	wm, err := watermarks.watermark(1001, now, clust, cluster.NewWriteBatch(testShardID))
+	require.NoError(t, err)
+	require.Nil(t, wm)
+}
+
+func requireWatermark(t *testing.T, watermarks *receivedWatermarks, entityID uint64, now time.Time,
+	clust cluster.Cluster, expected string) {
+	t.Helper()
+	wm, err := watermarks.watermark(entityID, now, clust, cluster.NewWriteBatch(testShardID))
+	require.NoError(t, err)
+	require.NotNil(t, wm)
+	require.Equal(t, 0, common.NewTimestampFromString(expected).Compare(*wm), "watermark is %s", wm.String())
+}
@@ -16,6 +16,7 @@ type Client interface {
 	SendRequest(message ClusterMessage, timeout time.Duration) (ClusterMessage, error)
 	BroadcastOneway(notif ClusterMessage) error
 	BroadcastSync(notif ClusterMessage) error
+	BroadcastRequest(request ClusterMessage) ([]ClusterMessage, error)
 	Start() error
 	Stop() error
 	AvailabilityListener() AvailabilityListener
@@ -174,6 +175,31 @@ func (c *client) BroadcastSync(notificationMessage ClusterMessage) error {
 	return err
 }
 
+// BroadcastRequest broadcasts a request to all nodes, waits until all nodes have responded and returns the responses.
+// Like BroadcastSync, nodes which aren't available are left out.
+func (c *client) BroadcastRequest(requestMessage ClusterMessage) ([]ClusterMessage, error) {
+	nf := c.createRequest(requestMessage, true)
+	messageBytes, err := nf.serialize(nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	respChan := make(chan error, 10000)
+	ri := &responseInfo{broadcastRespChan: respChan, conns: make(map[*clientConnection]struct{})}
+	c.responseChannels.Store(nf.sequence, ri)
+	if err := c.broadcast(messageBytes, ri); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	err, k := <-respChan
+	if !k {
+		return nil, errors.Error("channel was closed")
+	}
+	c.responseChannels.Delete(nf.sequence)
+	if err != nil {
+		return nil, err
+	}
+	return ri.getResponses(), nil
+}
+
 // BroadcastOneway broadcasts a notification to all members of the cluster, and does not wait for responses
 // Please note that this is best effort: servers will receive notifications only if they are available.
 // Notifications are not persisted and their is no total ordering. Ordering is guaranteed per client instance
@@ -359,6 +385,7 @@ type responseInfo struct {
 	conns             map[*clientConnection]struct{}
 	connCount         int32
 	rpc               bool
+	responses         []ClusterMessage
 }
 
 func (r *responseInfo) responseReceived(conn *clientConnection, resp *ClusterResponse) {
@@ -369,6 +396,12 @@ func (r *responseInfo) responseReceived(conn *clientConnection, resp *ClusterRes
 			// The server received the cluster message but sent back an error response
 			r.broadcastRespChan <- errors.Error(resp.errMsg)
 		} else {
+			// The response must be added before the count is decremented, as the last one unblocks the broadcast
+			if resp.responseMessage != nil {
+				r.lock.Lock()
+				r.responses = append(r.responses, resp.responseMessage)
+				r.lock.Unlock()
+			}
 			r.addToConnCount(-1)
 		}
 	}
@@ -388,6 +421,12 @@ func (r *responseInfo) addToConnCount(val int32) {
 	}
 }
 
+func (r *responseInfo) getResponses() []ClusterMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.responses
+}
+
 func (r *responseInfo) addConn(conn *clientConnection) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
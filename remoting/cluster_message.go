@@ -20,6 +20,8 @@ const (
 	ClusterMessageClusterReadRequest
 	ClusterMessageClusterProposeResponse
 	ClusterMessageClusterReadResponse
+	ClusterMessageSourceStatusRequest
+	ClusterMessageSourceStatusResponse
 )
 
 func TypeForClusterMessage(notification ClusterMessage) ClusterMessageType {
@@ -38,6 +40,10 @@ func TypeForClusterMessage(notification ClusterMessage) ClusterMessageType {
 		return ClusterMessageClusterProposeResponse
 	case *notifications.ClusterReadResponse:
 		return ClusterMessageClusterReadResponse
+	case *notifications.SourceStatusRequest:
+		return ClusterMessageSourceStatusRequest
+	case *notifications.SourceStatusResponse:
+		return ClusterMessageSourceStatusResponse
 	default:
 		return ClusterMessageTypeUnknown
 	}
@@ -75,6 +81,10 @@ func DeserializeClusterMessage(data []byte) (ClusterMessage, error) {
 		msg = &notifications.SessionClosedMessage{}
 	case ClusterMessageReloadProtobuf:
 		msg = &notifications.ReloadProtobuf{}
+	case ClusterMessageSourceStatusRequest:
+		msg = &notifications.SourceStatusRequest{}
+	case ClusterMessageSourceStatusResponse:
+		msg = &notifications.SourceStatusResponse{}
 	default:
 		return nil, errors.Errorf("invalid notification type %d", nt)
 	}
@@ -49,6 +49,20 @@ func (f *FakeServer) BroadcastSync(notif ClusterMessage) error {
 	return f.BroadcastOneway(notif)
 }
 
+func (f *FakeServer) BroadcastRequest(request ClusterMessage) ([]ClusterMessage, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	listener, ok := f.messageHandlers[TypeForClusterMessage(request)]
+	if !ok {
+		panic("no notification listener")
+	}
+	resp, err := listener.HandleMessage(request)
+	if err != nil {
+		return nil, err
+	}
+	return []ClusterMessage{resp}, nil
+}
+
 func (f *FakeServer) ConnectionCount() int {
 	return 0
 }
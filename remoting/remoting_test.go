@@ -4,6 +4,7 @@ import (
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"math/rand"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -319,6 +320,35 @@ func TestSyncBroadcast(t *testing.T) {
 
 }
 
+func TestBroadcastRequest(t *testing.T) {
+	numServers := 3
+
+	servers, listeners := startServers(t, numServers)
+	defer stopServers(t, servers...)
+	var listenAddresses []string
+	for i, server := range servers {
+		listenAddresses = append(listenAddresses, server.ListenAddress())
+		listeners[i].SetReturnVal(&notifications.SourceStatusResponse{NodeId: int64(i)})
+	}
+
+	client := newClient(listenAddresses...)
+	err := client.Start()
+	require.NoError(t, err)
+	defer stopClient(t, client)
+
+	for i := 0; i < 10; i++ {
+		resps, err := client.BroadcastRequest(&notifications.SessionClosedMessage{SessionId: fmt.Sprintf("request%d", i)})
+		require.NoError(t, err)
+		require.Equal(t, numServers, len(resps))
+		var nodeIDs []int
+		for _, resp := range resps {
+			nodeIDs = append(nodeIDs, int(resp.(*notifications.SourceStatusResponse).NodeId)) //nolint:forcetypeassert
+		}
+		sort.Ints(nodeIDs)
+		require.Equal(t, []int{0, 1, 2}, nodeIDs)
+	}
+}
+
 // TestBroadcastSyncServerUnavailable tests that, if a server becomes unavailable due to heartbeat failing then
 // the broadcast sync call will return ok and not hang forever
 //func TestBroadcastSyncServerUnavailable(t *testing.T) {
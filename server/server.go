@@ -78,6 +78,7 @@ func NewServer(config conf.Config) (*Server, error) {
 	remotingServer.RegisterMessageHandler(remoting.ClusterMessageDDLStatement, commandExecutor)
 	remotingServer.RegisterMessageHandler(remoting.ClusterMessageCloseSession, pullEngine)
 	remotingServer.RegisterMessageHandler(remoting.ClusterMessageReloadProtobuf, protoRegistry)
+	remotingServer.RegisterMessageHandler(remoting.ClusterMessageSourceStatusRequest, pushEngine.GetSourceStatusHandler())
 	schemaLoader := schema.NewLoader(metaController, pushEngine, pullEngine)
 	apiServer := api.NewAPIServer(metaController, commandExecutor, protoRegistry, config)
 
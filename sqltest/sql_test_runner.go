@@ -3,6 +3,7 @@ package sqltest
 import (
 	"bufio"
 	"context"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -59,6 +60,9 @@ var (
 const (
 	apiServerListenAddressBase = 63401
 	clientPageSize             = 3 // We set this to a small value to exercise the paging logic
+	// Rows are expired with the --expire rows command in tests, so the results don't depend on when the background
+	// retention check runs
+	retentionCheckInterval = 24 * time.Hour
 )
 
 type sqlTestsuite struct {
@@ -160,6 +164,7 @@ func (w *sqlTestsuite) setupPranaCluster() {
 			"127.0.0.1:63401",
 		}
 		cnf.ProtobufDescriptorDir = ProtoDescriptorDir
+		cnf.RetentionCheckInterval = retentionCheckInterval
 		s, err := server.NewServer(*cnf)
 		if err != nil {
 			log.Fatal(err)
@@ -192,6 +197,7 @@ func (w *sqlTestsuite) setupPranaCluster() {
 			cnf.EnableFailureInjector = true
 			cnf.ScreenDragonLogSpam = true
 			cnf.DisableShardPlacementSanityCheck = true
+			cnf.RetentionCheckInterval = retentionCheckInterval
 			s, err := server.NewServer(*cnf)
 			if err != nil {
 				log.Fatal(err)
@@ -263,7 +269,10 @@ func (w *sqlTestsuite) setup(fakeCluster bool, numNodes int, replicationFactor i
 
 func (w *sqlTestsuite) registerEncoders(registry protolib.Resolver) {
 	w.registerEncoder(&kafka.JSONKeyJSONValueEncoder{})
+	w.registerEncoder(&kafka.JSONKeyTombstoneEncoder{})
 	w.registerEncoder(&kafka.StringKeyTLJSONValueEncoder{})
+	w.registerEncoder(&kafka.StringKeyCSVValueEncoder{})
+	w.registerEncoder(&kafka.StringKeyRawValueEncoder{})
 	w.registerEncoder(&kafka.Int64BEKeyTLJSONValueEncoder{})
 	w.registerEncoder(&kafka.Int32BEKeyTLJSONValueEncoder{})
 	w.registerEncoder(&kafka.Int16BEKeyTLJSONValueEncoder{})
@@ -344,6 +353,14 @@ type sqlTest struct {
 	sessionID     string
 	clientNodeID  int
 	currentSchema string
+	subscriptions map[string]*testSubscription
+	replaceColumn string // The column whose values are masked in the output of the next statement
+}
+
+type testSubscription struct {
+	*client.Subscription
+	mvName string
+	filter string
 }
 
 func (st *sqlTest) run() {
@@ -413,12 +430,26 @@ func (st *sqlTest) runTestIteration(require *require.Assertions, commands []stri
 			st.executeCreateTopic(require, command)
 		} else if strings.HasPrefix(command, "--delete topic") {
 			st.executeDeleteTopic(require, command)
+		} else if strings.HasPrefix(command, "--consume topic") {
+			st.executeConsumeTopic(require, command)
+		} else if strings.HasPrefix(command, "--subscribe") {
+			st.executeSubscribe(require, command)
+		} else if strings.HasPrefix(command, "--receive") {
+			st.executeReceive(require, command)
+		} else if strings.HasPrefix(command, "--resubscribe") {
+			st.executeResubscribe(require, command)
+		} else if strings.HasPrefix(command, "--unsubscribe") {
+			st.executeUnsubscribe(require, command)
 		} else if strings.HasPrefix(command, "--restart cluster") {
 			st.executeRestartCluster(require)
 		} else if strings.HasPrefix(command, "--kafka fail") {
 			st.executeKafkaFail(require, command)
 		} else if strings.HasPrefix(command, "--wait for rows") {
 			st.executeWaitForRows(require, command)
+		} else if strings.HasPrefix(command, "--wait for processing") {
+			st.waitForProcessingToComplete(require)
+		} else if strings.HasPrefix(command, "--expire rows") {
+			st.executeExpireRows(require)
 		} else if strings.HasPrefix(command, "--wait for schedulers") {
 			st.waitForSchedulers(require)
 		} else if strings.HasPrefix(command, "--wait for committed") {
@@ -440,6 +471,8 @@ func (st *sqlTest) runTestIteration(require *require.Assertions, commands []stri
 			}
 		} else if strings.HasPrefix(command, "--pause") {
 			st.executePause(require, command)
+		} else if strings.HasPrefix(command, "--replace column") {
+			st.replaceColumn = trimBothEnds(command[16:])
 		}
 		if strings.HasPrefix(command, "--") {
 			// Just a normal comment - ignore
@@ -676,6 +709,17 @@ func (st *sqlTest) loadDataset(require *require.Assertions, fileName string, dsN
 				encoder = defaultEncoder
 			}
 			colTypes := sourceInfo.TableInfo.ColumnTypes
+			if sourceInfo.ColsVisible != nil {
+				// Generated columns, e.g. the key columns of a source with append semantics, come last and aren't in
+				// the data
+				numVisible := 0
+				for _, visible := range sourceInfo.ColsVisible {
+					if visible {
+						numVisible++
+					}
+				}
+				colTypes = colTypes[:numVisible]
+			}
 			if lp >= 4 {
 				colTypes, err = parseColumnTypes(parts[3])
 				require.NoError(err)
@@ -710,12 +754,25 @@ func (st *sqlTest) loadDataset(require *require.Assertions, fileName string, dsN
 							}
 						}
 						currDataSet.rows.AppendInt64ToColumn(i, val)
+					case common.TypeBoolean:
+						val, err := strconv.ParseBool(part)
+						require.NoError(err)
+						currDataSet.rows.AppendBoolToColumn(i, val)
 					case common.TypeDouble:
 						val, err := strconv.ParseFloat(part, 64)
 						require.NoError(err)
 						currDataSet.rows.AppendFloat64ToColumn(i, val)
 					case common.TypeVarchar:
 						currDataSet.rows.AppendStringToColumn(i, part)
+					case common.TypeVarbinary:
+						// Bytes can be given in hex with a 0x prefix
+						val := []byte(part)
+						if strings.HasPrefix(part, "0x") {
+							var err error
+							val, err = hex.DecodeString(part[2:])
+							require.NoError(err)
+						}
+						currDataSet.rows.AppendBytesToColumn(i, val)
 					case common.TypeDecimal:
 						val, err := common.NewDecFromString(part)
 						require.NoError(err)
@@ -751,13 +808,23 @@ func (st *sqlTest) executeLoadData(require *require.Assertions, command string)
 func (st *sqlTest) doLoadData(require *require.Assertions, command string, noWait bool) {
 	start := time.Now()
 	datasetName := command[12:]
+	// The topic to load the data into can be given for a source which has more than one topic
+	var topicName string
+	if i := strings.Index(datasetName, " to topic "); i != -1 {
+		datasetName, topicName = datasetName[:i], datasetName[i+10:]
+	}
 	dataset, encoder := st.loadDataset(require, st.testDataFile, datasetName)
 	fakeKafka := st.testSuite.fakeKafka
 	var initialCommitted int
 	if !noWait {
 		initialCommitted = st.getNumCommitted(require, dataset.sourceInfo.ID)
 	}
-	err := kafka.IngestRows(fakeKafka, dataset.sourceInfo, dataset.colTypes, dataset.rows, encoder)
+	var err error
+	if topicName != "" {
+		err = kafka.IngestRowsToTopic(fakeKafka, topicName, dataset.sourceInfo, dataset.colTypes, dataset.rows, encoder)
+	} else {
+		err = kafka.IngestRows(fakeKafka, dataset.sourceInfo, dataset.colTypes, dataset.rows, encoder)
+	}
 	require.NoError(err)
 	if !noWait {
 		st.waitForCommitted(require, dataset.rows.RowCount()+initialCommitted, dataset.sourceInfo.ID)
@@ -774,6 +841,9 @@ func (st *sqlTest) executeCloseSession(require *require.Assertions) {
 }
 
 func (st *sqlTest) closeClient(require *require.Assertions) {
+	for _, sub := range st.subscriptions {
+		sub.Close()
+	}
 	err := st.cli.CloseSession(st.sessionID)
 	require.NoError(err)
 	err = st.cli.Stop()
@@ -806,6 +876,129 @@ func (st *sqlTest) executeDeleteTopic(require *require.Assertions, command strin
 	log.Infof("Deleted topic %s ", topicName)
 }
 
+// executeConsumeTopic waits for a number of messages to be published to a topic, e.g. by a sink, and writes them to
+// the output. Messages are ordered by key, and messages with the same key are in the order they were published.
+func (st *sqlTest) executeConsumeTopic(require *require.Assertions, command string) {
+	parts := strings.Split(command, " ")
+	lp := len(parts)
+	require.True(lp == 4, "Invalid consume topic, should be --consume topic topic_name num_messages")
+	topicName := parts[2]
+	numMessages, err := strconv.ParseInt(parts[3], 10, 32)
+	require.NoError(err)
+	topic, ok := st.testSuite.fakeKafka.GetTopic(topicName)
+	require.True(ok, fmt.Sprintf("no such topic %s", topicName))
+	ok, err = commontest.WaitUntilWithError(func() (bool, error) {
+		return topic.TotalMessages() >= int(numMessages), nil
+	}, 10*time.Second, 10*time.Millisecond)
+	require.NoError(err)
+	messages := topic.Messages()
+	require.True(ok, fmt.Sprintf("timed out waiting for %d messages in topic %s, actual %d", numMessages, topicName, len(messages)))
+	require.Equal(int(numMessages), len(messages), fmt.Sprintf("expected %d messages in topic %s", numMessages, topicName))
+	// Messages with the same key are always in the same partition, so a stable sort keeps them in the order they were
+	// published
+	sort.SliceStable(messages, func(i, j int) bool {
+		return string(messages[i].Key) < string(messages[j].Key)
+	})
+	for _, msg := range messages {
+		value := "null"
+		if msg.Value != nil {
+			value = string(msg.Value)
+		}
+		st.output.WriteString(fmt.Sprintf("key: %s value: %s\n", string(msg.Key), value))
+	}
+}
+
+// executeSubscribe subscribes to a materialized view in the current schema, with an optional filter, e.g.
+// --subscribe sub1 test_mv_1 col1 > 3
+func (st *sqlTest) executeSubscribe(require *require.Assertions, command string) {
+	parts := strings.SplitN(command, " ", 4)
+	require.True(len(parts) >= 3, "Invalid subscribe, should be --subscribe subscription_name mv_name [filter]")
+	require.NotEmpty(st.currentSchema, "no schema selected")
+	filter := ""
+	if len(parts) == 4 {
+		filter = parts[3]
+	}
+	st.subscribe(require, parts[1], parts[2], filter, "")
+}
+
+// executeResubscribe subscribes again from the position of a subscription, closing it if it's open, e.g.
+// --resubscribe sub1
+func (st *sqlTest) executeResubscribe(require *require.Assertions, command string) {
+	parts := strings.Split(command, " ")
+	require.True(len(parts) == 2, "Invalid resubscribe, should be --resubscribe subscription_name")
+	sub := st.getSubscription(require, parts[1])
+	sub.Close()
+	st.subscribe(require, parts[1], sub.mvName, sub.filter, sub.Position())
+}
+
+func (st *sqlTest) executeUnsubscribe(require *require.Assertions, command string) {
+	parts := strings.Split(command, " ")
+	require.True(len(parts) == 2, "Invalid unsubscribe, should be --unsubscribe subscription_name")
+	st.getSubscription(require, parts[1]).Close()
+}
+
+func (st *sqlTest) subscribe(require *require.Assertions, subName string, mvName string, filter string, position string) {
+	sub, err := st.cli.Subscribe(st.currentSchema, mvName, filter, position)
+	require.NoError(err)
+	if st.subscriptions == nil {
+		st.subscriptions = make(map[string]*testSubscription)
+	}
+	st.subscriptions[subName] = &testSubscription{Subscription: sub, mvName: mvName, filter: filter}
+}
+
+func (st *sqlTest) getSubscription(require *require.Assertions, subName string) *testSubscription {
+	sub, ok := st.subscriptions[subName]
+	require.True(ok, fmt.Sprintf("no such subscription %s", subName))
+	return sub
+}
+
+// executeReceive waits for a number of lines of output from a subscription and writes them to the output, e.g.
+// --receive sub1 5
+// Rows from different shards can be received in any order, so the rows between snapshot start and snapshot end, and
+// the changes between them, are ordered by their first column. Changes to rows with the same first column are in the
+// order they were received.
+func (st *sqlTest) executeReceive(require *require.Assertions, command string) {
+	parts := strings.Split(command, " ")
+	require.True(len(parts) == 3, "Invalid receive, should be --receive subscription_name num_lines")
+	sub := st.getSubscription(require, parts[1])
+	numLines, err := strconv.ParseInt(parts[2], 10, 32)
+	require.NoError(err)
+	var lines []string
+	timeout := time.After(10 * time.Second)
+	for len(lines) < int(numLines) {
+		select {
+		case line, ok := <-sub.Lines():
+			require.True(ok, fmt.Sprintf("subscription %s ended after %d lines", parts[1], len(lines)))
+			lines = append(lines, line)
+		case <-timeout:
+			require.Fail(fmt.Sprintf("timed out waiting for %d lines from subscription %s, received %d", numLines,
+				parts[1], len(lines)))
+		}
+	}
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i == len(lines) || strings.HasPrefix(lines[i], "snapshot") {
+			segment := lines[start:i]
+			sort.SliceStable(segment, func(i, j int) bool {
+				return firstColumn(segment[i]) < firstColumn(segment[j])
+			})
+			start = i + 1
+		}
+	}
+	for _, line := range lines {
+		st.output.WriteString(line + "\n")
+	}
+}
+
+// firstColumn returns the value of the first column in a line of output such as |1|foo| or insert |1|foo|
+func firstColumn(line string) string {
+	parts := strings.Split(line, "|")
+	if len(parts) < 2 {
+		return line
+	}
+	return parts[1]
+}
+
 func (st *sqlTest) executeRestartCluster(require *require.Assertions) {
 	st.closeClient(require)
 	st.testSuite.restartCluster()
@@ -972,6 +1165,16 @@ func (st *sqlTest) waitForProcessingToComplete(require *require.Assertions) {
 	log.Debug("Waited for processing to complete")
 }
 
+// executeExpireRows deletes the expired rows of sources and materialized views with a retention now, rather than
+// waiting for the next check, then waits for the deletes to be processed
+func (st *sqlTest) executeExpireRows(require *require.Assertions) {
+	for _, prana := range st.testSuite.pranaCluster {
+		err := prana.GetPushEngine().ExpireRows()
+		require.NoError(err)
+	}
+	st.waitForProcessingToComplete(require)
+}
+
 func (st *sqlTest) waitForSchedulers(require *require.Assertions) {
 	log.Debug("Waiting for schedulers to complete")
 	for _, prana := range st.testSuite.pranaCluster {
@@ -987,11 +1190,16 @@ func (st *sqlTest) executeSQLStatement(require *require.Assertions, statement st
 	resChan, err := st.cli.ExecuteStatement(st.sessionID, statement)
 	require.NoError(err)
 	lastLine := ""
+	replaceIndex := -1
 	for line := range resChan {
 		log.Infof("output:%s", line)
+		if st.replaceColumn != "" && strings.HasPrefix(line, "|") {
+			line, replaceIndex = replaceColumnValue(require, line, st.replaceColumn, replaceIndex)
+		}
 		st.output.WriteString(line + "\n")
 		lastLine = line
 	}
+	st.replaceColumn = ""
 	successful := lastLine == "0 rows returned"
 	if isUse && successful {
 		st.currentSchema = statement[4:]
@@ -1001,6 +1209,23 @@ func (st *sqlTest) executeSQLStatement(require *require.Assertions, statement st
 	log.Infof("Statement execute time ms %d", dur.Milliseconds())
 }
 
+// replaceColumnValue replaces the value of the column in a row of the output with #, for values which can't be
+// asserted, e.g. because they depend on the time. The first row is the header, which the index of the column is found
+// from.
+func replaceColumnValue(require *require.Assertions, line string, columnName string, index int) (string, int) {
+	cols := strings.Split(line, "|")
+	if index == -1 {
+		for i, col := range cols {
+			if col == columnName {
+				return line, i
+			}
+		}
+		require.Failf("no column to replace", "no column %s in %s", columnName, line)
+	}
+	cols[index] = "#"
+	return strings.Join(cols, "|"), index
+}
+
 func (st *sqlTest) choosePrana() *server.Server {
 	pranas := st.testSuite.pranaCluster
 	lp := len(pranas)
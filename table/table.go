@@ -30,7 +30,30 @@ func EncodeTableKeyPrefix(tableID uint64, shardID uint64, capac int) []byte {
 
 func encodeKeyFromRow(tableInfo *common.TableInfo, row *common.Row, shardID uint64) ([]byte, error) {
 	keyBuff := EncodeTableKeyPrefix(tableInfo.ID, shardID, 32)
-	return common.EncodeKeyCols(row, tableInfo.PrimaryKeyCols, tableInfo.ColumnTypes, keyBuff)
+	return common.EncodeNullableKeyCols(row, tableInfo.PrimaryKeyCols, tableInfo.ColumnTypes, tableInfo.KeyNullMarkers,
+		keyBuff)
+}
+
+// EncodeRetentionKeyValue encodes the entry of the row in the retention index of the table. Like an index entry the
+// key is the retention column followed by the PK, and the value is the PK. It returns nil if the retention column is
+// null, as the row never expires.
+func EncodeRetentionKeyValue(tableInfo *common.TableInfo, shardID uint64, row *common.Row) ([]byte, []byte, error) {
+	retention := tableInfo.Retention
+	if row.IsNull(retention.ColIndex) {
+		return nil, nil, nil
+	}
+	keyBuff := EncodeTableKeyPrefix(retention.IndexID, shardID, 40)
+	keyBuff, err := common.KeyEncodeTimestamp(keyBuff, row.GetTimestamp(retention.ColIndex))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	pkStart := len(keyBuff)
+	keyBuff, err = common.EncodeNullableKeyCols(row, tableInfo.PrimaryKeyCols, tableInfo.ColumnTypes,
+		tableInfo.KeyNullMarkers, keyBuff)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return keyBuff, keyBuff[pkStart:], nil
 }
 
 func EncodeIndexKeyValue(tableInfo *common.TableInfo, indexInfo *common.IndexInfo, shardID uint64, row *common.Row) ([]byte, []byte, error) {
@@ -44,7 +67,8 @@ func EncodeIndexKeyValue(tableInfo *common.TableInfo, indexInfo *common.IndexInf
 	// It needs to be on the key to make the entry unique (for non unique indexes)
 	// and on the value so we can make looking up the PK easy for non covering indexes without having to parse the
 	// whole key
-	keyBuff, err = common.EncodeKeyCols(row, tableInfo.PrimaryKeyCols, tableInfo.ColumnTypes, keyBuff)
+	keyBuff, err = common.EncodeNullableKeyCols(row, tableInfo.PrimaryKeyCols, tableInfo.ColumnTypes,
+		tableInfo.KeyNullMarkers, keyBuff)
 	if err != nil {
 		return nil, nil, errors.WithStack(err)
 	}
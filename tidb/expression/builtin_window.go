@@ -0,0 +1,250 @@
+package expression
+
+import (
+	"strconv"
+	"strings"
+	gotime "time"
+
+	"github.com/pingcap/parser/mysql"
+	"github.com/squareup/pranadb/errors"
+	"github.com/squareup/pranadb/tidb/sessionctx"
+	"github.com/squareup/pranadb/tidb/types"
+	"github.com/squareup/pranadb/tidb/util/chunk"
+)
+
+// Window functions used to group materialized view aggregations into tumbling and hopping windows over an event time
+// column, e.g. GROUP BY TUMBLE(event_time, INTERVAL 1 MINUTE). The interval arguments are strings such as '1 minute' -
+// the parser does not accept INTERVAL expressions as function arguments so the planner rewrites them to strings before
+// parsing.
+const (
+	Tumble       = "tumble"
+	TumbleStart  = "tumble_start"
+	TumbleEnd    = "tumble_end"
+	TumbleClosed = "tumble_closed"
+	Hop          = "hop"
+	HopStart     = "hop_start"
+	HopEnd       = "hop_end"
+	HopClosed    = "hop_closed"
+)
+
+// WindowFuncNames contains the names of all the window functions.
+var WindowFuncNames = map[string]struct{}{
+	Tumble:       {},
+	TumbleStart:  {},
+	TumbleEnd:    {},
+	TumbleClosed: {},
+	Hop:          {},
+	HopStart:     {},
+	HopEnd:       {},
+	HopClosed:    {},
+}
+
+var windowFuncs = map[string]functionClass{
+	Tumble:       &windowFunctionClass{baseFunctionClass{Tumble, 2, 2}, false, false},
+	TumbleStart:  &windowFunctionClass{baseFunctionClass{TumbleStart, 2, 2}, false, false},
+	TumbleEnd:    &windowFunctionClass{baseFunctionClass{TumbleEnd, 2, 2}, false, true},
+	TumbleClosed: &windowClosedFunctionClass{baseFunctionClass{TumbleClosed, 2, 2}},
+	Hop:          &windowFunctionClass{baseFunctionClass{Hop, 3, 3}, true, false},
+	HopStart:     &windowFunctionClass{baseFunctionClass{HopStart, 3, 3}, true, false},
+	HopEnd:       &windowFunctionClass{baseFunctionClass{HopEnd, 3, 3}, true, true},
+	HopClosed:    &windowClosedFunctionClass{baseFunctionClass{HopClosed, 3, 3}},
+}
+
+func init() {
+	for name, fc := range windowFuncs {
+		funcs[name] = fc
+	}
+}
+
+// WindowSpec describes the windows of a TUMBLE or HOP function. Windows are aligned to the unix epoch. A tumbling
+// window has a slide equal to its size.
+type WindowSpec struct {
+	Size  gotime.Duration
+	Slide gotime.Duration
+}
+
+// NewWindowSpec creates a WindowSpec from the constant interval arguments of a window function.
+func NewWindowSpec(funcName string, args []Expression) (*WindowSpec, error) {
+	intervals := make([]gotime.Duration, len(args)-1)
+	for i, arg := range args[1:] {
+		con, ok := arg.(*Constant)
+		if !ok {
+			return nil, errors.NewInvalidStatementError("Window interval must be a constant")
+		}
+		interval, err := ParseInterval(con.Value.GetString())
+		if err != nil {
+			return nil, err
+		}
+		intervals[i] = interval
+	}
+	if len(intervals) == 1 {
+		return &WindowSpec{Size: intervals[0], Slide: intervals[0]}, nil
+	}
+	// HOP(event_time, slide, size)
+	spec := &WindowSpec{Slide: intervals[0], Size: intervals[1]}
+	if spec.Size%spec.Slide != 0 {
+		return nil, errors.NewPranaErrorf(errors.InvalidStatement, "Window size must be a multiple of window slide in %s", funcName)
+	}
+	return spec, nil
+}
+
+// ParseInterval parses an interval of the form '<n> <unit>', e.g. '10 seconds' or '1 hour'.
+func ParseInterval(interval string) (gotime.Duration, error) {
+	parts := strings.Fields(strings.Trim(interval, "'\""))
+	if len(parts) != 2 {
+		return 0, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid interval '%s'", interval)
+	}
+	n, err := strconv.ParseInt(strings.Trim(parts[0], "'\""), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid interval '%s'", interval)
+	}
+	var unit gotime.Duration
+	switch strings.TrimSuffix(strings.ToLower(parts[1]), "s") {
+	case "millisecond":
+		unit = gotime.Millisecond
+	case "second":
+		unit = gotime.Second
+	case "minute":
+		unit = gotime.Minute
+	case "hour":
+		unit = gotime.Hour
+	case "day":
+		unit = types.GoDurationDay
+	default:
+		return 0, errors.NewPranaErrorf(errors.InvalidStatement, "Invalid interval '%s'", interval)
+	}
+	return gotime.Duration(n) * unit, nil
+}
+
+// LatestWindowStart returns the start of the most recent window that contains t.
+func (w *WindowSpec) LatestWindowStart(t types.Time) (types.Time, error) {
+	gt, err := t.GoTime(gotime.UTC)
+	if err != nil {
+		return types.ZeroTime, errors.WithStack(err)
+	}
+	return w.fromGoTime(gt.Truncate(w.Slide), t), nil
+}
+
+// WindowStarts returns the starts of all the windows that contain t, latest first. As the window size is a multiple of
+// the slide, these are the same for t and for the start of the latest window that contains t.
+func (w *WindowSpec) WindowStarts(t types.Time) ([]types.Time, error) {
+	gt, err := t.GoTime(gotime.UTC)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var starts []types.Time
+	for start := gt.Truncate(w.Slide); start.Add(w.Size).After(gt); start = start.Add(-w.Slide) {
+		starts = append(starts, w.fromGoTime(start, t))
+	}
+	return starts, nil
+}
+
+// WindowEnd returns the (exclusive) end of the window that starts at start.
+func (w *WindowSpec) WindowEnd(start types.Time) (types.Time, error) {
+	gt, err := start.GoTime(gotime.UTC)
+	if err != nil {
+		return types.ZeroTime, errors.WithStack(err)
+	}
+	return w.fromGoTime(gt.Add(w.Size), start), nil
+}
+
+func (w *WindowSpec) fromGoTime(gt gotime.Time, like types.Time) types.Time {
+	return types.NewTime(types.FromGoTime(gt), like.Type(), like.Fsp())
+}
+
+type windowFunctionClass struct {
+	baseFunctionClass
+	hop bool
+	end bool
+}
+
+func (c *windowFunctionClass) getFunction(ctx sessionctx.Context, args []Expression) (builtinFunc, error) {
+	if err := c.verifyArgs(args); err != nil {
+		return nil, err
+	}
+	argTps := []types.EvalType{types.ETDatetime, types.ETString}
+	if c.hop {
+		argTps = append(argTps, types.ETString)
+	}
+	bf, err := newBaseBuiltinFuncWithTp(ctx, c.funcName, args, types.ETDatetime, argTps...)
+	if err != nil {
+		return nil, err
+	}
+	bf.tp.Tp = mysql.TypeTimestamp
+	bf.tp.Flen, bf.tp.Decimal = args[0].GetType().Flen, args[0].GetType().Decimal
+	spec, err := NewWindowSpec(c.funcName, args)
+	if err != nil {
+		return nil, err
+	}
+	return &builtinWindowSig{baseBuiltinFunc: bf, spec: spec, end: c.end}, nil
+}
+
+// builtinWindowSig evaluates a window function for a single row. In a windowed aggregation the window columns are
+// provided by the aggregation itself, so this is only used for a row on its own, where it gives the most recent window
+// that contains the row.
+type builtinWindowSig struct {
+	baseBuiltinFunc
+	spec *WindowSpec
+	end  bool
+}
+
+func (b *builtinWindowSig) Clone() builtinFunc {
+	newSig := &builtinWindowSig{spec: b.spec, end: b.end}
+	newSig.cloneFrom(&b.baseBuiltinFunc)
+	return newSig
+}
+
+func (b *builtinWindowSig) evalTime(row chunk.Row) (types.Time, bool, error) {
+	t, isNull, err := b.args[0].EvalTime(b.ctx, row)
+	if isNull || err != nil {
+		return types.ZeroTime, true, err
+	}
+	start, err := b.spec.LatestWindowStart(t)
+	if err != nil {
+		return types.ZeroTime, true, err
+	}
+	if b.end {
+		end, err := b.spec.WindowEnd(start)
+		return end, false, err
+	}
+	return start, false, nil
+}
+
+type windowClosedFunctionClass struct {
+	baseFunctionClass
+}
+
+func (c *windowClosedFunctionClass) getFunction(ctx sessionctx.Context, args []Expression) (builtinFunc, error) {
+	if err := c.verifyArgs(args); err != nil {
+		return nil, err
+	}
+	argTps := []types.EvalType{types.ETDatetime, types.ETString}
+	if len(args) == 3 {
+		argTps = append(argTps, types.ETString)
+	}
+	bf, err := newBaseBuiltinFuncWithTp(ctx, c.funcName, args, types.ETInt, argTps...)
+	if err != nil {
+		return nil, err
+	}
+	bf.tp.Flen = 1
+	if _, err := NewWindowSpec(c.funcName, args); err != nil {
+		return nil, err
+	}
+	return &builtinWindowClosedSig{bf}, nil
+}
+
+// builtinWindowClosedSig is replaced with the closed state of the window when it is used in the select list of a
+// windowed aggregation, it can't be evaluated for a row on its own.
+type builtinWindowClosedSig struct {
+	baseBuiltinFunc
+}
+
+func (b *builtinWindowClosedSig) Clone() builtinFunc {
+	newSig := &builtinWindowClosedSig{}
+	newSig.cloneFrom(&b.baseBuiltinFunc)
+	return newSig
+}
+
+func (b *builtinWindowClosedSig) evalInt(row chunk.Row) (int64, bool, error) {
+	return 0, true, errors.NewInvalidStatementError("Window closed functions can only be used with a windowed aggregation")
+}
@@ -280,6 +280,10 @@ func (c *Constant) EvalDecimal(ctx sessionctx.Context, row chunk.Row) (*types.My
 	if c.GetType().Tp == mysql.TypeNull || dt.IsNull() {
 		return nil, true, nil
 	}
+	if dt.Kind() == types.KindMysqlDecimal {
+		// Decimal literals are evaluated without a session context
+		return dt.GetMysqlDecimal(), false, nil
+	}
 	res, err := dt.ToDecimal(ctx.GetSessionVars().StmtCtx)
 	return res, false, err
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/squareup/pranadb/errors"
 	"github.com/squareup/pranadb/tidb"
 	"github.com/squareup/pranadb/tidb/expression"
+	"github.com/squareup/pranadb/tidb/expression/aggregation"
 	"github.com/squareup/pranadb/tidb/sessionctx"
 	"github.com/squareup/pranadb/tidb/types"
 	driver "github.com/squareup/pranadb/tidb/types/parser_driver"
@@ -255,6 +256,433 @@ func (er *expressionRewriter) buildSubquery(subq *ast.SubqueryExpr) (LogicalPlan
 	return np, nil
 }
 
+// handleExistSubquery rewrites [NOT] EXISTS (SELECT ...). The subquery is outer joined to the plan on the equality
+// conditions which correlate it with the outer query, after removing duplicates, so the result is whether a row was
+// joined. An uncorrelated subquery is replaced by a count of its rows.
+func (er *expressionRewriter) handleExistSubquery(v *ast.ExistsSubqueryExpr) (ast.Node, bool) {
+	subq, ok := v.Sel.(*ast.SubqueryExpr)
+	if !ok {
+		er.err = errors.Errorf("unknown exists type %T", v.Sel)
+		return v, true
+	}
+	np, err := er.buildSubquery(subq)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	np, outerCols, innerCols, err := er.decorrelate(np)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	var marker expression.Expression
+	if len(outerCols) > 0 {
+		distinct, err := er.buildDistinct(np, innerCols)
+		if err != nil {
+			er.err = err
+			return v, true
+		}
+		conds, err := er.correlatedConds(outerCols, distinct.Schema().Columns)
+		if err != nil {
+			er.err = err
+			return v, true
+		}
+		cols := er.joinSubquery(distinct, conds)
+		marker, err = er.newFunction(ast.IsNull, types.NewFieldType(mysql.TypeTiny), cols[0])
+		if err != nil {
+			er.err = err
+			return v, true
+		}
+		if !v.Not {
+			marker, err = er.newFunction(ast.UnaryNot, types.NewFieldType(mysql.TypeTiny), marker)
+			if err != nil {
+				er.err = err
+				return v, true
+			}
+		}
+	} else {
+		count, err := er.buildCount(np)
+		if err != nil {
+			er.err = err
+			return v, true
+		}
+		cols := er.joinSubquery(count, nil)
+		op := ast.GT
+		if v.Not {
+			op = ast.EQ
+		}
+		marker, err = er.newFunction(op, types.NewFieldType(mysql.TypeTiny), cols[0], expression.NewZero())
+		if err != nil {
+			er.err = err
+			return v, true
+		}
+	}
+	er.ctxStackAppend(marker, types.EmptyName)
+	return v, true
+}
+
+// handleInSubquery rewrites expr IN (SELECT ...). The distinct values of the subquery are outer joined to the plan on
+// equality with the expression, so the result is whether a value was joined.
+func (er *expressionRewriter) handleInSubquery(v *ast.PatternInExpr) (ast.Node, bool) {
+	if v.Not {
+		er.err = errors.NewInvalidStatementError("NOT IN with a subquery is not supported, use NOT EXISTS instead")
+		return v, true
+	}
+	asScalar := er.asScalar
+	er.asScalar = true
+	v.Expr.Accept(er)
+	er.asScalar = asScalar
+	if er.err != nil {
+		return v, true
+	}
+	lexpr := er.ctxStack[len(er.ctxStack)-1]
+	er.ctxStackPop(1)
+	if expression.GetRowLen(lexpr) != 1 {
+		er.err = tidb.ErrOperandColumns.GenWithStackByArgs(1)
+		return v, true
+	}
+	subq, ok := v.Sel.(*ast.SubqueryExpr)
+	if !ok {
+		er.err = errors.Errorf("unknown compare type %T", v.Sel)
+		return v, true
+	}
+	np, err := er.buildSubquery(subq)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	if np.Schema().Len() != 1 {
+		er.err = tidb.ErrOperandColumns.GenWithStackByArgs(1)
+		return v, true
+	}
+	np, outerCols, innerCols, err := er.decorrelate(np)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	distinctCols := append([]*expression.Column{np.Schema().Columns[0]}, innerCols...)
+	distinct, err := er.buildDistinct(np, distinctCols)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	eq, err := er.constructBinaryOpFunction(lexpr, distinct.Schema().Columns[0], ast.EQ)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	conds, err := er.correlatedConds(outerCols, distinct.Schema().Columns[1:])
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	cols := er.joinSubquery(distinct, append([]expression.Expression{eq}, conds...))
+	marker, err := er.newFunction(ast.IsNull, types.NewFieldType(mysql.TypeTiny), cols[0])
+	if err == nil {
+		marker, err = er.newFunction(ast.UnaryNot, types.NewFieldType(mysql.TypeTiny), marker)
+	}
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	er.ctxStackAppend(marker, types.EmptyName)
+	return v, true
+}
+
+// handleScalarSubquery rewrites a subquery used as a value. The subquery is outer joined to the plan on the equality
+// conditions which correlate it with the outer query, and the value is its column in the join. The subquery must not
+// return more than one row for each row of the outer query, which is always the case if it aggregates, as it is then
+// grouped by the correlated columns.
+func (er *expressionRewriter) handleScalarSubquery(v *ast.SubqueryExpr) (ast.Node, bool) {
+	np, err := er.buildSubquery(v)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	if np.Schema().Len() != 1 {
+		er.err = tidb.ErrOperandColumns.GenWithStackByArgs(1)
+		return v, true
+	}
+	count := isCount(np)
+	np, outerCols, innerCols, err := er.decorrelate(np)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	conds, err := er.correlatedConds(outerCols, innerCols)
+	if err != nil {
+		er.err = err
+		return v, true
+	}
+	cols := er.joinSubquery(np, conds)
+	if !isSingleRow(np) {
+		// Whether it returns more than one row for a row of the outer query can only be checked as the rows are joined
+		er.p.(*LogicalJoin).ScalarSubquery = true //nolint:forcetypeassert
+	}
+	var value expression.Expression = cols[0]
+	if count && len(outerCols) > 0 {
+		// There are no rows to join when the count is zero
+		value, err = er.newFunction(ast.Ifnull, cols[0].RetType, cols[0], expression.NewZero())
+		if err != nil {
+			er.err = err
+			return v, true
+		}
+	}
+	er.ctxStackAppend(value, types.EmptyName)
+	return v, true
+}
+
+// decorrelate removes the equality conditions between columns of the outer query and columns of the subquery from the
+// WHERE clause of the subquery, and adds the columns of the subquery to its output, so it can be joined to the outer
+// query instead. If the subquery aggregates, the aggregation is grouped by the columns. It returns the columns of the
+// outer query and the corresponding columns added to the output of the subquery.
+func (er *expressionRewriter) decorrelate(np LogicalPlan) (LogicalPlan, []*expression.Column, []*expression.Column, error) {
+	if !hasCorrelatedColumns(np) {
+		return np, nil, nil, nil
+	}
+	errUnsupported := errors.NewInvalidStatementError("Correlated subqueries are only supported with equality conditions in the WHERE clause")
+	proj, ok := np.(*LogicalProjection)
+	if !ok {
+		return nil, nil, nil, errUnsupported
+	}
+	var agg *LogicalAggregation
+	child := proj.Children()[0]
+	if a, ok := child.(*LogicalAggregation); ok {
+		if len(a.GroupByItems) > 0 {
+			return nil, nil, nil, errUnsupported
+		}
+		agg = a
+		child = a.Children()[0]
+	}
+	sel, ok := child.(*LogicalSelection)
+	if !ok {
+		return nil, nil, nil, errUnsupported
+	}
+	var outerCols, innerCols []*expression.Column
+	conds := make([]expression.Expression, 0, len(sel.Conditions))
+	for _, cond := range sel.Conditions {
+		outerCol, innerCol := er.correlatedEquality(cond)
+		if outerCol == nil {
+			conds = append(conds, cond)
+			continue
+		}
+		outerCols = append(outerCols, outerCol)
+		innerCols = append(innerCols, innerCol)
+	}
+	if len(conds) > 0 {
+		sel.Conditions = conds
+	} else if agg != nil {
+		agg.SetChildren(sel.Children()[0])
+	} else {
+		proj.SetChildren(sel.Children()[0])
+	}
+	if hasCorrelatedColumns(np) {
+		return nil, nil, nil, errUnsupported
+	}
+	if agg != nil {
+		for i, col := range innerCols {
+			agg.GroupByItems = append(agg.GroupByItems, col)
+			if idx := agg.Schema().ColumnIndex(col); idx >= 0 {
+				innerCols[i] = agg.Schema().Columns[idx]
+				continue
+			}
+			firstRow, err := aggregation.NewAggFuncDesc(er.sctx, ast.AggFuncFirstRow, []expression.Expression{col}, false)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			agg.AggFuncs = append(agg.AggFuncs, firstRow)
+			aggCol, _ := col.Clone().(*expression.Column)
+			aggCol.RetType = firstRow.RetTp
+			agg.Schema().Append(aggCol)
+			agg.SetOutputNames(append(agg.OutputNames(), types.EmptyName))
+			innerCols[i] = aggCol
+		}
+	}
+	names := proj.OutputNames()
+	for i, col := range innerCols {
+		proj.Exprs = append(proj.Exprs, col)
+		projCol := &expression.Column{
+			UniqueID: er.sctx.GetSessionVars().AllocPlanColumnID(),
+			RetType:  col.RetType,
+		}
+		proj.Schema().Append(projCol)
+		names = append(names, types.EmptyName)
+		innerCols[i] = projCol
+	}
+	proj.SetOutputNames(names)
+	return proj, outerCols, innerCols, nil
+}
+
+// correlatedEquality returns the columns of a condition which is an equality between a column of the outer query and
+// a column of the subquery, or nil if it isn't one.
+func (er *expressionRewriter) correlatedEquality(cond expression.Expression) (*expression.Column, *expression.Column) {
+	f, ok := cond.(*expression.ScalarFunction)
+	if !ok || f.FuncName.L != ast.EQ {
+		return nil, nil
+	}
+	args := f.GetArgs()
+	for i := 0; i < 2; i++ {
+		corCol, ok := args[i].(*expression.CorrelatedColumn)
+		if !ok {
+			continue
+		}
+		innerCol, ok := args[1-i].(*expression.Column)
+		if !ok || er.schema == nil {
+			return nil, nil
+		}
+		outerCol := er.schema.RetrieveColumn(&corCol.Column)
+		if outerCol == nil {
+			return nil, nil
+		}
+		return outerCol, innerCol
+	}
+	return nil, nil
+}
+
+func (er *expressionRewriter) correlatedConds(outerCols []*expression.Column, innerCols []*expression.Column) ([]expression.Expression, error) {
+	conds := make([]expression.Expression, len(outerCols))
+	for i, outerCol := range outerCols {
+		cond, err := er.newFunction(ast.EQ, types.NewFieldType(mysql.TypeTiny), outerCol, innerCols[i])
+		if err != nil {
+			return nil, err
+		}
+		conds[i] = cond
+	}
+	return conds, nil
+}
+
+// buildDistinct builds an aggregation which outputs the distinct values of the columns.
+func (er *expressionRewriter) buildDistinct(p LogicalPlan, cols []*expression.Column) (LogicalPlan, error) {
+	er.b.optFlag |= flagBuildKeyInfo
+	er.b.optFlag |= flagEliminateAgg
+	agg := LogicalAggregation{AggFuncs: make([]*aggregation.AggFuncDesc, 0, len(cols))}.Init(er.sctx, er.b.getSelectOffset())
+	schema := expression.NewSchema(make([]*expression.Column, 0, len(cols))...)
+	names := make(types.NameSlice, 0, len(cols))
+	for _, col := range cols {
+		firstRow, err := aggregation.NewAggFuncDesc(er.sctx, ast.AggFuncFirstRow, []expression.Expression{col}, false)
+		if err != nil {
+			return nil, err
+		}
+		agg.AggFuncs = append(agg.AggFuncs, firstRow)
+		agg.GroupByItems = append(agg.GroupByItems, col)
+		schema.Append(&expression.Column{
+			UniqueID: er.sctx.GetSessionVars().AllocPlanColumnID(),
+			RetType:  firstRow.RetTp,
+		})
+		names = append(names, types.EmptyName)
+	}
+	agg.SetChildren(p)
+	agg.setSchemaAndNames(schema, names)
+	return agg, nil
+}
+
+// buildCount builds an aggregation which outputs the number of rows.
+func (er *expressionRewriter) buildCount(p LogicalPlan) (LogicalPlan, error) {
+	er.b.optFlag |= flagEliminateAgg
+	count, err := aggregation.NewAggFuncDesc(er.sctx, ast.AggFuncCount, []expression.Expression{expression.NewOne()}, false)
+	if err != nil {
+		return nil, err
+	}
+	agg := LogicalAggregation{AggFuncs: []*aggregation.AggFuncDesc{count}}.Init(er.sctx, er.b.getSelectOffset())
+	agg.SetChildren(p)
+	agg.setSchemaAndNames(expression.NewSchema(&expression.Column{
+		UniqueID: er.sctx.GetSessionVars().AllocPlanColumnID(),
+		RetType:  count.RetTp,
+	}), types.NameSlice{types.EmptyName})
+	return agg, nil
+}
+
+// joinSubquery left outer joins the plan of a subquery to the plan being rewritten, and returns the columns of the
+// subquery in the schema of the join.
+func (er *expressionRewriter) joinSubquery(inner LogicalPlan, conds []expression.Expression) []*expression.Column {
+	er.b.optFlag |= flagPredicatePushDown
+	er.b.optFlag |= flagEliminateOuterJoin
+	outer := er.p
+	outerLen := outer.Schema().Len()
+	join := LogicalJoin{JoinType: LeftOuterJoin}.Init(er.sctx, er.b.getSelectOffset())
+	join.SetChildren(outer, inner)
+	join.SetSchema(expression.MergeSchema(outer.Schema(), inner.Schema()))
+	join.names = make([]*types.FieldName, outerLen+inner.Schema().Len())
+	copy(join.names, outer.OutputNames())
+	for i := outerLen; i < len(join.names); i++ {
+		join.names[i] = types.EmptyName
+	}
+	resetNotNullFlag(join.schema, outerLen, join.schema.Len())
+	join.AttachOnConds(conds)
+	er.p = join
+	er.schema = join.Schema()
+	er.names = join.OutputNames()
+	return join.Schema().Columns[outerLen:]
+}
+
+// hasCorrelatedColumns returns whether any expression in the plan refers to a column of an outer query.
+func hasCorrelatedColumns(p LogicalPlan) bool {
+	var exprs []expression.Expression
+	switch x := p.(type) {
+	case *LogicalProjection:
+		exprs = x.Exprs
+	case *LogicalSelection:
+		exprs = x.Conditions
+	case *LogicalAggregation:
+		exprs = append(exprs, x.GroupByItems...)
+		for _, aggFunc := range x.AggFuncs {
+			exprs = append(exprs, aggFunc.Args...)
+		}
+	case *LogicalJoin:
+		for _, cond := range x.EqualConditions {
+			exprs = append(exprs, cond)
+		}
+		exprs = append(exprs, x.LeftConditions...)
+		exprs = append(exprs, x.RightConditions...)
+		exprs = append(exprs, x.OtherConditions...)
+	}
+	for _, expr := range exprs {
+		if len(expression.ExtractCorColumns(expr)) > 0 {
+			return true
+		}
+	}
+	for _, child := range p.Children() {
+		if hasCorrelatedColumns(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCount returns whether the value of a subquery is a COUNT aggregate
+// isSingleRow returns whether the subquery returns at most one row for each value of the columns it is grouped by
+func isSingleRow(p LogicalPlan) bool {
+	for {
+		switch x := p.(type) {
+		case *LogicalProjection, *LogicalSelection:
+			p = x.Children()[0]
+		case *LogicalAggregation:
+			return true
+		case *LogicalLimit:
+			return x.Count <= 1
+		default:
+			return false
+		}
+	}
+}
+
+func isCount(p LogicalPlan) bool {
+	proj, ok := p.(*LogicalProjection)
+	if !ok {
+		return false
+	}
+	col, ok := proj.Exprs[0].(*expression.Column)
+	if !ok {
+		return false
+	}
+	agg, ok := proj.Children()[0].(*LogicalAggregation)
+	if !ok {
+		return false
+	}
+	idx := agg.Schema().ColumnIndex(col)
+	return idx >= 0 && idx < len(agg.AggFuncs) && agg.AggFuncs[idx].Name == ast.AggFuncCount
+}
+
 // Enter implements Visitor interface.
 func (er *expressionRewriter) Enter(inNode ast.Node) (ast.Node, bool) {
 	switch v := inNode.(type) {
@@ -287,7 +715,17 @@ func (er *expressionRewriter) Enter(inNode ast.Node) (ast.Node, bool) {
 			er.ctxStackAppend(er.schema.Columns[index], er.names[index])
 			return inNode, true
 		}
+	case *ast.SubqueryExpr:
+		return er.handleScalarSubquery(v)
+	case *ast.ExistsSubqueryExpr:
+		return er.handleExistSubquery(v)
+	case *ast.CompareSubqueryExpr:
+		er.err = errors.NewInvalidStatementError("Subqueries with ANY, SOME or ALL are not supported")
+		return inNode, true
 	case *ast.PatternInExpr:
+		if v.Sel != nil {
+			return er.handleInSubquery(v)
+		}
 		if len(v.List) != 1 {
 			break
 		}
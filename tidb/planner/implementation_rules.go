@@ -63,6 +63,9 @@ var defaultImplementationMap = map[Operand][]ImplementationRule{
 	OperandUnionAll: {
 		&ImplUnionAll{},
 	},
+	OperandJoin: {
+		&ImplHashJoin{},
+	},
 }
 
 // ImplProjection implements LogicalProjection as PhysicalProjection.
@@ -302,6 +305,31 @@ func (r *ImplUnionAll) OnImplement(expr *GroupExpr, reqProp *property.PhysicalPr
 	return []Implementation{NewUnionAllImpl(physicalUnion)}, nil
 }
 
+// ImplHashJoin implements LogicalJoin to PhysicalHashJoin.
+type ImplHashJoin struct {
+}
+
+// Match implements ImplementationRule Match interface.
+func (r *ImplHashJoin) Match(expr *GroupExpr, prop *property.PhysicalProperty) (matched bool) {
+	return prop.IsEmpty()
+}
+
+// OnImplement implements ImplementationRule OnImplement interface.
+func (r *ImplHashJoin) OnImplement(expr *GroupExpr, reqProp *property.PhysicalProperty) ([]Implementation, error) {
+	logicalJoin := expr.ExprNode.(*LogicalJoin)
+	chReqProps := make([]*property.PhysicalProperty, len(expr.Children))
+	for i := range expr.Children {
+		chReqProps[i] = &property.PhysicalProperty{ExpectedCnt: math.MaxFloat64}
+	}
+	hashJoin := NewPhysicalHashJoin(
+		logicalJoin,
+		expr.Group.Prop.Stats.ScaleByExpectCnt(reqProp.ExpectedCnt),
+		chReqProps...,
+	)
+	hashJoin.SetSchema(expr.Group.Prop.Schema)
+	return []Implementation{NewHashJoinImpl(hashJoin)}, nil
+}
+
 // matchItems checks if this prop's columns can match by items totally.
 func matchItems(p *property.PhysicalProperty, items []*util.ByItems) bool {
 	if len(items) < len(p.SortItems) {
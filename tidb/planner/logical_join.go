@@ -35,6 +35,9 @@ type LogicalJoin struct {
 	reordered     bool
 	cartesianJoin bool
 	StraightJoin  bool
+	// ScalarSubquery is true if the right of the join is a scalar subquery that could return more than one row for a
+	// row on the left, which is an error
+	ScalarSubquery bool
 
 	preferJoinType uint
 
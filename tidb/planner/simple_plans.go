@@ -134,3 +134,21 @@ func (impl *UnionAllImpl) GetCostLimit(costLimit float64, children ...Implementa
 func NewUnionAllImpl(union *PhysicalUnionAll) *UnionAllImpl {
 	return &UnionAllImpl{baseImpl{plan: union}}
 }
+
+// HashJoinImpl is the implementation of PhysicalHashJoin.
+type HashJoinImpl struct {
+	baseImpl
+}
+
+// CalcCost implements Implementation CalcCost interface.
+func (impl *HashJoinImpl) CalcCost(outCount float64, children ...Implementation) float64 {
+	hashJoin := impl.plan.(*PhysicalHashJoin)
+	selfCost := hashJoin.GetCost(children[0].GetPlan().Stats().RowCount, children[1].GetPlan().Stats().RowCount)
+	impl.cost = selfCost + children[0].GetCost() + children[1].GetCost()
+	return impl.cost
+}
+
+// NewHashJoinImpl creates a new HashJoinImpl.
+func NewHashJoinImpl(hashJoin *PhysicalHashJoin) *HashJoinImpl {
+	return &HashJoinImpl{baseImpl{plan: hashJoin}}
+}
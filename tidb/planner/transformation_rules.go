@@ -63,7 +63,7 @@ var PullQueryBatch = []TransformationRuleBatch{
 }
 
 var PushQueryBatch = []TransformationRuleBatch{
-	Batch1,
+	PushQueryBatch1,
 	PushQueryBatch2,
 	PostTransformationBatch,
 }
@@ -108,6 +108,47 @@ var Batch1 = TransformationRuleBatch{
 	},
 }
 
+// PushQueryBatch1 is the same as Batch1 without EliminateSingleMaxMin - a push query must keep a MIN or MAX as an
+// aggregation so it can be maintained as rows are added and removed.
+var PushQueryBatch1 = TransformationRuleBatch{
+	OperandSelection: {
+		NewRulePushSelDownSort(),
+		NewRulePushSelDownProjection(),
+		NewRulePushSelDownAggregation(),
+		NewRulePushSelDownJoin(),
+		NewRulePushSelDownUnionAll(),
+		NewRuleMergeAdjacentSelection(),
+	},
+	OperandAggregation: {
+		NewRuleMergeAggregationProjection(),
+		NewRuleEliminateOuterJoinBelowAggregation(),
+		NewRuleTransformAggregateCaseToSelection(),
+		NewRuleTransformAggToProj(),
+	},
+	OperandLimit: {
+		NewRuleTransformLimitToTopN(),
+		NewRulePushLimitDownProjection(),
+		NewRulePushLimitDownUnionAll(),
+		NewRulePushLimitDownOuterJoin(),
+		NewRuleMergeAdjacentLimit(),
+		NewRuleTransformLimitToTableDual(),
+	},
+	OperandProjection: {
+		NewRuleEliminateProjection(),
+		NewRuleMergeAdjacentProjection(),
+		NewRuleEliminateOuterJoinBelowProjection(),
+	},
+	OperandTopN: {
+		NewRulePushTopNDownProjection(),
+		NewRulePushTopNDownOuterJoin(),
+		NewRulePushTopNDownUnionAll(),
+		NewRuleMergeAdjacentTopN(),
+	},
+	OperandJoin: {
+		NewRuleTransformJoinCondToSel(),
+	},
+}
+
 var Batch2 = TransformationRuleBatch{
 	OperandDataSource: {
 		NewRuleDSToScans(),